@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/archive"
+	"goci/backend/audit"
+	"goci/backend/auth"
+)
+
+// Export 处理导出Schema归档的请求
+//
+// ids查询参数是逗号分隔的Schema ID，为空时导出调用者可以查看的全部Schema；format为tar.gz（默认）或zip
+func (h *SchemaHandler) Export(c *gin.Context) {
+	format, err := archive.ParseFormat(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var ids []string
+	for _, id := range strings.Split(c.Query("ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		schemas, err := h.storage.ListSchemas()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for _, schema := range schemas {
+			if auth.Allowed(c, schema.ID, auth.RoleViewer) {
+				ids = append(ids, schema.ID)
+			}
+		}
+	} else {
+		for _, id := range ids {
+			if !auth.Allowed(c, id, auth.RoleViewer) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "viewer role required on schema " + id})
+				return
+			}
+		}
+	}
+
+	// 没有可以导出的Schema时导出空归档，而不是全部Schema
+	schemas := []archive.Schema{}
+	if len(ids) > 0 {
+		schemas, err = archive.Collect(h.storage, ids)
+		if err != nil {
+			c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 先写入缓冲区，出错时仍然可以返回JSON错误
+	var buf bytes.Buffer
+	if err := archive.Write(&buf, schemas, format); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("schemas-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, format.ContentType(), buf.Bytes())
+}
+
+// errEditorRequired 表示调用者没有归档中某个目标Schema的editor角色
+var errEditorRequired = errors.New("editor role required on schema")
+
+// Import 处理导入Schema归档的请求，请求体是tar.gz或zip归档
+//
+// policy查询参数是ID冲突时的策略：skip（默认）、overwrite或rename；dryRun=true时只返回报告。
+// 写入中途失败时已写入的Schema会被撤销，响应的report中rolledBack为true；撤销也失败时partial为true
+func (h *SchemaHandler) Import(c *gin.Context) {
+	policy, err := archive.ParsePolicy(c.Query("policy"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.Query("dryRun"))
+
+	schemas, err := archive.Read(http.MaxBytesReader(c.Writer, c.Request.Body, archive.MaxSize))
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, archive.ErrInvalidArchive):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 授权检查在Import中进行，检查的正是随后写入的计划
+	report, err := archive.Import(audit.Schemas(c, h.storage), schemas, archive.Options{
+		Policy:  policy,
+		DryRun:  dryRun,
+		Resolve: viewableRefs(c, h.storage),
+		Authorize: func(result archive.Result) error {
+			if !auth.Allowed(c, result.TargetID, auth.RoleEditor) {
+				return fmt.Errorf("%w %s", errEditorRequired, result.TargetID)
+			}
+			return nil
+		},
+	})
+
+	switch {
+	case errors.Is(err, errEditorRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, archive.ErrImportFailed):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "report": report})
+	case err != nil:
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error(), "report": report})
+	default:
+		c.JSON(http.StatusOK, report)
+	}
+}
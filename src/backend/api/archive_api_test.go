@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/archive"
+	"goci/backend/auth"
+	"goci/backend/storage"
+)
+
+// 测试从一个环境导出Schema并导入另一个环境
+func TestArchiveAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func() (*gin.Engine, storage.SchemaStore) {
+		r := gin.New()
+		schemas := storage.NewMemorySchemaStore()
+		RegisterRoutes(r, schemas, nil)
+		return r, schemas
+	}
+	request := func(r *gin.Engine, method, path string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	dev, devSchemas := newRouter()
+	for _, id := range []string{"app", "db", "cache"} {
+		if err := devSchemas.SaveSchema(id, id, "", []byte(`{"type": "object"}`)); err != nil {
+			t.Fatalf("Failed to save schema: %v", err)
+		}
+	}
+
+	w := request(dev, http.MethodGet, "/api/export?ids=app,db&format=zip", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Type") != "application/zip" {
+		t.Errorf("Unexpected content type %q", w.Header().Get("Content-Type"))
+	}
+	exported := w.Body.Bytes()
+
+	w = request(dev, http.MethodGet, "/api/export?ids=missing", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+	w = request(dev, http.MethodGet, "/api/export?format=rar", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	prod, prodSchemas := newRouter()
+	if err := prodSchemas.SaveSchema("app", "App", "", []byte(`{"type": "string"}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+
+	// dryRun只返回报告
+	w = request(prod, http.MethodPost, "/api/import?policy=rename&dryRun=true", exported)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var report archive.Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if !report.DryRun || len(report.Schemas) != 2 || report.Schemas[0].TargetID != "app-2" || report.Schemas[1].Action != archive.ActionCreate {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if list, _ := prodSchemas.ListSchemas(); len(list) != 1 {
+		t.Errorf("Dry run saved schemas: %+v", list)
+	}
+
+	w = request(prod, http.MethodPost, "/api/import?policy=rename", exported)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	for _, id := range []string{"app", "app-2", "db"} {
+		if _, _, err := prodSchemas.GetSchema(id); err != nil {
+			t.Errorf("Failed to get %s: %v", id, err)
+		}
+	}
+
+	// 没有目标Schema的editor角色时不写入任何Schema
+	tokens, err := auth.NewTokenAuthenticator([]auth.Token{
+		{Name: "team", Token: "secret", Grants: []auth.Grant{{Role: auth.RoleEditor, Schema: "app*"}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	staging := gin.New()
+	staging.Use(auth.Middleware([]auth.Authenticator{tokens}, nil))
+	stagingSchemas := storage.NewMemorySchemaStore()
+	RegisterRoutes(staging, stagingSchemas, nil)
+	req := httptest.NewRequest(http.MethodPost, "/api/import", bytes.NewReader(exported))
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	staging.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden || !bytes.Contains(w.Body.Bytes(), []byte("editor role required on schema db")) {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusForbidden, w.Code, w.Body.String())
+	}
+	if list, _ := stagingSchemas.ListSchemas(); len(list) != 0 {
+		t.Errorf("Forbidden import saved schemas: %+v", list)
+	}
+
+	w = request(prod, http.MethodPost, "/api/import?policy=replace", exported)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	w = request(prod, http.MethodPost, "/api/import", []byte(`{"type": "object"}`))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
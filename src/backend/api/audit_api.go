@@ -0,0 +1,89 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/audit"
+	"goci/backend/auth"
+)
+
+// defaultAuditLimit 是未指定limit时最多返回的审计记录数
+const defaultAuditLimit = 100
+
+// AuditHandler 处理审计记录的查询
+type AuditHandler struct {
+	log *audit.Log
+}
+
+// NewAuditHandler 创建一个新的AuditHandler实例
+func NewAuditHandler(log *audit.Log) *AuditHandler {
+	return &AuditHandler{log: log}
+}
+
+// QueryAudit 按target、actor和since查询审计记录，按序号从新到旧返回
+//
+// since是RFC 3339格式的时间；启用认证时只返回调用者拥有admin角色的Schema的记录。
+// 还有更早的记录时响应包含nextCursor，作为cursor参数传入即可获取下一页
+func (h *AuditHandler) QueryAudit(c *gin.Context) {
+	filter := audit.Filter{
+		Target: c.Query("target"),
+		Actor:  c.Query("actor"),
+		// 只返回调用者有权管理的Schema的记录
+		Visible: func(record audit.Record) bool {
+			return auth.Allowed(c, record.Target, auth.RoleAdmin)
+		},
+	}
+
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since, expected RFC 3339 time: " + since})
+			return
+		}
+		filter.Since = t
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		seq, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil || seq == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor: " + cursor})
+			return
+		}
+		filter.Before = seq
+	}
+
+	limit := defaultAuditLimit
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit: " + value})
+			return
+		}
+		limit = n
+	}
+	// 多取一条用于判断是否还有下一页
+	filter.Limit = limit + 1
+
+	records, err := h.log.Query(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"records": records}
+	if len(records) > limit {
+		records = records[:limit]
+		response["records"] = records
+		response["nextCursor"] = strconv.FormatUint(records[limit-1].Seq, 10)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RegisterAuditRoutes 注册审计查询路由
+func RegisterAuditRoutes(r *gin.Engine, log *audit.Log) {
+	handler := NewAuditHandler(log)
+	r.GET("/api/audit", handler.QueryAudit)
+}
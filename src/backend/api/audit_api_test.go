@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/audit"
+	"goci/backend/auth"
+	"goci/backend/storage"
+)
+
+// 测试API写操作被记录到审计文件，并按条件和角色查询
+func TestAuditAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	tokens, err := auth.NewTokenAuthenticator([]auth.Token{
+		{Name: "alice", Token: "alice-secret", Grants: []auth.Grant{{Role: auth.RoleAdmin, Schema: "*"}}},
+		{Name: "bob", Token: "bob-secret", Grants: []auth.Grant{{Role: auth.RoleAdmin, Schema: "team-*"}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	auditLog, err := audit.Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer auditLog.Close()
+
+	r.Use(auth.Middleware([]auth.Authenticator{tokens}, nil))
+	r.Use(audit.Middleware(auditLog))
+
+	schemas := storage.NewMemorySchemaStore()
+	configs := storage.NewMemoryConfigStore()
+	RegisterRoutes(r, schemas, configs)
+	RegisterConfigRoutes(r, configs, schemas)
+	RegisterAuditRoutes(r, auditLog)
+
+	request := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set(audit.RequestIDHeader, "req-"+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "/api/schemas/team-a", "bob-secret", `{"schema": {"type": "object"}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if got := w.Header().Get(audit.RequestIDHeader); got != "req-bob-secret" {
+		t.Errorf("Expected request ID to be echoed, got %q", got)
+	}
+	w = request(http.MethodPost, "/api/configs/team-a", "bob-secret", `{"config": {}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	w = request(http.MethodPost, "/api/schemas/other", "alice-secret", `{"schema": {"type": "object"}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	query := func(path, token string) []audit.Record {
+		w := request(http.MethodGet, path, token, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response struct {
+			Records []audit.Record `json:"records"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response.Records
+	}
+
+	records := query("/api/audit", "alice-secret")
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d: %+v", len(records), records)
+	}
+	// 从新到旧返回
+	if records[2].Actor != "bob" || records[2].Operation != storage.EventSchemaSaved || records[2].RequestID != "req-bob-secret" {
+		t.Errorf("Unexpected oldest record: %+v", records[2])
+	}
+	if records[1].Operation != storage.EventConfigSaved || records[1].Version != 1 {
+		t.Errorf("Unexpected second record: %+v", records[1])
+	}
+
+	// 按条件查询
+	if records := query("/api/audit?actor=alice", "alice-secret"); len(records) != 1 || records[0].Target != "other" {
+		t.Errorf("Unexpected records for actor: %+v", records)
+	}
+	if records := query("/api/audit?target=team-a&limit=1", "alice-secret"); len(records) != 1 || records[0].Seq != 2 {
+		t.Errorf("Unexpected records for target: %+v", records)
+	}
+
+	// 只返回调用者有admin角色的Schema的记录，不可见的记录不占用limit
+	if records := query("/api/audit?limit=2", "bob-secret"); len(records) != 2 || records[0].Seq != 2 || records[1].Seq != 1 {
+		t.Errorf("Expected 2 records visible to bob, got %+v", records)
+	}
+
+	// 用nextCursor翻页
+	var page struct {
+		Records    []audit.Record `json:"records"`
+		NextCursor string         `json:"nextCursor"`
+	}
+	w = request(http.MethodGet, "/api/audit?limit=2", "alice-secret", "")
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if len(page.Records) != 2 || page.Records[0].Seq != 3 || page.NextCursor != "2" {
+		t.Fatalf("Unexpected first page: %s", w.Body.String())
+	}
+	w = request(http.MethodGet, "/api/audit?limit=2&cursor="+page.NextCursor, "alice-secret", "")
+	page.NextCursor = ""
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if len(page.Records) != 1 || page.Records[0].Seq != 1 || page.NextCursor != "" {
+		t.Errorf("Unexpected last page: %s", w.Body.String())
+	}
+
+	// 无效的参数
+	for _, path := range []string{"/api/audit?since=yesterday", "/api/audit?limit=0", "/api/audit?cursor=abc"} {
+		if w := request(http.MethodGet, path, "alice-secret", ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", path, http.StatusBadRequest, w.Code)
+		}
+	}
+}
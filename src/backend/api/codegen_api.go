@@ -0,0 +1,35 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/codegen"
+)
+
+// GenerateGo 处理根据Schema生成Go代码的请求
+//
+// package查询参数指定包名（默认config），type查询参数指定根类型名（默认使用Schema的title）
+func (h *SchemaHandler) GenerateGo(c *gin.Context) {
+	id := c.Param("id")
+
+	schemaData, metadata, err := bundleViewable(c, h.storage, id)
+	if err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	src, err := codegen.GenerateGo(schemaData, codegen.GoOptions{
+		Package:  c.DefaultQuery("package", "config"),
+		TypeName: c.Query("type"),
+		Source:   fmt.Sprintf("schema %q version %d", id, metadata.Version),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", id+".go"))
+	c.Data(http.StatusOK, "text/x-go; charset=utf-8", src)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// 测试根据Schema生成Go代码
+func TestGenerateGoAPI(t *testing.T) {
+	// 设置测试环境
+	r, schemaStorage, oldWd := setupTest(t)
+	defer os.Chdir(oldWd)
+
+	id := "app"
+	schemaData := []byte(`{"title": "app", "type": "object", "properties": {"port": {"type": "integer", "default": 8080}}}`)
+	if err := schemaStorage.SaveSchema(id, "App", "", schemaData); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/schemas/"+id+"/codegen/go?package=appconfig", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/x-go") {
+		t.Errorf("Unexpected content type: %s", w.Header().Get("Content-Type"))
+	}
+
+	code := w.Body.String()
+	for _, want := range []string{`from schema "app" version 1`, "package appconfig", "type App struct", "Port: 8080"} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+
+	// 指定类型名
+	req = httptest.NewRequest(http.MethodGet, "/api/schemas/"+id+"/codegen/go?type=Settings", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if !strings.Contains(w.Body.String(), "package config") || !strings.Contains(w.Body.String(), "type Settings struct") {
+		t.Errorf("Unexpected generated code:\n%s", w.Body.String())
+	}
+
+	// 无效的包名
+	req = httptest.NewRequest(http.MethodGet, "/api/schemas/"+id+"/codegen/go?package=my-config", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	// 不存在的Schema
+	req = httptest.NewRequest(http.MethodGet, "/api/schemas/missing/codegen/go", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
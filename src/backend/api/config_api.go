@@ -0,0 +1,252 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/audit"
+	"goci/backend/auth"
+	"goci/backend/storage"
+	"goci/backend/validation"
+)
+
+// ConfigHandler 处理配置相关的API请求
+type ConfigHandler struct {
+	configs storage.ConfigStore
+	schemas storage.SchemaStore
+}
+
+// NewConfigHandler 创建一个新的ConfigHandler实例
+func NewConfigHandler(configs storage.ConfigStore, schemas storage.SchemaStore) *ConfigHandler {
+	return &ConfigHandler{
+		configs: configs,
+		schemas: schemas,
+	}
+}
+
+// configRequestBody 是保存配置时的请求体
+type configRequestBody struct {
+	Config json.RawMessage `json:"config"`
+}
+
+// bindConfig 解析请求体并返回配置数据，失败时已写入错误响应
+func (h *ConfigHandler) bindConfig(c *gin.Context) ([]byte, bool) {
+	var requestBody configRequestBody
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse request body: " + err.Error()})
+		return nil, false
+	}
+
+	if len(requestBody.Config) == 0 || string(requestBody.Config) == "null" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Config is required"})
+		return nil, false
+	}
+
+	return requestBody.Config, true
+}
+
+// CreateConfig 处理为Schema创建配置的请求
+func (h *ConfigHandler) CreateConfig(c *gin.Context) {
+	// 从URL参数获取Schema ID
+	schemaID := c.Param("schemaId")
+	if schemaID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Schema ID is required"})
+		return
+	}
+
+	configData, ok := h.bindConfig(c)
+	if !ok {
+		return
+	}
+
+	// 只在配置不存在时保存，已存在的配置应使用PUT更新
+	h.saveConfig(c, schemaID, configData, storage.CreateOnly, http.StatusCreated, "Config created successfully")
+}
+
+// UpdateConfig 处理更新Schema配置的请求
+func (h *ConfigHandler) UpdateConfig(c *gin.Context) {
+	// 从URL参数获取Schema ID
+	schemaID := c.Param("schemaId")
+	if schemaID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Schema ID is required"})
+		return
+	}
+
+	configData, ok := h.bindConfig(c)
+	if !ok {
+		return
+	}
+
+	// 只能更新已存在的配置
+	if _, _, err := h.configs.GetConfig(schemaID); err != nil {
+		if errors.Is(err, storage.ErrConfigNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// 携带If-Match时仅在ETag一致时保存
+	h.saveConfig(c, schemaID, configData, c.GetHeader("If-Match"), http.StatusOK, "Config saved successfully")
+}
+
+// saveConfig 使用Schema校验配置后在ifMatch条件满足时保存，并写入统一格式的响应
+func (h *ConfigHandler) saveConfig(c *gin.Context, schemaID string, configData []byte, ifMatch string, status int, message string) {
+	// 配置必须对应一个已存在的Schema，引用的定义内联后再校验
+	schemaData, schemaMetadata, err := bundleViewable(c, h.schemas, schemaID)
+	if errors.Is(err, storage.ErrSchemaNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// 校验配置，不通过时列出每个失败项
+	if err := validation.ValidateConfig(schemaData, configData); err != nil {
+		var validationErr *validation.Error
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":      "Config does not match schema",
+				"violations": validationErr.Violations,
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// 记录校验所用的Schema版本，作为之后迁移的起点
+	if err := audit.Configs(c, h.configs).SaveConfigIfMatch(schemaID, configData, schemaMetadata.Version, ifMatch); err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("ETag", storage.ConfigETag(configData))
+	c.JSON(status, gin.H{
+		"metadata": gin.H{
+			"schemaId": schemaID,
+		},
+		"message": message,
+	})
+}
+
+// GetConfig 处理获取配置的请求
+func (h *ConfigHandler) GetConfig(c *gin.Context) {
+	// 从URL参数获取Schema ID
+	schemaID := c.Param("schemaId")
+	if schemaID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Schema ID is required"})
+		return
+	}
+
+	// 获取配置
+	configData, metadata, err := h.configs.GetConfig(schemaID)
+	if err != nil {
+		if errors.Is(err, storage.ErrConfigNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// 内容未变化时返回304
+	etag := storage.ConfigETag(configData)
+	c.Header("ETag", etag)
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" && storage.MatchETag(ifNoneMatch, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	// Schema被删除后配置仍然保留，标记为孤立
+	if _, _, err := h.schemas.GetSchema(schemaID); errors.Is(err, storage.ErrSchemaNotFound) {
+		metadata.Orphaned = true
+	}
+
+	// 返回统一格式的JSON响应
+	c.JSON(http.StatusOK, gin.H{
+		"metadata": metadata,
+		"config":   json.RawMessage(configData),
+	})
+}
+
+// ListConfigs 处理列出所有配置的请求
+func (h *ConfigHandler) ListConfigs(c *gin.Context) {
+	configs, err := h.configs.ListConfigs()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	schemas, err := h.schemas.ListSchemas()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	live := make(map[string]bool, len(schemas))
+	for _, schema := range schemas {
+		live[schema.ID] = true
+	}
+
+	// 只列出调用者有权查看的配置，Schema已被删除的标记为孤立
+	visible := configs[:0]
+	for _, config := range configs {
+		if auth.Allowed(c, config.SchemaID, auth.RoleViewer) {
+			config.Orphaned = !live[config.SchemaID]
+			visible = append(visible, config)
+		}
+	}
+	configs = visible
+
+	c.JSON(http.StatusOK, gin.H{"configs": configs})
+}
+
+// DeleteConfig 处理删除配置的请求
+func (h *ConfigHandler) DeleteConfig(c *gin.Context) {
+	// 从URL参数获取Schema ID
+	schemaID := c.Param("schemaId")
+	if schemaID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Schema ID is required"})
+		return
+	}
+
+	// 删除配置，携带If-Match时仅在配置未被他人修改的情况下删除
+	if err := audit.Configs(c, h.configs).DeleteConfigIfMatch(schemaID, c.GetHeader("If-Match")); err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Config deleted successfully", "schemaId": schemaID})
+}
+
+// RegisterConfigRoutes 注册配置API路由
+func RegisterConfigRoutes(r *gin.Engine, configs storage.ConfigStore, schemas storage.SchemaStore) {
+	// 创建处理器
+	handler := NewConfigHandler(configs, schemas)
+
+	// 创建API组
+	api := r.Group("/api")
+	{
+		// 配置API
+		group := api.Group("/configs", validateIDs, negotiate)
+		{
+			// 列出所有配置
+			group.GET("", handler.ListConfigs)
+			// 获取配置
+			group.GET("/:schemaId", handler.GetConfig)
+			// 创建配置
+			group.POST("/:schemaId", handler.CreateConfig)
+			// 更新配置
+			group.PUT("/:schemaId", handler.UpdateConfig)
+			// 删除配置
+			group.DELETE("/:schemaId", handler.DeleteConfig)
+			// 迁移配置到Schema的新版本
+			group.POST("/:schemaId/migrate", handler.MigrateConfig)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/auth"
+	"goci/backend/storage"
+)
+
+// 测试辅助函数：设置配置API的测试环境
+func setupConfigTest(t *testing.T) (*gin.Engine, *storage.SchemaStorage, string) {
+	r, schemaStorage, oldWd := setupTest(t)
+
+	// 注册配置API路由
+	RegisterConfigRoutes(r, storage.NewConfigStorage("."), schemaStorage)
+
+	return r, schemaStorage, oldWd
+}
+
+// 测试配置的创建、获取、更新和删除
+func TestConfigAPILifecycle(t *testing.T) {
+	// 设置测试环境
+	r, schemaStorage, oldWd := setupConfigTest(t)
+	defer os.Chdir(oldWd)
+
+	id := "test-schema"
+	schemaData := []byte(`{"type": "object", "properties": {"name": {"type": "string"}}}`)
+	if err := schemaStorage.SaveSchema(id, "Test Schema", "", schemaData); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+
+	// 创建配置
+	body := []byte(`{"config": {"name": "demo"}}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/configs/"+id, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	// 重复创建应返回冲突
+	req = httptest.NewRequest(http.MethodPost, "/api/configs/"+id, bytes.NewBuffer(body))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status code %d, got %d", http.StatusConflict, w.Code)
+	}
+
+	// 更新配置
+	body = []byte(`{"config": {"name": "updated"}}`)
+	req = httptest.NewRequest(http.MethodPut, "/api/configs/"+id, bytes.NewBuffer(body))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// 获取配置
+	req = httptest.NewRequest(http.MethodGet, "/api/configs/"+id, nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response struct {
+		Metadata storage.ConfigMetadata `json:"metadata"`
+		Config   map[string]interface{} `json:"config"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if response.Metadata.SchemaID != id {
+		t.Errorf("Expected schemaId '%s', got %s", id, response.Metadata.SchemaID)
+	}
+
+	if response.Config["name"] != "updated" {
+		t.Errorf("Expected config name 'updated', got %v", response.Config["name"])
+	}
+
+	// 删除配置
+	req = httptest.NewRequest(http.MethodDelete, "/api/configs/"+id, nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// 删除后获取应返回404
+	req = httptest.NewRequest(http.MethodGet, "/api/configs/"+id, nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// 测试配置的ETag、If-None-Match和If-Match
+func TestConfigAPIETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	schemas := storage.NewMemorySchemaStore()
+	configs := storage.NewMemoryConfigStore()
+	RegisterConfigRoutes(r, configs, schemas)
+	schemas.SaveSchema("app", "App", "", []byte(`{"type": "object"}`))
+
+	request := func(method, body string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/configs/app", bytes.NewBufferString(body))
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, `{"config": {"port": 80}}`)
+	if w.Code != http.StatusCreated || w.Header().Get("ETag") == "" {
+		t.Fatalf("Expected status code %d with an ETag, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	w = request(http.MethodGet, "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag != storage.ConfigETag([]byte(`{"port": 80}`)) {
+		t.Fatalf("Unexpected ETag %q: %d %s", etag, w.Code, w.Body.String())
+	}
+	if w = request(http.MethodGet, "", "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotModified, w.Code)
+	}
+
+	// If-Match与当前内容不一致时不保存
+	if w = request(http.MethodPut, `{"config": {"port": 81}}`, "If-Match", `"stale"`); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusPreconditionFailed, w.Code, w.Body.String())
+	}
+	if w = request(http.MethodPut, `{"config": {"port": 82}}`, "If-Match", etag); w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if data, _, _ := configs.GetConfig("app"); string(data) != `{"port": 82}` {
+		t.Errorf("Unexpected config: %s", string(data))
+	}
+
+	// 创建不会覆盖已有的配置
+	if w = request(http.MethodPost, `{"config": {"port": 83}}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+	if data, _, _ := configs.GetConfig("app"); string(data) != `{"port": 82}` {
+		t.Errorf("Config overwritten by create: %s", string(data))
+	}
+}
+
+// 测试为不存在的Schema创建或更新配置
+func TestConfigAPIMissingSchema(t *testing.T) {
+	// 设置测试环境
+	r, _, oldWd := setupConfigTest(t)
+	defer os.Chdir(oldWd)
+
+	body := []byte(`{"config": {"name": "demo"}}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/configs/non-existent", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPut, "/api/configs/non-existent", bytes.NewBuffer(body))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	// 缺少配置内容应返回400
+	req = httptest.NewRequest(http.MethodPut, "/api/configs/non-existent", bytes.NewBufferString(`{}`))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// 测试不符合Schema的配置被拒绝
+func TestConfigAPIValidation(t *testing.T) {
+	// 设置测试环境
+	r, schemaStorage, oldWd := setupConfigTest(t)
+	defer os.Chdir(oldWd)
+
+	id := "test-schema"
+	schemaData := []byte(`{"type": "object", "properties": {"port": {"type": "integer", "maximum": 65535}}, "required": ["port"]}`)
+	if err := schemaStorage.SaveSchema(id, "Test Schema", "", schemaData); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+
+	body := []byte(`{"config": {"port": 70000}}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/configs/"+id, bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Violations []struct {
+			Pointer string `json:"pointer"`
+			Keyword string `json:"keyword"`
+			Message string `json:"message"`
+		} `json:"violations"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if len(response.Violations) != 1 || response.Violations[0].Pointer != "/port" || response.Violations[0].Keyword != "maximum" {
+		t.Errorf("Unexpected violations: %+v", response.Violations)
+	}
+
+	// 被拒绝的配置不应被保存
+	req = httptest.NewRequest(http.MethodGet, "/api/configs/"+id, nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// 测试使用内存存储注册路由时不会写入工作目录
+func TestRoutesWithMemoryStore(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	schemas := storage.NewMemorySchemaStore()
+	configs := storage.NewMemoryConfigStore()
+	RegisterRoutes(r, schemas, configs)
+	RegisterConfigRoutes(r, configs, schemas)
+
+	body := []byte(`{"metadata": {"name": "Test"}, "schema": {"type": "object"}}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/schemas/test-schema", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/configs/test-schema", bytes.NewBufferString(`{"config": {}}`))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	if _, err := os.Stat("schemas"); !os.IsNotExist(err) {
+		t.Errorf("Memory store should not create a schemas directory")
+	}
+}
+
+// 测试启用认证时列表只包含有权查看的Schema和配置
+func TestListFilteredByRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	tokens, err := auth.NewTokenAuthenticator([]auth.Token{
+		{Name: "team", Token: "secret", Grants: []auth.Grant{{Role: auth.RoleViewer, Schema: "team-*"}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	r.Use(auth.Middleware([]auth.Authenticator{tokens}, nil))
+
+	schemas := storage.NewMemorySchemaStore()
+	configs := storage.NewMemoryConfigStore()
+	RegisterRoutes(r, schemas, configs)
+	RegisterConfigRoutes(r, configs, schemas)
+
+	for _, id := range []string{"team-a", "team-b", "other"} {
+		schemas.SaveSchema(id, id, "", []byte(`{"type": "object"}`))
+		configs.SaveConfig(id, []byte(`{}`))
+	}
+
+	list := func(path string) []string {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var response struct {
+			Schemas []storage.SchemaMetadata `json:"schemas"`
+			Configs []storage.ConfigMetadata `json:"configs"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+
+		var ids []string
+		for _, schema := range response.Schemas {
+			ids = append(ids, schema.ID)
+		}
+		for _, config := range response.Configs {
+			ids = append(ids, config.SchemaID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	if ids := list("/api/schemas"); !reflect.DeepEqual(ids, []string{"team-a", "team-b"}) {
+		t.Errorf("Unexpected schemas: %v", ids)
+	}
+	if ids := list("/api/configs"); !reflect.DeepEqual(ids, []string{"team-a", "team-b"}) {
+		t.Errorf("Unexpected configs: %v", ids)
+	}
+
+	// 无权查看的Schema返回403
+	req := httptest.NewRequest(http.MethodGet, "/api/configs/other", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status code %d, got %d", http.StatusForbidden, w.Code)
+	}
+}
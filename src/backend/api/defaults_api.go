@@ -0,0 +1,38 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/defaults"
+)
+
+// GetDefaultConfig 处理根据Schema生成默认配置的请求
+//
+// 响应格式与保存配置的请求体相同，可以直接用于创建配置；
+// unresolved列出无法自动生成值、需要手动填写的位置（JSON Pointer）
+func (h *SchemaHandler) GetDefaultConfig(c *gin.Context) {
+	id := c.Param("id")
+
+	schemaData, _, err := bundleViewable(c, h.storage, id)
+	if err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	configData, unresolved, err := defaults.Generate(schemaData)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if unresolved == nil {
+		unresolved = []string{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"schemaId":   id,
+		"config":     json.RawMessage(configData),
+		"unresolved": unresolved,
+	})
+}
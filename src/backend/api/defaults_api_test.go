@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// 测试根据Schema生成默认配置
+func TestGetDefaultConfigAPI(t *testing.T) {
+	// 设置测试环境
+	r, schemaStorage, oldWd := setupTest(t)
+	defer os.Chdir(oldWd)
+
+	schemaData := []byte(`{"type": "object", "required": ["name", "email"], "properties": {"name": {"type": "string"}, "email": {"type": "string", "format": "email"}, "port": {"type": "integer", "default": 8080}}}`)
+	if err := schemaStorage.SaveSchema("app", "App", "", schemaData); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/schemas/app/default", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		SchemaID   string         `json:"schemaId"`
+		Config     map[string]any `json:"config"`
+		Unresolved []string       `json:"unresolved"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if response.SchemaID != "app" || response.Config["name"] != "" || response.Config["port"] != float64(8080) {
+		t.Errorf("Unexpected default config: %+v", response)
+	}
+	// 无法自动生成的值需要手动填写
+	if _, exists := response.Config["email"]; exists || len(response.Unresolved) != 1 || response.Unresolved[0] != "/email" {
+		t.Errorf("Unexpected unresolved values: %+v", response)
+	}
+
+	// 不存在的Schema
+	req = httptest.NewRequest(http.MethodGet, "/api/schemas/missing/default", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"goci/backend/auth"
+	"goci/backend/storage"
+)
+
+// eventHeartbeat 是SSE心跳间隔，避免代理关闭空闲连接
+var eventHeartbeat = 15 * time.Second
+
+// EventHandler 处理存储变更事件流
+type EventHandler struct {
+	bus *storage.EventBus
+}
+
+// NewEventHandler 创建一个新的事件处理器
+func NewEventHandler(bus *storage.EventBus) *EventHandler {
+	return &EventHandler{bus: bus}
+}
+
+// StreamEvents 以Server-Sent Events推送存储变更
+//
+// 断线重连时通过Last-Event-ID请求头（或lastEventId查询参数）补发错过的事件；
+// 如果错过的事件已不在缓冲区中，先发送一个reset事件，客户端应重新读取完整状态。
+// 可以用schemaId查询参数只订阅某个Schema的事件。
+func (h *EventHandler) StreamEvents(c *gin.Context) {
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("lastEventId")
+	}
+
+	var afterID uint64
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+			return
+		}
+		afterID = id
+	}
+	schemaID := c.Query("schemaId")
+
+	replay, complete, sub := h.bus.Subscribe(afterID)
+	defer sub.Close()
+
+	// 事件流是长连接，不受服务器写超时限制
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	if !complete {
+		sse.Encode(c.Writer, sse.Event{Event: "reset", Data: gin.H{"lastEventId": lastEventID}})
+	}
+	for _, event := range replay {
+		writeEvent(c, event, schemaID)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				// 订阅因处理过慢被断开，客户端会用Last-Event-ID重连
+				return
+			}
+			writeEvent(c, event, schemaID)
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			c.Writer.WriteString(": heartbeat\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// writeEvent 写入一个事件，schemaID非空时跳过其他Schema的事件，
+// 调用者无权查看的Schema的事件也会被跳过
+func writeEvent(c *gin.Context, event storage.Event, schemaID string) {
+	if schemaID != "" && event.SchemaID != schemaID {
+		return
+	}
+	if !auth.Allowed(c, event.SchemaID, auth.RoleViewer) {
+		return
+	}
+	sse.Encode(c.Writer, sse.Event{
+		Id:    strconv.FormatUint(event.ID, 10),
+		Event: event.Type,
+		Data:  event,
+	})
+}
+
+// RegisterEventRoutes 注册事件流路由
+func RegisterEventRoutes(r *gin.Engine, bus *storage.EventBus) {
+	handler := NewEventHandler(bus)
+	r.GET("/api/events", handler.StreamEvents)
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/storage"
+)
+
+// 测试辅助函数：启动带事件流的测试服务器
+func setupEventsTest(t *testing.T) (*httptest.Server, *storage.EventBus) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	bus := storage.NewEventBus(100)
+	schemas := storage.WithSchemaEvents(storage.NewMemorySchemaStore(), bus)
+	configs := storage.WithConfigEvents(storage.NewMemoryConfigStore(), bus)
+	RegisterRoutes(r, schemas, configs)
+	RegisterConfigRoutes(r, configs, schemas)
+	RegisterEventRoutes(r, bus)
+
+	return httptest.NewServer(r), bus
+}
+
+// 测试辅助函数：打开事件流，返回逐行读取器
+func openEvents(t *testing.T, ctx context.Context, url string, lastEventID string) *bufio.Scanner {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open event stream: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Unexpected response: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	return bufio.NewScanner(resp.Body)
+}
+
+// 测试辅助函数：读取下一个事件的字段
+func nextEvent(t *testing.T, scanner *bufio.Scanner) map[string]string {
+	fields := map[string]string{}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(fields) > 0 {
+				return fields
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		name, value, _ := strings.Cut(line, ":")
+		fields[name] = value
+	}
+	t.Fatalf("Event stream ended: %v", scanner.Err())
+	return nil
+}
+
+// 测试写操作通过事件流推送
+func TestStreamEvents(t *testing.T) {
+	server, _ := setupEventsTest(t)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	scanner := openEvents(t, ctx, server.URL+"/api/events", "")
+
+	body := []byte(`{"metadata": {"name": "Test"}, "schema": {"type": "object"}}`)
+	resp, err := http.Post(server.URL+"/api/schemas/app", "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Post(server.URL+"/api/configs/app", "application/json", bytes.NewBufferString(`{"config": {}}`))
+	if err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	resp.Body.Close()
+
+	event := nextEvent(t, scanner)
+	if event["event"] != storage.EventSchemaSaved || event["id"] == "" || !strings.Contains(event["data"], `"schemaId":"app"`) {
+		t.Errorf("Unexpected event: %v", event)
+	}
+
+	event = nextEvent(t, scanner)
+	if event["event"] != storage.EventConfigSaved {
+		t.Errorf("Unexpected event: %v", event)
+	}
+}
+
+// 测试使用Last-Event-ID续传
+func TestStreamEventsResume(t *testing.T) {
+	server, bus := setupEventsTest(t)
+	defer server.Close()
+
+	first := bus.Publish(storage.Event{Type: storage.EventSchemaSaved, SchemaID: "a", Version: 1})
+	bus.Publish(storage.Event{Type: storage.EventSchemaSaved, SchemaID: "b", Version: 1})
+	bus.Publish(storage.Event{Type: storage.EventSchemaDeleted, SchemaID: "a"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 只补发Schema a在first之后的事件
+	scanner := openEvents(t, ctx, server.URL+"/api/events?schemaId=a", strconv.FormatUint(first.ID, 10))
+	event := nextEvent(t, scanner)
+	if event["event"] != storage.EventSchemaDeleted {
+		t.Errorf("Unexpected event: %v", event)
+	}
+
+	// 重启前的ID需要重新同步
+	scanner = openEvents(t, ctx, server.URL+"/api/events", "1")
+	event = nextEvent(t, scanner)
+	if event["event"] != "reset" {
+		t.Errorf("Expected reset event, got %v", event)
+	}
+}
+
+// 测试无效的Last-Event-ID
+func TestStreamEventsInvalidID(t *testing.T) {
+	server, _ := setupEventsTest(t)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/events?lastEventId=abc")
+	if err != nil {
+		t.Fatalf("Failed to request events: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+}
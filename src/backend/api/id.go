@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/storage"
+)
+
+// validateIDs 拒绝路由参数id或schemaId不符合ID语法的请求，避免非法ID进入存储层
+func validateIDs(c *gin.Context) {
+	for _, name := range []string{"id", "schemaId"} {
+		if id := c.Param(name); id != "" {
+			if err := storage.ValidateID(id); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+	}
+	c.Next()
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/storage"
+)
+
+// 测试非法和保留的Schema ID返回400
+func TestInvalidSchemaIDAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	schemas := storage.NewMemorySchemaStore()
+	configs := storage.NewMemoryConfigStore()
+	RegisterRoutes(r, schemas, configs)
+	RegisterConfigRoutes(r, configs, schemas)
+
+	requests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPost, "/api/schemas/%2E%2E"},
+		{http.MethodPost, "/api/schemas/schema-registry.json"},
+		{http.MethodDelete, "/api/schemas/.journal.json"},
+		{http.MethodGet, "/api/schemas/a%20b/versions"},
+		{http.MethodPost, "/api/configs/config-registry.json"},
+		{http.MethodPost, "/api/trash/%2E%2E/restore"},
+	}
+	for _, request := range requests {
+		req := httptest.NewRequest(request.method, request.path, bytes.NewBufferString(`{"schema": {}, "config": {}}`))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid schema ID") {
+			t.Errorf("%s %s: expected status code %d, got %d: %s", request.method, request.path, http.StatusBadRequest, w.Code, w.Body.String())
+		}
+	}
+}
+
+// 测试不带ID创建Schema时由服务端生成ID
+func TestCreateSchemaAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	schemas := storage.NewMemorySchemaStore()
+	RegisterRoutes(r, schemas, nil)
+
+	body := `{"metadata": {"name": "Billing Service"}, "schema": {"type": "object"}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/schemas", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Metadata struct {
+			ID      string `json:"id"`
+			Name    string `json:"name"`
+			Version int    `json:"version"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if !strings.HasPrefix(response.Metadata.ID, "billing-service-") || response.Metadata.Version != 1 {
+		t.Errorf("Unexpected metadata: %+v", response.Metadata)
+	}
+	if location := w.Header().Get("Location"); location != "/api/schemas/"+response.Metadata.ID {
+		t.Errorf("Unexpected Location header: %q", location)
+	}
+
+	if _, metadata, err := schemas.GetSchema(response.Metadata.ID); err != nil || metadata.Name != "Billing Service" {
+		t.Errorf("Schema not saved under generated ID: %+v, %v", metadata, err)
+	}
+}
+
+// 测试生成的ID与已有Schema冲突时换一个ID重试，不会覆盖已有Schema
+func TestCreateSchemaIDCollision(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	schemas := storage.NewMemorySchemaStore()
+	RegisterRoutes(r, schemas, nil)
+	if err := schemas.SaveSchema("billing-taken", "Existing", "", []byte(`{"type": "string"}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+
+	// 第一次生成的ID已被占用
+	ids := []string{"billing-taken", "billing-free"}
+	defer func(original func(string) string) { newSchemaID = original }(newSchemaID)
+	newSchemaID = func(string) string {
+		id := ids[0]
+		ids = ids[1:]
+		return id
+	}
+
+	body := `{"metadata": {"name": "Billing"}, "schema": {"type": "object"}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/schemas", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != "/api/schemas/billing-free" {
+		t.Fatalf("Expected schema to be created under a new ID, got %d %q: %s", w.Code, w.Header().Get("Location"), w.Body.String())
+	}
+	if data, metadata, err := schemas.GetSchema("billing-taken"); err != nil || string(data) != `{"type": "string"}` || metadata.Version != 1 {
+		t.Errorf("Existing schema was modified: %s %+v, %v", data, metadata, err)
+	}
+
+	// 一直冲突时返回409
+	newSchemaID = func(string) string { return "billing-taken" }
+	req = httptest.NewRequest(http.MethodPost, "/api/schemas", bytes.NewBufferString(body))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/audit"
+	"goci/backend/migration"
+	"goci/backend/refs"
+	"goci/backend/storage"
+)
+
+// MigrateConfig 处理把配置升级到Schema新版本的请求
+//
+// 查询参数from和to指定起始和目标版本，默认分别为配置记录的版本和Schema的当前版本；
+// dryRun=true时只返回迁移后的配置，不保存
+func (h *ConfigHandler) MigrateConfig(c *gin.Context) {
+	// 从URL参数获取Schema ID
+	schemaID := c.Param("schemaId")
+	if schemaID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Schema ID is required"})
+		return
+	}
+
+	var opts migration.Options
+	for name, target := range map[string]*int{"from": &opts.From, "to": &opts.To} {
+		if value := c.Query(name); value != "" {
+			version, err := strconv.Atoi(value)
+			if err != nil || version < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name + " version: " + value})
+				return
+			}
+			*target = version
+		}
+	}
+	opts.DryRun, _ = strconv.ParseBool(c.Query("dryRun"))
+	opts.Resolve = viewableRefs(c, h.schemas)
+
+	result, err := migration.NewRunner(h.schemas, audit.Configs(c, h.configs)).Migrate(schemaID, opts)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, result)
+	case errors.Is(err, storage.ErrConfigNotFound), errors.Is(err, storage.ErrSchemaNotFound), errors.Is(err, storage.ErrVersionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, storage.ErrPreconditionFailed):
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+	case errors.Is(err, migration.ErrUnknownVersion), errors.Is(err, migration.ErrInvalidRange):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, migration.ErrInvalidResult):
+		// 返回迁移结果，便于查看哪些字段还需要处理
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":      err.Error(),
+			"violations": result.Violations,
+			"result":     result,
+		})
+	case errors.Is(err, migration.ErrStepFailed), errors.Is(err, migration.ErrInvalidStep), errors.Is(err, refs.ErrUnresolved), errors.Is(err, refs.ErrCycle):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
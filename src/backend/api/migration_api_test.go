@@ -0,0 +1,142 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/migration"
+	"goci/backend/storage"
+)
+
+// 测试通过API保存带迁移步骤的Schema并迁移配置
+func TestMigrateConfigAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	schemas := storage.NewMemorySchemaStore()
+	configs := storage.NewMemoryConfigStore()
+	RegisterRoutes(r, schemas, configs)
+	RegisterConfigRoutes(r, configs, schemas)
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// v1和对应的配置
+	w := request(http.MethodPost, "/api/schemas/app", `{"schema": {"type": "object", "properties": {"port": {"type": "string"}}}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	w = request(http.MethodPost, "/api/configs/app", `{"config": {"port": "8080"}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	// 无效的迁移步骤不能保存
+	w = request(http.MethodPost, "/api/schemas/app", `{"schema": {"type": "object"}, "revision": {"migration": [{"op": "copy", "path": "/port"}]}}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	// v2把port改为整数并移动到server下
+	w = request(http.MethodPost, "/api/schemas/app", `{
+		"schema": {"type": "object", "properties": {"server": {"type": "object", "properties": {"port": {"type": "integer"}}, "required": ["port"]}}, "required": ["server"]},
+		"revision": {"message": "Group server settings", "migration": [
+			{"op": "convert", "path": "/port", "type": "integer"},
+			{"op": "move", "path": "/port", "to": "/server/port"}
+		]}
+	}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// 预览迁移结果
+	w = request(http.MethodPost, "/api/configs/app/migrate?dryRun=true", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var result migration.Result
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if !result.DryRun || result.Migrated || !result.Valid || result.From != 1 || result.To != 2 || len(result.Versions) != 1 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	if _, metadata, _ := configs.GetConfig("app"); metadata.SchemaVersion != 1 {
+		t.Errorf("Dry run should not migrate, got schema version %d", metadata.SchemaVersion)
+	}
+
+	// 执行迁移
+	w = request(http.MethodPost, "/api/configs/app/migrate", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	data, metadata, _ := configs.GetConfig("app")
+	var config struct {
+		Server struct {
+			Port int `json:"port"`
+		} `json:"server"`
+	}
+	json.Unmarshal(data, &config)
+	if config.Server.Port != 8080 || metadata.SchemaVersion != 2 {
+		t.Errorf("Unexpected migrated config: %s %+v", string(data), metadata)
+	}
+
+	// 错误的参数
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/api/configs/app/migrate?to=abc", http.StatusBadRequest},
+		{"/api/configs/app/migrate?from=2&to=1", http.StatusBadRequest},
+		{"/api/configs/app/migrate?to=9", http.StatusNotFound},
+		{"/api/configs/missing/migrate", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if w := request(http.MethodPost, tt.path, ""); w.Code != tt.code {
+			t.Errorf("%s: expected status code %d, got %d", tt.path, tt.code, w.Code)
+		}
+	}
+}
+
+// 测试迁移结果不符合Schema时返回失败项
+func TestMigrateConfigAPIInvalidResult(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	schemas := storage.NewMemorySchemaStore()
+	configs := storage.NewMemoryConfigStore()
+	RegisterConfigRoutes(r, configs, schemas)
+
+	schemas.SaveSchema("app", "App", "", []byte(`{"type": "object"}`))
+	schemas.SaveSchema("app", "App", "", []byte(`{"type": "object", "required": ["name"]}`))
+	configs.SaveConfigVersion("app", []byte(`{}`), 1)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/configs/app/migrate", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Violations []struct {
+			Keyword string `json:"keyword"`
+		} `json:"violations"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if len(response.Violations) != 1 || response.Violations[0].Keyword != "required" {
+		t.Errorf("Unexpected violations: %+v", response.Violations)
+	}
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/docformat"
+)
+
+// convertedFormats 是响应可以转换成的格式，它们的ETag带有格式后缀
+var convertedFormats = []docformat.Format{docformat.YAML, docformat.TOML}
+
+// negotiate 按Content-Type和Accept在JSON与YAML、TOML之间转换请求体和响应体
+//
+// 处理器始终读写JSON：YAML和TOML请求体先转换为JSON，Accept选择YAML或TOML时再把JSON响应转换为对应格式。
+// 转换后的响应体的ETag带有格式后缀，条件请求头中的后缀在交给处理器之前去掉
+func negotiate(c *gin.Context) {
+	if !convertRequest(c) {
+		return
+	}
+
+	// If-Match比较的是存储的内容，任何格式的ETag都可以使用
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" {
+		c.Request.Header.Set("If-Match", trimETagSuffix(ifMatch, convertedFormats...))
+	}
+
+	c.Writer.Header().Add("Vary", "Accept")
+	format := docformat.Negotiate(c.GetHeader("Accept"))
+	if format == docformat.JSON {
+		c.Next()
+		return
+	}
+
+	// If-None-Match只匹配同一格式的ETag
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" {
+		if etags := formatETags(ifNoneMatch, format); etags != "" {
+			c.Request.Header.Set("If-None-Match", etags)
+		} else {
+			c.Request.Header.Del("If-None-Match")
+		}
+	}
+
+	writer := &bufferedWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	c.Next()
+	c.Writer = writer.ResponseWriter
+
+	if etag := writer.Header().Get("ETag"); etag != "" {
+		writer.Header().Set("ETag", formatETag(etag, format))
+	}
+
+	body := writer.buf.Bytes()
+	if len(body) == 0 || !isJSONResponse(writer.Header().Get("Content-Type")) {
+		writer.ResponseWriter.Write(body)
+		return
+	}
+
+	converted, err := docformat.FromJSON(format, body)
+	if err != nil {
+		// 状态码还没有写出，可以改为406
+		writer.Header().Del("ETag")
+		writer.Header().Set("Content-Type", docformat.JSON.ContentType())
+		writer.ResponseWriter.WriteHeader(http.StatusNotAcceptable)
+		writer.ResponseWriter.Write(mustJSON(gin.H{"error": err.Error()}))
+		return
+	}
+	writer.Header().Set("Content-Type", format.ContentType())
+	writer.ResponseWriter.Write(converted)
+}
+
+// convertRequest 把非JSON的请求体转换为JSON，失败时写入带行号和列号的400响应并返回false
+func convertRequest(c *gin.Context) bool {
+	format := docformat.FromContentType(c.GetHeader("Content-Type"))
+	if format == docformat.JSON || c.Request.Body == nil {
+		return true
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body: " + err.Error()})
+		return false
+	}
+
+	converted, err := docformat.ToJSON(format, data)
+	if err != nil {
+		response := gin.H{"error": "Failed to parse request body: " + err.Error()}
+		var syntaxErr *docformat.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			response["line"] = syntaxErr.Line
+			response["column"] = syntaxErr.Column
+		}
+		c.AbortWithStatusJSON(http.StatusBadRequest, response)
+		return false
+	}
+
+	c.Request.Body = io.NopCloser(bytes.NewReader(converted))
+	c.Request.ContentLength = int64(len(converted))
+	c.Request.Header.Set("Content-Type", docformat.JSON.ContentType())
+	c.Request.Header.Set("Content-Length", strconv.Itoa(len(converted)))
+	return true
+}
+
+// formatETag 给ETag加上格式后缀，"abc"变为"abc-yaml"
+func formatETag(etag string, format docformat.Format) string {
+	if !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + "-" + string(format) + `"`
+}
+
+// trimETagSuffix 去掉条件请求头中各个ETag的格式后缀，只处理formats中的格式
+func trimETagSuffix(header string, formats ...docformat.Format) string {
+	candidates := strings.Split(header, ",")
+	for i, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		for _, format := range formats {
+			suffix := "-" + string(format) + `"`
+			if strings.HasSuffix(candidate, suffix) {
+				candidate = strings.TrimSuffix(candidate, suffix) + `"`
+				break
+			}
+		}
+		candidates[i] = candidate
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// formatETags 返回条件请求头中带有format后缀的ETag，去掉后缀，其他ETag被丢弃
+func formatETags(header string, format docformat.Format) string {
+	suffix := "-" + string(format) + `"`
+	var etags []string
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			etags = append(etags, candidate)
+		} else if strings.HasSuffix(candidate, suffix) {
+			etags = append(etags, strings.TrimSuffix(candidate, suffix)+`"`)
+		}
+	}
+	return strings.Join(etags, ", ")
+}
+
+// isJSONResponse 判断响应是否为JSON
+func isJSONResponse(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+// mustJSON 返回已知可以序列化的值的JSON
+func mustJSON(value any) []byte {
+	data, _ := json.Marshal(value)
+	return data
+}
+
+// bufferedWriter 缓存处理器写出的响应体，状态码和响应头仍然记录在原来的ResponseWriter中
+type bufferedWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+// Write 把数据写入缓冲区
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.buf.Write(data)
+}
+
+// WriteString 把字符串写入缓冲区
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.buf.WriteString(s)
+}
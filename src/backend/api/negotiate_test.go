@@ -0,0 +1,167 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/storage"
+)
+
+// 测试Schema和配置API通过Content-Type和Accept读写YAML和TOML
+func TestNegotiateAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	schemas := storage.NewMemorySchemaStore()
+	configs := storage.NewMemoryConfigStore()
+	RegisterRoutes(r, schemas, configs)
+	RegisterConfigRoutes(r, configs, schemas)
+
+	request := func(method, path, contentType, accept, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// 以YAML保存Schema，存储的是JSON
+	schemaYAML := `
+metadata:
+  name: App
+schema:
+  type: object
+  properties:
+    port:
+      type: integer
+      minimum: 1
+  required: [port]
+`
+	w := request(http.MethodPost, "/api/schemas/app", "application/yaml", "", schemaYAML)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	schemaData, _, err := schemas.GetSchema("app")
+	if err != nil {
+		t.Fatalf("Failed to get schema: %v", err)
+	}
+	if !json.Valid(schemaData) || !bytes.Contains(schemaData, []byte(`"minimum":1`)) {
+		t.Errorf("Expected schema to be stored as JSON, got %s", schemaData)
+	}
+
+	// 以TOML创建配置
+	w = request(http.MethodPost, "/api/configs/app", "application/toml", "", "[config]\nport = 8080\n")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	// 以YAML更新配置，验证仍然按Schema进行
+	w = request(http.MethodPut, "/api/configs/app", "application/yaml", "", "config:\n  port: 0\n")
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+
+	// 转换错误带有行号和列号
+	w = request(http.MethodPut, "/api/configs/app", "application/yaml", "", "config:\n  port: 1\n  port: 2\n")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	var errorResponse struct {
+		Error  string `json:"error"`
+		Line   int    `json:"line"`
+		Column int    `json:"column"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &errorResponse); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if errorResponse.Line != 3 || errorResponse.Column != 3 || !strings.Contains(errorResponse.Error, "duplicate key") {
+		t.Errorf("Expected duplicate key at line 3, column 3, got %+v", errorResponse)
+	}
+
+	// 以YAML读取Schema，ETag带有格式后缀
+	w = request(http.MethodGet, "/api/schemas/app", "", "", "")
+	jsonETag := w.Header().Get("ETag")
+	w = request(http.MethodGet, "/api/schemas/app", "", "application/yaml", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/yaml") {
+		t.Errorf("Expected YAML content type, got %s", contentType)
+	}
+	if !strings.Contains(w.Body.String(), "minimum: 1") {
+		t.Errorf("Expected YAML schema, got %s", w.Body.String())
+	}
+	yamlETag := strings.TrimSuffix(jsonETag, `"`) + `-yaml"`
+	if w.Header().Get("ETag") != yamlETag || w.Header().Get("Vary") != "Accept" {
+		t.Errorf("Expected ETag %s and Vary: Accept, got %v", yamlETag, w.Header())
+	}
+
+	conditional := func(method, path, accept, header, etag, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Accept", accept)
+		req.Header.Set(header, etag)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// 内容未变化时返回304，ETag带有同样的后缀
+	w = conditional(http.MethodGet, "/api/schemas/app", "application/yaml", "If-None-Match", yamlETag, "")
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != yamlETag {
+		t.Errorf("Expected status code %d with empty body, got %d: %s %v", http.StatusNotModified, w.Code, w.Body.String(), w.Header())
+	}
+	// 其他格式的ETag不匹配
+	w = conditional(http.MethodGet, "/api/schemas/app", "application/yaml", "If-None-Match", jsonETag, "")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d for JSON ETag, got %d", http.StatusOK, w.Code)
+	}
+	w = conditional(http.MethodGet, "/api/schemas/app", "", "If-None-Match", yamlETag, "")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d for YAML ETag, got %d", http.StatusOK, w.Code)
+	}
+
+	// 以TOML读取配置
+	w = request(http.MethodGet, "/api/configs/app", "", "application/toml", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/toml") || !strings.Contains(w.Body.String(), "port = 8080") {
+		t.Errorf("Expected TOML config, got %s: %s", w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	// TOML响应的ETag可以用作JSON更新的If-Match条件
+	tomlETag := w.Header().Get("ETag")
+	if !strings.HasSuffix(tomlETag, `-toml"`) {
+		t.Errorf("Expected TOML ETag, got %s", tomlETag)
+	}
+	w = conditional(http.MethodPut, "/api/configs/app", "", "If-Match", tomlETag, `{"config": {"port": 9090}}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// 错误响应同样按Accept输出
+	w = request(http.MethodGet, "/api/schemas/missing", "", "application/yaml", "")
+	if w.Code != http.StatusNotFound || !strings.HasPrefix(w.Body.String(), "error: ") {
+		t.Errorf("Expected YAML error, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// TOML无法表示null时返回406
+	w = request(http.MethodPost, "/api/schemas/nullable", "", "", `{"metadata": {"name": "Nullable"}, "schema": {"type": "object", "default": null}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	w = request(http.MethodGet, "/api/schemas/nullable", "", "application/toml", "")
+	if w.Code != http.StatusNotAcceptable || !strings.Contains(w.Body.String(), "/schema/default") {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusNotAcceptable, w.Code, w.Body.String())
+	}
+}
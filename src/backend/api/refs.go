@@ -0,0 +1,46 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/auth"
+	"goci/backend/refs"
+	"goci/backend/storage"
+)
+
+// viewableRefs 返回API请求打包时使用的Resolver，只解析调用者有权查看的Schema
+//
+// 打包会把引用的内容内联进响应和校验结果，无权查看的Schema按不存在处理，
+// 错误信息与引用不存在的Schema相同，不会透露它是否存在。命令行和内部调用使用refs.FromStore
+func viewableRefs(c *gin.Context, store storage.SchemaStore) refs.Resolver {
+	resolve := refs.FromStore(store)
+	return func(id string) ([]byte, error) {
+		if !auth.Allowed(c, id, auth.RoleViewer) {
+			return nil, fmt.Errorf("%w: %s", storage.ErrSchemaNotFound, id)
+		}
+		return resolve(id)
+	}
+}
+
+// bundleViewable 读取store中的Schema并用viewableRefs打包，同时返回其元数据
+func bundleViewable(c *gin.Context, store storage.SchemaStore, id string) ([]byte, storage.SchemaMetadata, error) {
+	data, metadata, err := store.GetSchema(id)
+	if err != nil {
+		return nil, metadata, err
+	}
+	bundled, err := refs.Bundle(viewableRefs(c, store), id, data)
+	return bundled, metadata, err
+}
+
+// visibleError 去掉错误中调用者无权查看的Schema ID
+func visibleError(c *gin.Context, err error) error {
+	var referenced *refs.ReferencedError
+	if errors.As(err, &referenced) {
+		return referenced.Visible(func(id string) bool {
+			return auth.Allowed(c, id, auth.RoleViewer)
+		})
+	}
+	return err
+}
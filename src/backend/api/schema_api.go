@@ -2,24 +2,69 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"goci/backend/audit"
+	"goci/backend/auth"
+	"goci/backend/impact"
+	"goci/backend/migration"
+	"goci/backend/refs"
 	"goci/backend/storage"
+	"goci/backend/validation"
 )
 
+// createAttempts 是创建Schema时生成的ID与已有Schema冲突后最多尝试的次数
+const createAttempts = 5
+
+// newSchemaID 为新建的Schema生成ID，测试中可以替换
+var newSchemaID = storage.NewID
+
 // SchemaHandler 处理Schema相关的API请求
 type SchemaHandler struct {
-	storage *storage.SchemaStorage
+	storage storage.SchemaStore
+	// configs 用于分析Schema变更对配置的影响，为nil时只比较Schema结构
+	configs storage.ConfigStore
 }
 
 // NewSchemaHandler 创建一个新的SchemaHandler实例
-func NewSchemaHandler(storage *storage.SchemaStorage) *SchemaHandler {
+func NewSchemaHandler(storage storage.SchemaStore, configs storage.ConfigStore) *SchemaHandler {
 	return &SchemaHandler{
 		storage: storage,
+		configs: configs,
+	}
+}
+
+// storageErrorStatus 将存储层错误映射为HTTP状态码
+func storageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrSchemaNotFound), errors.Is(err, storage.ErrVersionNotFound), errors.Is(err, storage.ErrNotInTrash), errors.Is(err, storage.ErrConfigNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrInvalidID), errors.Is(err, storage.ErrInvalidQuery), errors.Is(err, refs.ErrUnresolved), errors.Is(err, refs.ErrCycle):
+		return http.StatusBadRequest
+	case errors.Is(err, storage.ErrSchemaExists), errors.Is(err, storage.ErrConfigExists), errors.Is(err, refs.ErrReferenced):
+		return http.StatusConflict
+	case errors.Is(err, storage.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
 	}
 }
 
+// saveSchemaRequest 是保存Schema的请求体
+type saveSchemaRequest struct {
+	Metadata struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	} `json:"metadata"`
+	Schema json.RawMessage `json:"schema"`
+	// 可选的版本作者、说明和配置迁移步骤
+	Revision storage.RevisionInfo `json:"revision"`
+}
+
 // SaveSchema 处理保存Schema的请求
 func (h *SchemaHandler) SaveSchema(c *gin.Context) {
 	// 从URL参数获取Schema ID
@@ -30,19 +75,43 @@ func (h *SchemaHandler) SaveSchema(c *gin.Context) {
 	}
 
 	// 解析请求体
-	var requestBody struct {
-		Metadata struct {
-			Name        string `json:"name"`
-			Description string `json:"description"`
-		} `json:"metadata"`
-		Schema json.RawMessage `json:"schema"`
+	var requestBody saveSchemaRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse request body: " + err.Error()})
+		return
 	}
 
+	h.saveSchema(c, id, requestBody, http.StatusOK, c.GetHeader("If-Match"))
+}
+
+// CreateSchema 处理不带ID的创建Schema请求，ID由服务端根据名称生成
+func (h *SchemaHandler) CreateSchema(c *gin.Context) {
+	var requestBody saveSchemaRequest
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse request body: " + err.Error()})
 		return
 	}
 
+	// 生成的ID只在不存在时保存，极少数情况下与已有Schema冲突时换一个ID重试
+	for attempt := 0; attempt < createAttempts; attempt++ {
+		// 认证中间件只检查了任意Schema上的角色，生成ID后再检查该ID
+		id := newSchemaID(requestBody.Metadata.Name)
+		if !auth.Allowed(c, id, auth.RoleEditor) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "editor role required on schema " + id})
+			return
+		}
+
+		if taken := h.saveSchema(c, id, requestBody, http.StatusCreated, storage.CreateOnly); !taken {
+			return
+		}
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": "Failed to generate an unused schema ID"})
+}
+
+// saveSchema 校验并保存Schema，成功时以status返回，ifMatch是保存的条件
+//
+// ifMatch为storage.CreateOnly且ID已被占用时不写响应并返回true，由调用者换一个ID重试
+func (h *SchemaHandler) saveSchema(c *gin.Context, id string, requestBody saveSchemaRequest, status int, ifMatch string) (taken bool) {
 	// 获取元数据
 	name := requestBody.Metadata.Name
 	if name == "" {
@@ -57,21 +126,98 @@ func (h *SchemaHandler) SaveSchema(c *gin.Context) {
 		return
 	}
 
-	// 保存Schema
-	if err := h.storage.SaveSchema(id, name, description, schemaData); err != nil {
+	// 内联引用的其他Schema，引用不存在或形成环时拒绝保存
+	bundled, err := refs.Bundle(refs.Override(viewableRefs(c, h.storage), id, schemaData), id, schemaData)
+	if err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// 使用draft-07元Schema校验，避免保存无法渲染的Schema
+	if err := validation.ValidateSchema(bundled); err != nil {
+		var validationErr *validation.Error
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":      "Invalid JSON Schema",
+				"violations": validationErr.Violations,
+			})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// 迁移步骤在保存时校验，避免升级配置时才发现错误
+	if err := migration.Validate(requestBody.Revision.Migration); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 分析变更对已有配置的影响
+	previous, _, err := h.storage.GetSchema(id)
+	if err != nil && !errors.Is(err, storage.ErrSchemaNotFound) {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// 比较打包后的内容，引用的定义变化也会反映出来；旧内容无法打包时按原样比较
+	if previous != nil {
+		if bundledPrevious, err := refs.Bundle(viewableRefs(c, h.storage), id, previous); err == nil {
+			previous = bundledPrevious
+		}
+	}
+
+	report, err := impact.Analyze(id, previous, bundled, h.configs)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	// dryRun只返回影响分析，不保存
+	if dryRun, _ := strconv.ParseBool(c.Query("dryRun")); dryRun {
+		c.JSON(http.StatusOK, gin.H{
+			"metadata": gin.H{
+				"id":          id,
+				"name":        name,
+				"description": description,
+			},
+			"dryRun": true,
+			"impact": report,
+		})
+		return
+	}
+
+	// 保存Schema
+	// 携带If-Match时仅在Schema未被他人修改的情况下保存
+	info := requestBody.Revision
+	info.IfMatch = ifMatch
+
+	version, err := audit.Schemas(c, h.storage).SaveSchemaRevision(id, name, description, schemaData, info)
+	if ifMatch == storage.CreateOnly && errors.Is(err, storage.ErrSchemaExists) {
+		return true
+	}
+	if err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("ETag", storage.SchemaETag(version.Version, schemaData))
+	if status == http.StatusCreated {
+		c.Header("Location", "/api/schemas/"+id)
+	}
+
 	// 构建统一格式的响应
-	c.JSON(http.StatusOK, gin.H{
+	c.JSON(status, gin.H{
 		"metadata": gin.H{
 			"id":          id,
 			"name":        name,
 			"description": description,
+			"version":     version.Version,
 		},
 		"message": "Schema saved successfully",
+		"impact":  report,
 	})
+	return false
 }
 
 // GetSchema 处理获取Schema的请求
@@ -86,7 +232,24 @@ func (h *SchemaHandler) GetSchema(c *gin.Context) {
 	// 获取Schema
 	schemaData, metadata, err := h.storage.GetSchema(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// bundle=true时内联所有引用，ETag对应打包后的内容
+	if bundle, _ := strconv.ParseBool(c.Query("bundle")); bundle {
+		schemaData, err = refs.Bundle(viewableRefs(c, h.storage), id, schemaData)
+		if err != nil {
+			c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 内容未变化时返回304
+	etag := storage.SchemaETag(metadata.Version, schemaData)
+	c.Header("ETag", etag)
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" && storage.MatchETag(ifNoneMatch, etag) {
+		c.Status(http.StatusNotModified)
 		return
 	}
 
@@ -105,6 +268,7 @@ func (h *SchemaHandler) GetSchema(c *gin.Context) {
 			"description": metadata.Description,
 			"createdAt":   metadata.CreatedAt,
 			"updatedAt":   metadata.UpdatedAt,
+			"version":     metadata.Version,
 		},
 		"schema": schemaJSON,
 	}
@@ -113,17 +277,62 @@ func (h *SchemaHandler) GetSchema(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
-// ListSchemas 处理列出所有Schema的请求
+// defaultSchemaLimit 是未指定limit时每页返回的Schema数，maxSchemaLimit是limit的上限
+const (
+	defaultSchemaLimit = 100
+	maxSchemaLimit     = 1000
+)
+
+// ListSchemas 处理列出Schema的请求，支持过滤、排序、全文搜索和游标分页
+//
+// 查询参数：limit、cursor、sort（name、createdAt或updatedAt）、order（asc或desc）、
+// namePrefix、createdAfter、createdBefore、updatedAfter、updatedBefore（RFC 3339时间）和q（搜索词）
 func (h *SchemaHandler) ListSchemas(c *gin.Context) {
-	// 获取所有Schema
-	schemas, err := h.storage.ListSchemas()
+	query := storage.SchemaQuery{
+		Limit:      defaultSchemaLimit,
+		Cursor:     c.Query("cursor"),
+		Sort:       c.Query("sort"),
+		Order:      c.Query("order"),
+		NamePrefix: c.Query("namePrefix"),
+		Search:     c.Query("q"),
+		// 只列出调用者有权查看的Schema，总数也只计算这些
+		Visible: func(id string) bool { return auth.Allowed(c, id, auth.RoleViewer) },
+	}
+
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxSchemaLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit, expected 1 to " + strconv.Itoa(maxSchemaLimit) + ": " + value})
+			return
+		}
+		query.Limit = n
+	}
+
+	times := map[string]*time.Time{
+		"createdAfter":  &query.CreatedAfter,
+		"createdBefore": &query.CreatedBefore,
+		"updatedAfter":  &query.UpdatedAfter,
+		"updatedBefore": &query.UpdatedBefore,
+	}
+	for name, target := range times {
+		if value := c.Query(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name + ", expected RFC 3339 time: " + value})
+				return
+			}
+			*target = t
+		}
+	}
+
+	page, err := storage.QuerySchemas(h.storage, query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	// 构建统一格式的响应
-	c.JSON(http.StatusOK, gin.H{"schemas": schemas})
+	c.JSON(http.StatusOK, page)
 }
 
 // DeleteSchema 处理删除Schema的请求
@@ -135,9 +344,9 @@ func (h *SchemaHandler) DeleteSchema(c *gin.Context) {
 		return
 	}
 
-	// 删除Schema
-	if err := h.storage.DeleteSchema(id); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	// 删除Schema，携带If-Match时仅在ETag一致时删除
+	if err := audit.Schemas(c, h.storage).DeleteSchemaIfMatch(id, c.GetHeader("If-Match")); err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": visibleError(c, err).Error()})
 		return
 	}
 
@@ -145,16 +354,20 @@ func (h *SchemaHandler) DeleteSchema(c *gin.Context) {
 }
 
 // RegisterRoutes 注册API路由
-func RegisterRoutes(r *gin.Engine, storage *storage.SchemaStorage) {
+//
+// configs用于在保存Schema时分析对已有配置的影响，可以为nil
+func RegisterRoutes(r *gin.Engine, storage storage.SchemaStore, configs storage.ConfigStore) {
 	// 创建处理器
-	handler := NewSchemaHandler(storage)
+	handler := NewSchemaHandler(storage, configs)
 
 	// 创建API组
 	api := r.Group("/api")
 	{
 		// Schema API
-		schemas := api.Group("/schemas")
+		schemas := api.Group("/schemas", validateIDs, negotiate)
 		{
+			// 创建Schema，由服务端生成ID
+			schemas.POST("", handler.CreateSchema)
 			// 保存Schema
 			schemas.POST("/:id", handler.SaveSchema)
 			// 获取Schema
@@ -163,6 +376,29 @@ func RegisterRoutes(r *gin.Engine, storage *storage.SchemaStorage) {
 			schemas.GET("", handler.ListSchemas)
 			// 删除Schema
 			schemas.DELETE("/:id", handler.DeleteSchema)
+			// 列出Schema历史版本
+			schemas.GET("/:id/versions", handler.ListVersions)
+			// 获取Schema指定版本
+			schemas.GET("/:id/versions/:version", handler.GetVersion)
+			// 恢复Schema指定版本
+			schemas.POST("/:id/versions/:version/restore", handler.RestoreVersion)
+			// 生成默认配置
+			schemas.GET("/:id/default", handler.GetDefaultConfig)
+			// 生成Go代码
+			schemas.GET("/:id/codegen/go", handler.GenerateGo)
 		}
+
+		// 回收站API
+		trash := api.Group("/trash", validateIDs, negotiate)
+		{
+			// 列出回收站中的Schema
+			trash.GET("", handler.ListTrash)
+			// 从回收站恢复Schema
+			trash.POST("/:id/restore", handler.RestoreSchema)
+		}
+
+		// 导出和导入Schema归档
+		api.GET("/export", handler.Export)
+		api.POST("/import", handler.Import)
 	}
 }
@@ -3,10 +3,12 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -50,10 +52,10 @@ func setupTest(t *testing.T) (*gin.Engine, *storage.SchemaStorage, string) {
 	r := gin.New()
 
 	// 创建存储实例
-	schemaStorage := storage.NewSchemaStorage()
+	schemaStorage := storage.NewSchemaStorage(".")
 
 	// 注册API路由
-	RegisterRoutes(r, schemaStorage)
+	RegisterRoutes(r, schemaStorage, nil)
 
 	return r, schemaStorage, oldWd
 }
@@ -71,9 +73,14 @@ func TestSaveSchemaAPI(t *testing.T) {
 	schemaData := []byte(`{"type": "object", "properties": {"name": {"type": "string"}}}`)
 
 	// 创建请求
-	req := httptest.NewRequest(http.MethodPost, "/api/schemas/"+id, bytes.NewBuffer(schemaData))
-	req.Header.Set("X-Schema-Name", name)
-	req.Header.Set("X-Schema-Description", description)
+	body, err := json.Marshal(gin.H{
+		"metadata": gin.H{"name": name, "description": description},
+		"schema":   json.RawMessage(schemaData),
+	})
+	if err != nil {
+		t.Fatalf("Failed to build request body: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/schemas/"+id, bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
 
 	// 创建响应记录器
@@ -97,8 +104,9 @@ func TestSaveSchemaAPI(t *testing.T) {
 		t.Errorf("Expected message 'Schema saved successfully', got %v", response["message"])
 	}
 
-	if responseID, ok := response["id"].(string); !ok || responseID != id {
-		t.Errorf("Expected id '%s', got %v", id, response["id"])
+	metadata, _ := response["metadata"].(map[string]interface{})
+	if responseID, ok := metadata["id"].(string); !ok || responseID != id {
+		t.Errorf("Expected id '%s', got %v", id, metadata["id"])
 	}
 
 	// 验证Schema文件是否已创建
@@ -114,8 +122,70 @@ func TestSaveSchemaAPI(t *testing.T) {
 		t.Fatalf("Failed to read schema file: %v", err)
 	}
 
-	if string(data) != string(schemaData) {
-		t.Errorf("Schema content is incorrect: got %s, want %s", string(data), string(schemaData))
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, schemaData); err != nil {
+		t.Fatalf("Failed to compact schema: %v", err)
+	}
+
+	if string(data) != compacted.String() {
+		t.Errorf("Schema content is incorrect: got %s, want %s", string(data), compacted.String())
+	}
+}
+
+// 测试SaveSchema API拒绝不合法的Schema
+func TestSaveSchemaAPIInvalidSchema(t *testing.T) {
+	// 设置测试环境
+	r, _, oldWd := setupTest(t)
+	defer os.Chdir(oldWd)
+
+	tests := []struct {
+		name    string
+		schema  string
+		pointer string
+	}{
+		{"missing", `null`, ""},
+		{"array", `[]`, ""},
+		{"unknown type", `{"type": "strnig"}`, "/type"},
+		{"nested", `{"type": "object", "properties": {"port": {"minimum": "1"}}}`, "/properties/port/minimum"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := []byte(`{"metadata": {"name": "Invalid"}, "schema": ` + tt.schema + `}`)
+			req := httptest.NewRequest(http.MethodPost, "/api/schemas/invalid-schema", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status code %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+			}
+
+			var response struct {
+				Violations []struct {
+					Pointer string `json:"pointer"`
+					Message string `json:"message"`
+				} `json:"violations"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+
+			if len(response.Violations) == 0 {
+				t.Fatalf("Expected violations in response: %s", w.Body.String())
+			}
+
+			for _, violation := range response.Violations {
+				if violation.Pointer != tt.pointer {
+					t.Errorf("Pointer is incorrect: got %q, want %q", violation.Pointer, tt.pointer)
+				}
+			}
+		})
+	}
+
+	// 不合法的Schema不应被保存
+	if _, err := os.Stat(filepath.Join(".", "schemas", "invalid-schema")); !os.IsNotExist(err) {
+		t.Errorf("Invalid schema directory was created")
 	}
 }
 
@@ -151,21 +221,44 @@ func TestGetSchemaAPI(t *testing.T) {
 	}
 
 	// 验证响应头
-	if w.Header().Get("Content-Type") != "application/json" {
-		t.Errorf("Expected Content-Type 'application/json', got %s", w.Header().Get("Content-Type"))
+	if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		t.Errorf("Expected Content-Type 'application/json', got %s", contentType)
 	}
 
-	if w.Header().Get("X-Schema-Name") != name {
-		t.Errorf("Expected X-Schema-Name '%s', got %s", name, w.Header().Get("X-Schema-Name"))
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Errorf("Expected ETag header")
 	}
 
-	if w.Header().Get("X-Schema-Description") != description {
-		t.Errorf("Expected X-Schema-Description '%s', got %s", description, w.Header().Get("X-Schema-Description"))
+	// 验证响应内容
+	var response struct {
+		Metadata storage.SchemaMetadata `json:"metadata"`
+		Schema   map[string]interface{} `json:"schema"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
 	}
 
-	// 验证响应内容
-	if string(w.Body.Bytes()) != string(schemaData) {
-		t.Errorf("Schema content is incorrect: got %s, want %s", string(w.Body.Bytes()), string(schemaData))
+	if response.Metadata.Name != name {
+		t.Errorf("Expected name '%s', got %s", name, response.Metadata.Name)
+	}
+
+	if response.Metadata.Description != description {
+		t.Errorf("Expected description '%s', got %s", description, response.Metadata.Description)
+	}
+
+	if response.Schema["type"] != "object" {
+		t.Errorf("Schema content is incorrect: got %s", w.Body.String())
+	}
+
+	// 内容未变化时返回304
+	req = httptest.NewRequest(http.MethodGet, "/api/schemas/"+id, nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotModified, w.Code)
 	}
 
 	// 测试获取不存在的Schema
@@ -228,14 +321,15 @@ func TestListSchemasAPI(t *testing.T) {
 	}
 
 	// 验证响应内容
-	var response []storage.SchemaMetadata
-	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+	var page storage.SchemaPage
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
 		t.Fatalf("Failed to parse response: %v", err)
 	}
+	response := page.Schemas
 
 	// 验证列表长度是否正确
-	if len(response) != len(schemas) {
-		t.Errorf("Schema list length is incorrect: got %d, want %d", len(response), len(schemas))
+	if len(response) != len(schemas) || page.Total != len(schemas) || page.NextCursor != "" {
+		t.Errorf("Schema list length is incorrect: got %d (total %d), want %d", len(response), page.Total, len(schemas))
 	}
 
 	// 验证列表内容是否正确
@@ -320,3 +414,27 @@ func TestDeleteSchemaAPI(t *testing.T) {
 		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
 	}
 }
+
+// failingSchemaStore 读取Schema时总是返回I/O错误
+type failingSchemaStore struct {
+	storage.SchemaStore
+}
+
+func (failingSchemaStore) GetSchema(id string) ([]byte, storage.SchemaMetadata, error) {
+	return nil, storage.SchemaMetadata{}, errors.New("disk failure")
+}
+
+// 测试读取Schema的存储错误返回500而不是404
+func TestGetSchemaStorageError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	RegisterRoutes(r, failingSchemaStore{SchemaStore: storage.NewMemorySchemaStore()}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/schemas/app", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusInternalServerError, w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// 测试保存和删除Schema时的If-Match并发控制
+func TestSchemaIfMatch(t *testing.T) {
+	// 设置测试环境
+	r, _, oldWd := setupTest(t)
+	defer os.Chdir(oldWd)
+
+	id := "test-schema"
+	save := func(schema string, ifMatch string) *httptest.ResponseRecorder {
+		body := `{"metadata": {"name": "Test"}, "schema": ` + schema + `}`
+		req := httptest.NewRequest(http.MethodPost, "/api/schemas/"+id, bytes.NewBufferString(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// 不存在的Schema不能满足If-Match
+	if w := save(`{"type": "object"}`, `"stale"`); w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("Expected status code %d, got %d", http.StatusPreconditionFailed, w.Code)
+	}
+
+	w := save(`{"type": "object"}`, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	etag := w.Header().Get("ETag")
+
+	// 两个编辑者基于同一个ETag保存，后保存的被拒绝
+	w = save(`{"type": "object", "title": "first"}`, etag)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	newETag := w.Header().Get("ETag")
+	if newETag == "" || newETag == etag {
+		t.Errorf("Expected a new ETag after save, got %q", newETag)
+	}
+
+	if w := save(`{"type": "object", "title": "second"}`, etag); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected status code %d, got %d", http.StatusPreconditionFailed, w.Code)
+	}
+
+	// 过期的ETag不能删除
+	req := httptest.NewRequest(http.MethodDelete, "/api/schemas/"+id, nil)
+	req.Header.Set("If-Match", etag)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected status code %d, got %d", http.StatusPreconditionFailed, w.Code)
+	}
+
+	// 当前ETag可以删除
+	req = httptest.NewRequest(http.MethodDelete, "/api/schemas/"+id, nil)
+	req.Header.Set("If-Match", newETag)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+}
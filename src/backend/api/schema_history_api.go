@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/audit"
+	"goci/backend/storage"
+)
+
+// parseVersion 从URL参数解析版本号，失败时已写入错误响应
+func parseVersion(c *gin.Context) (int, bool) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Version must be a positive integer"})
+		return 0, false
+	}
+	return version, true
+}
+
+// ListVersions 处理列出Schema历史版本的请求
+func (h *SchemaHandler) ListVersions(c *gin.Context) {
+	id := c.Param("id")
+
+	versions, err := h.storage.ListVersions(id)
+	if err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": id, "versions": versions})
+}
+
+// GetVersion 处理获取Schema指定版本的请求
+func (h *SchemaHandler) GetVersion(c *gin.Context) {
+	id := c.Param("id")
+	version, ok := parseVersion(c)
+	if !ok {
+		return
+	}
+
+	schemaData, metadata, err := h.storage.GetVersion(id, version)
+	if err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":       id,
+		"metadata": metadata,
+		"schema":   json.RawMessage(schemaData),
+	})
+}
+
+// RestoreVersion 处理将Schema恢复为指定版本的请求
+func (h *SchemaHandler) RestoreVersion(c *gin.Context) {
+	id := c.Param("id")
+	version, ok := parseVersion(c)
+	if !ok {
+		return
+	}
+
+	// 请求体可选，用于记录作者和说明
+	var info storage.RevisionInfo
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&info); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse request body: " + err.Error()})
+			return
+		}
+	}
+
+	info.IfMatch = c.GetHeader("If-Match")
+
+	restored, err := audit.Schemas(c, h.storage).RestoreVersion(id, version, info)
+	if err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// 返回恢复后内容的ETag，便于客户端继续编辑
+	if schemaData, _, err := h.storage.GetVersion(id, restored.Version); err == nil {
+		c.Header("ETag", storage.SchemaETag(restored.Version, schemaData))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":       id,
+		"metadata": restored,
+		"message":  "Schema restored successfully",
+	})
+}
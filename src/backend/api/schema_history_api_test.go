@@ -0,0 +1,110 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"goci/backend/storage"
+)
+
+// 测试Schema历史版本API
+func TestSchemaVersionsAPI(t *testing.T) {
+	// 设置测试环境
+	r, _, oldWd := setupTest(t)
+	defer os.Chdir(oldWd)
+
+	id := "test-schema"
+	bodies := []string{
+		`{"metadata": {"name": "Test"}, "schema": {"type": "object"}, "revision": {"author": "alice", "message": "initial"}}`,
+		`{"metadata": {"name": "Test"}, "schema": {"type": "string"}, "revision": {"author": "bob"}}`,
+	}
+
+	for _, body := range bodies {
+		req := httptest.NewRequest(http.MethodPost, "/api/schemas/"+id, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+
+	// 列出历史版本
+	req := httptest.NewRequest(http.MethodGet, "/api/schemas/"+id+"/versions", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var list struct {
+		Versions []storage.SchemaVersion `json:"versions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if len(list.Versions) != 2 || list.Versions[0].Author != "alice" || list.Versions[1].Author != "bob" {
+		t.Errorf("Unexpected versions: %+v", list.Versions)
+	}
+
+	// 获取第一个版本
+	req = httptest.NewRequest(http.MethodGet, "/api/schemas/"+id+"/versions/1", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var version struct {
+		Metadata storage.SchemaVersion  `json:"metadata"`
+		Schema   map[string]interface{} `json:"schema"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &version); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if version.Metadata.Version != 1 || version.Schema["type"] != "object" {
+		t.Errorf("Unexpected version response: %s", w.Body.String())
+	}
+
+	// 恢复第一个版本
+	req = httptest.NewRequest(http.MethodPost, "/api/schemas/"+id+"/versions/1/restore", bytes.NewBufferString(`{"author": "carol"}`))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var restored struct {
+		Metadata storage.SchemaVersion `json:"metadata"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &restored); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if restored.Metadata.Version != 3 || restored.Metadata.Author != "carol" {
+		t.Errorf("Unexpected restored version: %+v", restored.Metadata)
+	}
+
+	// 错误情况
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodGet, "/api/schemas/" + id + "/versions/9", http.StatusNotFound},
+		{http.MethodGet, "/api/schemas/" + id + "/versions/abc", http.StatusBadRequest},
+		{http.MethodGet, "/api/schemas/non-existent/versions", http.StatusNotFound},
+		{http.MethodPost, "/api/schemas/non-existent/versions/1/restore", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		req = httptest.NewRequest(tt.method, tt.path, nil)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s %s: expected status code %d, got %d", tt.method, tt.path, tt.status, w.Code)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/impact"
+	"goci/backend/storage"
+)
+
+// 测试保存Schema时返回影响分析，dryRun时不保存
+func TestSaveSchemaImpact(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	schemas := storage.NewMemorySchemaStore()
+	configs := storage.NewMemoryConfigStore()
+	RegisterRoutes(r, schemas, configs)
+
+	id := "app"
+	schemas.SaveSchema(id, "App", "", []byte(`{"type": "object", "properties": {"port": {"type": "integer"}}}`))
+	configs.SaveConfig(id, []byte(`{"port": 8080}`))
+
+	save := func(query string) (*httptest.ResponseRecorder, impact.Report) {
+		body := `{"metadata": {"name": "App"}, "schema": {"type": "object", "properties": {"port": {"type": "integer", "maximum": 1024}}}}`
+		req := httptest.NewRequest(http.MethodPost, "/api/schemas/"+id+query, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var response struct {
+			Impact impact.Report `json:"impact"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response.Impact
+	}
+
+	// 预览影响
+	w, report := save("?dryRun=true")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if !report.Breaking || report.InvalidConfigs != 1 || len(report.Changes) != 1 || report.Changes[0].Keyword != "maximum" {
+		t.Errorf("Unexpected impact: %+v", report)
+	}
+
+	if _, metadata, _ := schemas.GetSchema(id); metadata.Version != 1 {
+		t.Errorf("Dry run should not save the schema, version is %d", metadata.Version)
+	}
+
+	// 实际保存也返回影响
+	w, report = save("")
+	if w.Code != http.StatusOK || report.InvalidConfigs != 1 {
+		t.Errorf("Unexpected response: %d %+v", w.Code, report)
+	}
+
+	if _, metadata, _ := schemas.GetSchema(id); metadata.Version != 2 {
+		t.Errorf("Schema was not saved, version is %d", metadata.Version)
+	}
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/auth"
+	"goci/backend/refs"
+	"goci/backend/storage"
+)
+
+// 测试引用共享定义的Schema：打包、验证配置、拒绝无法解析的引用和删除被引用的定义
+func TestSchemaRefsAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	schemas := refs.WithReferenceCheck(storage.NewMemorySchemaStore())
+	configs := storage.NewMemoryConfigStore()
+	RegisterRoutes(r, schemas, configs)
+	RegisterConfigRoutes(r, configs, schemas)
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// 引用不存在的定义
+	w := request(http.MethodPost, "/api/schemas/app", `{"metadata": {"name": "App"}, "schema": {"properties": {"port": {"$ref": "goci://defs/port"}}}}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	w = request(http.MethodPost, "/api/schemas/port", `{"metadata": {"name": "Port"}, "schema": {"type": "integer", "minimum": 1, "maximum": 65535}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	w = request(http.MethodPost, "/api/schemas/app", `{"metadata": {"name": "App"}, "schema": {"type": "object", "properties": {"port": {"$ref": "goci://defs/port"}}}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// 默认返回原始内容，bundle=true时把引用的定义放进definitions
+	w = request(http.MethodGet, "/api/schemas/app", "")
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"$ref":"goci://defs/port"`)) {
+		t.Errorf("Expected raw schema, got %d: %s", w.Code, w.Body.String())
+	}
+	rawETag := w.Header().Get("ETag")
+
+	w = request(http.MethodGet, "/api/schemas/app?bundle=true", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Schema struct {
+			Properties  map[string]map[string]any `json:"properties"`
+			Definitions map[string]map[string]any `json:"definitions"`
+		} `json:"schema"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if port := response.Schema.Properties["port"]; port["$ref"] != "#/definitions/port" || response.Schema.Definitions["port"]["type"] != "integer" {
+		t.Errorf("Expected inlined port definition, got %v, %v", port, response.Schema.Definitions)
+	}
+	if w.Header().Get("ETag") == rawETag {
+		t.Errorf("Expected bundled ETag to differ from raw ETag")
+	}
+
+	// 配置按打包后的Schema验证
+	w = request(http.MethodPost, "/api/configs/app", `{"config": {"port": 0}}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+	w = request(http.MethodPost, "/api/configs/app", `{"config": {"port": 8080}}`)
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	// 共享定义内部的递归引用不是环，配置按递归的定义验证
+	w = request(http.MethodPost, "/api/schemas/menu", `{"metadata": {"name": "Menu"}, "schema": {"$ref": "#/definitions/item", "definitions": {"item": {"type": "object", "properties": {"label": {"type": "string"}, "children": {"type": "array", "items": {"$ref": "#/definitions/item"}}}}}}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	w = request(http.MethodPost, "/api/schemas/site", `{"metadata": {"name": "Site"}, "schema": {"type": "object", "properties": {"menu": {"$ref": "goci://defs/menu"}}}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	w = request(http.MethodPost, "/api/configs/site", `{"config": {"menu": {"label": "root", "children": [{"label": 1}]}}}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+	w = request(http.MethodPost, "/api/configs/site", `{"config": {"menu": {"label": "root", "children": [{"label": "child"}]}}}`)
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	// 文档之间形成环的引用
+	w = request(http.MethodPost, "/api/schemas/port", `{"metadata": {"name": "Port"}, "schema": {"$ref": "app#/properties/port"}}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	// 被引用的定义不能删除
+	w = request(http.MethodDelete, "/api/schemas/port", "")
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+	w = request(http.MethodDelete, "/api/schemas/app", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	w = request(http.MethodDelete, "/api/schemas/port", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+}
+
+// 测试启用认证时只能引用和内联有权查看的Schema，409响应不列出无权查看的引用方
+func TestSchemaRefsAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	tokens, err := auth.NewTokenAuthenticator([]auth.Token{
+		{Name: "team", Token: "secret", Grants: []auth.Grant{{Role: auth.RoleAdmin, Schema: "team-*"}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	r.Use(auth.Middleware([]auth.Authenticator{tokens}, nil))
+
+	schemas := refs.WithReferenceCheck(storage.NewMemorySchemaStore())
+	configs := storage.NewMemoryConfigStore()
+	RegisterRoutes(r, schemas, configs)
+	RegisterConfigRoutes(r, configs, schemas)
+
+	// 直接写入存储，模拟其他团队保存的Schema
+	for _, schema := range []struct{ id, content string }{
+		{"team-port", `{"type": "integer"}`},
+		{"other-port", `{"type": "integer", "maximum": 1024}`},
+		{"team-app", `{"type": "object", "properties": {"port": {"$ref": "team-port"}}}`},
+		{"other-app", `{"type": "object", "properties": {"port": {"$ref": "team-port"}}}`},
+		{"team-leak", `{"type": "object", "properties": {"port": {"$ref": "other-port"}}}`},
+	} {
+		if err := schemas.SaveSchema(schema.id, schema.id, "", []byte(schema.content)); err != nil {
+			t.Fatalf("Failed to save schema %s: %v", schema.id, err)
+		}
+	}
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	errorMessage := func(w *httptest.ResponseRecorder) string {
+		var response struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response.Error
+	}
+
+	// 引用无权查看的Schema与引用不存在的Schema得到相同的错误
+	w := request(http.MethodPost, "/api/schemas/team-new", `{"schema": {"properties": {"port": {"$ref": "other-port"}}}}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	hidden := errorMessage(w)
+	w = request(http.MethodPost, "/api/schemas/team-new", `{"schema": {"properties": {"port": {"$ref": "missing-port"}}}}`)
+	if missing := errorMessage(w); w.Code != http.StatusBadRequest || strings.ReplaceAll(missing, "missing-port", "other-port") != hidden {
+		t.Errorf("Expected the same error as a missing schema, got %q and %q", hidden, missing)
+	}
+
+	// 已保存的引用也不能在打包、生成和校验时读取
+	for _, path := range []string{
+		"/api/schemas/team-leak?bundle=true",
+		"/api/schemas/team-leak/default",
+		"/api/schemas/team-leak/codegen/go",
+	} {
+		w = request(http.MethodGet, path, "")
+		if w.Code != http.StatusBadRequest || strings.Contains(w.Body.String(), "1024") {
+			t.Errorf("Expected status code %d for %s, got %d: %s", http.StatusBadRequest, path, w.Code, w.Body.String())
+		}
+	}
+	w = request(http.MethodPost, "/api/configs/team-leak", `{"config": {"port": 8080}}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	// 有权查看的引用正常打包
+	w = request(http.MethodGet, "/api/schemas/team-app?bundle=true", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"integer"`) {
+		t.Errorf("Expected bundled schema, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// 409响应只列出有权查看的引用方
+	w = request(http.MethodDelete, "/api/schemas/team-port", "")
+	if message := errorMessage(w); w.Code != http.StatusConflict || message != "schema is referenced: team-port is referenced by team-app and other schemas" {
+		t.Errorf("Unexpected conflict: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/audit"
+	"goci/backend/auth"
+)
+
+// ListTrash 处理列出回收站中Schema的请求
+func (h *SchemaHandler) ListTrash(c *gin.Context) {
+	trash, err := h.storage.ListTrash()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 只列出调用者有权查看的Schema
+	visible := trash[:0]
+	for _, trashed := range trash {
+		if auth.Allowed(c, trashed.ID, auth.RoleViewer) {
+			visible = append(visible, trashed)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schemas": visible})
+}
+
+// RestoreSchema 处理从回收站恢复Schema的请求，查询参数trashId选择同一Schema的某个副本，缺省时恢复最近删除的副本
+func (h *SchemaHandler) RestoreSchema(c *gin.Context) {
+	id := c.Param("id")
+
+	metadata, err := audit.Schemas(c, h.storage).RestoreSchema(id, c.Query("trashId"))
+	if err != nil {
+		c.JSON(storageErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Schema restored successfully", "metadata": metadata})
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/storage"
+)
+
+// 测试删除的Schema进入回收站并可以恢复，其配置被标记为孤立
+func TestSchemaTrashAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	schemas := storage.NewMemorySchemaStore()
+	configs := storage.NewMemoryConfigStore()
+	RegisterRoutes(r, schemas, configs)
+	RegisterConfigRoutes(r, configs, schemas)
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request(http.MethodPost, "/api/schemas/app", `{"metadata": {"name": "App"}, "schema": {"type": "object"}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	w = request(http.MethodPost, "/api/configs/app", `{"config": {}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	w = request(http.MethodDelete, "/api/schemas/app", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// 回收站中的Schema
+	w = request(http.MethodGet, "/api/trash", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var trashResponse struct {
+		Schemas []storage.TrashedSchema `json:"schemas"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &trashResponse); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(trashResponse.Schemas) != 1 || trashResponse.Schemas[0].ID != "app" || trashResponse.Schemas[0].DeletedAt == "" {
+		t.Errorf("Unexpected trash: %+v", trashResponse.Schemas)
+	}
+
+	// 配置仍然保留，并标记为孤立
+	orphaned := func() bool {
+		w := request(http.MethodGet, "/api/configs", "")
+		var response struct {
+			Configs []storage.ConfigMetadata `json:"configs"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if len(response.Configs) != 1 {
+			t.Fatalf("Unexpected configs: %s", w.Body.String())
+		}
+
+		w = request(http.MethodGet, "/api/configs/app", "")
+		var config struct {
+			Metadata storage.ConfigMetadata `json:"metadata"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &config)
+		if config.Metadata.Orphaned != response.Configs[0].Orphaned {
+			t.Errorf("Orphaned flag differs between list and get: %s", w.Body.String())
+		}
+		return config.Metadata.Orphaned
+	}
+	if !orphaned() {
+		t.Errorf("Expected config to be orphaned")
+	}
+
+	// 恢复
+	w = request(http.MethodPost, "/api/trash/app/restore", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if orphaned() {
+		t.Errorf("Expected config not to be orphaned after restore")
+	}
+	w = request(http.MethodGet, "/api/schemas/app", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// 不在回收站中
+	w = request(http.MethodPost, "/api/trash/app/restore", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	// 同一ID已被重新创建
+	request(http.MethodDelete, "/api/schemas/app", "")
+	request(http.MethodPost, "/api/schemas/app", `{"schema": {"type": "object"}}`)
+	w = request(http.MethodPost, "/api/trash/app/restore", "")
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status code %d, got %d", http.StatusConflict, w.Code)
+	}
+
+	// 再次删除后回收站中有两个副本，可以按trashId选择
+	request(http.MethodDelete, "/api/schemas/app", "")
+	w = request(http.MethodGet, "/api/trash", "")
+	trashResponse.Schemas = nil
+	if err := json.Unmarshal(w.Body.Bytes(), &trashResponse); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(trashResponse.Schemas) != 2 || trashResponse.Schemas[1].Name != "App" {
+		t.Fatalf("Unexpected trash: %+v", trashResponse.Schemas)
+	}
+	w = request(http.MethodPost, "/api/trash/app/restore?trashId=app@1", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+	w = request(http.MethodPost, "/api/trash/app/restore?trashId="+trashResponse.Schemas[1].TrashID, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var restoreResponse struct {
+		Metadata storage.SchemaMetadata `json:"metadata"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &restoreResponse); err != nil || restoreResponse.Metadata.Name != "App" {
+		t.Errorf("Unexpected restored schema: %s, %v", w.Body.String(), err)
+	}
+}
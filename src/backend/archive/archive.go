@@ -0,0 +1,436 @@
+// Package archive 把Schema连同元数据和历史导出为tar.gz或zip归档，并把归档导入另一个环境
+//
+// 归档中的文件布局与文件系统后端的数据目录一致：
+//
+//	manifest.json
+//	schemas/schema-registry.json
+//	schemas/<id>/schema.json
+//	schemas/<id>/history/versions.json
+//	schemas/<id>/history/schema_v<n>.json
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"goci/backend/storage"
+)
+
+// ErrInvalidArchive 表示归档无法读取或内容不完整
+var ErrInvalidArchive = errors.New("invalid archive")
+
+// Format 是归档格式
+type Format string
+
+const (
+	// FormatTarGz 是gzip压缩的tar归档
+	FormatTarGz Format = "tar.gz"
+	// FormatZip 是zip归档
+	FormatZip Format = "zip"
+)
+
+// FormatVersion 是当前的归档格式版本，读取时拒绝更高的版本
+const FormatVersion = 1
+
+// MaxSize 是归档解压后所有文件的总大小上限
+const MaxSize = 256 << 20
+
+const (
+	manifestPath = "manifest.json"
+	registryPath = "schemas/schema-registry.json"
+)
+
+// ParseFormat 解析归档格式，空字符串表示tar.gz
+func ParseFormat(value string) (Format, error) {
+	switch value {
+	case "", "tar.gz", "tgz":
+		return FormatTarGz, nil
+	case "zip":
+		return FormatZip, nil
+	default:
+		return "", fmt.Errorf("unknown archive format %q, expected tar.gz or zip", value)
+	}
+}
+
+// ContentType 返回归档的MIME类型
+func (f Format) ContentType() string {
+	if f == FormatZip {
+		return "application/zip"
+	}
+	return "application/gzip"
+}
+
+// Manifest 描述归档本身
+type Manifest struct {
+	Format     int    `json:"format"`
+	ExportedAt string `json:"exportedAt"`
+}
+
+// Schema 是归档中的一个Schema
+type Schema struct {
+	Metadata storage.SchemaMetadata
+	// Content 是Schema的当前内容
+	Content []byte
+	// Versions 是按版本号升序排列的历史，旧数据可能没有历史
+	Versions []Version
+}
+
+// Version 是归档中的一个历史版本
+type Version struct {
+	storage.SchemaVersion
+	Content []byte
+}
+
+// schemaDir 返回Schema在归档中的目录
+func schemaDir(id string) string {
+	return path.Join("schemas", id)
+}
+
+// versionPath 返回版本内容在归档中的路径
+func versionPath(id string, version int) string {
+	return path.Join(schemaDir(id), "history", fmt.Sprintf("schema_v%d.json", version))
+}
+
+// Collect 从store读取要导出的Schema，ids为空时导出全部Schema，结果按ID排序
+func Collect(store storage.SchemaStore, ids []string) ([]Schema, error) {
+	if len(ids) == 0 {
+		all, err := store.ListSchemas()
+		if err != nil {
+			return nil, err
+		}
+		for _, metadata := range all {
+			ids = append(ids, metadata.ID)
+		}
+	}
+
+	seen := make(map[string]bool)
+	schemas := []Schema{}
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		content, metadata, err := store.GetSchema(id)
+		if err != nil {
+			return nil, err
+		}
+		versions, err := store.ListVersions(id)
+		if err != nil {
+			return nil, err
+		}
+
+		schema := Schema{Metadata: metadata, Content: content}
+		for _, version := range versions {
+			data, _, err := store.GetVersion(id, version.Version)
+			if err != nil {
+				return nil, err
+			}
+			schema.Versions = append(schema.Versions, Version{SchemaVersion: version, Content: data})
+		}
+		schemas = append(schemas, schema)
+	}
+
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Metadata.ID < schemas[j].Metadata.ID })
+	return schemas, nil
+}
+
+// Export 把store中的Schema写入归档，ids为空时导出全部Schema
+//
+// 所有内容读取成功后才开始写入，Schema不存在时返回storage.ErrSchemaNotFound
+func Export(w io.Writer, store storage.SchemaStore, ids []string, format Format) error {
+	schemas, err := Collect(store, ids)
+	if err != nil {
+		return err
+	}
+	return Write(w, schemas, format)
+}
+
+// Write 把Schema写入归档
+func Write(w io.Writer, schemas []Schema, format Format) error {
+	var archive archiveWriter
+	if format == FormatZip {
+		archive = &zipWriter{zip.NewWriter(w)}
+	} else {
+		gz := gzip.NewWriter(w)
+		archive = &tarWriter{gz: gz, tar: tar.NewWriter(gz)}
+	}
+
+	files, err := encode(schemas)
+	if err != nil {
+		return err
+	}
+
+	modTime := time.Now()
+	for _, file := range files {
+		if err := archive.add(file.name, file.data, modTime); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// file 是归档中的一个文件
+type file struct {
+	name string
+	data []byte
+}
+
+// encode 返回归档中的所有文件
+func encode(schemas []Schema) ([]file, error) {
+	marshal := func(v any) ([]byte, error) {
+		return json.MarshalIndent(v, "", "  ")
+	}
+
+	manifest, err := marshal(Manifest{Format: FormatVersion, ExportedAt: time.Now().Format(time.RFC3339)})
+	if err != nil {
+		return nil, err
+	}
+	registry := make(map[string]storage.SchemaMetadata, len(schemas))
+	for _, schema := range schemas {
+		registry[schema.Metadata.ID] = schema.Metadata
+	}
+	registryData, err := marshal(registry)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []file{{manifestPath, manifest}, {registryPath, registryData}}
+	for _, schema := range schemas {
+		id := schema.Metadata.ID
+		files = append(files, file{path.Join(schemaDir(id), "schema.json"), schema.Content})
+		if len(schema.Versions) == 0 {
+			continue
+		}
+
+		index := make([]storage.SchemaVersion, len(schema.Versions))
+		for i, version := range schema.Versions {
+			index[i] = version.SchemaVersion
+		}
+		indexData, err := marshal(index)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file{path.Join(schemaDir(id), "history", "versions.json"), indexData})
+		for _, version := range schema.Versions {
+			files = append(files, file{versionPath(id, version.Version), version.Content})
+		}
+	}
+	return files, nil
+}
+
+// archiveWriter 向归档中添加文件
+type archiveWriter interface {
+	add(name string, data []byte, modTime time.Time) error
+	Close() error
+}
+
+// tarWriter 写入tar.gz归档
+type tarWriter struct {
+	gz  *gzip.Writer
+	tar *tar.Writer
+}
+
+// add 添加一个文件
+func (w *tarWriter) add(name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: modTime, Typeflag: tar.TypeReg}
+	if err := w.tar.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := w.tar.Write(data)
+	return err
+}
+
+// Close 完成tar和gzip流
+func (w *tarWriter) Close() error {
+	if err := w.tar.Close(); err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
+
+// zipWriter 写入zip归档
+type zipWriter struct {
+	zip *zip.Writer
+}
+
+// add 添加一个文件
+func (w *zipWriter) add(name string, data []byte, modTime time.Time) error {
+	writer, err := w.zip.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime})
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	return err
+}
+
+// Close 写入zip目录
+func (w *zipWriter) Close() error {
+	return w.zip.Close()
+}
+
+// Read 读取tar.gz或zip归档，格式根据内容判断
+func Read(r io.Reader) ([]Schema, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxSize {
+		return nil, fmt.Errorf("%w: larger than %d bytes", ErrInvalidArchive, MaxSize)
+	}
+
+	var files map[string][]byte
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		files, err = readTarGz(data)
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")), bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		files, err = readZip(data)
+	default:
+		return nil, fmt.Errorf("%w: not a tar.gz or zip archive", ErrInvalidArchive)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	return decode(files)
+}
+
+// cleanName 规范化归档中的文件名，去掉开头的./
+func cleanName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// readTarGz 读取tar.gz归档中的所有普通文件
+func readTarGz(data []byte) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	files := make(map[string][]byte)
+	size := 0
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		content, err := io.ReadAll(io.LimitReader(reader, int64(MaxSize-size+1)))
+		if err != nil {
+			return nil, err
+		}
+		size += len(content)
+		if size > MaxSize {
+			return nil, fmt.Errorf("contents larger than %d bytes", MaxSize)
+		}
+		files[cleanName(header.Name)] = content
+	}
+}
+
+// readZip 读取zip归档中的所有文件
+func readZip(data []byte) (map[string][]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	size := 0
+	for _, entry := range reader.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, err := entry.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, int64(MaxSize-size+1)))
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		size += len(content)
+		if size > MaxSize {
+			return nil, fmt.Errorf("contents larger than %d bytes", MaxSize)
+		}
+		files[cleanName(entry.Name)] = content
+	}
+	return files, nil
+}
+
+// decode 从归档文件中解析Schema，结果按ID排序
+func decode(files map[string][]byte) ([]Schema, error) {
+	manifestData, exists := files[manifestPath]
+	if !exists {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidArchive, manifestPath)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, manifestPath, err)
+	}
+	if manifest.Format < 1 || manifest.Format > FormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version %d", ErrInvalidArchive, manifest.Format)
+	}
+
+	registryData, exists := files[registryPath]
+	if !exists {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidArchive, registryPath)
+	}
+	var registry map[string]storage.SchemaMetadata
+	if err := json.Unmarshal(registryData, &registry); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, registryPath, err)
+	}
+
+	schemas := []Schema{}
+	for id, metadata := range registry {
+		// ID同时用作归档中的路径，必须先校验
+		if err := storage.ValidateID(id); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		metadata.ID = id
+
+		content, exists := files[path.Join(schemaDir(id), "schema.json")]
+		if !exists {
+			return nil, fmt.Errorf("%w: missing schema.json for %s", ErrInvalidArchive, id)
+		}
+		schema := Schema{Metadata: metadata, Content: content}
+
+		if indexData, exists := files[path.Join(schemaDir(id), "history", "versions.json")]; exists {
+			var index []storage.SchemaVersion
+			if err := json.Unmarshal(indexData, &index); err != nil {
+				return nil, fmt.Errorf("%w: version index of %s: %v", ErrInvalidArchive, id, err)
+			}
+			sort.Slice(index, func(i, j int) bool { return index[i].Version < index[j].Version })
+
+			for _, version := range index {
+				data, exists := files[versionPath(id, version.Version)]
+				if !exists {
+					return nil, fmt.Errorf("%w: missing version %d of %s", ErrInvalidArchive, version.Version, id)
+				}
+				schema.Versions = append(schema.Versions, Version{SchemaVersion: version, Content: data})
+			}
+		}
+		schemas = append(schemas, schema)
+	}
+
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Metadata.ID < schemas[j].Metadata.ID })
+	return schemas, nil
+}
@@ -0,0 +1,114 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+
+	"goci/backend/storage"
+)
+
+// 测试导出后读取得到相同的Schema和历史
+func TestExportRead(t *testing.T) {
+	store := storage.NewMemorySchemaStore()
+	if err := store.SaveSchema("app", "App", "Application", []byte(`{"type": "object"}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if _, err := store.SaveSchemaRevision("app", "App", "Application", []byte(`{"type": "object", "required": ["port"]}`), storage.RevisionInfo{Author: "alice", Message: "Require port"}); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if err := store.SaveSchema("db", "DB", "", []byte(`{"type": "string"}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+
+	for _, format := range []Format{FormatTarGz, FormatZip} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Export(&buf, store, []string{"app"}, format); err != nil {
+				t.Fatalf("Failed to export: %v", err)
+			}
+
+			schemas, err := Read(&buf)
+			if err != nil {
+				t.Fatalf("Failed to read archive: %v", err)
+			}
+			if len(schemas) != 1 {
+				t.Fatalf("Expected 1 schema, got %d", len(schemas))
+			}
+			schema := schemas[0]
+			if schema.Metadata.ID != "app" || schema.Metadata.Name != "App" || schema.Metadata.Version != 2 {
+				t.Errorf("Unexpected metadata: %+v", schema.Metadata)
+			}
+			if string(schema.Content) != `{"type": "object", "required": ["port"]}` {
+				t.Errorf("Unexpected content: %s", schema.Content)
+			}
+			if len(schema.Versions) != 2 || schema.Versions[1].Author != "alice" || string(schema.Versions[0].Content) != `{"type": "object"}` {
+				t.Errorf("Unexpected versions: %+v", schema.Versions)
+			}
+		})
+	}
+
+	// 不指定ID时导出全部Schema
+	var buf bytes.Buffer
+	if err := Export(&buf, store, nil, FormatTarGz); err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	schemas, err := Read(&buf)
+	if err != nil || len(schemas) != 2 {
+		t.Errorf("Expected 2 schemas, got %d, %v", len(schemas), err)
+	}
+
+	if err := Export(&buf, store, []string{"missing"}, FormatTarGz); !errors.Is(err, storage.ErrSchemaNotFound) {
+		t.Errorf("Expected ErrSchemaNotFound, got %v", err)
+	}
+}
+
+// 测试拒绝无效的归档
+func TestReadInvalid(t *testing.T) {
+	zipArchive := func(files map[string]string) []byte {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		for name, content := range files {
+			f, _ := w.Create(name)
+			f.Write([]byte(content))
+		}
+		w.Close()
+		return buf.Bytes()
+	}
+	manifest := `{"format": 1}`
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not an archive", []byte(`{"type": "object"}`)},
+		{"missing manifest", zipArchive(map[string]string{"schemas/schema-registry.json": `{}`})},
+		{"newer format", zipArchive(map[string]string{"manifest.json": `{"format": 2}`, "schemas/schema-registry.json": `{}`})},
+		{"missing schema", zipArchive(map[string]string{"manifest.json": manifest, "schemas/schema-registry.json": `{"app": {}}`})},
+		{"invalid ID", zipArchive(map[string]string{"manifest.json": manifest, "schemas/schema-registry.json": `{"../app": {}}`, "app/schema.json": `{}`})},
+		{"missing version", zipArchive(map[string]string{
+			"manifest.json":                     manifest,
+			"schemas/schema-registry.json":      `{"app": {}}`,
+			"schemas/app/schema.json":           `{}`,
+			"schemas/app/history/versions.json": `[{"version": 1}]`,
+		})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(bytes.NewReader(tt.data)); !errors.Is(err, ErrInvalidArchive) {
+				t.Errorf("Expected ErrInvalidArchive, got %v", err)
+			}
+		})
+	}
+
+	// 文件名带./前缀的归档也可以读取
+	schemas, err := Read(bytes.NewReader(zipArchive(map[string]string{
+		"./manifest.json":                manifest,
+		"./schemas/schema-registry.json": `{"app": {"name": "App"}}`,
+		"./schemas/app/schema.json":      `{}`,
+	})))
+	if err != nil || len(schemas) != 1 || schemas[0].Metadata.ID != "app" {
+		t.Errorf("Unexpected schemas: %+v, %v", schemas, err)
+	}
+}
@@ -0,0 +1,467 @@
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"goci/backend/migration"
+	"goci/backend/refs"
+	"goci/backend/storage"
+	"goci/backend/validation"
+)
+
+// ErrImportFailed 表示归档中有Schema无法导入，此时不会写入任何Schema
+var ErrImportFailed = errors.New("import failed")
+
+// Policy 决定归档中的Schema与已有Schema的ID冲突时如何处理
+type Policy string
+
+const (
+	// PolicySkip 保留已有的Schema
+	PolicySkip Policy = "skip"
+	// PolicyOverwrite 把归档中的内容保存为已有Schema的新版本
+	PolicyOverwrite Policy = "overwrite"
+	// PolicyRename 以新的ID导入，归档中指向它的引用随之改写
+	PolicyRename Policy = "rename"
+)
+
+// ParsePolicy 解析冲突策略，空字符串表示skip
+func ParsePolicy(value string) (Policy, error) {
+	switch Policy(value) {
+	case "", PolicySkip:
+		return PolicySkip, nil
+	case PolicyOverwrite, PolicyRename:
+		return Policy(value), nil
+	default:
+		return "", fmt.Errorf("unknown conflict policy %q, expected skip, overwrite or rename", value)
+	}
+}
+
+// Action 是对归档中一个Schema执行的操作
+type Action string
+
+const (
+	// ActionCreate 创建新的Schema并导入全部历史
+	ActionCreate Action = "create"
+	// ActionSkip 保留已有的Schema
+	ActionSkip Action = "skip"
+	// ActionOverwrite 把归档中的当前内容保存为已有Schema的新版本
+	ActionOverwrite Action = "overwrite"
+	// ActionUnchanged 已有Schema的内容与归档相同，不需要写入
+	ActionUnchanged Action = "unchanged"
+	// ActionRename 以新的ID创建Schema并导入全部历史
+	ActionRename Action = "rename"
+)
+
+// Options 是导入选项
+type Options struct {
+	Policy Policy
+	// DryRun 为true时只检查并返回报告，不写入
+	DryRun bool
+	// Resolve 解析引用的已有Schema，为nil时直接读取store
+	Resolve refs.Resolver
+	// Authorize 非nil时对每个要写入的Schema调用，返回错误时不写入任何Schema，Import原样返回该错误。
+	// 检查的正是随后写入的计划，DryRun时同样会调用
+	Authorize func(result Result) error
+}
+
+// Result 是归档中一个Schema的导入结果
+type Result struct {
+	// ID 是Schema在归档中的ID
+	ID string `json:"id"`
+	// TargetID 是导入后的ID，只有rename时与ID不同
+	TargetID string `json:"targetId"`
+	Action   Action `json:"action"`
+	// Versions 是写入的版本数
+	Versions int    `json:"versions"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Report 是导入报告
+type Report struct {
+	DryRun  bool     `json:"dryRun"`
+	Schemas []Result `json:"schemas"`
+	// RolledBack 表示写入中途失败，已经写入的Schema都已撤销
+	RolledBack bool `json:"rolledBack,omitempty"`
+	// Partial 表示写入中途失败且有Schema无法撤销，这些Schema的Error中说明了撤销失败的原因，其内容保持导入后的状态
+	Partial bool `json:"partial,omitempty"`
+}
+
+// Failed 返回无法导入的Schema数量
+func (r Report) Failed() int {
+	failed := 0
+	for _, result := range r.Schemas {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// revision 是导入时要保存的一个版本
+type revision struct {
+	// version 是归档中的版本号，当前内容为0
+	version int
+	content []byte
+	info    storage.RevisionInfo
+}
+
+// plan 是归档中一个Schema的导入计划
+type plan struct {
+	schema    Schema
+	result    *Result
+	revisions []revision
+	// ifMatch 是写入第一个版本的条件：新建时为storage.CreateOnly，覆盖时为计划时读取到的ETag
+	ifMatch string
+	// previous 是覆盖前的版本号，撤销时恢复为该版本
+	previous int
+	// saved 是已经写入的版本数，etag 是最后一次写入后的ETag
+	saved int
+	etag  string
+}
+
+// Import 把归档中的Schema导入store
+//
+// 先按依赖顺序模拟全部写入：每个版本都必须能解析引用、是合法的Schema、迁移步骤有效。
+// 有任何Schema无法导入时返回ErrImportFailed，不写入任何Schema；DryRun时只返回报告。
+// 报告中的Schema按写入顺序排列，被引用的在前。
+//
+// 每次写入都以计划时读取到的状态为条件，计划之后被其他请求创建或修改的Schema会使写入失败。
+// 写入中途失败时按相反顺序撤销已经写入的Schema：新建的移入回收站，覆盖的恢复为导入前的版本（作为新版本），
+// 报告的RolledBack为true。撤销也失败时报告的Partial为true，这些Schema保持部分导入的状态
+func Import(store storage.SchemaStore, schemas []Schema, options Options) (Report, error) {
+	report := Report{DryRun: options.DryRun}
+
+	plans, err := planImport(store, schemas, options.Policy)
+	if err != nil {
+		return report, err
+	}
+
+	// 模拟写入，只有已经处理过的Schema以归档中的内容解析引用
+	imported := make(map[string][]byte)
+	existing := options.Resolve
+	if existing == nil {
+		existing = refs.FromStore(store)
+	}
+	resolve := func(id string) ([]byte, error) {
+		if content, exists := imported[id]; exists {
+			return content, nil
+		}
+		return existing(id)
+	}
+	for _, p := range plans {
+		if p.result.Error != "" {
+			continue
+		}
+		if err := check(resolve, p); err != nil {
+			p.result.Error = err.Error()
+			continue
+		}
+		if len(p.revisions) > 0 {
+			imported[p.result.TargetID] = p.revisions[len(p.revisions)-1].content
+		}
+	}
+
+	for _, p := range plans {
+		report.Schemas = append(report.Schemas, *p.result)
+	}
+	if failed := report.Failed(); failed > 0 {
+		return report, fmt.Errorf("%w: %d of %d schemas cannot be imported", ErrImportFailed, failed, len(plans))
+	}
+	if options.Authorize != nil {
+		for _, p := range plans {
+			if len(p.revisions) == 0 {
+				continue
+			}
+			if err := options.Authorize(*p.result); err != nil {
+				return report, err
+			}
+		}
+	}
+	if options.DryRun {
+		return report, nil
+	}
+
+	for i, p := range plans {
+		if err := p.write(store); err != nil {
+			p.result.Error = err.Error()
+			if rollback(store, plans[:i+1]) {
+				report.RolledBack = true
+			} else {
+				report.Partial = true
+			}
+			report.Schemas = report.Schemas[:0]
+			for _, p := range plans {
+				report.Schemas = append(report.Schemas, *p.result)
+			}
+			return report, fmt.Errorf("error importing %s: %w", p.result.TargetID, err)
+		}
+	}
+	return report, nil
+}
+
+// write 依次保存计划的版本，每个版本都以上一次写入后的ETag为条件
+func (p *plan) write(store storage.SchemaStore) error {
+	metadata := p.schema.Metadata
+	ifMatch := p.ifMatch
+	for _, rev := range p.revisions {
+		info := rev.info
+		info.IfMatch = ifMatch
+		version, err := store.SaveSchemaRevision(p.result.TargetID, metadata.Name, metadata.Description, rev.content, info)
+		if err != nil {
+			return err
+		}
+		ifMatch = storage.SchemaETag(version.Version, rev.content)
+		p.saved++
+		p.etag = ifMatch
+	}
+	return nil
+}
+
+// rollback 按相反顺序撤销已经写入的计划，引用方先于被引用的Schema撤销；返回是否全部撤销
+//
+// 撤销以最后一次写入后的ETag为条件，不会覆盖导入之后其他请求的修改
+func rollback(store storage.SchemaStore, plans []*plan) bool {
+	complete := true
+	for i := len(plans) - 1; i >= 0; i-- {
+		p := plans[i]
+		if p.saved == 0 {
+			continue
+		}
+
+		var err error
+		if p.result.Action == ActionOverwrite {
+			_, err = store.RestoreVersion(p.result.TargetID, p.previous, storage.RevisionInfo{Message: "Roll back import", IfMatch: p.etag})
+		} else {
+			err = store.DeleteSchemaIfMatch(p.result.TargetID, p.etag)
+		}
+		if err != nil {
+			message := "rollback failed: " + err.Error()
+			if p.result.Error != "" {
+				message = p.result.Error + "; " + message
+			}
+			p.result.Error = message
+			complete = false
+		}
+	}
+	return complete
+}
+
+// check 检查计划中的每个版本
+func check(resolve refs.Resolver, p *plan) error {
+	for _, rev := range p.revisions {
+		if err := rev.check(resolve, p.result.TargetID); err != nil {
+			if rev.version > 0 {
+				return fmt.Errorf("version %d: %w", rev.version, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// check 检查版本能否以id保存
+func (rev revision) check(resolve refs.Resolver, id string) error {
+	bundled, err := refs.Bundle(refs.Override(resolve, id, rev.content), id, rev.content)
+	if err != nil {
+		return err
+	}
+	if err := validation.ValidateSchema(bundled); err != nil {
+		return err
+	}
+	return migration.Validate(rev.info.Migration)
+}
+
+// planImport 决定每个Schema的操作和目标ID，并按依赖顺序返回
+func planImport(store storage.SchemaStore, schemas []Schema, policy Policy) ([]*plan, error) {
+	existing, err := store.ListSchemas()
+	if err != nil {
+		return nil, err
+	}
+	taken := make(map[string]bool)
+	for _, metadata := range existing {
+		taken[metadata.ID] = true
+	}
+	conflicts := make(map[string]bool, len(taken))
+	for id := range taken {
+		conflicts[id] = true
+	}
+	for _, schema := range schemas {
+		taken[schema.Metadata.ID] = true
+	}
+
+	plans := make(map[string]*plan, len(schemas))
+	renamed := make(map[string]string)
+	for _, schema := range schemas {
+		id := schema.Metadata.ID
+		result := &Result{ID: id, TargetID: id, Action: ActionCreate}
+		if conflicts[id] {
+			switch policy {
+			case PolicyOverwrite:
+				result.Action = ActionOverwrite
+			case PolicyRename:
+				result.Action = ActionRename
+				result.TargetID = uniqueID(id, taken)
+				taken[result.TargetID] = true
+				renamed[id] = result.TargetID
+			default:
+				result.Action = ActionSkip
+			}
+		}
+		plans[id] = &plan{schema: schema, result: result}
+	}
+
+	for _, p := range plans {
+		if err := p.build(store, renamed); err != nil {
+			p.result.Error = err.Error()
+		}
+	}
+	return sortPlans(plans), nil
+}
+
+// build 生成计划要保存的版本
+//
+// 新建的Schema按顺序导入全部历史，当前内容与最后一个版本不同时再追加一个版本；
+// 覆盖已有Schema时只追加归档中的当前内容，已有的历史保持不变
+func (p *plan) build(store storage.SchemaStore, renamed map[string]string) error {
+	schema := p.schema
+	switch p.result.Action {
+	case ActionSkip:
+		return nil
+	case ActionOverwrite:
+		current, metadata, err := store.GetSchema(p.result.TargetID)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(current, schema.Content) && metadata.Name == schema.Metadata.Name && metadata.Description == schema.Metadata.Description {
+			p.result.Action = ActionUnchanged
+			return nil
+		}
+		p.ifMatch = storage.SchemaETag(metadata.Version, current)
+		p.previous = metadata.Version
+		content, err := rename(schema.Content, renamed)
+		if err != nil {
+			return err
+		}
+		p.revisions = []revision{{content: content, info: storage.RevisionInfo{Message: importMessage(schema), Imported: true}}}
+	default:
+		p.ifMatch = storage.CreateOnly
+		for _, version := range schema.Versions {
+			content, err := rename(version.Content, renamed)
+			if err != nil {
+				return fmt.Errorf("version %d: %w", version.Version, err)
+			}
+			p.revisions = append(p.revisions, revision{version: version.Version, content: content, info: storage.RevisionInfo{
+				Author:    version.Author,
+				Message:   version.Message,
+				Migration: version.Migration,
+				Imported:  true,
+			}})
+		}
+		if len(schema.Versions) == 0 || !bytes.Equal(schema.Versions[len(schema.Versions)-1].Content, schema.Content) {
+			content, err := rename(schema.Content, renamed)
+			if err != nil {
+				return err
+			}
+			p.revisions = append(p.revisions, revision{content: content, info: storage.RevisionInfo{Message: importMessage(schema), Imported: true}})
+		}
+	}
+	p.result.Versions = len(p.revisions)
+	return nil
+}
+
+// importMessage 返回导入当前内容时的版本说明
+func importMessage(schema Schema) string {
+	if schema.Metadata.Version > 0 {
+		return fmt.Sprintf("Import version %d", schema.Metadata.Version)
+	}
+	return "Import"
+}
+
+// rename 改写指向改名Schema的引用
+func rename(content []byte, renamed map[string]string) ([]byte, error) {
+	if len(renamed) == 0 {
+		return content, nil
+	}
+	return refs.Rename(content, renamed)
+}
+
+// uniqueID 返回不在taken中的<id>-<n>
+func uniqueID(id string, taken map[string]bool) string {
+	for n := 2; ; n++ {
+		suffix := fmt.Sprintf("-%d", n)
+		base := id
+		if len(base)+len(suffix) > storage.MaxIDLength {
+			base = strings.TrimRight(base[:storage.MaxIDLength-len(suffix)], "-._")
+		}
+		if candidate := base + suffix; !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// sortPlans 按依赖排序，被归档中其他Schema引用的在前，没有依赖关系时按ID排序；
+// 互相引用的Schema按ID排在最后，由检查报告错误
+func sortPlans(plans map[string]*plan) []*plan {
+	deps := make(map[string][]string, len(plans))
+	for id, p := range plans {
+		seen := make(map[string]bool)
+		contents := [][]byte{p.schema.Content}
+		for _, version := range p.schema.Versions {
+			contents = append(contents, version.Content)
+		}
+		for _, content := range contents {
+			ids, err := refs.References(content)
+			if err != nil {
+				continue
+			}
+			for _, dep := range ids {
+				if dep != id && plans[dep] != nil && !seen[dep] {
+					seen[dep] = true
+					deps[id] = append(deps[id], dep)
+				}
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(plans))
+	for id := range plans {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	sorted := make([]*plan, 0, len(plans))
+	done := make(map[string]bool, len(plans))
+	for len(sorted) < len(ids) {
+		progress := false
+		for _, id := range ids {
+			if done[id] {
+				continue
+			}
+			ready := true
+			for _, dep := range deps[id] {
+				if !done[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				sorted = append(sorted, plans[id])
+				done[id] = true
+				progress = true
+			}
+		}
+		if !progress {
+			for _, id := range ids {
+				if !done[id] {
+					sorted = append(sorted, plans[id])
+					done[id] = true
+				}
+			}
+		}
+	}
+	return sorted
+}
@@ -0,0 +1,235 @@
+package archive
+
+import (
+	"errors"
+	"testing"
+
+	"goci/backend/refs"
+	"goci/backend/storage"
+)
+
+// exportStore 返回源环境中的Schema：app引用port，app有两个版本
+func exportStore(t *testing.T) []Schema {
+	source := storage.NewMemorySchemaStore()
+	if err := source.SaveSchema("port", "Port", "", []byte(`{"type": "integer", "minimum": 1}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if _, err := source.SaveSchemaRevision("app", "App", "", []byte(`{"type": "object"}`), storage.RevisionInfo{Author: "alice", Message: "Create"}); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if _, err := source.SaveSchemaRevision("app", "App", "", []byte(`{"type": "object", "properties": {"port": {"$ref": "goci://defs/port"}}}`), storage.RevisionInfo{Author: "bob", Message: "Add port"}); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+
+	schemas, err := Collect(source, nil)
+	if err != nil {
+		t.Fatalf("Failed to collect schemas: %v", err)
+	}
+	return schemas
+}
+
+// 测试导入新的Schema及其历史，被引用的Schema先导入
+func TestImportCreate(t *testing.T) {
+	schemas := exportStore(t)
+	store := refs.WithReferenceCheck(storage.NewMemorySchemaStore())
+
+	// dryRun不写入
+	report, err := Import(store, schemas, Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("Failed to plan import: %v", err)
+	}
+	if !report.DryRun || len(report.Schemas) != 2 || report.Schemas[0].ID != "port" || report.Schemas[1].Action != ActionCreate || report.Schemas[1].Versions != 2 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if list, _ := store.ListSchemas(); len(list) != 0 {
+		t.Errorf("Dry run saved %d schemas", len(list))
+	}
+
+	if _, err := Import(store, schemas, Options{}); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	versions, err := store.ListVersions("app")
+	if err != nil {
+		t.Fatalf("Failed to list versions: %v", err)
+	}
+	if len(versions) != 2 || versions[0].Author != "alice" || versions[1].Message != "Add port" {
+		t.Errorf("Unexpected versions: %+v", versions)
+	}
+}
+
+// 测试ID冲突时的各个策略
+func TestImportPolicies(t *testing.T) {
+	schemas := exportStore(t)
+
+	newStore := func() storage.SchemaStore {
+		store := refs.WithReferenceCheck(storage.NewMemorySchemaStore())
+		if err := store.SaveSchema("port", "Port", "", []byte(`{"type": "string"}`)); err != nil {
+			t.Fatalf("Failed to save schema: %v", err)
+		}
+		return store
+	}
+
+	// skip保留已有的port，app引用已有的port
+	store := newStore()
+	report, err := Import(store, schemas, Options{Policy: PolicySkip})
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if report.Schemas[0].Action != ActionSkip || report.Schemas[1].Action != ActionCreate {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if content, _, _ := store.GetSchema("port"); string(content) != `{"type": "string"}` {
+		t.Errorf("Expected port to be kept, got %s", content)
+	}
+
+	// overwrite追加一个版本，内容相同时不写入
+	store = newStore()
+	report, err = Import(store, schemas, Options{Policy: PolicyOverwrite})
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if report.Schemas[0].Action != ActionOverwrite || report.Schemas[0].Versions != 1 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	versions, _ := store.ListVersions("port")
+	if len(versions) != 2 || versions[1].Message != "Import version 1" {
+		t.Errorf("Unexpected versions: %+v", versions)
+	}
+	report, err = Import(store, schemas, Options{Policy: PolicyOverwrite})
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if report.Schemas[0].Action != ActionUnchanged || report.Schemas[1].Action != ActionUnchanged {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	// rename以新ID导入，app的引用指向新ID
+	store = newStore()
+	report, err = Import(store, schemas, Options{Policy: PolicyRename})
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if report.Schemas[0].Action != ActionRename || report.Schemas[0].TargetID != "port-2" {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	content, _, err := store.GetSchema("app")
+	if err != nil || string(content) != `{"type":"object","properties":{"port":{"$ref":"goci://defs/port-2"}}}` {
+		t.Errorf("Unexpected app content: %s, %v", content, err)
+	}
+}
+
+// 测试有Schema无法导入时不写入任何Schema
+func TestImportFailed(t *testing.T) {
+	schemas := exportStore(t)
+	// 只导入app，port在目标环境不存在
+	schemas = schemas[:1]
+	if schemas[0].Metadata.ID != "app" {
+		t.Fatalf("Unexpected schemas: %+v", schemas)
+	}
+	schemas = append(schemas, Schema{Metadata: storage.SchemaMetadata{ID: "bad", Name: "Bad"}, Content: []byte(`{"type": 1}`)})
+
+	store := refs.WithReferenceCheck(storage.NewMemorySchemaStore())
+	report, err := Import(store, schemas, Options{})
+	if !errors.Is(err, ErrImportFailed) {
+		t.Fatalf("Expected ErrImportFailed, got %v", err)
+	}
+	if report.Failed() != 2 {
+		t.Errorf("Expected 2 failures, got %+v", report)
+	}
+	if list, _ := store.ListSchemas(); len(list) != 0 {
+		t.Errorf("Failed import saved %d schemas", len(list))
+	}
+}
+
+// failingStore 保存或删除指定ID时失败
+type failingStore struct {
+	storage.SchemaStore
+	saveID   string
+	deleteID string
+}
+
+func (s *failingStore) SaveSchemaRevision(id string, name string, description string, schemaData []byte, info storage.RevisionInfo) (storage.SchemaVersion, error) {
+	if id == s.saveID {
+		return storage.SchemaVersion{}, errors.New("disk full")
+	}
+	return s.SchemaStore.SaveSchemaRevision(id, name, description, schemaData, info)
+}
+
+func (s *failingStore) DeleteSchemaIfMatch(id string, ifMatch string) error {
+	if id == s.deleteID {
+		return errors.New("disk full")
+	}
+	return s.SchemaStore.DeleteSchemaIfMatch(id, ifMatch)
+}
+
+// 测试写入中途失败时撤销已写入的Schema，撤销失败时报告部分导入
+func TestImportRollback(t *testing.T) {
+	schemas := exportStore(t)
+
+	// 覆盖的port恢复为导入前的内容
+	base := refs.WithReferenceCheck(storage.NewMemorySchemaStore())
+	if err := base.SaveSchema("port", "Port", "", []byte(`{"type": "string"}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	report, err := Import(&failingStore{SchemaStore: base, saveID: "app"}, schemas, Options{Policy: PolicyOverwrite})
+	if err == nil || !report.RolledBack || report.Partial {
+		t.Fatalf("Expected rolled back import, got %+v, %v", report, err)
+	}
+	if content, metadata, _ := base.GetSchema("port"); string(content) != `{"type": "string"}` || metadata.Version != 3 {
+		t.Errorf("Expected port to be restored, got %s %+v", content, metadata)
+	}
+	if _, _, err := base.GetSchema("app"); !errors.Is(err, storage.ErrSchemaNotFound) {
+		t.Errorf("Expected app not to be imported, got %v", err)
+	}
+
+	// 新建的port无法撤销
+	base = refs.WithReferenceCheck(storage.NewMemorySchemaStore())
+	report, err = Import(&failingStore{SchemaStore: base, saveID: "app", deleteID: "port"}, schemas, Options{})
+	if err == nil || report.RolledBack || !report.Partial {
+		t.Fatalf("Expected partial import, got %+v, %v", report, err)
+	}
+	if report.Schemas[0].ID != "port" || report.Schemas[0].Error == "" || report.Schemas[1].Error == "" {
+		t.Errorf("Expected errors for port and app: %+v", report.Schemas)
+	}
+	if _, _, err := base.GetSchema("port"); err != nil {
+		t.Errorf("Expected port to be kept after failed rollback, got %v", err)
+	}
+}
+
+// 测试授权检查的正是写入的计划，计划之后被创建的Schema不会被覆盖
+func TestImportAuthorizeAndConflict(t *testing.T) {
+	schemas := exportStore(t)
+	store := refs.WithReferenceCheck(storage.NewMemorySchemaStore())
+
+	// 拒绝时不写入任何Schema
+	denied := errors.New("denied")
+	_, err := Import(store, schemas, Options{Authorize: func(result Result) error {
+		if result.TargetID == "app" {
+			return denied
+		}
+		return nil
+	}})
+	if !errors.Is(err, denied) {
+		t.Fatalf("Expected authorization error, got %v", err)
+	}
+	if list, _ := store.ListSchemas(); len(list) != 0 {
+		t.Errorf("Denied import saved %d schemas", len(list))
+	}
+
+	// 授权之后、写入之前有其他请求创建了app
+	report, err := Import(store, schemas, Options{Authorize: func(result Result) error {
+		if result.TargetID == "app" {
+			return store.SaveSchema("app", "Other", "", []byte(`{"type": "string"}`))
+		}
+		return nil
+	}})
+	if !errors.Is(err, storage.ErrSchemaExists) || !report.RolledBack {
+		t.Fatalf("Expected ErrSchemaExists and rollback, got %+v, %v", report, err)
+	}
+	if content, metadata, _ := store.GetSchema("app"); string(content) != `{"type": "string"}` || metadata.Version != 1 {
+		t.Errorf("Concurrently created app was modified: %s %+v", content, metadata)
+	}
+	if _, _, err := store.GetSchema("port"); !errors.Is(err, storage.ErrSchemaNotFound) {
+		t.Errorf("Expected port to be rolled back, got %v", err)
+	}
+}
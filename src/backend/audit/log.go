@@ -0,0 +1,266 @@
+// Package audit 记录Schema和配置的每一次修改
+//
+// 审计记录以JSON Lines格式追加到文件中，每条记录包含前一条记录的哈希，
+// 修改或删除任何一条记录都会使之后的哈希链校验失败。
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrTampered 表示审计文件的哈希链校验失败
+var ErrTampered = errors.New("audit log tampered")
+
+// Record 是一条审计记录
+type Record struct {
+	// Seq 是从1开始的连续序号
+	Seq  uint64 `json:"seq"`
+	Time string `json:"time"`
+	// RequestID 是触发修改的HTTP请求ID，服务端自身的修改为空
+	RequestID string `json:"requestId,omitempty"`
+	// Actor 是执行修改的主体
+	Actor string `json:"actor"`
+	// ClientIP 是请求的来源地址
+	ClientIP string `json:"clientIp,omitempty"`
+	// Operation 是操作类型，与storage中的事件类型相同，例如schema.saved
+	Operation string `json:"operation"`
+	// Target 是被修改的Schema ID，配置以其Schema ID标识
+	Target string `json:"target"`
+	// Version 是Schema保存或恢复后的版本号，配置记录中是配置对应的Schema版本
+	Version int `json:"version,omitempty"`
+	// Before 和 After 是修改前后内容的sha256摘要，不存在时为空
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+	// PrevHash 是前一条记录的Hash，第一条记录为空
+	PrevHash string `json:"prevHash"`
+	// Hash 是除Hash以外所有字段的sha256摘要
+	Hash string `json:"hash"`
+}
+
+// computeHash 计算记录的哈希
+func (r Record) computeHash() string {
+	r.Hash = ""
+	data, _ := json.Marshal(r)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ContentHash 返回内容的摘要，用于记录修改前后的内容
+func ContentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Filter 是查询审计记录的条件，零值字段不做限制
+type Filter struct {
+	Target string
+	Actor  string
+	// Since 只返回该时间及之后的记录
+	Since time.Time
+	// Before 只返回序号小于它的记录，用作从新到旧翻页的游标
+	Before uint64
+	// Visible 非nil时只返回它允许的记录，例如调用者有权查看的记录
+	Visible func(Record) bool
+	// Limit 限制返回的记录数，0表示不限制
+	Limit int
+}
+
+// match 判断记录是否满足条件
+func (f Filter) match(r Record) bool {
+	if f.Target != "" && r.Target != f.Target {
+		return false
+	}
+	if f.Actor != "" && r.Actor != f.Actor {
+		return false
+	}
+	if !f.Since.IsZero() {
+		t, err := time.Parse(time.RFC3339Nano, r.Time)
+		if err != nil || t.Before(f.Since) {
+			return false
+		}
+	}
+	return f.Visible == nil || f.Visible(r)
+}
+
+// Log 是只追加的审计文件
+type Log struct {
+	mutex    sync.Mutex
+	path     string
+	file     *os.File
+	seq      uint64
+	lastHash string
+	// size 是已完整写入的记录的总长度，查询只读取这一部分
+	size int64
+}
+
+// Open 打开审计文件，不存在时创建
+//
+// 打开时会校验整个哈希链，文件被修改过时返回ErrTampered。
+// 最后一行没有换行符时是写入中途崩溃留下的半条记录，会被截掉并记录日志
+func Open(path string) (*Log, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening audit log: %w", err)
+	}
+
+	last, end, err := verify(file, nil)
+	if err == nil {
+		err = truncateTorn(file, end)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &Log{path: path, file: file, seq: last.Seq, lastHash: last.Hash, size: end}, nil
+}
+
+// truncateTorn 截掉end之后不完整的最后一行
+func truncateTorn(file *os.File, end int64) error {
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("error reading audit log: %w", err)
+	}
+	if info.Size() == end {
+		return nil
+	}
+
+	slog.Warn("Truncating incomplete audit record", "path", file.Name(), "offset", end, "bytes", info.Size()-end)
+	if err := file.Truncate(end); err != nil {
+		return fmt.Errorf("error truncating audit log: %w", err)
+	}
+	return file.Sync()
+}
+
+// Close 关闭审计文件
+func (l *Log) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.file.Close()
+}
+
+// Append 追加一条记录，填写序号、时间和哈希链，写入磁盘后返回完整的记录
+func (l *Log) Append(record Record) (Record, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	record.Seq = l.seq + 1
+	if record.Time == "" {
+		record.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	record.PrevHash = l.lastHash
+	record.Hash = record.computeHash()
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return Record{}, fmt.Errorf("error marshaling audit record: %w", err)
+	}
+	data = append(data, '\n')
+
+	if _, err := l.file.Write(data); err != nil {
+		// 去掉可能写入的半条记录，以免之后的记录接在它后面破坏哈希链
+		l.file.Truncate(l.size)
+		return Record{}, fmt.Errorf("error writing audit log: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return Record{}, fmt.Errorf("error syncing audit log: %w", err)
+	}
+
+	l.seq = record.Seq
+	l.lastHash = record.Hash
+	l.size += int64(len(data))
+	return record, nil
+}
+
+// Query 按条件查询审计记录，按序号从新到旧返回
+//
+// 查询只在开始时短暂持有锁记下已完整写入的长度，之后读取和校验文件时不会阻塞写入
+func (l *Log) Query(filter Filter) ([]Record, error) {
+	l.mutex.Lock()
+	size := l.size
+	l.mutex.Unlock()
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening audit log: %w", err)
+	}
+	defer file.Close()
+
+	// 只保留最新的Limit条，读到游标处即停止
+	records := []Record{}
+	_, _, err = verify(io.NewSectionReader(file, 0, size), func(record Record) bool {
+		if filter.Before != 0 && record.Seq >= filter.Before {
+			return false
+		}
+		if filter.match(record) {
+			records = append(records, record)
+			if filter.Limit > 0 && len(records) > filter.Limit {
+				records = records[1:]
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(records)
+	return records, nil
+}
+
+// Verify 校验审计文件的哈希链，返回完整记录的数量
+//
+// 最后一行不完整的记录不计入，也不视为篡改，下次打开时会被截掉
+func Verify(path string) (uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	last, _, err := verify(file, nil)
+	return last.Seq, err
+}
+
+// verify 依次读取并校验记录，visit返回false时停止读取；返回最后一条完整记录及其结束位置
+//
+// 没有换行符的最后一行是写入中途崩溃留下的半条记录，不会被校验，返回的结束位置在它之前
+func verify(r io.Reader, visit func(Record) bool) (Record, int64, error) {
+	var last Record
+	var end int64
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return last, end, nil
+		}
+		if err != nil {
+			return Record{}, 0, fmt.Errorf("error reading audit log: %w", err)
+		}
+
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			return Record{}, 0, fmt.Errorf("%w: record %d is not valid JSON", ErrTampered, last.Seq+1)
+		}
+		if record.Seq != last.Seq+1 || record.PrevHash != last.Hash || record.Hash != record.computeHash() {
+			return Record{}, 0, fmt.Errorf("%w: record %d does not match the hash chain", ErrTampered, last.Seq+1)
+		}
+		last = record
+		end += int64(len(line))
+
+		if visit != nil && !visit(record) {
+			return last, end, nil
+		}
+	}
+}
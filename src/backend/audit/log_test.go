@@ -0,0 +1,177 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 测试追加、查询和重新打开后继续哈希链
+func TestLogAppendAndQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+
+	first, err := log.Append(Record{Actor: "alice", Operation: "schema.saved", Target: "app", Version: 1})
+	if err != nil {
+		t.Fatalf("Failed to append record: %v", err)
+	}
+	if first.Seq != 1 || first.PrevHash != "" || first.Hash == "" {
+		t.Errorf("Unexpected first record: %+v", first)
+	}
+	second, err := log.Append(Record{Actor: "bob", Operation: "config.saved", Target: "app"})
+	if err != nil {
+		t.Fatalf("Failed to append record: %v", err)
+	}
+	if second.Seq != 2 || second.PrevHash != first.Hash {
+		t.Errorf("Record is not chained: %+v", second)
+	}
+	if err := log.Close(); err != nil {
+		t.Fatalf("Failed to close audit log: %v", err)
+	}
+
+	// 重新打开后继续序号和哈希链
+	log, err = Open(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen audit log: %v", err)
+	}
+	defer log.Close()
+	third, err := log.Append(Record{Actor: "alice", Operation: "schema.deleted", Target: "other"})
+	if err != nil {
+		t.Fatalf("Failed to append record: %v", err)
+	}
+	if third.Seq != 3 || third.PrevHash != second.Hash {
+		t.Errorf("Record is not chained after reopen: %+v", third)
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []uint64
+	}{
+		{"all", Filter{}, []uint64{3, 2, 1}},
+		{"target", Filter{Target: "app"}, []uint64{2, 1}},
+		{"actor", Filter{Actor: "alice"}, []uint64{3, 1}},
+		{"limit", Filter{Limit: 2}, []uint64{3, 2}},
+		{"before", Filter{Before: 3, Limit: 1}, []uint64{2}},
+		{"before first", Filter{Before: 1}, nil},
+		{"visible", Filter{Visible: func(r Record) bool { return r.Target == "other" }}, []uint64{3}},
+		{"since", Filter{Since: time.Now().Add(time.Hour)}, nil},
+	}
+	for _, tt := range tests {
+		records, err := log.Query(tt.filter)
+		if err != nil {
+			t.Fatalf("%s: failed to query audit log: %v", tt.name, err)
+		}
+		if len(records) != len(tt.expected) {
+			t.Errorf("%s: expected %d records, got %d", tt.name, len(tt.expected), len(records))
+			continue
+		}
+		for i, record := range records {
+			if record.Seq != tt.expected[i] {
+				t.Errorf("%s: expected seq %d, got %d", tt.name, tt.expected[i], record.Seq)
+			}
+		}
+	}
+
+	count, err := Verify(path)
+	if err != nil || count != 3 {
+		t.Errorf("Expected 3 verified records, got %d, %v", count, err)
+	}
+}
+
+// 测试修改或删除记录后校验失败
+func TestLogTampered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	for _, actor := range []string{"alice", "bob", "carol"} {
+		if _, err := log.Append(Record{Actor: actor, Operation: "config.saved", Target: "app"}); err != nil {
+			t.Fatalf("Failed to append record: %v", err)
+		}
+	}
+	log.Close()
+
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	lines := bytes.SplitAfter(original, []byte("\n"))
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"modified", bytes.Replace(original, []byte(`"actor":"bob"`), []byte(`"actor":"eve"`), 1)},
+		{"removed", append(append([]byte{}, lines[0]...), lines[2]...)},
+	}
+	for _, tt := range tests {
+		if err := os.WriteFile(path, tt.data, 0600); err != nil {
+			t.Fatalf("Failed to write audit log: %v", err)
+		}
+		if _, err := Verify(path); !errors.Is(err, ErrTampered) {
+			t.Errorf("%s: expected ErrTampered from Verify, got %v", tt.name, err)
+		}
+		if _, err := Open(path); !errors.Is(err, ErrTampered) {
+			t.Errorf("%s: expected ErrTampered from Open, got %v", tt.name, err)
+		}
+	}
+}
+
+// 测试写入中断留下的不完整末行在打开时被截断，已有记录和哈希链保持可用
+func TestLogTornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	for _, actor := range []string{"alice", "bob", "carol"} {
+		if _, err := log.Append(Record{Actor: actor, Operation: "config.saved", Target: "app"}); err != nil {
+			t.Fatalf("Failed to append record: %v", err)
+		}
+	}
+	log.Close()
+
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	lines := bytes.SplitAfter(original, []byte("\n"))
+	complete := len(lines[0]) + len(lines[1])
+	if err := os.WriteFile(path, original[:len(original)-10], 0600); err != nil {
+		t.Fatalf("Failed to write audit log: %v", err)
+	}
+
+	// 校验忽略不完整的末行
+	if count, err := Verify(path); err != nil || count != 2 {
+		t.Errorf("Expected 2 verified records, got %d, %v", count, err)
+	}
+
+	log, err = Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open audit log with torn line: %v", err)
+	}
+	defer log.Close()
+	if info, err := os.Stat(path); err != nil || info.Size() != int64(complete) {
+		t.Errorf("Expected torn line to be truncated to %d bytes, got %v, %v", complete, info, err)
+	}
+
+	// 新记录接在最后一条完整记录之后
+	record, err := log.Append(Record{Actor: "dave", Operation: "config.saved", Target: "app"})
+	if err != nil {
+		t.Fatalf("Failed to append record: %v", err)
+	}
+	if record.Seq != 3 {
+		t.Errorf("Expected seq 3 after truncation, got %d", record.Seq)
+	}
+	if count, err := Verify(path); err != nil || count != 3 {
+		t.Errorf("Expected 3 verified records, got %d, %v", count, err)
+	}
+}
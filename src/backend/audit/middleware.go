@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"goci/backend/auth"
+	"goci/backend/storage"
+)
+
+// RequestIDHeader 是携带请求ID的请求头和响应头
+const RequestIDHeader = "X-Request-ID"
+
+// gin.Context中的键
+const (
+	logKey       = "audit.log"
+	requestIDKey = "audit.requestId"
+)
+
+// requestIDPattern 限制客户端提供的请求ID，避免把任意内容写入审计记录
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// Middleware 为每个请求分配请求ID，并使Schemas和Configs记录该请求的写操作
+//
+// 客户端提供了有效的X-Request-ID时沿用，否则生成一个新的ID；ID会写入响应头
+func Middleware(log *Log) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+
+		c.Set(requestIDKey, id)
+		c.Set(logKey, log)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// newRequestID 生成一个随机的请求ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestID 返回当前请求的ID，没有使用Middleware时为空
+func RequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// actorFrom 返回当前请求的主体，未启用认证时为anonymous
+func actorFrom(c *gin.Context) Actor {
+	actor := Actor{Name: "anonymous", RequestID: RequestID(c), ClientIP: c.ClientIP()}
+	if principal, ok := auth.FromContext(c); ok {
+		actor.Name = principal.Name
+	}
+	return actor
+}
+
+// logFrom 返回Middleware设置的审计文件
+func logFrom(c *gin.Context) (*Log, bool) {
+	value, exists := c.Get(logKey)
+	if !exists {
+		return nil, false
+	}
+	log, ok := value.(*Log)
+	return log, ok
+}
+
+// Schemas 返回以当前请求的名义记录写操作的SchemaStore，没有启用审计时直接返回store
+func Schemas(c *gin.Context, store storage.SchemaStore) storage.SchemaStore {
+	if log, ok := logFrom(c); ok {
+		return WithSchemas(store, log, actorFrom(c))
+	}
+	return store
+}
+
+// Configs 返回以当前请求的名义记录写操作的ConfigStore，没有启用审计时直接返回store
+func Configs(c *gin.Context, store storage.ConfigStore) storage.ConfigStore {
+	if log, ok := logFrom(c); ok {
+		return WithConfigs(store, log, actorFrom(c))
+	}
+	return store
+}
@@ -0,0 +1,271 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"goci/backend/storage"
+)
+
+// Actor 是执行修改的主体和触发修改的请求
+type Actor struct {
+	Name      string
+	RequestID string
+	// ClientIP 是请求的来源地址，服务端自身的修改为空
+	ClientIP string
+}
+
+// SystemActor 是服务端自身发起的修改（例如生成默认配置）的主体
+var SystemActor = Actor{Name: "system"}
+
+// ErrNotRecorded 表示修改已经完成，但审计记录没有写入
+var ErrNotRecorded = errors.New("change was saved but not recorded in the audit log")
+
+// conditionalAttempts 是读取到的内容在写入前被并发修改时最多尝试的次数
+const conditionalAttempts = 5
+
+// record 追加一条记录；修改已经完成，写入失败时返回包装了ErrNotRecorded的错误，调用者不能当作修改成功
+func record(log *Log, actor Actor, operation, target string, version int, before, after string) error {
+	_, err := log.Append(Record{
+		RequestID: actor.RequestID,
+		Actor:     actor.Name,
+		ClientIP:  actor.ClientIP,
+		Operation: operation,
+		Target:    target,
+		Version:   version,
+		Before:    before,
+		After:     after,
+	})
+	if err != nil {
+		slog.Error("Failed to write audit record", "operation", operation, "target", target, "actor", actor.Name, "error", err)
+		return fmt.Errorf("%w: %s %s: %w", ErrNotRecorded, operation, target, err)
+	}
+	return nil
+}
+
+// snapshot 是写入前读取到的内容
+type snapshot struct {
+	exists bool
+	etag   string
+	hash   string
+}
+
+// conditional 以读取到的内容为条件执行write，使记录的修改前摘要正是被替换的内容
+//
+// read读取当前内容，先按调用者的ifMatch检查它，再把它的ETag（不存在时为storage.CreateOnly）
+// 作为条件传给write；内容在两步之间被并发修改时重新读取，调用者的ifMatch随之重新检查。
+// exists是ifMatch为storage.CreateOnly而内容已存在时返回的错误。返回修改前内容的摘要
+func conditional(kind, id, ifMatch string, exists error, read func() (snapshot, error), write func(ifMatch string) error) (string, error) {
+	for attempt := 1; ; attempt++ {
+		current, err := read()
+		if err != nil {
+			return "", err
+		}
+
+		switch {
+		case ifMatch == "":
+		case ifMatch == storage.CreateOnly:
+			if current.exists {
+				return "", fmt.Errorf("%w: %s", exists, id)
+			}
+		case !current.exists:
+			return "", fmt.Errorf("%w: %s %s does not exist", storage.ErrPreconditionFailed, kind, id)
+		case !storage.MatchETag(ifMatch, current.etag):
+			return "", fmt.Errorf("%w: %s %s has been modified", storage.ErrPreconditionFailed, kind, id)
+		}
+
+		condition := storage.CreateOnly
+		if current.exists {
+			condition = current.etag
+		}
+		err = write(condition)
+		changed := errors.Is(err, storage.ErrPreconditionFailed) || errors.Is(err, exists)
+		if changed && attempt < conditionalAttempts {
+			continue
+		}
+		return current.hash, err
+	}
+}
+
+// schemaStore 在Schema写操作成功后追加审计记录
+type schemaStore struct {
+	storage.SchemaStore
+	log   *Log
+	actor Actor
+}
+
+// WithSchemas 包装一个SchemaStore，以actor的名义记录其所有写操作
+//
+// 修改前的摘要取自写入时实际被替换的内容；审计记录写入失败时返回包装了ErrNotRecorded的错误
+func WithSchemas(store storage.SchemaStore, log *Log, actor Actor) storage.SchemaStore {
+	return &schemaStore{SchemaStore: store, log: log, actor: actor}
+}
+
+// snapshot 读取Schema的当前内容
+func (s *schemaStore) snapshot(id string) (snapshot, error) {
+	data, metadata, err := s.SchemaStore.GetSchema(id)
+	if errors.Is(err, storage.ErrSchemaNotFound) {
+		return snapshot{}, nil
+	}
+	if err != nil {
+		return snapshot{}, err
+	}
+	return snapshot{exists: true, etag: storage.SchemaETag(metadata.Version, data), hash: ContentHash(data)}, nil
+}
+
+// versionHash 返回Schema某个版本内容的摘要，版本内容不会改变，不受之后的并发修改影响
+func (s *schemaStore) versionHash(id string, version int) string {
+	data, _, err := s.SchemaStore.GetVersion(id, version)
+	if err != nil {
+		return ""
+	}
+	return ContentHash(data)
+}
+
+// SaveSchema 保存Schema并记录schema.saved
+func (s *schemaStore) SaveSchema(id string, name string, description string, schemaData []byte) error {
+	_, err := s.SaveSchemaRevision(id, name, description, schemaData, storage.RevisionInfo{})
+	return err
+}
+
+// SaveSchemaRevision 保存Schema并记录schema.saved
+func (s *schemaStore) SaveSchemaRevision(id string, name string, description string, schemaData []byte, info storage.RevisionInfo) (storage.SchemaVersion, error) {
+	var version storage.SchemaVersion
+	before, err := conditional("schema", id, info.IfMatch, storage.ErrSchemaExists, func() (snapshot, error) {
+		return s.snapshot(id)
+	}, func(ifMatch string) error {
+		conditional := info
+		conditional.IfMatch = ifMatch
+		var err error
+		version, err = s.SchemaStore.SaveSchemaRevision(id, name, description, schemaData, conditional)
+		return err
+	})
+	if err != nil {
+		return version, err
+	}
+	return version, record(s.log, s.actor, storage.EventSchemaSaved, id, version.Version, before, ContentHash(schemaData))
+}
+
+// RestoreVersion 恢复Schema版本并记录schema.restored
+func (s *schemaStore) RestoreVersion(id string, version int, info storage.RevisionInfo) (storage.SchemaVersion, error) {
+	var restored storage.SchemaVersion
+	before, err := conditional("schema", id, info.IfMatch, storage.ErrSchemaExists, func() (snapshot, error) {
+		return s.snapshot(id)
+	}, func(ifMatch string) error {
+		conditional := info
+		conditional.IfMatch = ifMatch
+		var err error
+		restored, err = s.SchemaStore.RestoreVersion(id, version, conditional)
+		return err
+	})
+	if err != nil {
+		return restored, err
+	}
+	return restored, record(s.log, s.actor, storage.EventSchemaRestored, id, restored.Version, before, s.versionHash(id, restored.Version))
+}
+
+// DeleteSchema 删除Schema并记录schema.deleted
+func (s *schemaStore) DeleteSchema(id string) error {
+	return s.DeleteSchemaIfMatch(id, "")
+}
+
+// DeleteSchemaIfMatch 删除Schema并记录schema.deleted
+func (s *schemaStore) DeleteSchemaIfMatch(id string, ifMatch string) error {
+	before, err := conditional("schema", id, ifMatch, storage.ErrSchemaExists, func() (snapshot, error) {
+		return s.snapshot(id)
+	}, func(ifMatch string) error {
+		return s.SchemaStore.DeleteSchemaIfMatch(id, ifMatch)
+	})
+	if err != nil {
+		return err
+	}
+	return record(s.log, s.actor, storage.EventSchemaDeleted, id, 0, before, "")
+}
+
+// RestoreSchema 从回收站恢复Schema并记录schema.undeleted
+func (s *schemaStore) RestoreSchema(id string, trashID string) (storage.SchemaMetadata, error) {
+	metadata, err := s.SchemaStore.RestoreSchema(id, trashID)
+	if err != nil {
+		return metadata, err
+	}
+	return metadata, record(s.log, s.actor, storage.EventSchemaUndeleted, id, metadata.Version, "", s.versionHash(id, metadata.Version))
+}
+
+// PurgeTrash 清理回收站并为每个被永久删除的Schema记录schema.purged
+func (s *schemaStore) PurgeTrash(deletedBefore time.Time) ([]string, error) {
+	purged, err := s.SchemaStore.PurgeTrash(deletedBefore)
+	errs := []error{err}
+	for _, id := range purged {
+		errs = append(errs, record(s.log, s.actor, storage.EventSchemaPurged, id, 0, "", ""))
+	}
+	return purged, errors.Join(errs...)
+}
+
+// configStore 在配置写操作成功后追加审计记录
+type configStore struct {
+	storage.ConfigStore
+	log   *Log
+	actor Actor
+}
+
+// WithConfigs 包装一个ConfigStore，以actor的名义记录其所有写操作
+//
+// 修改前的摘要取自写入时实际被替换的内容；审计记录写入失败时返回包装了ErrNotRecorded的错误
+func WithConfigs(store storage.ConfigStore, log *Log, actor Actor) storage.ConfigStore {
+	return &configStore{ConfigStore: store, log: log, actor: actor}
+}
+
+// snapshot 读取配置的当前内容
+func (s *configStore) snapshot(schemaID string) (snapshot, error) {
+	data, _, err := s.ConfigStore.GetConfig(schemaID)
+	if errors.Is(err, storage.ErrConfigNotFound) {
+		return snapshot{}, nil
+	}
+	if err != nil {
+		return snapshot{}, err
+	}
+	return snapshot{exists: true, etag: storage.ConfigETag(data), hash: ContentHash(data)}, nil
+}
+
+// SaveConfig 保存配置并记录config.saved
+func (s *configStore) SaveConfig(schemaID string, configData []byte) error {
+	return s.SaveConfigVersion(schemaID, configData, 0)
+}
+
+// SaveConfigVersion 保存配置并记录config.saved，Version是配置对应的Schema版本
+func (s *configStore) SaveConfigVersion(schemaID string, configData []byte, schemaVersion int) error {
+	return s.SaveConfigIfMatch(schemaID, configData, schemaVersion, "")
+}
+
+// SaveConfigIfMatch 在If-Match条件满足时保存配置并记录config.saved
+func (s *configStore) SaveConfigIfMatch(schemaID string, configData []byte, schemaVersion int, ifMatch string) error {
+	before, err := conditional("config", schemaID, ifMatch, storage.ErrConfigExists, func() (snapshot, error) {
+		return s.snapshot(schemaID)
+	}, func(ifMatch string) error {
+		return s.ConfigStore.SaveConfigIfMatch(schemaID, configData, schemaVersion, ifMatch)
+	})
+	if err != nil {
+		return err
+	}
+	return record(s.log, s.actor, storage.EventConfigSaved, schemaID, schemaVersion, before, ContentHash(configData))
+}
+
+// DeleteConfig 删除配置并记录config.deleted
+func (s *configStore) DeleteConfig(schemaID string) error {
+	return s.DeleteConfigIfMatch(schemaID, "")
+}
+
+// DeleteConfigIfMatch 在If-Match条件满足时删除配置并记录config.deleted
+func (s *configStore) DeleteConfigIfMatch(schemaID string, ifMatch string) error {
+	before, err := conditional("config", schemaID, ifMatch, storage.ErrConfigExists, func() (snapshot, error) {
+		return s.snapshot(schemaID)
+	}, func(ifMatch string) error {
+		return s.ConfigStore.DeleteConfigIfMatch(schemaID, ifMatch)
+	})
+	if err != nil {
+		return err
+	}
+	return record(s.log, s.actor, storage.EventConfigDeleted, schemaID, 0, before, "")
+}
@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"goci/backend/storage"
+)
+
+// 测试存储装饰器记录修改前后的内容摘要
+func TestStoreDecorators(t *testing.T) {
+	log, err := Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer log.Close()
+
+	actor := Actor{Name: "alice", RequestID: "req-1", ClientIP: "192.0.2.1"}
+	schemas := WithSchemas(storage.NewMemorySchemaStore(), log, actor)
+	configs := WithConfigs(storage.NewMemoryConfigStore(), log, SystemActor)
+
+	v1 := []byte(`{"type": "object"}`)
+	v2 := []byte(`{"type": "object", "properties": {}}`)
+	if err := schemas.SaveSchema("app", "App", "", v1); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if err := schemas.SaveSchema("app", "App", "", v2); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if _, err := schemas.RestoreVersion("app", 1, storage.RevisionInfo{}); err != nil {
+		t.Fatalf("Failed to restore schema: %v", err)
+	}
+	config := []byte(`{"name": "demo"}`)
+	if err := configs.SaveConfigVersion("app", config, 3); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	if err := configs.DeleteConfig("app"); err != nil {
+		t.Fatalf("Failed to delete config: %v", err)
+	}
+	if err := schemas.DeleteSchema("app"); err != nil {
+		t.Fatalf("Failed to delete schema: %v", err)
+	}
+
+	// 失败的操作不记录
+	if err := schemas.DeleteSchema("missing"); err == nil {
+		t.Fatalf("Expected error deleting missing schema")
+	}
+
+	records, err := log.Query(Filter{})
+	if err != nil {
+		t.Fatalf("Failed to query audit log: %v", err)
+	}
+	// 查询结果从新到旧，按写入顺序比较
+	slices.Reverse(records)
+	expected := []Record{
+		{Actor: "alice", Operation: storage.EventSchemaSaved, Version: 1, After: ContentHash(v1)},
+		{Actor: "alice", Operation: storage.EventSchemaSaved, Version: 2, Before: ContentHash(v1), After: ContentHash(v2)},
+		{Actor: "alice", Operation: storage.EventSchemaRestored, Version: 3, Before: ContentHash(v2), After: ContentHash(v1)},
+		{Actor: "system", Operation: storage.EventConfigSaved, Version: 3, After: ContentHash(config)},
+		{Actor: "system", Operation: storage.EventConfigDeleted, Before: ContentHash(config)},
+		{Actor: "alice", Operation: storage.EventSchemaDeleted, Before: ContentHash(v1)},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %d records, got %d: %+v", len(expected), len(records), records)
+	}
+	for i, record := range records {
+		want := expected[i]
+		if record.Actor != want.Actor || record.Operation != want.Operation || record.Target != "app" ||
+			record.Version != want.Version || record.Before != want.Before || record.After != want.After {
+			t.Errorf("Record %d: expected %+v, got %+v", i+1, want, record)
+		}
+	}
+	if records[0].RequestID != "req-1" || records[0].ClientIP != "192.0.2.1" {
+		t.Errorf("Request details not recorded: %+v", records[0])
+	}
+	if records[3].RequestID != "" || records[3].ClientIP != "" {
+		t.Errorf("System record should not have request details: %+v", records[3])
+	}
+}
+
+// 测试审计记录写入失败时返回ErrNotRecorded，而不是当作成功
+func TestStoreRecordError(t *testing.T) {
+	log, err := Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	store := storage.NewMemoryConfigStore()
+	configs := WithConfigs(store, log, SystemActor)
+	log.Close()
+
+	if err := configs.SaveConfig("app", []byte(`{}`)); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("Expected ErrNotRecorded, got %v", err)
+	}
+	// 修改本身已经生效
+	if _, _, err := store.GetConfig("app"); err != nil {
+		t.Errorf("Expected config to be saved, got %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+// Package auth 为API提供认证和基于角色的授权
+//
+// 认证方式通过Authenticator扩展，目前支持静态API令牌、htpasswd文件的HTTP Basic认证
+// 和通过JWKS校验的JWT/OIDC Bearer令牌。认证得到的Principal带有一组Grant，
+// 每个Grant在匹配的Schema上授予viewer、editor或admin角色。
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Role 是对Schema的访问级别，高级别包含低级别的全部权限
+type Role string
+
+// 支持的角色
+const (
+	// RoleViewer 可以读取Schema和配置
+	RoleViewer Role = "viewer"
+	// RoleEditor 还可以创建和修改Schema和配置
+	RoleEditor Role = "editor"
+	// RoleAdmin 还可以删除Schema和配置
+	RoleAdmin Role = "admin"
+)
+
+// roleRanks 是角色的级别，数字越大权限越多
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// Includes 判断角色是否包含required的权限
+func (r Role) Includes(required Role) bool {
+	return roleRanks[r] > 0 && roleRanks[r] >= roleRanks[required]
+}
+
+// 错误定义
+var (
+	// ErrNoCredentials 表示请求没有携带该认证方式的凭据
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials 表示请求携带的凭据无效
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrInvalidGrant 表示角色授权的格式错误
+	ErrInvalidGrant = errors.New("invalid grant")
+)
+
+// Grant 在名称匹配Schema的Schema上授予一个角色
+type Grant struct {
+	Role Role
+	// Schema 是path.Match格式的Schema ID模式，"*"匹配所有Schema
+	Schema string
+}
+
+// ParseGrant 解析"role"或"role:schema-pattern"格式的授权，只写角色时适用于所有Schema
+func ParseGrant(value string) (Grant, error) {
+	role, schema, found := strings.Cut(strings.TrimSpace(value), ":")
+	if !found {
+		schema = "*"
+	}
+
+	grant := Grant{Role: Role(role), Schema: schema}
+	if roleRanks[grant.Role] == 0 {
+		return Grant{}, fmt.Errorf("%w: unknown role %q", ErrInvalidGrant, role)
+	}
+	if _, err := path.Match(schema, ""); err != nil || schema == "" {
+		return Grant{}, fmt.Errorf("%w: bad schema pattern %q", ErrInvalidGrant, schema)
+	}
+	return grant, nil
+}
+
+// ParseGrants 解析一组授权
+func ParseGrants(values []string) ([]Grant, error) {
+	grants := make([]Grant, 0, len(values))
+	for _, value := range values {
+		grant, err := ParseGrant(value)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+	return grants, nil
+}
+
+// String 返回授权的文本格式
+func (g Grant) String() string {
+	if g.Schema == "*" {
+		return string(g.Role)
+	}
+	return string(g.Role) + ":" + g.Schema
+}
+
+// Bindings 把用户名映射到授权，"*"中的授权适用于所有认证通过的用户
+type Bindings map[string][]Grant
+
+// Grants 返回用户的全部授权
+func (b Bindings) Grants(name string) []Grant {
+	grants := append([]Grant(nil), b[name]...)
+	if name != "*" {
+		grants = append(grants, b["*"]...)
+	}
+	return grants
+}
+
+// Principal 是认证通过的请求主体
+type Principal struct {
+	Name   string
+	Grants []Grant
+}
+
+// Can 判断主体是否拥有schemaID上的role
+func (p Principal) Can(schemaID string, role Role) bool {
+	for _, grant := range p.Grants {
+		if matched, _ := path.Match(grant.Schema, schemaID); matched && grant.Role.Includes(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAny 判断主体是否至少在一个Schema上拥有role，用于不针对单个Schema的请求
+func (p Principal) CanAny(role Role) bool {
+	for _, grant := range p.Grants {
+		if grant.Role.Includes(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator 是一种认证方式
+type Authenticator interface {
+	// Authenticate 认证请求，请求没有携带该方式的凭据时返回ErrNoCredentials
+	Authenticate(r *http.Request) (Principal, error)
+	// Challenge 是认证失败时WWW-Authenticate响应头的值
+	Challenge() string
+}
+
+// principalKey 是Principal在gin.Context中的键
+const principalKey = "auth.principal"
+
+// RequiredRole 返回HTTP方法需要的角色：读取需要viewer，删除需要admin，其他写操作需要editor
+func RequiredRole(method string) Role {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return RoleViewer
+	case http.MethodDelete:
+		return RoleAdmin
+	default:
+		return RoleEditor
+	}
+}
+
+// Middleware 返回认证和授权中间件，只检查/api下的请求
+//
+// 依次尝试各个认证方式，任何一个成功即可；携带了凭据但都不被接受时返回401。
+// 没有携带凭据的请求使用anonymous中的授权，anonymous为空时返回401。
+// 路由参数id或schemaId（或查询参数schemaId）指定了Schema时检查该Schema上的角色，
+// 否则只要求主体在任意Schema上拥有该角色，由处理器按Allowed过滤结果。
+func Middleware(authenticators []Authenticator, anonymous []Grant) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.URL.Path != "/api" && !strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			c.Next()
+			return
+		}
+
+		principal, err := authenticate(c.Request, authenticators)
+		if errors.Is(err, ErrNoCredentials) && len(anonymous) > 0 {
+			principal, err = Principal{Name: "anonymous", Grants: anonymous}, nil
+		}
+		if err != nil {
+			slog.Debug("Authentication failed", "path", c.Request.URL.Path, "error", err)
+			for _, authenticator := range authenticators {
+				c.Writer.Header().Add("WWW-Authenticate", authenticator.Challenge())
+			}
+			message := "Authentication required"
+			if !errors.Is(err, ErrNoCredentials) {
+				message = "Invalid credentials"
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
+			return
+		}
+
+		role := RequiredRole(c.Request.Method)
+		schemaID := requestSchemaID(c)
+
+		allowed := principal.CanAny(role)
+		if schemaID != "" {
+			allowed = principal.Can(schemaID, role)
+		}
+		if !allowed {
+			message := fmt.Sprintf("%s role required", role)
+			if schemaID != "" {
+				message += " on schema " + schemaID
+			}
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": message})
+			return
+		}
+
+		c.Set(principalKey, principal)
+		c.Next()
+	}
+}
+
+// authenticate 依次尝试各个认证方式
+func authenticate(r *http.Request, authenticators []Authenticator) (Principal, error) {
+	err := ErrNoCredentials
+	for _, authenticator := range authenticators {
+		principal, authErr := authenticator.Authenticate(r)
+		if authErr == nil {
+			return principal, nil
+		}
+		// 保留携带了凭据的认证方式的错误
+		if !errors.Is(authErr, ErrNoCredentials) {
+			err = authErr
+		}
+	}
+	return Principal{}, err
+}
+
+// requestSchemaID 返回请求针对的Schema ID
+func requestSchemaID(c *gin.Context) string {
+	if id := c.Param("id"); id != "" {
+		return id
+	}
+	if id := c.Param("schemaId"); id != "" {
+		return id
+	}
+	return c.Query("schemaId")
+}
+
+// FromContext 返回认证通过的主体，未启用认证时返回false
+func FromContext(c *gin.Context) (Principal, bool) {
+	value, exists := c.Get(principalKey)
+	if !exists {
+		return Principal{}, false
+	}
+	principal, ok := value.(Principal)
+	return principal, ok
+}
+
+// Allowed 判断当前请求的主体是否拥有schemaID上的role，未启用认证时总是返回true
+func Allowed(c *gin.Context, schemaID string, role Role) bool {
+	principal, ok := FromContext(c)
+	return !ok || principal.Can(schemaID, role)
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 测试解析角色授权
+func TestParseGrant(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected Grant
+	}{
+		{"viewer", Grant{Role: RoleViewer, Schema: "*"}},
+		{"editor:app", Grant{Role: RoleEditor, Schema: "app"}},
+		{" admin:team-* ", Grant{Role: RoleAdmin, Schema: "team-*"}},
+	}
+	for _, tt := range tests {
+		grant, err := ParseGrant(tt.value)
+		if err != nil || grant != tt.expected {
+			t.Errorf("ParseGrant(%q) = %+v, %v", tt.value, grant, err)
+		}
+	}
+
+	for _, value := range []string{"owner", "viewer:", "editor:[", ""} {
+		if _, err := ParseGrant(value); !errors.Is(err, ErrInvalidGrant) {
+			t.Errorf("ParseGrant(%q): expected ErrInvalidGrant, got %v", value, err)
+		}
+	}
+}
+
+// 测试按Schema检查角色
+func TestPrincipalCan(t *testing.T) {
+	p := Principal{Name: "alice", Grants: []Grant{
+		{Role: RoleViewer, Schema: "*"},
+		{Role: RoleEditor, Schema: "team-*"},
+		{Role: RoleAdmin, Schema: "app"},
+	}}
+
+	tests := []struct {
+		schema   string
+		role     Role
+		expected bool
+	}{
+		{"other", RoleViewer, true},
+		{"other", RoleEditor, false},
+		{"team-a", RoleEditor, true},
+		{"team-a", RoleAdmin, false},
+		{"app", RoleAdmin, true},
+	}
+	for _, tt := range tests {
+		if got := p.Can(tt.schema, tt.role); got != tt.expected {
+			t.Errorf("Can(%s, %s) = %v", tt.schema, tt.role, got)
+		}
+	}
+
+	if !p.CanAny(RoleAdmin) || (Principal{}).CanAny(RoleViewer) {
+		t.Errorf("Unexpected CanAny result")
+	}
+
+	bindings := Bindings{"alice": {{Role: RoleEditor, Schema: "*"}}, "*": {{Role: RoleViewer, Schema: "*"}}}
+	if grants := bindings.Grants("alice"); len(grants) != 2 {
+		t.Errorf("Unexpected grants for alice: %+v", grants)
+	}
+	if grants := bindings.Grants("bob"); len(grants) != 1 || grants[0].Role != RoleViewer {
+		t.Errorf("Unexpected grants for bob: %+v", grants)
+	}
+}
+
+// fakeAuthenticator 通过X-User请求头认证，用于测试中间件
+type fakeAuthenticator map[string][]Grant
+
+func (f fakeAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	user := r.Header.Get("X-User")
+	if user == "" {
+		return Principal{}, ErrNoCredentials
+	}
+	grants, exists := f[user]
+	if !exists {
+		return Principal{}, ErrInvalidCredentials
+	}
+	return Principal{Name: user, Grants: grants}, nil
+}
+
+func (f fakeAuthenticator) Challenge() string {
+	return `Fake realm="goci"`
+}
+
+// 测试中间件的认证和授权
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authenticator := fakeAuthenticator{
+		"viewer": {{Role: RoleViewer, Schema: "*"}},
+		"editor": {{Role: RoleEditor, Schema: "app"}},
+		"admin":  {{Role: RoleAdmin, Schema: "*"}},
+	}
+
+	setup := func(anonymous []Grant) *gin.Engine {
+		r := gin.New()
+		r.Use(Middleware([]Authenticator{authenticator}, anonymous))
+		ok := func(c *gin.Context) {
+			principal, _ := FromContext(c)
+			c.String(http.StatusOK, principal.Name)
+		}
+		r.GET("/api/schemas", ok)
+		r.GET("/api/schemas/:id", ok)
+		r.POST("/api/schemas/:id", ok)
+		r.DELETE("/api/schemas/:id", ok)
+		r.PUT("/api/configs/:schemaId", ok)
+		r.GET("/index.html", ok)
+		return r
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		user   string
+		code   int
+	}{
+		{http.MethodGet, "/api/schemas/app", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/schemas/app", "mallory", http.StatusUnauthorized},
+		{http.MethodGet, "/index.html", "", http.StatusOK},
+		{http.MethodGet, "/api/schemas/app", "viewer", http.StatusOK},
+		{http.MethodPost, "/api/schemas/app", "viewer", http.StatusForbidden},
+		{http.MethodPost, "/api/schemas/app", "editor", http.StatusOK},
+		{http.MethodPost, "/api/schemas/other", "editor", http.StatusForbidden},
+		{http.MethodPut, "/api/configs/app", "editor", http.StatusOK},
+		{http.MethodDelete, "/api/schemas/app", "editor", http.StatusForbidden},
+		{http.MethodDelete, "/api/schemas/app", "admin", http.StatusOK},
+		{http.MethodGet, "/api/schemas", "editor", http.StatusOK},
+	}
+
+	r := setup(nil)
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.user != "" {
+			req.Header.Set("X-User", tt.user)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.code {
+			t.Errorf("%s %s as %q: expected status code %d, got %d", tt.method, tt.path, tt.user, tt.code, w.Code)
+		}
+		if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != `Fake realm="goci"` {
+			t.Errorf("Expected WWW-Authenticate challenge, got %q", w.Header().Get("WWW-Authenticate"))
+		}
+	}
+
+	// 匿名访问
+	r = setup([]Grant{{Role: RoleViewer, Schema: "*"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/schemas/app", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "anonymous" {
+		t.Errorf("Expected anonymous access, got %d %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/schemas/app", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status code %d, got %d", http.StatusForbidden, w.Code)
+	}
+
+	// 错误的凭据不会退回匿名访问
+	req = httptest.NewRequest(http.MethodGet, "/api/schemas/app", nil)
+	req.Header.Set("X-User", "mallory")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HtpasswdAuthenticator 使用htpasswd文件认证HTTP Basic请求
+//
+// 只支持bcrypt（htpasswd -B）和{SHA}格式的密码。文件修改后会在下一次请求时重新加载。
+type HtpasswdAuthenticator struct {
+	path     string
+	bindings Bindings
+
+	mutex   sync.RWMutex
+	modTime time.Time
+	users   map[string]string
+}
+
+// NewHtpasswdAuthenticator 加载htpasswd文件，bindings为用户分配角色
+func NewHtpasswdAuthenticator(path string, bindings Bindings) (*HtpasswdAuthenticator, error) {
+	a := &HtpasswdAuthenticator{path: path, bindings: bindings}
+	if err := a.reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Authenticate 认证请求
+func (a *HtpasswdAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+
+	a.reloadIfChanged()
+
+	a.mutex.RLock()
+	hash, exists := a.users[username]
+	a.mutex.RUnlock()
+
+	if !exists || !checkPassword(hash, password) {
+		return Principal{}, fmt.Errorf("%w: wrong username or password for %s", ErrInvalidCredentials, username)
+	}
+	return Principal{Name: username, Grants: a.bindings.Grants(username)}, nil
+}
+
+// Challenge 返回WWW-Authenticate响应头的值
+func (a *HtpasswdAuthenticator) Challenge() string {
+	return `Basic realm="goci", charset="UTF-8"`
+}
+
+// reloadIfChanged 在文件修改后重新加载，失败时继续使用之前的内容
+func (a *HtpasswdAuthenticator) reloadIfChanged() {
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return
+	}
+
+	a.mutex.RLock()
+	changed := !info.ModTime().Equal(a.modTime)
+	a.mutex.RUnlock()
+
+	if changed {
+		if err := a.reload(); err != nil {
+			slog.Warn("Failed to reload htpasswd file", "path", a.path, "error", err)
+		}
+	}
+}
+
+// reload 读取并解析htpasswd文件
+func (a *HtpasswdAuthenticator) reload() error {
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return fmt.Errorf("error reading htpasswd file: %w", err)
+	}
+	data, err := os.ReadFile(a.path)
+	if err != nil {
+		return fmt.Errorf("error reading htpasswd file: %w", err)
+	}
+
+	users, err := parseHtpasswd(data)
+	if err != nil {
+		return fmt.Errorf("error parsing htpasswd file %s: %w", a.path, err)
+	}
+
+	a.mutex.Lock()
+	a.users = users
+	a.modTime = info.ModTime()
+	a.mutex.Unlock()
+	return nil
+}
+
+// parseHtpasswd 解析"user:hash"格式的行，忽略空行和#注释
+func parseHtpasswd(data []byte) (map[string]string, error) {
+	users := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		username, hash, found := strings.Cut(text, ":")
+		if !found || username == "" {
+			return nil, fmt.Errorf("line %d: expected user:hash", line)
+		}
+		if !supportedHash(hash) {
+			return nil, fmt.Errorf("line %d: unsupported hash for user %s, use bcrypt (htpasswd -B)", line, username)
+		}
+		users[username] = hash
+	}
+	return users, scanner.Err()
+}
+
+// supportedHash 判断是否支持该密码格式
+func supportedHash(hash string) bool {
+	for _, prefix := range []string{"$2y$", "$2a$", "$2b$", "{SHA}"} {
+		if strings.HasPrefix(hash, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPassword 校验密码
+func checkPassword(hash, password string) bool {
+	if encoded, found := strings.CutPrefix(hash, "{SHA}"); found {
+		sum := sha1.Sum([]byte(password))
+		expected := base64.StdEncoding.EncodeToString(sum[:])
+		return subtle.ConstantTimeCompare([]byte(encoded), []byte(expected)) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 测试使用htpasswd文件的Basic认证
+func TestHtpasswdAuthenticator(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "htpasswd-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+
+	// bob的密码是"password"，使用{SHA}格式
+	path := filepath.Join(tempDir, "htpasswd")
+	content := "# users\nalice:" + string(hash) + "\nbob:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write htpasswd file: %v", err)
+	}
+
+	bindings := Bindings{"alice": {{Role: RoleAdmin, Schema: "*"}}}
+	authenticator, err := NewHtpasswdAuthenticator(path, bindings)
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+
+	check := func(username, password string) (Principal, error) {
+		req := httptest.NewRequest("GET", "/api/schemas", nil)
+		req.SetBasicAuth(username, password)
+		return authenticator.Authenticate(req)
+	}
+
+	principal, err := check("alice", "secret")
+	if err != nil || principal.Name != "alice" || !principal.Can("app", RoleAdmin) {
+		t.Errorf("Unexpected principal: %+v, %v", principal, err)
+	}
+
+	if principal, err := check("bob", "password"); err != nil || len(principal.Grants) != 0 {
+		t.Errorf("Unexpected principal: %+v, %v", principal, err)
+	}
+
+	if _, err := check("alice", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+	if _, err := check("carol", "secret"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+
+	if _, err := authenticator.Authenticate(httptest.NewRequest("GET", "/", nil)); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected ErrNoCredentials, got %v", err)
+	}
+
+	// 文件修改后重新加载
+	if err := os.WriteFile(path, []byte("carol:"+string(hash)+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write htpasswd file: %v", err)
+	}
+	os.Chtimes(path, time.Now(), time.Now().Add(time.Second))
+
+	if _, err := check("carol", "secret"); err != nil {
+		t.Errorf("Expected reloaded user, got %v", err)
+	}
+	if _, err := check("alice", "secret"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected removed user to fail, got %v", err)
+	}
+
+	// 不支持的密码格式
+	if err := os.WriteFile(path, []byte("dave:$apr1$abc$def\n"), 0600); err != nil {
+		t.Fatalf("Failed to write htpasswd file: %v", err)
+	}
+	if _, err := NewHtpasswdAuthenticator(path, nil); err == nil {
+		t.Errorf("Expected error for unsupported hash")
+	}
+}
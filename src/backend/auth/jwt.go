@@ -0,0 +1,420 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JWKS缓存和时钟误差
+const (
+	// jwksMaxAge 是JWKS缓存的有效期
+	jwksMaxAge = time.Hour
+	// jwksMinRefresh 是遇到未知kid时两次刷新JWKS的最小间隔，避免被无效令牌放大请求
+	jwksMinRefresh = time.Minute
+	// clockSkew 是校验exp和nbf时允许的时钟误差
+	clockSkew = time.Minute
+)
+
+// JWTOptions 配置JWT校验
+type JWTOptions struct {
+	// Issuer 非空时要求iss与其相同；没有设置JWKSURL时通过OIDC发现获取JWKS地址
+	Issuer string
+	// JWKSURL 是签名公钥集合的地址
+	JWKSURL string
+	// Audience 非空时要求aud包含该值
+	Audience string
+	// RolesClaim 是包含授权的claim，可以用点号访问嵌套字段，默认为roles
+	RolesClaim string
+	// Bindings 按sub为主体分配角色，与RolesClaim中的授权合并
+	Bindings Bindings
+	// Client 用于获取JWKS，为nil时使用10秒超时的默认客户端
+	Client *http.Client
+}
+
+// JWTAuthenticator 校验Authorization: Bearer请求头中的JWT
+//
+// 支持RS*、PS*、ES*和EdDSA签名，公钥从JWKS获取并缓存。
+type JWTAuthenticator struct {
+	opts JWTOptions
+
+	mutex   sync.Mutex
+	jwksURL string
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// NewJWTAuthenticator 创建一个新的JWTAuthenticator实例，JWKS在第一次认证时获取
+func NewJWTAuthenticator(opts JWTOptions) (*JWTAuthenticator, error) {
+	if opts.JWKSURL == "" && opts.Issuer == "" {
+		return nil, fmt.Errorf("jwt authentication requires a jwks url or an issuer")
+	}
+	if opts.RolesClaim == "" {
+		opts.RolesClaim = "roles"
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &JWTAuthenticator{opts: opts, jwksURL: opts.JWKSURL}, nil
+}
+
+// Authenticate 认证请求
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+
+	claims, err := a.verify(token, time.Now())
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %s", ErrInvalidCredentials, err.Error())
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return Principal{}, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+
+	grants := a.opts.Bindings.Grants(subject)
+	for _, value := range claimStrings(claims, a.opts.RolesClaim) {
+		// 身份提供方的角色中可能有与本服务无关的值，忽略无法解析的项
+		if grant, err := ParseGrant(value); err == nil {
+			grants = append(grants, grant)
+		}
+	}
+
+	return Principal{Name: subject, Grants: grants}, nil
+}
+
+// Challenge 返回WWW-Authenticate响应头的值
+func (a *JWTAuthenticator) Challenge() string {
+	return `Bearer realm="goci"`
+}
+
+// verify 校验令牌签名和标准claim，返回全部claim
+func (a *JWTAuthenticator) verify(token string, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed token header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature: %w", err)
+	}
+
+	key, err := a.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+	if err := a.checkClaims(claims, now); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// checkClaims 校验exp、nbf、iss和aud
+func (a *JWTAuthenticator) checkClaims(claims map[string]interface{}, now time.Time) error {
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return fmt.Errorf("token has no expiry")
+	}
+	if now.Add(-clockSkew).After(time.Unix(int64(exp), 0)) {
+		return fmt.Errorf("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return fmt.Errorf("token not valid yet")
+	}
+
+	if a.opts.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != a.opts.Issuer {
+			return fmt.Errorf("unexpected issuer %q", iss)
+		}
+	}
+
+	if a.opts.Audience != "" {
+		found := false
+		switch aud := claims["aud"].(type) {
+		case string:
+			found = aud == a.opts.Audience
+		case []interface{}:
+			for _, value := range aud {
+				found = found || value == a.opts.Audience
+			}
+		}
+		if !found {
+			return fmt.Errorf("token is not issued for audience %q", a.opts.Audience)
+		}
+	}
+	return nil
+}
+
+// key 返回kid对应的公钥，缓存过期或遇到未知kid时刷新JWKS
+func (a *JWTAuthenticator) key(kid string) (crypto.PublicKey, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.keys == nil || time.Since(a.fetched) > jwksMaxAge {
+		// 刷新失败时继续使用之前的公钥
+		if err := a.refreshNoLock(); err != nil && a.keys == nil {
+			return nil, err
+		}
+	}
+
+	key, found := a.lookupNoLock(kid)
+	if !found && time.Since(a.fetched) > jwksMinRefresh {
+		if err := a.refreshNoLock(); err != nil {
+			return nil, err
+		}
+		key, found = a.lookupNoLock(kid)
+	}
+	if !found {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// lookupNoLock 按kid查找公钥（无锁版本），令牌没有kid且只有一个公钥时使用该公钥
+func (a *JWTAuthenticator) lookupNoLock(kid string) (crypto.PublicKey, bool) {
+	if key, found := a.keys[kid]; found {
+		return key, true
+	}
+	if kid == "" && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// refreshNoLock 获取JWKS（无锁版本）
+func (a *JWTAuthenticator) refreshNoLock() error {
+	// 无论成功与否都记录时间，避免在身份提供方不可用时频繁请求
+	a.fetched = time.Now()
+
+	if a.jwksURL == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := a.getJSON(strings.TrimSuffix(a.opts.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return fmt.Errorf("error discovering jwks url: %w", err)
+		}
+		if discovery.JWKSURI == "" {
+			return fmt.Errorf("error discovering jwks url: no jwks_uri in openid configuration")
+		}
+		a.jwksURL = discovery.JWKSURI
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := a.getJSON(a.jwksURL, &set); err != nil {
+		return fmt.Errorf("error fetching jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// 跳过不支持的公钥类型，不影响其他公钥
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	a.keys = keys
+	return nil
+}
+
+// getJSON 获取并解析JSON
+func (a *JWTAuthenticator) getJSON(url string, target interface{}) error {
+	resp, err := a.opts.Client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
+
+// jsonWebKey 是JWKS中的一个公钥
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey 把JWK转换为公钥
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, fmt.Errorf("invalid rsa exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("invalid ec key")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// signingHashes 是支持的非对称签名算法及其摘要算法
+var signingHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"PS256": crypto.SHA256, "PS384": crypto.SHA384, "PS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+// verifySignature 按alg校验签名，不接受none和对称算法
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	if alg == "EdDSA" {
+		if k, ok := key.(ed25519.PublicKey); ok && ed25519.Verify(k, []byte(signed), signature) {
+			return nil
+		}
+		return fmt.Errorf("invalid token signature")
+	}
+
+	hash, ok := signingHashes[alg]
+	if !ok {
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	hasher := hash.New()
+	hasher.Write([]byte(signed))
+	digest := hasher.Sum(nil)
+
+	valid := false
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			valid = rsa.VerifyPKCS1v15(k, hash, digest, signature) == nil
+		case "PS":
+			valid = rsa.VerifyPSS(k, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		}
+	case *ecdsa.PublicKey:
+		// ES*签名是定长的r和s拼接
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if alg[:2] == "ES" && len(signature) == 2*size {
+			r := new(big.Int).SetBytes(signature[:size])
+			s := new(big.Int).SetBytes(signature[size:])
+			valid = ecdsa.Verify(k, digest, r, s)
+		}
+	}
+
+	if !valid {
+		return fmt.Errorf("invalid token signature")
+	}
+	return nil
+}
+
+// decodeSegment 解码JWT中base64url编码的JSON
+func decodeSegment(segment string, target interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// decodeBigInt 解码base64url编码的大整数
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, fmt.Errorf("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// claimStrings 按点号分隔的路径读取claim，支持字符串数组和空格分隔的字符串
+func claimStrings(claims map[string]interface{}, path string) []string {
+	var value interface{} = claims
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 测试辅助函数：签发JWT
+func signJWT(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signed))
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		sig, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		signature = sig
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// 测试辅助函数：启动提供OIDC发现和JWKS的服务器
+func serveJWKS(t *testing.T, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey, requests *int32) *httptest.Server {
+	encode := func(b *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(b.Bytes())
+	}
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": server.URL, "jwks_uri": server.URL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "n": encode(rsaKey.N), "e": encode(big.NewInt(int64(rsaKey.E)))},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": encode(ecKey.X), "y": encode(ecKey.Y)},
+			{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"},
+		}})
+	})
+	server = httptest.NewServer(mux)
+	return server
+}
+
+// 测试JWT校验
+func TestJWTAuthenticator(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	var requests int32
+	server := serveJWKS(t, rsaKey, ecKey, &requests)
+	defer server.Close()
+
+	// 只配置issuer，通过OIDC发现获取JWKS
+	authenticator, err := NewJWTAuthenticator(JWTOptions{
+		Issuer:     server.URL,
+		Audience:   "goci",
+		RolesClaim: "realm_access.roles",
+		Bindings:   Bindings{"alice": {{Role: RoleViewer, Schema: "*"}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+
+	now := time.Now().Unix()
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss":          server.URL,
+			"aud":          []string{"goci", "other"},
+			"sub":          "alice",
+			"exp":          now + 300,
+			"realm_access": map[string]interface{}{"roles": []string{"editor:app", "offline_access"}},
+		}
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+
+	authenticate := func(token string) (Principal, error) {
+		req := httptest.NewRequest("GET", "/api/schemas", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return authenticator.Authenticate(req)
+	}
+
+	// RS256和ES256
+	for _, token := range []string{
+		signJWT(t, "RS256", "rsa", rsaKey, claims(nil)),
+		signJWT(t, "ES256", "ec", ecKey, claims(nil)),
+	} {
+		principal, err := authenticate(token)
+		if err != nil {
+			t.Fatalf("Failed to authenticate: %v", err)
+		}
+		if principal.Name != "alice" || !principal.Can("app", RoleEditor) || principal.Can("other", RoleEditor) || !principal.Can("other", RoleViewer) {
+			t.Errorf("Unexpected principal: %+v", principal)
+		}
+	}
+
+	// JWKS被缓存
+	if atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("Expected JWKS to be fetched once, got %d", requests)
+	}
+
+	invalid := map[string]string{
+		"expired":        signJWT(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"exp": now - 3600})),
+		"not yet valid":  signJWT(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"nbf": now + 3600})),
+		"no expiry":      signJWT(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"exp": nil})),
+		"wrong issuer":   signJWT(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"iss": "https://evil.example"})),
+		"wrong audience": signJWT(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"aud": "other"})),
+		"no subject":     signJWT(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"sub": ""})),
+		"wrong key":      signJWT(t, "RS256", "rsa", otherKey, claims(nil)),
+		"wrong alg":      signJWT(t, "ES256", "rsa", ecKey, claims(nil)),
+		"malformed":      "not-a-jwt",
+	}
+	for name, token := range invalid {
+		if _, err := authenticate(token); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%s: expected ErrInvalidCredentials, got %v", name, err)
+		}
+	}
+
+	// 不接受none算法
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"rsa"}`))
+	payload, _ := json.Marshal(claims(nil))
+	if _, err := authenticate(header + "." + base64.RawURLEncoding.EncodeToString(payload) + "."); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected alg none to be rejected, got %v", err)
+	}
+
+	// 未知kid在最小刷新间隔内不会重新获取JWKS
+	authenticate(signJWT(t, "RS256", "unknown", rsaKey, claims(nil)))
+	if atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("Expected no JWKS refresh, got %d requests", requests)
+	}
+
+	if _, err := NewJWTAuthenticator(JWTOptions{}); err == nil {
+		t.Errorf("Expected error without jwks url or issuer")
+	}
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Token 是一个静态API令牌
+type Token struct {
+	// Name 是令牌的名称，作为Principal的名称
+	Name string
+	// Token 是令牌本身，或"sha256:"加十六进制摘要，避免在配置文件中保存明文
+	Token  string
+	Grants []Grant
+}
+
+// TokenAuthenticator 使用静态令牌认证Authorization: Bearer请求头
+type TokenAuthenticator struct {
+	tokens []hashedToken
+}
+
+// hashedToken 是保存在内存中的令牌摘要
+type hashedToken struct {
+	name   string
+	digest []byte
+	grants []Grant
+}
+
+// NewTokenAuthenticator 创建一个新的TokenAuthenticator实例
+func NewTokenAuthenticator(tokens []Token) (*TokenAuthenticator, error) {
+	a := &TokenAuthenticator{}
+	for _, token := range tokens {
+		if token.Name == "" || token.Token == "" {
+			return nil, fmt.Errorf("api token requires a name and a token")
+		}
+
+		var digest []byte
+		if hexDigest, found := strings.CutPrefix(token.Token, "sha256:"); found {
+			decoded, err := hex.DecodeString(hexDigest)
+			if err != nil || len(decoded) != sha256.Size {
+				return nil, fmt.Errorf("invalid sha256 digest for api token %s", token.Name)
+			}
+			digest = decoded
+		} else {
+			sum := sha256.Sum256([]byte(token.Token))
+			digest = sum[:]
+		}
+
+		a.tokens = append(a.tokens, hashedToken{name: token.Name, digest: digest, grants: token.Grants})
+	}
+	return a, nil
+}
+
+// Authenticate 认证请求
+func (a *TokenAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	value, ok := bearerToken(r)
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+
+	// 比较摘要而不是令牌本身，使比较时间与令牌内容无关
+	sum := sha256.Sum256([]byte(value))
+	for _, token := range a.tokens {
+		if subtle.ConstantTimeCompare(sum[:], token.digest) == 1 {
+			return Principal{Name: token.name, Grants: token.grants}, nil
+		}
+	}
+	return Principal{}, fmt.Errorf("%w: unknown api token", ErrInvalidCredentials)
+}
+
+// Challenge 返回WWW-Authenticate响应头的值
+func (a *TokenAuthenticator) Challenge() string {
+	return `Bearer realm="goci"`
+}
+
+// bearerToken 返回Authorization: Bearer请求头中的令牌
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, value, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	value = strings.TrimSpace(value)
+	return value, value != ""
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+// 测试静态API令牌认证
+func TestTokenAuthenticator(t *testing.T) {
+	sum := sha256.Sum256([]byte("hashed-secret"))
+	authenticator, err := NewTokenAuthenticator([]Token{
+		{Name: "ci", Token: "plain-secret", Grants: []Grant{{Role: RoleEditor, Schema: "*"}}},
+		{Name: "deploy", Token: "sha256:" + hex.EncodeToString(sum[:])},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+
+	tests := []struct {
+		header   string
+		expected string
+		err      error
+	}{
+		{"", "", ErrNoCredentials},
+		{"Basic dXNlcjpwYXNz", "", ErrNoCredentials},
+		{"Bearer plain-secret", "ci", nil},
+		{"bearer hashed-secret", "deploy", nil},
+		{"Bearer wrong", "", ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/schemas", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+
+		principal, err := authenticator.Authenticate(req)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%q: expected %v, got %v", tt.header, tt.err, err)
+			}
+			continue
+		}
+		if err != nil || principal.Name != tt.expected {
+			t.Errorf("%q: unexpected principal %+v, %v", tt.header, principal, err)
+		}
+	}
+
+	// 无效的摘要
+	if _, err := NewTokenAuthenticator([]Token{{Name: "bad", Token: "sha256:xyz"}}); err == nil {
+		t.Errorf("Expected error for invalid digest")
+	}
+}
@@ -0,0 +1,214 @@
+// Package client 供使用配置的Go应用在运行时读取配置
+//
+// 配置可以从服务端的数据目录读取，也可以通过HTTP从服务端获取。读取时会先按Schema校验，
+// 然后用Schema中的default补全缺失的值，再通过点分隔的路径访问，例如：
+//
+//	cfg, err := client.Load(client.NewDirSource("/var/lib/goci"), "app")
+//	port, err := cfg.GetInt("server.port")
+//
+// 数组元素使用数字下标访问，例如 "servers.0.host"。
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"goci/backend/validation"
+)
+
+var (
+	// ErrPathNotFound 表示配置和Schema默认值中都不存在该路径
+	ErrPathNotFound = errors.New("path not found")
+	// ErrTypeMismatch 表示路径上的值不是请求的类型
+	ErrTypeMismatch = errors.New("type mismatch")
+)
+
+// Config 是某个Schema对应配置的一份只读快照
+type Config struct {
+	schemaID string
+	raw      []byte
+	data     any
+}
+
+// Load 从source读取配置，校验后补全Schema默认值
+func Load(source Source, schemaID string) (*Config, error) {
+	schemaData, configData, err := source.Load(schemaID)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(schemaID, schemaData, configData)
+}
+
+// Parse 根据已有的Schema和配置内容创建Config
+func Parse(schemaID string, schemaData, configData []byte) (*Config, error) {
+	if err := validation.ValidateConfig(schemaData, configData); err != nil {
+		return nil, err
+	}
+
+	schema, err := decode(schemaData)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing schema: %w", err)
+	}
+
+	data, err := decode(configData)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config: %w", err)
+	}
+
+	return &Config{
+		schemaID: schemaID,
+		raw:      configData,
+		data:     applyDefaults(schema, data),
+	}, nil
+}
+
+// SchemaID 返回配置对应的Schema ID
+func (c *Config) SchemaID() string {
+	return c.schemaID
+}
+
+// Raw 返回未补全默认值的原始配置内容
+func (c *Config) Raw() []byte {
+	return c.raw
+}
+
+// Get 返回路径上的值，空路径返回整个配置
+//
+// 对象为map[string]any，数组为[]any，数字为json.Number
+func (c *Config) Get(path string) (any, error) {
+	value := c.data
+	if path == "" {
+		return value, nil
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]any:
+			child, exists := node[segment]
+			if !exists {
+				return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+			}
+			value = child
+		case []any:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+			}
+			value = node[index]
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrPathNotFound, path)
+		}
+	}
+
+	return value, nil
+}
+
+// GetString 返回路径上的字符串
+func (c *Config) GetString(path string) (string, error) {
+	value, err := c.Get(path)
+	if err != nil {
+		return "", err
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return "", mismatch(path, value, "string")
+	}
+	return s, nil
+}
+
+// GetInt 返回路径上的整数，带小数的数字视为类型不匹配
+func (c *Config) GetInt(path string) (int64, error) {
+	value, err := c.Get(path)
+	if err != nil {
+		return 0, err
+	}
+
+	n, ok := value.(json.Number)
+	if !ok {
+		return 0, mismatch(path, value, "integer")
+	}
+
+	i, err := n.Int64()
+	if err != nil {
+		return 0, mismatch(path, value, "integer")
+	}
+	return i, nil
+}
+
+// GetFloat 返回路径上的数字
+func (c *Config) GetFloat(path string) (float64, error) {
+	value, err := c.Get(path)
+	if err != nil {
+		return 0, err
+	}
+
+	n, ok := value.(json.Number)
+	if !ok {
+		return 0, mismatch(path, value, "number")
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return 0, mismatch(path, value, "number")
+	}
+	return f, nil
+}
+
+// GetBool 返回路径上的布尔值
+func (c *Config) GetBool(path string) (bool, error) {
+	value, err := c.Get(path)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := value.(bool)
+	if !ok {
+		return false, mismatch(path, value, "boolean")
+	}
+	return b, nil
+}
+
+// mismatch 构造类型不匹配错误
+func mismatch(path string, value any, want string) error {
+	return fmt.Errorf("%w: %s is %s, not %s", ErrTypeMismatch, path, kind(value), want)
+}
+
+// kind 返回JSON值的类型名称
+func kind(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// decode 解析JSON，数字保留为json.Number以免丢失整数精度
+func decode(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"errors"
+	"testing"
+
+	"goci/backend/validation"
+)
+
+// 测试用的Schema，包含各层级的默认值
+const testSchema = `{
+	"type": "object",
+	"properties": {
+		"server": {
+			"type": "object",
+			"properties": {
+				"host": {"type": "string", "default": "localhost"},
+				"port": {"type": "integer", "default": 8080},
+				"tls": {"type": "boolean", "default": false}
+			}
+		},
+		"ratio": {"type": "number", "default": 0.5},
+		"name": {"type": "string"},
+		"limits": {"type": "object", "default": {"rps": 100}},
+		"servers": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {"weight": {"type": "integer", "default": 1}}
+			}
+		}
+	}
+}`
+
+// 测试类型化的读取和默认值
+func TestConfigGetters(t *testing.T) {
+	cfg, err := Parse("app", []byte(testSchema), []byte(`{"server": {"port": 9090}, "name": "demo", "servers": [{"weight": 3}, {}]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	if cfg.SchemaID() != "app" {
+		t.Errorf("Unexpected schema ID: %s", cfg.SchemaID())
+	}
+
+	// 配置中的值
+	if port, err := cfg.GetInt("server.port"); err != nil || port != 9090 {
+		t.Errorf("Unexpected server.port: %d, %v", port, err)
+	}
+	if name, err := cfg.GetString("name"); err != nil || name != "demo" {
+		t.Errorf("Unexpected name: %s, %v", name, err)
+	}
+	if weight, err := cfg.GetInt("servers.0.weight"); err != nil || weight != 3 {
+		t.Errorf("Unexpected servers.0.weight: %d, %v", weight, err)
+	}
+
+	// Schema默认值
+	if host, err := cfg.GetString("server.host"); err != nil || host != "localhost" {
+		t.Errorf("Unexpected server.host: %s, %v", host, err)
+	}
+	if tls, err := cfg.GetBool("server.tls"); err != nil || tls {
+		t.Errorf("Unexpected server.tls: %v, %v", tls, err)
+	}
+	if ratio, err := cfg.GetFloat("ratio"); err != nil || ratio != 0.5 {
+		t.Errorf("Unexpected ratio: %v, %v", ratio, err)
+	}
+	if rps, err := cfg.GetInt("limits.rps"); err != nil || rps != 100 {
+		t.Errorf("Unexpected limits.rps: %d, %v", rps, err)
+	}
+	if weight, err := cfg.GetInt("servers.1.weight"); err != nil || weight != 1 {
+		t.Errorf("Unexpected servers.1.weight: %d, %v", weight, err)
+	}
+
+	// 原始配置不包含默认值
+	if string(cfg.Raw()) != `{"server": {"port": 9090}, "name": "demo", "servers": [{"weight": 3}, {}]}` {
+		t.Errorf("Unexpected raw config: %s", string(cfg.Raw()))
+	}
+}
+
+// 测试缺失对象中的默认值
+func TestConfigDefaultsForMissingObject(t *testing.T) {
+	cfg, err := Parse("app", []byte(testSchema), []byte(`{}`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	if port, err := cfg.GetInt("server.port"); err != nil || port != 8080 {
+		t.Errorf("Unexpected server.port: %d, %v", port, err)
+	}
+
+	// 没有默认值的路径仍然缺失
+	if _, err := cfg.GetString("name"); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("Expected ErrPathNotFound, got %v", err)
+	}
+	if _, err := cfg.Get("servers"); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("Expected ErrPathNotFound, got %v", err)
+	}
+}
+
+// 测试打包后的Schema中通过$ref引用的默认值，递归的定义不会无限展开
+func TestConfigDefaultsThroughRefs(t *testing.T) {
+	schema := `{
+		"type": "object",
+		"properties": {
+			"server": {"$ref": "#/definitions/server"},
+			"tree": {"$ref": "#/definitions/node"}
+		},
+		"definitions": {
+			"server": {"type": "object", "properties": {"port": {"type": "integer", "default": 8080}}},
+			"node": {"type": "object", "properties": {"weight": {"type": "integer", "default": 1}, "child": {"$ref": "#/definitions/node"}}}
+		}
+	}`
+	cfg, err := Parse("app", []byte(schema), []byte(`{"tree": {"child": {}}}`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	if port, err := cfg.GetInt("server.port"); err != nil || port != 8080 {
+		t.Errorf("Unexpected server.port: %d, %v", port, err)
+	}
+	if weight, err := cfg.GetInt("tree.child.weight"); err != nil || weight != 1 {
+		t.Errorf("Unexpected tree.child.weight: %d, %v", weight, err)
+	}
+	if _, err := cfg.Get("tree.child.child"); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("Expected ErrPathNotFound, got %v", err)
+	}
+}
+
+// 测试路径和类型错误
+func TestConfigErrors(t *testing.T) {
+	cfg, err := Parse("app", []byte(testSchema), []byte(`{"ratio": 1.5, "servers": [{}]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		get  func() error
+		want error
+	}{
+		{"missing property", func() error { _, err := cfg.GetString("server.missing"); return err }, ErrPathNotFound},
+		{"through scalar", func() error { _, err := cfg.GetString("server.port.value"); return err }, ErrPathNotFound},
+		{"index out of range", func() error { _, err := cfg.GetInt("servers.5.weight"); return err }, ErrPathNotFound},
+		{"non-numeric index", func() error { _, err := cfg.GetInt("servers.first.weight"); return err }, ErrPathNotFound},
+		{"string as int", func() error { _, err := cfg.GetInt("server.host"); return err }, ErrTypeMismatch},
+		{"float as int", func() error { _, err := cfg.GetInt("ratio"); return err }, ErrTypeMismatch},
+		{"int as bool", func() error { _, err := cfg.GetBool("server.port"); return err }, ErrTypeMismatch},
+		{"object as string", func() error { _, err := cfg.GetString("server"); return err }, ErrTypeMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.get(); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// 测试不符合Schema的配置被拒绝
+func TestParseInvalidConfig(t *testing.T) {
+	_, err := Parse("app", []byte(testSchema), []byte(`{"server": {"port": "http"}}`))
+
+	var validationErr *validation.Error
+	if !errors.As(err, &validationErr) {
+		t.Errorf("Expected validation error, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// applyDefaults 用Schema中的default补全缺失的值
+//
+// value为nil表示缺失。缺失的对象即使没有default，只要其属性有default也会被创建。
+// 打包后的Schema把引用的定义放在definitions下，文档内的$ref会被解析
+func applyDefaults(schema, value any) any {
+	d := &defaulter{root: schema, expanding: map[string]bool{"#": true}}
+	return d.apply(schema, value)
+}
+
+// defaulter 保存根Schema和正在展开的$ref
+type defaulter struct {
+	root any
+	// expanding 中的引用不会再为缺失的值展开，避免递归的定义无限创建对象
+	expanding map[string]bool
+}
+
+// apply 补全value中缺失的值
+func (d *defaulter) apply(schema, value any) any {
+	node, ok := schema.(map[string]any)
+	if !ok {
+		return value
+	}
+
+	if value == nil {
+		if def, exists := node["default"]; exists {
+			value = deepCopy(def)
+		}
+	}
+
+	if ref, ok := node["$ref"].(string); ok && !(value == nil && d.expanding[ref]) {
+		if target, ok := resolveLocal(d.root, ref); ok {
+			expanding := d.expanding[ref]
+			d.expanding[ref] = true
+			value = d.apply(target, value)
+			d.expanding[ref] = expanding
+		}
+	}
+
+	if properties, ok := node["properties"].(map[string]any); ok {
+		object, isObject := value.(map[string]any)
+		if value == nil {
+			object = map[string]any{}
+		} else if !isObject {
+			return value
+		}
+
+		for name, propertySchema := range properties {
+			if filled := d.apply(propertySchema, object[name]); filled != nil {
+				object[name] = filled
+			}
+		}
+
+		// 缺失且没有任何默认值的对象仍然视为缺失
+		if value == nil && len(object) == 0 {
+			return nil
+		}
+		return object
+	}
+
+	if items, ok := node["items"].(map[string]any); ok {
+		if array, isArray := value.([]any); isArray {
+			for i, item := range array {
+				array[i] = d.apply(items, item)
+			}
+		}
+	}
+
+	return value
+}
+
+// resolveLocal 按文档内的$ref（例如 #/definitions/port）查找Schema，无法解析时返回false
+func resolveLocal(root any, ref string) (any, bool) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, false
+	}
+	pointer, err := url.PathUnescape(ref[1:])
+	if err != nil {
+		return nil, false
+	}
+	if pointer == "" {
+		return root, true
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, false
+	}
+
+	node := root
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch value := node.(type) {
+		case map[string]any:
+			child, exists := value[token]
+			if !exists {
+				return nil, false
+			}
+			node = child
+		case []any:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(value) {
+				return nil, false
+			}
+			node = value[index]
+		default:
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// deepCopy 复制默认值，避免多个配置共享同一个map或slice
+func deepCopy(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(v))
+		for key, item := range v {
+			copied[key] = deepCopy(item)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(v))
+		for i, item := range v {
+			copied[i] = deepCopy(item)
+		}
+		return copied
+	default:
+		return v
+	}
+}
@@ -0,0 +1,193 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"goci/backend/refs"
+	"goci/backend/storage"
+)
+
+// Source 提供某个Schema ID对应的Schema和配置内容
+type Source interface {
+	Load(schemaID string) (schemaData, configData []byte, err error)
+}
+
+// DirSource 从服务端的数据目录（文件系统存储）读取Schema和配置
+//
+// 只读取文件，不会像storage包那样创建目录或注册表，适合与服务端共享数据目录的应用
+type DirSource struct {
+	dataDir string
+}
+
+// NewDirSource 创建读取dataDir的Source
+func NewDirSource(dataDir string) *DirSource {
+	return &DirSource{dataDir: dataDir}
+}
+
+// Load 读取 schemas/<id>/schema.json 和 configs/<id>/config.json
+func (s *DirSource) Load(schemaID string) ([]byte, []byte, error) {
+	if err := storage.ValidateID(schemaID); err != nil {
+		return nil, nil, err
+	}
+
+	schemaData, err := os.ReadFile(s.SchemaPath(schemaID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("%w: %s", storage.ErrSchemaNotFound, schemaID)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading schema: %w", err)
+	}
+
+	// 引用的其他Schema从同一个数据目录读取并内联
+	schemaData, err = refs.Bundle(s.readSchema, schemaID, schemaData)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	configData, err := os.ReadFile(s.ConfigPath(schemaID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("%w: %s", storage.ErrConfigNotFound, schemaID)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading config: %w", err)
+	}
+
+	return schemaData, configData, nil
+}
+
+// readSchema 读取Schema文件，用于解析引用
+func (s *DirSource) readSchema(schemaID string) ([]byte, error) {
+	data, err := os.ReadFile(s.SchemaPath(schemaID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", storage.ErrSchemaNotFound, schemaID)
+	}
+	return data, err
+}
+
+// SchemaPath 返回Schema文件路径
+func (s *DirSource) SchemaPath(schemaID string) string {
+	return filepath.Join(s.dataDir, "schemas", schemaID, "schema.json")
+}
+
+// ConfigPath 返回配置文件路径
+func (s *DirSource) ConfigPath(schemaID string) string {
+	return filepath.Join(s.dataDir, "configs", schemaID, "config.json")
+}
+
+// StoreSource 从任意storage实现读取，例如bolt存储
+type StoreSource struct {
+	schemas storage.SchemaStore
+	configs storage.ConfigStore
+}
+
+// NewStoreSource 创建基于存储接口的Source
+func NewStoreSource(schemas storage.SchemaStore, configs storage.ConfigStore) *StoreSource {
+	return &StoreSource{schemas: schemas, configs: configs}
+}
+
+// Load 从存储中读取Schema和配置，Schema中的引用会被内联
+func (s *StoreSource) Load(schemaID string) ([]byte, []byte, error) {
+	schemaData, _, err := refs.BundleStored(s.schemas, schemaID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	configData, _, err := s.configs.GetConfig(schemaID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return schemaData, configData, nil
+}
+
+// HTTPSource 通过服务端的 /api 读取Schema和配置
+type HTTPSource struct {
+	baseURL    string
+	httpClient *http.Client
+	header     http.Header
+}
+
+// NewHTTPSource 创建访问baseURL（例如 http://localhost:8080）的Source，httpClient为nil时使用http.DefaultClient
+func NewHTTPSource(baseURL string, httpClient *http.Client) *HTTPSource {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &HTTPSource{baseURL: strings.TrimSuffix(baseURL, "/"), httpClient: httpClient, header: make(http.Header)}
+}
+
+// WithHeader 设置每个请求（包括事件流重连）都会附带的请求头，返回s本身
+func (s *HTTPSource) WithHeader(key, value string) *HTTPSource {
+	s.header.Set(key, value)
+	return s
+}
+
+// WithToken 以 Authorization: Bearer 请求头携带API令牌，返回s本身
+func (s *HTTPSource) WithToken(token string) *HTTPSource {
+	return s.WithHeader("Authorization", "Bearer "+token)
+}
+
+// newRequest 创建附带s.header的GET请求
+func (s *HTTPSource) newRequest(ctx context.Context, path string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range s.header {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	return req, nil
+}
+
+// Load 请求 /api/schemas/:id?bundle=true 和 /api/configs/:id
+func (s *HTTPSource) Load(schemaID string) ([]byte, []byte, error) {
+	var schemaResponse struct {
+		Schema json.RawMessage `json:"schema"`
+	}
+	if err := s.get("/api/schemas/"+url.PathEscape(schemaID)+"?bundle=true", storage.ErrSchemaNotFound, schemaID, &schemaResponse); err != nil {
+		return nil, nil, err
+	}
+
+	var configResponse struct {
+		Config json.RawMessage `json:"config"`
+	}
+	if err := s.get("/api/configs/"+url.PathEscape(schemaID), storage.ErrConfigNotFound, schemaID, &configResponse); err != nil {
+		return nil, nil, err
+	}
+
+	return schemaResponse.Schema, configResponse.Config, nil
+}
+
+// get 请求一个API并解析JSON响应，404时返回notFound错误
+func (s *HTTPSource) get(path string, notFound error, schemaID string, v any) error {
+	req, err := s.newRequest(context.Background(), path)
+	if err != nil {
+		return fmt.Errorf("error requesting %s: %w", path, err)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error requesting %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", notFound, schemaID)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d from %s: %s", resp.StatusCode, path, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("error parsing response from %s: %w", path, err)
+	}
+	return nil
+}
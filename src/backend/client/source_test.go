@@ -0,0 +1,87 @@
+package client
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"goci/backend/api"
+	"goci/backend/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 测试从数据目录读取配置
+func TestDirSource(t *testing.T) {
+	// 创建临时目录
+	tempDir, err := os.MkdirTemp("", "client-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	schemas := storage.NewSchemaStorage(tempDir)
+	configs := storage.NewConfigStorage(tempDir)
+	if err := schemas.SaveSchema("app", "App", "", []byte(testSchema)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+
+	source := NewDirSource(tempDir)
+	if _, err := Load(source, "app"); !errors.Is(err, storage.ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound, got %v", err)
+	}
+	if _, err := Load(source, "missing"); !errors.Is(err, storage.ErrSchemaNotFound) {
+		t.Errorf("Expected ErrSchemaNotFound, got %v", err)
+	}
+
+	if err := configs.SaveConfig("app", []byte(`{"server": {"port": 9090}}`)); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	cfg, err := Load(source, "app")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if port, err := cfg.GetInt("server.port"); err != nil || port != 9090 {
+		t.Errorf("Unexpected server.port: %d, %v", port, err)
+	}
+}
+
+// 测试通过HTTP从服务端读取配置
+func TestHTTPSource(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	schemas := storage.NewMemorySchemaStore()
+	configs := storage.NewMemoryConfigStore()
+	if err := schemas.SaveSchema("app", "App", "", []byte(testSchema)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if err := configs.SaveConfig("app", []byte(`{"name": "remote"}`)); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	r := gin.New()
+	api.RegisterRoutes(r, schemas, configs)
+	api.RegisterConfigRoutes(r, configs, schemas)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	source := NewHTTPSource(server.URL+"/", nil)
+	cfg, err := Load(source, "app")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if name, err := cfg.GetString("name"); err != nil || name != "remote" {
+		t.Errorf("Unexpected name: %s, %v", name, err)
+	}
+	if host, err := cfg.GetString("server.host"); err != nil || host != "localhost" {
+		t.Errorf("Unexpected server.host: %s, %v", host, err)
+	}
+
+	if _, err := Load(source, "missing"); !errors.Is(err, storage.ErrSchemaNotFound) {
+		t.Errorf("Expected ErrSchemaNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,431 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"goci/backend/refs"
+	"goci/backend/storage"
+)
+
+// ChangeFunc 在配置变化后按变化的路径逐一调用，old或new为nil表示该路径不存在
+type ChangeFunc func(path string, old, new any)
+
+// WatchableSource 是能够主动通知内容变化的Source，其他Source通过轮询检测变化
+type WatchableSource interface {
+	Source
+	// Watch 返回一个通道，内容可能发生变化时发送通知，stop关闭后停止
+	Watch(schemaID string, stop <-chan struct{}) (<-chan struct{}, error)
+}
+
+// WatchOptions 是Watch的可选参数
+type WatchOptions struct {
+	// PollInterval 是不支持通知的Source的轮询间隔，默认5秒
+	PollInterval time.Duration
+	// Debounce 是合并连续通知的等待时间，默认100毫秒
+	Debounce time.Duration
+}
+
+// Watcher 持有最新的有效配置，并在配置变化时重新加载
+//
+// 重新加载失败（包括校验失败）时保留上一份有效配置，并调用OnError注册的回调
+type Watcher struct {
+	source   Source
+	schemaID string
+	options  WatchOptions
+	current  atomic.Pointer[Config]
+
+	// reloadMutex 保证重新加载和回调按顺序执行，mutex 保护回调列表
+	reloadMutex sync.Mutex
+	mutex       sync.Mutex
+	onChange    []ChangeFunc
+	onError     []func(error)
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Watch 加载配置并开始监听变化，首次加载失败时返回错误
+func Watch(source Source, schemaID string, options WatchOptions) (*Watcher, error) {
+	if options.PollInterval <= 0 {
+		options.PollInterval = 5 * time.Second
+	}
+	if options.Debounce <= 0 {
+		options.Debounce = 100 * time.Millisecond
+	}
+
+	cfg, err := Load(source, schemaID)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		source:   source,
+		schemaID: schemaID,
+		options:  options,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	w.current.Store(cfg)
+
+	var notify <-chan struct{}
+	if watchable, ok := source.(WatchableSource); ok {
+		notify, err = watchable.Watch(schemaID, w.stop)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	go w.run(notify)
+	return w, nil
+}
+
+// Config 返回当前的配置快照
+func (w *Watcher) Config() *Config {
+	return w.current.Load()
+}
+
+// OnChange 注册配置变化回调
+func (w *Watcher) OnChange(fn ChangeFunc) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.onChange = append(w.onChange, fn)
+}
+
+// OnError 注册重新加载失败的回调
+func (w *Watcher) OnError(fn func(error)) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.onError = append(w.onError, fn)
+}
+
+// Close 停止监听
+func (w *Watcher) Close() error {
+	select {
+	case <-w.stop:
+	default:
+		close(w.stop)
+	}
+	<-w.done
+	return nil
+}
+
+// Reload 立即重新加载配置，失败时保留当前配置并返回错误
+//
+// 回调在Reload中同步执行，回调内不能再调用Reload
+func (w *Watcher) Reload() error {
+	w.reloadMutex.Lock()
+	defer w.reloadMutex.Unlock()
+
+	w.mutex.Lock()
+	onChange := append([]ChangeFunc{}, w.onChange...)
+	onError := append([](func(error)){}, w.onError...)
+	w.mutex.Unlock()
+
+	next, err := Load(w.source, w.schemaID)
+	if err != nil {
+		for _, fn := range onError {
+			fn(err)
+		}
+		return err
+	}
+
+	// 比较补全默认值后的配置，只修改Schema默认值也是一次变化
+	previous := w.current.Load()
+	changes := diff("", previous.data, next.data, nil)
+	if len(changes) == 0 && bytes.Equal(previous.raw, next.raw) {
+		return nil
+	}
+	w.current.Store(next)
+
+	for _, fn := range onChange {
+		for _, change := range changes {
+			fn(change.path, change.old, change.new)
+		}
+	}
+	return nil
+}
+
+// run 根据通知或轮询重新加载配置
+func (w *Watcher) run(notify <-chan struct{}) {
+	defer close(w.done)
+
+	var poll <-chan time.Time
+	if notify == nil {
+		ticker := time.NewTicker(w.options.PollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-poll:
+			w.Reload()
+		case _, ok := <-notify:
+			if !ok {
+				return
+			}
+			// 等待连续的写入结束后再加载
+			if !w.debounce(notify) {
+				return
+			}
+			w.Reload()
+		}
+	}
+}
+
+// debounce 丢弃等待期内的后续通知，Watcher关闭时返回false
+func (w *Watcher) debounce(notify <-chan struct{}) bool {
+	timer := time.NewTimer(w.options.Debounce)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return false
+		case <-notify:
+		case <-timer.C:
+			return true
+		}
+	}
+}
+
+// Watch 通过fsnotify监听Schema、其引用的Schema和配置所在的目录
+//
+// 监听目录而不是文件，这样通过重命名替换的文件也能被发现。
+// schemas和configs根目录也被监听，目录被删除后重新创建时会重新加入监听；
+// 每次变化后重新计算引用，引用的共享定义被修改时同样会通知
+func (s *DirSource) Watch(schemaID string, stop <-chan struct{}) (<-chan struct{}, error) {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, root := range []string{filepath.Join(s.dataDir, "schemas"), filepath.Join(s.dataDir, "configs")} {
+		if err := fsWatcher.Add(root); err != nil {
+			fsWatcher.Close()
+			return nil, err
+		}
+	}
+	watched := s.watchPaths(fsWatcher, schemaID, nil)
+
+	notify := make(chan struct{}, 1)
+	send := func() {
+		select {
+		case notify <- struct{}{}:
+		default:
+		}
+	}
+	go func() {
+		defer fsWatcher.Close()
+		for {
+			select {
+			case <-stop:
+				return
+			case event, ok := <-fsWatcher.Events:
+				if !ok {
+					return
+				}
+				if !watched[filepath.Clean(event.Name)] {
+					continue
+				}
+				watched = s.watchPaths(fsWatcher, schemaID, watched)
+				send()
+			case _, ok := <-fsWatcher.Errors:
+				if !ok {
+					return
+				}
+				// 事件可能丢失，重新加入监听并重新加载
+				watched = s.watchPaths(fsWatcher, schemaID, watched)
+				send()
+			}
+		}
+	}()
+
+	return notify, nil
+}
+
+// watchPaths 监听schemaID的配置目录及其Schema和所有引用的Schema的目录，返回需要关注的文件和目录
+//
+// 尚不存在的目录无法监听，它们被创建时根目录的事件会触发下一次watchPaths
+func (s *DirSource) watchPaths(fsWatcher *fsnotify.Watcher, schemaID string, previous map[string]bool) map[string]bool {
+	watched := make(map[string]bool)
+	add := func(path string) {
+		dir := filepath.Clean(filepath.Dir(path))
+		watched[filepath.Clean(path)] = true
+		watched[dir] = true
+		// 目录被删除后监听会失效，重复添加是安全的
+		fsWatcher.Add(dir)
+	}
+
+	add(s.ConfigPath(schemaID))
+	for _, id := range s.dependencies(schemaID) {
+		add(s.SchemaPath(id))
+	}
+
+	// 不再引用的Schema不再监听
+	for path := range previous {
+		if !watched[path] {
+			fsWatcher.Remove(path)
+		}
+	}
+	return watched
+}
+
+// dependencies 返回schemaID及其直接或间接引用的Schema ID，无法读取或解析的Schema不再继续展开
+func (s *DirSource) dependencies(schemaID string) []string {
+	ids := []string{schemaID}
+	seen := map[string]bool{schemaID: true}
+	for i := 0; i < len(ids); i++ {
+		data, err := s.readSchema(ids[i])
+		if err != nil {
+			continue
+		}
+		references, err := refs.References(data)
+		if err != nil {
+			continue
+		}
+		for _, id := range references {
+			if !seen[id] && storage.ValidateID(id) == nil {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// change 是一个路径上的变化
+type change struct {
+	path     string
+	old, new any
+}
+
+// diff 比较两份配置，返回变化的叶子路径
+func diff(path string, old, new any, changes []change) []change {
+	oldMap, oldIsMap := old.(map[string]any)
+	newMap, newIsMap := new.(map[string]any)
+	if oldIsMap && newIsMap {
+		for key, value := range oldMap {
+			changes = diff(join(path, key), value, newMap[key], changes)
+		}
+		for key, value := range newMap {
+			if _, exists := oldMap[key]; !exists {
+				changes = diff(join(path, key), nil, value, changes)
+			}
+		}
+		return changes
+	}
+
+	oldArray, oldIsArray := old.([]any)
+	newArray, newIsArray := new.([]any)
+	if oldIsArray && newIsArray {
+		for i := 0; i < len(oldArray) || i < len(newArray); i++ {
+			var oldItem, newItem any
+			if i < len(oldArray) {
+				oldItem = oldArray[i]
+			}
+			if i < len(newArray) {
+				newItem = newArray[i]
+			}
+			changes = diff(join(path, strconv.Itoa(i)), oldItem, newItem, changes)
+		}
+		return changes
+	}
+
+	// 标量，或者类型发生了变化
+	if oldIsMap || newIsMap || oldIsArray || newIsArray || old != new {
+		changes = append(changes, change{path: path, old: old, new: new})
+	}
+	return changes
+}
+
+// join 拼接点分隔路径
+func join(path, segment string) string {
+	if path == "" {
+		return segment
+	}
+	return path + "." + segment
+}
+
+// Watch 订阅服务端的 /api/events，收到该Schema的事件时发送通知
+//
+// 连接断开后会自动重连，重连时通过Last-Event-ID让服务端补发错过的事件，
+// 每次连上也都会通知一次，以免遗漏已不在服务端缓冲区中的变更。
+// 事件流是长连接，httpClient不应设置Timeout。
+func (s *HTTPSource) Watch(schemaID string, stop <-chan struct{}) (<-chan struct{}, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	notify := make(chan struct{}, 1)
+	send := func() {
+		select {
+		case notify <- struct{}{}:
+		default:
+		}
+	}
+
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	go func() {
+		lastEventID := ""
+		for {
+			lastEventID = s.streamEvents(ctx, schemaID, lastEventID, send)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+
+	return notify, nil
+}
+
+// streamEvents 从lastEventID之后读取事件流直到连接断开，返回最后收到的事件ID
+func (s *HTTPSource) streamEvents(ctx context.Context, schemaID, lastEventID string, send func()) string {
+	req, err := s.newRequest(ctx, "/api/events?schemaId="+url.QueryEscape(schemaID))
+	if err != nil {
+		return lastEventID
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return lastEventID
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return lastEventID
+	}
+	send()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "id:"):
+			lastEventID = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+		case strings.HasPrefix(line, "event:"):
+			send()
+		}
+	}
+	return lastEventID
+}
@@ -0,0 +1,354 @@
+package client
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"goci/backend/api"
+	"goci/backend/auth"
+	"goci/backend/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 等待通道中的值，超时则测试失败
+func waitFor[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for watcher")
+	}
+	var zero T
+	return zero
+}
+
+// 测试通过fsnotify发现数据目录中的变化
+func TestWatchDirSource(t *testing.T) {
+	// 创建临时目录
+	tempDir, err := os.MkdirTemp("", "client-watch-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	schemas := storage.NewSchemaStorage(tempDir)
+	configs := storage.NewConfigStorage(tempDir)
+	if err := schemas.SaveSchema("app", "App", "", []byte(testSchema)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if err := configs.SaveConfig("app", []byte(`{"server": {"port": 9090}}`)); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	source := NewDirSource(tempDir)
+	w, err := Watch(source, "app", WatchOptions{Debounce: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer w.Close()
+
+	changes := make(chan change, 10)
+	w.OnChange(func(path string, old, new any) {
+		changes <- change{path: path, old: old, new: new}
+	})
+	errs := make(chan error, 10)
+	w.OnError(func(err error) {
+		errs <- err
+	})
+
+	// 修改配置
+	if err := configs.SaveConfig("app", []byte(`{"server": {"port": 9091}}`)); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	got := waitFor(t, changes)
+	if got.path != "server.port" || fmt.Sprint(got.old) != "9090" || fmt.Sprint(got.new) != "9091" {
+		t.Errorf("Unexpected change: %+v", got)
+	}
+
+	if port, _ := w.Config().GetInt("server.port"); port != 9091 {
+		t.Errorf("Config was not swapped: %d", port)
+	}
+
+	// 不符合Schema的配置不会替换上一份有效配置
+	if err := os.WriteFile(source.ConfigPath("app"), []byte(`{"server": {"port": "http"}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	waitFor(t, errs)
+	if port, _ := w.Config().GetInt("server.port"); port != 9091 {
+		t.Errorf("Invalid config replaced the last good one: %d", port)
+	}
+}
+
+// 测试配置目录被删除后重新创建以及引用的Schema被修改时仍能发现变化
+func TestWatchDirSourceRecreatedAndReferenced(t *testing.T) {
+	// 创建临时目录
+	tempDir, err := os.MkdirTemp("", "client-watch-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	schemas := storage.NewSchemaStorage(tempDir)
+	configs := storage.NewConfigStorage(tempDir)
+	if err := schemas.SaveSchema("port", "Port", "", []byte(`{"type": "integer", "default": 80}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if err := schemas.SaveSchema("app", "App", "", []byte(`{"type": "object", "properties": {"port": {"$ref": "goci://defs/port"}}}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if err := configs.SaveConfig("app", []byte(`{"name": "first"}`)); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	w, err := Watch(NewDirSource(tempDir), "app", WatchOptions{PollInterval: time.Hour, Debounce: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer w.Close()
+
+	changes := make(chan change, 10)
+	w.OnChange(func(path string, old, new any) {
+		changes <- change{path: path, old: old, new: new}
+	})
+
+	// 删除配置目录后重新创建
+	if err := configs.DeleteConfig("app"); err != nil {
+		t.Fatalf("Failed to delete config: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := configs.SaveConfig("app", []byte(`{"name": "second"}`)); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	for got := waitFor(t, changes); got.path != "name" || got.new != "second"; got = waitFor(t, changes) {
+	}
+
+	// 再次修改重新创建的配置
+	if err := configs.SaveConfig("app", []byte(`{"name": "third"}`)); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	for got := waitFor(t, changes); got.path != "name" || got.new != "third"; got = waitFor(t, changes) {
+	}
+
+	// 修改引用的共享定义中的默认值
+	if err := schemas.SaveSchema("port", "Port", "", []byte(`{"type": "integer", "default": 9090}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	for got := waitFor(t, changes); got.path != "port" || fmt.Sprint(got.new) != "9090"; got = waitFor(t, changes) {
+	}
+}
+
+// 测试不支持通知的Source通过轮询重新加载
+func TestWatchPolling(t *testing.T) {
+	schemas := storage.NewMemorySchemaStore()
+	configs := storage.NewMemoryConfigStore()
+	if err := schemas.SaveSchema("app", "App", "", []byte(testSchema)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if err := configs.SaveConfig("app", []byte(`{"name": "first"}`)); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	w, err := Watch(NewStoreSource(schemas, configs), "app", WatchOptions{PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer w.Close()
+
+	changes := make(chan change, 10)
+	w.OnChange(func(path string, old, new any) {
+		changes <- change{path: path, old: old, new: new}
+	})
+
+	if err := configs.SaveConfig("app", []byte(`{"name": "second"}`)); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	got := waitFor(t, changes)
+	if got.path != "name" || got.old != "first" || got.new != "second" {
+		t.Errorf("Unexpected change: %+v", got)
+	}
+}
+
+// 测试只修改Schema默认值时重新加载
+func TestReloadSchemaDefault(t *testing.T) {
+	schemas := storage.NewMemorySchemaStore()
+	configs := storage.NewMemoryConfigStore()
+	if err := schemas.SaveSchema("app", "App", "", []byte(`{"type": "object", "properties": {"port": {"type": "integer", "default": 80}}}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if err := configs.SaveConfig("app", []byte(`{}`)); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	// 轮询间隔足够长，只通过Reload重新加载
+	w, err := Watch(NewStoreSource(schemas, configs), "app", WatchOptions{PollInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer w.Close()
+
+	var changes []change
+	w.OnChange(func(path string, old, new any) {
+		changes = append(changes, change{path: path, old: old, new: new})
+	})
+
+	if err := schemas.SaveSchema("app", "App", "", []byte(`{"type": "object", "properties": {"port": {"type": "integer", "default": 9090}}}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+
+	if port, _ := w.Config().GetInt("port"); port != 9090 {
+		t.Errorf("Schema default change was not applied: %d", port)
+	}
+	if len(changes) != 1 || changes[0].path != "port" || fmt.Sprint(changes[0].old) != "80" || fmt.Sprint(changes[0].new) != "9090" {
+		t.Errorf("Unexpected changes: %+v", changes)
+	}
+}
+
+// 测试配置差异只包含变化的叶子路径
+func TestDiff(t *testing.T) {
+	old, _ := decode([]byte(`{"a": 1, "b": {"c": true, "d": "x"}, "e": [1, 2], "f": {"g": 1}}`))
+	new, _ := decode([]byte(`{"a": 1, "b": {"c": false, "d": "x"}, "e": [1], "f": "flat", "h": 0}`))
+
+	var paths []string
+	for _, c := range diff("", old, new, nil) {
+		paths = append(paths, c.path)
+	}
+	sort.Strings(paths)
+
+	want := []string{"b.c", "e.1", "f", "h"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Unexpected changed paths: got %v, want %v", paths, want)
+	}
+}
+
+// 测试通过服务端事件流发现变化
+func TestWatchHTTPSource(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	bus := storage.NewEventBus(100)
+	schemas := storage.WithSchemaEvents(storage.NewMemorySchemaStore(), bus)
+	configs := storage.WithConfigEvents(storage.NewMemoryConfigStore(), bus)
+	if err := schemas.SaveSchema("app", "App", "", []byte(testSchema)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if err := configs.SaveConfig("app", []byte(`{"name": "first"}`)); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	r := gin.New()
+	api.RegisterRoutes(r, schemas, configs)
+	api.RegisterConfigRoutes(r, configs, schemas)
+	api.RegisterEventRoutes(r, bus)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	// 轮询间隔足够长，变化只能来自事件流
+	w, err := Watch(NewHTTPSource(server.URL, nil), "app", WatchOptions{PollInterval: time.Hour, Debounce: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+
+	changes := make(chan change, 10)
+	w.OnChange(func(path string, old, new any) {
+		changes <- change{path: path, old: old, new: new}
+	})
+
+	// 等待事件流连接建立
+	deadline := time.Now().Add(5 * time.Second)
+	for bus.SubscriberCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := configs.SaveConfig("app", []byte(`{"name": "second"}`)); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	got := waitFor(t, changes)
+	if got.path != "name" || got.new != "second" {
+		t.Errorf("Unexpected change: %+v", got)
+	}
+
+	w.Close()
+}
+
+// 测试事件流携带认证请求头，并在重连时发送Last-Event-ID
+func TestWatchHTTPSourceAuthAndResume(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	bus := storage.NewEventBus(100)
+	schemas := storage.WithSchemaEvents(storage.NewMemorySchemaStore(), bus)
+	configs := storage.WithConfigEvents(storage.NewMemoryConfigStore(), bus)
+	if err := schemas.SaveSchema("app", "App", "", []byte(testSchema)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if err := configs.SaveConfig("app", []byte(`{"name": "first"}`)); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	tokens, err := auth.NewTokenAuthenticator([]auth.Token{{Name: "ci", Token: "secret", Grants: []auth.Grant{{Role: auth.RoleViewer, Schema: "*"}}}})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	lastEventIDs := make(chan string, 10)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if c.Request.URL.Path == "/api/events" {
+			lastEventIDs <- c.GetHeader("Last-Event-ID")
+		}
+	})
+	r.Use(auth.Middleware([]auth.Authenticator{tokens}, nil))
+	api.RegisterRoutes(r, schemas, configs)
+	api.RegisterConfigRoutes(r, configs, schemas)
+	api.RegisterEventRoutes(r, bus)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	// 没有令牌时无法读取
+	if _, err := Load(NewHTTPSource(server.URL, nil), "app"); err == nil {
+		t.Fatalf("Expected unauthenticated load to fail")
+	}
+
+	w, err := Watch(NewHTTPSource(server.URL, nil).WithToken("secret"), "app", WatchOptions{PollInterval: time.Hour, Debounce: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to watch config: %v", err)
+	}
+	defer w.Close()
+
+	changes := make(chan change, 10)
+	w.OnChange(func(path string, old, new any) {
+		changes <- change{path: path, old: old, new: new}
+	})
+
+	if id := waitFor(t, lastEventIDs); id != "" {
+		t.Errorf("Unexpected Last-Event-ID on first connect: %q", id)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for bus.SubscriberCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := configs.SaveConfig("app", []byte(`{"name": "second"}`)); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	if got := waitFor(t, changes); got.path != "name" || got.new != "second" {
+		t.Errorf("Unexpected change: %+v", got)
+	}
+
+	// 服务端断开连接后客户端带着最后的事件ID重连
+	server.CloseClientConnections()
+	if id := waitFor(t, lastEventIDs); id == "" {
+		t.Errorf("Expected Last-Event-ID on reconnect")
+	}
+}
@@ -0,0 +1,683 @@
+// Package codegen 根据JSON Schema生成访问配置的代码
+package codegen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"goci/backend/internal/ordered"
+)
+
+// GoOptions 是生成Go代码的参数
+type GoOptions struct {
+	// Package 是生成文件的包名
+	Package string
+	// TypeName 是根类型名，为空时使用Schema的title，再为空时使用Config
+	TypeName string
+	// Source 写入文件头注释，用于说明代码来源
+	Source string
+}
+
+// GenerateGo 根据Schema生成Go源文件
+//
+// 生成内容包括：对象对应的结构体及json标签、description生成的文档注释、
+// enum生成的具名类型和常量，以及返回Schema默认值的Default函数。
+// $ref只支持文档内的引用，引用的定义生成为具名类型，递归引用的字段使用指针
+func GenerateGo(schemaData []byte, options GoOptions) ([]byte, error) {
+	if !token.IsIdentifier(options.Package) || token.IsKeyword(options.Package) {
+		return nil, fmt.Errorf("invalid package name: %q", options.Package)
+	}
+
+	schema, err := ordered.Decode(schemaData)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing schema: %w", err)
+	}
+	root, ok := schema.(*ordered.Object)
+	if !ok {
+		return nil, fmt.Errorf("schema must be a JSON object")
+	}
+
+	typeName := options.TypeName
+	if typeName == "" {
+		if title, ok := stringKeyword(root, "title"); ok {
+			typeName = title
+		} else {
+			typeName = "Config"
+		}
+	}
+
+	g := &goGenerator{
+		root:       root,
+		names:      nameSet{"Default": true, "ptr": true},
+		types:      make(map[*ordered.Object]string),
+		enums:      make(map[*ordered.Object]*goEnum),
+		generating: make(map[*ordered.Object]bool),
+		resolving:  make(map[*ordered.Object]bool),
+		recursive:  make(map[*ordered.Object]bool),
+	}
+	rootType := g.goType(root, exportedName(typeName))
+	if g.err != nil {
+		return nil, g.err
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by goci codegen")
+	if options.Source != "" {
+		out.WriteString(" from " + options.Source)
+	}
+	out.WriteString(". DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n", options.Package)
+	for _, decl := range g.decls {
+		out.WriteString(decl)
+	}
+
+	// Default 函数
+	expr, ok := g.literal(root, rootType, nil)
+	if g.err != nil {
+		return nil, g.err
+	}
+	if !ok {
+		expr = g.zero(root, rootType)
+	}
+	fmt.Fprintf(&out, "\n// Default returns the defaults declared in the schema.\nfunc Default() %s {\n\treturn %s\n}\n", rootType, expr)
+
+	if g.needPtr {
+		out.WriteString("\n// ptr returns a pointer to v.\nfunc ptr[T any](v T) *T {\n\treturn &v\n}\n")
+	}
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("error formatting generated code: %w", err)
+	}
+	return formatted, nil
+}
+
+// goEnum 是enum生成的具名类型
+type goEnum struct {
+	name   string
+	base   string
+	consts map[string]string
+}
+
+// goGenerator 保存生成过程中的类型声明
+type goGenerator struct {
+	root    *ordered.Object
+	decls   []string
+	names   nameSet
+	types   map[*ordered.Object]string
+	enums   map[*ordered.Object]*goEnum
+	needPtr bool
+
+	// generating 是正在生成字段的结构体，resolving 是正在解析的$ref，
+	// recursive 是引用了正在生成的结构体、因此使用指针的$ref
+	generating map[*ordered.Object]bool
+	resolving  map[*ordered.Object]bool
+	recursive  map[*ordered.Object]bool
+
+	// err 是生成过程中的第一个错误
+	err error
+}
+
+// goType 返回Schema对应的Go类型，需要时生成具名类型声明
+func (g *goGenerator) goType(schema any, name string) string {
+	node, ok := schema.(*ordered.Object)
+	if !ok {
+		return "any"
+	}
+	if t, exists := g.types[node]; exists {
+		return t
+	}
+	if ref, ok := node.Get("$ref"); ok {
+		return g.refType(node, ref)
+	}
+
+	types, nullable := schemaTypes(node)
+	var t string
+
+	if enum, ok := g.enum(node, types, name); ok {
+		t = enum
+	} else if len(types) != 1 {
+		t = "any"
+	} else {
+		switch types[0] {
+		case "string":
+			t = "string"
+		case "integer":
+			t = "int64"
+		case "number":
+			t = "float64"
+		case "boolean":
+			t = "bool"
+		case "array":
+			items, _ := node.Get("items")
+			t = "[]" + g.elemType(items, name+"Item")
+		case "object":
+			t = g.object(node, name)
+		default:
+			t = "any"
+		}
+	}
+
+	// 允许null的标量使用指针
+	if nullable && t != "any" && !strings.HasPrefix(t, "[]") && !strings.HasPrefix(t, "map[") {
+		t = "*" + t
+	}
+
+	g.types[node] = t
+	return t
+}
+
+// refType 返回$ref指向的Schema的类型，引用正在生成的结构体时使用指针
+func (g *goGenerator) refType(node *ordered.Object, ref any) string {
+	if g.resolving[node] {
+		g.fail(fmt.Errorf("circular $ref: %v", ref))
+		return "any"
+	}
+	target, name, err := g.resolve(ref)
+	if err != nil {
+		g.fail(err)
+		return "any"
+	}
+
+	g.resolving[node] = true
+	defer delete(g.resolving, node)
+
+	t := g.goType(target, name)
+	if targetNode, ok := target.(*ordered.Object); ok && g.generating[targetNode] {
+		t = "*" + t
+		g.recursive[node] = true
+	}
+	g.types[node] = t
+	return t
+}
+
+// elemType 返回数组元素或map值的类型，切片和map本身就是间接引用，递归引用时不需要指针
+func (g *goGenerator) elemType(schema any, name string) string {
+	t := g.goType(schema, name)
+	if node, ok := schema.(*ordered.Object); ok && g.recursive[node] {
+		return strings.TrimPrefix(t, "*")
+	}
+	return t
+}
+
+// resolve 解析文档内的$ref，例如 #/definitions/server，返回引用的Schema和生成类型时使用的名称
+func (g *goGenerator) resolve(ref any) (any, string, error) {
+	s, ok := ref.(string)
+	if !ok || !strings.HasPrefix(s, "#") {
+		return nil, "", fmt.Errorf("unsupported $ref: %v", ref)
+	}
+	fragment, err := url.PathUnescape(s[1:])
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid $ref %s: %w", s, err)
+	}
+	if fragment == "" {
+		return g.root, "", nil
+	}
+	if !strings.HasPrefix(fragment, "/") {
+		return nil, "", fmt.Errorf("unsupported $ref: %s", s)
+	}
+
+	var current any = g.root
+	var name string
+	for _, token := range strings.Split(fragment[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		name = token
+		switch value := current.(type) {
+		case *ordered.Object:
+			child, exists := value.Get(token)
+			if !exists {
+				return nil, "", fmt.Errorf("unresolved $ref: %s", s)
+			}
+			current = child
+		case []any:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(value) {
+				return nil, "", fmt.Errorf("unresolved $ref: %s", s)
+			}
+			current = value[index]
+		default:
+			return nil, "", fmt.Errorf("unresolved $ref: %s", s)
+		}
+	}
+	return current, exportedName(name), nil
+}
+
+// deref 返回$ref最终指向的Schema，无法解析时返回schema本身
+func (g *goGenerator) deref(schema any) any {
+	for range 32 {
+		node, ok := schema.(*ordered.Object)
+		if !ok {
+			return schema
+		}
+		ref, ok := node.Get("$ref")
+		if !ok {
+			return schema
+		}
+		target, _, err := g.resolve(ref)
+		if err != nil {
+			return schema
+		}
+		schema = target
+	}
+	return schema
+}
+
+// fail 记录第一个错误
+func (g *goGenerator) fail(err error) {
+	if g.err == nil {
+		g.err = err
+	}
+}
+
+// object 为带properties的对象生成结构体，其他对象使用map
+func (g *goGenerator) object(node *ordered.Object, name string) string {
+	properties, ok := propertiesOf(node)
+	if !ok {
+		additional, _ := node.Get("additionalProperties")
+		if _, isSchema := additional.(*ordered.Object); isSchema {
+			return "map[string]" + g.elemType(additional, name+"Value")
+		}
+		return "map[string]any"
+	}
+
+	typeName := g.names.unique(name)
+	// 先登记并占位，避免递归时重复生成，并让外层类型排在内层类型之前
+	g.types[node] = typeName
+	slot := len(g.decls)
+	g.decls = append(g.decls, "")
+	g.generating[node] = true
+	defer delete(g.generating, node)
+
+	required := map[string]bool{}
+	if list, ok := node.Get("required"); ok {
+		if array, ok := list.([]any); ok {
+			for _, item := range array {
+				if s, ok := item.(string); ok {
+					required[s] = true
+				}
+			}
+		}
+	}
+
+	var fields bytes.Buffer
+	fieldNames := nameSet{}
+	for _, key := range properties.Keys() {
+		property := properties.Value(key)
+		fieldName := fieldNames.unique(exportedName(key))
+		fieldType := g.goType(property, typeName+fieldName)
+
+		if propertyNode, ok := property.(*ordered.Object); ok {
+			writeComment(&fields, "\t", fieldName, description(propertyNode))
+		}
+
+		// 默认值非零的字段不能省略零值，否则显式的false、0或""序列化后会被默认值取代
+		tag := key
+		if !required[key] && !g.hasNonZeroDefault(property) {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&fields, "\t%s %s `json:%s`\n", fieldName, fieldType, strconv.Quote(tag))
+	}
+
+	var decl bytes.Buffer
+	decl.WriteString("\n")
+	writeComment(&decl, "", typeName, description(node))
+	fmt.Fprintf(&decl, "type %s struct {\n%s}\n", typeName, fields.String())
+	g.decls[slot] = decl.String()
+
+	return typeName
+}
+
+// hasNonZeroDefault 判断Schema的default是否不是JSON中的零值（false、0、""、空数组、空对象或null）
+func (g *goGenerator) hasNonZeroDefault(schema any) bool {
+	node, _ := g.deref(schema).(*ordered.Object)
+	if node == nil {
+		return false
+	}
+	value, ok := node.Get("default")
+	if !ok {
+		return false
+	}
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case json.Number:
+		f, err := v.Float64()
+		return err != nil || f != 0
+	case []any:
+		return len(v) > 0
+	case *ordered.Object:
+		return v.Len() > 0
+	}
+	return true
+}
+
+// enum 为全是字符串或全是整数的enum生成具名类型和常量
+func (g *goGenerator) enum(node *ordered.Object, types []string, name string) (string, bool) {
+	values, ok := node.Get("enum")
+	array, isArray := values.([]any)
+	if !ok || !isArray || len(array) == 0 {
+		return "", false
+	}
+
+	base := ""
+	for _, value := range array {
+		var kind string
+		switch v := value.(type) {
+		case string:
+			kind = "string"
+		case json.Number:
+			if _, err := v.Int64(); err != nil {
+				return "", false
+			}
+			kind = "int64"
+		default:
+			return "", false
+		}
+		if base != "" && base != kind {
+			return "", false
+		}
+		base = kind
+	}
+
+	// enum与type矛盾时不生成具名类型
+	if len(types) == 1 && !(types[0] == "string" && base == "string" || (types[0] == "integer" || types[0] == "number") && base == "int64") {
+		return "", false
+	}
+
+	typeName := g.names.unique(name)
+	enum := &goEnum{name: typeName, base: base, consts: make(map[string]string)}
+
+	var decl bytes.Buffer
+	decl.WriteString("\n")
+	writeComment(&decl, "", typeName, description(node))
+	fmt.Fprintf(&decl, "type %s %s\n\n", typeName, base)
+	fmt.Fprintf(&decl, "// Allowed %s values.\nconst (\n", typeName)
+	for _, value := range array {
+		literal := fmt.Sprint(value)
+		if base == "string" {
+			literal = strconv.Quote(value.(string))
+		}
+		constName := g.names.unique(typeName + identifierPart(fmt.Sprint(value)))
+		enum.consts[fmt.Sprint(value)] = constName
+		fmt.Fprintf(&decl, "\t%s %s = %s\n", constName, typeName, literal)
+	}
+	decl.WriteString(")\n")
+	g.decls = append(g.decls, decl.String())
+
+	g.enums[node] = enum
+	return typeName, true
+}
+
+// literal 生成值为value（为nil时使用Schema的default）的Go表达式，没有可设置的值时返回false
+func (g *goGenerator) literal(schema any, goType string, value any) (string, bool) {
+	schema = g.deref(schema)
+	node, _ := schema.(*ordered.Object)
+	if value == nil && node != nil {
+		value, _ = node.Get("default")
+	}
+
+	if strings.HasPrefix(goType, "*") {
+		if value == nil {
+			return "", false
+		}
+		expr, ok := g.literal(schema, goType[1:], value)
+		if !ok {
+			return "", false
+		}
+		g.needPtr = true
+		return fmt.Sprintf("ptr[%s](%s)", goType[1:], expr), true
+	}
+
+	// 结构体：逐个字段生成，字段默认值和对象默认值合并
+	if properties, ok := propertiesOf(node); ok && !strings.HasPrefix(goType, "map[") && goType != "any" {
+		values, _ := value.(*ordered.Object)
+		var fields []string
+		for _, key := range properties.Keys() {
+			var fieldValue any
+			if values != nil {
+				fieldValue, _ = values.Get(key)
+			}
+			fieldType := g.types[asObject(properties.Value(key))]
+			if fieldType == "" {
+				fieldType = "any"
+			}
+			if expr, ok := g.literal(properties.Value(key), fieldType, fieldValue); ok {
+				fields = append(fields, fmt.Sprintf("%s: %s,", fieldNameFor(properties, key), expr))
+			}
+		}
+		if len(fields) == 0 {
+			return "", false
+		}
+		return fmt.Sprintf("%s{\n%s\n}", goType, strings.Join(fields, "\n")), true
+	}
+
+	if value == nil {
+		return "", false
+	}
+
+	if enum, ok := g.enums[node]; ok {
+		if constName, ok := enum.consts[fmt.Sprint(value)]; ok {
+			return constName, true
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(goType, "[]"):
+		array, ok := value.([]any)
+		if !ok {
+			return "", false
+		}
+		var items any
+		if node != nil {
+			items, _ = node.Get("items")
+		}
+		var elems []string
+		for _, item := range array {
+			expr, ok := g.literal(items, goType[2:], item)
+			if !ok {
+				expr = g.zero(items, goType[2:])
+			}
+			elems = append(elems, expr+",")
+		}
+		return fmt.Sprintf("%s{%s}", goType, strings.Join(elems, " ")), true
+	case strings.HasPrefix(goType, "map[string]"):
+		values, ok := value.(*ordered.Object)
+		if !ok {
+			return "", false
+		}
+		var additional any
+		if node != nil {
+			additional, _ = node.Get("additionalProperties")
+		}
+		elemType := strings.TrimPrefix(goType, "map[string]")
+		var elems []string
+		for _, key := range values.Keys() {
+			expr, ok := g.literal(additional, elemType, values.Value(key))
+			if !ok {
+				expr = g.zero(additional, elemType)
+			}
+			elems = append(elems, fmt.Sprintf("%s: %s,", strconv.Quote(key), expr))
+		}
+		return fmt.Sprintf("%s{%s}", goType, strings.Join(elems, " ")), true
+	case goType == "any":
+		return anyLiteral(value), true
+	}
+
+	switch v := value.(type) {
+	case string:
+		return strconv.Quote(v), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case json.Number:
+		switch goType {
+		case "int64":
+			// 1.0、1e3这样的整数也接受，小数和超出int64范围的值报错，而不是静默丢弃
+			n, ok := new(big.Rat).SetString(v.String())
+			if !ok || !n.IsInt() || !n.Num().IsInt64() {
+				g.fail(fmt.Errorf("default %s is not a valid int64", v))
+				return "", false
+			}
+			return n.Num().String(), true
+		case "float64":
+			if _, err := v.Float64(); err != nil {
+				g.fail(fmt.Errorf("default %s is not a valid float64", v))
+				return "", false
+			}
+		}
+		return v.String(), true
+	}
+	return "", false
+}
+
+// zero 返回类型的零值表达式
+func (g *goGenerator) zero(schema any, goType string) string {
+	schema = g.deref(schema)
+	switch {
+	case goType == "any" || strings.HasPrefix(goType, "*") || strings.HasPrefix(goType, "[]") || strings.HasPrefix(goType, "map["):
+		return "nil"
+	case goType == "string":
+		return `""`
+	case goType == "bool":
+		return "false"
+	case goType == "int64" || goType == "float64":
+		return "0"
+	}
+	if enum, ok := g.enums[asObject(schema)]; ok {
+		if enum.base == "string" {
+			return enum.name + `("")`
+		}
+		return enum.name + "(0)"
+	}
+	return goType + "{}"
+}
+
+// anyLiteral 生成任意JSON值的Go表达式
+func anyLiteral(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return "float64(" + v.String() + ")"
+	case []any:
+		var elems []string
+		for _, item := range v {
+			elems = append(elems, anyLiteral(item)+",")
+		}
+		return "[]any{" + strings.Join(elems, " ") + "}"
+	case *ordered.Object:
+		var elems []string
+		for _, key := range v.Keys() {
+			elems = append(elems, strconv.Quote(key)+": "+anyLiteral(v.Value(key))+",")
+		}
+		return "map[string]any{" + strings.Join(elems, " ") + "}"
+	}
+	return "nil"
+}
+
+// fieldNameFor 返回属性在结构体中的字段名，与object中的分配方式一致
+func fieldNameFor(properties *ordered.Object, key string) string {
+	names := nameSet{}
+	for _, k := range properties.Keys() {
+		name := names.unique(exportedName(k))
+		if k == key {
+			return name
+		}
+	}
+	return exportedName(key)
+}
+
+// schemaTypes 返回type关键字中除null以外的类型，以及是否允许null
+func schemaTypes(node *ordered.Object) ([]string, bool) {
+	value, ok := node.Get("type")
+	if !ok {
+		if _, hasProperties := propertiesOf(node); hasProperties {
+			return []string{"object"}, false
+		}
+		return nil, false
+	}
+
+	var types []string
+	nullable := false
+	add := func(t any) {
+		if s, ok := t.(string); ok {
+			if s == "null" {
+				nullable = true
+			} else {
+				types = append(types, s)
+			}
+		}
+	}
+
+	if array, ok := value.([]any); ok {
+		for _, t := range array {
+			add(t)
+		}
+	} else {
+		add(value)
+	}
+	return types, nullable
+}
+
+// propertiesOf 返回Schema的properties
+func propertiesOf(node *ordered.Object) (*ordered.Object, bool) {
+	if node == nil {
+		return nil, false
+	}
+	value, ok := node.Get("properties")
+	if !ok {
+		return nil, false
+	}
+	properties, ok := value.(*ordered.Object)
+	return properties, ok
+}
+
+// stringKeyword 返回字符串类型的关键字
+func stringKeyword(node *ordered.Object, keyword string) (string, bool) {
+	value, ok := node.Get(keyword)
+	if !ok {
+		return "", false
+	}
+	s, ok := value.(string)
+	return s, ok && s != ""
+}
+
+// description 返回用于文档注释的说明
+func description(node *ordered.Object) string {
+	s, _ := stringKeyword(node, "description")
+	return s
+}
+
+// asObject 把Schema转换为*ordered.Object，不是对象时返回nil
+func asObject(schema any) *ordered.Object {
+	node, _ := schema.(*ordered.Object)
+	return node
+}
+
+// writeComment 写入以标识符开头的文档注释
+func writeComment(b *bytes.Buffer, indent, name, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	for i, line := range strings.Split(text, "\n") {
+		if i == 0 {
+			line = name + " " + line
+		}
+		fmt.Fprintf(b, "%s// %s\n", indent, strings.TrimRight(line, " \t"))
+	}
+}
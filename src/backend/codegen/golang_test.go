@@ -0,0 +1,218 @@
+package codegen
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+// 测试用的Schema，覆盖嵌套对象、enum、可空字段、数组、map和默认值
+const testSchema = `{
+	"title": "app config",
+	"description": "Settings for the demo app.",
+	"type": "object",
+	"required": ["server"],
+	"properties": {
+		"server": {
+			"type": "object",
+			"description": "HTTP server settings.",
+			"properties": {
+				"host": {"type": "string", "default": "localhost"},
+				"port": {"type": "integer", "default": 8080, "description": "Port to listen on."},
+				"tls_cert": {"type": ["string", "null"], "default": "cert.pem"}
+			}
+		},
+		"mode": {"type": "string", "enum": ["debug", "release"], "default": "release"},
+		"level": {"enum": [1, 2, 3]},
+		"ratio": {"type": "number", "default": 0.5},
+		"tags": {"type": "array", "items": {"type": "string"}, "default": ["a", "b"]},
+		"labels": {"type": "object", "additionalProperties": {"type": "string"}, "default": {"team": "core"}},
+		"extra": {"default": {"x": [1, true]}},
+		"backends": {
+			"type": "array",
+			"items": {"type": "object", "properties": {"url": {"type": "string"}, "weight": {"type": "integer", "default": 1}}},
+			"default": [{"url": "http://a"}]
+		}
+	}
+}`
+
+// 测试辅助函数：对生成的代码做类型检查
+func typeCheck(t *testing.T, src []byte) *types.Package {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "generated.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("Generated code does not parse: %v\n%s", err, src)
+	}
+
+	pkg, err := (&types.Config{}).Check("config", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatalf("Generated code does not type check: %v\n%s", err, src)
+	}
+	return pkg
+}
+
+// 测试生成结构体、enum和Default函数
+func TestGenerateGo(t *testing.T) {
+	src, err := GenerateGo([]byte(testSchema), GoOptions{Package: "config", Source: `schema "app"`})
+	if err != nil {
+		t.Fatalf("Failed to generate code: %v", err)
+	}
+
+	pkg := typeCheck(t, src)
+	for _, name := range []string{"AppConfig", "AppConfigServer", "AppConfigMode", "AppConfigModeRelease", "AppConfigLevel1", "AppConfigBackendsItem", "Default"} {
+		if pkg.Scope().Lookup(name) == nil {
+			t.Errorf("Generated code does not declare %s", name)
+		}
+	}
+
+	code := string(src)
+	for _, want := range []string{
+		`// Code generated by goci codegen from schema "app". DO NOT EDIT.`,
+		"// AppConfig Settings for the demo app.",
+		"// Port Port to listen on.",
+		"Server   AppConfigServer         `json:\"server\"`",
+		"TLSCert *string `json:\"tls_cert\"`",
+		"Labels   map[string]string       `json:\"labels\"`",
+		// 默认值非零的可选字段不使用omitempty
+		"Port    int64   `json:\"port\"`",
+		`Port:    8080,`,
+		`TLSCert: ptr[string]("cert.pem"),`,
+		`Mode:   AppConfigModeRelease,`,
+		`Tags:   []string{"a", "b"},`,
+		`Weight: 1,`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+
+	// 外层类型在内层类型之前
+	if strings.Index(code, "type AppConfig struct") > strings.Index(code, "type AppConfigServer struct") {
+		t.Errorf("Root type should be declared first")
+	}
+}
+
+// 测试没有默认值和没有title的Schema
+func TestGenerateGoMinimal(t *testing.T) {
+	src, err := GenerateGo([]byte(`{"type": "object", "properties": {"name": {"type": "string"}}}`), GoOptions{Package: "config"})
+	if err != nil {
+		t.Fatalf("Failed to generate code: %v", err)
+	}
+
+	typeCheck(t, src)
+	if !strings.Contains(string(src), "func Default() Config {\n\treturn Config{}\n}") {
+		t.Errorf("Unexpected Default function:\n%s", src)
+	}
+	if strings.Contains(string(src), "func ptr") {
+		t.Errorf("ptr helper should only be generated when needed")
+	}
+
+	// 显式指定类型名
+	src, err = GenerateGo([]byte(`{"type": "string", "default": "x"}`), GoOptions{Package: "config", TypeName: "name"})
+	if err != nil {
+		t.Fatalf("Failed to generate code: %v", err)
+	}
+	typeCheck(t, src)
+	if !strings.Contains(string(src), "func Default() string {\n\treturn \"x\"\n}") {
+		t.Errorf("Unexpected Default function:\n%s", src)
+	}
+
+	// 以小数或指数形式写出的整数默认值
+	src, err = GenerateGo([]byte(`{"type": "integer", "default": 1e3}`), GoOptions{Package: "config", TypeName: "size"})
+	if err != nil {
+		t.Fatalf("Failed to generate code: %v", err)
+	}
+	typeCheck(t, src)
+	if !strings.Contains(string(src), "func Default() int64 {\n\treturn 1000\n}") {
+		t.Errorf("Unexpected Default function:\n%s", src)
+	}
+}
+
+// 测试文档内的$ref：引用的定义生成具名类型，递归引用生成自引用的类型
+func TestGenerateGoRefs(t *testing.T) {
+	schema := `{
+		"title": "node",
+		"type": "object",
+		"definitions": {
+			"server": {
+				"type": "object",
+				"properties": {
+					"host": {"type": "string", "default": "localhost"},
+					"mode": {"$ref": "#/definitions/mode"}
+				}
+			},
+			"mode": {"type": "string", "enum": ["a", "b"], "default": "b"}
+		},
+		"properties": {
+			"primary": {"$ref": "#/definitions/server"},
+			"backup": {"$ref": "#/definitions/server"},
+			"children": {"type": "array", "items": {"$ref": "#"}},
+			"byName": {"type": "object", "additionalProperties": {"$ref": "#"}},
+			"parent": {"$ref": "#"}
+		}
+	}`
+
+	src, err := GenerateGo([]byte(schema), GoOptions{Package: "config"})
+	if err != nil {
+		t.Fatalf("Failed to generate code: %v", err)
+	}
+
+	pkg := typeCheck(t, src)
+	for _, name := range []string{"Node", "Server", "Mode", "ModeB"} {
+		if pkg.Scope().Lookup(name) == nil {
+			t.Errorf("Generated code does not declare %s", name)
+		}
+	}
+
+	code := string(src)
+	for _, want := range []string{
+		"Primary  Server          `json:\"primary,omitempty\"`",
+		"Backup   Server          `json:\"backup,omitempty\"`",
+		"Children []Node          `json:\"children,omitempty\"`",
+		"ByName   map[string]Node `json:\"byName,omitempty\"`",
+		"Parent   *Node           `json:\"parent,omitempty\"`",
+		"Mode Mode   `json:\"mode\"`",
+		`Host: "localhost",`,
+		`Mode: ModeB,`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("Generated code does not contain %q:\n%s", want, code)
+		}
+	}
+	if strings.Count(code, "type Server struct") != 1 {
+		t.Errorf("Shared definition should be declared once:\n%s", code)
+	}
+}
+
+// 测试无效输入
+func TestGenerateGoErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		pkgName string
+	}{
+		{"invalid package", `{"type": "object"}`, "my-package"},
+		{"keyword package", `{"type": "object"}`, "func"},
+		{"invalid JSON", `{"type":`, "config"},
+		{"not an object", `[]`, "config"},
+		{"unresolved ref", `{"properties": {"a": {"$ref": "#/definitions/missing"}}}`, "config"},
+		{"external ref", `{"properties": {"a": {"$ref": "goci://defs/port"}}}`, "config"},
+		{"fractional integer default", `{"properties": {"a": {"type": "integer", "default": 1.5}}}`, "config"},
+		{"out of range integer default", `{"properties": {"a": {"type": "integer", "default": 12345678901234567890}}}`, "config"},
+		{"out of range number default", `{"properties": {"a": {"type": "number", "default": 1e400}}}`, "config"},
+		{"circular ref", `{"definitions": {"a": {"$ref": "#/definitions/b"}, "b": {"$ref": "#/definitions/a"}}, "properties": {"a": {"$ref": "#/definitions/a"}}}`, "config"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := GenerateGo([]byte(tt.schema), GoOptions{Package: tt.pkgName}); err == nil {
+				t.Errorf("Expected error")
+			}
+		})
+	}
+}
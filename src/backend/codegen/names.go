@@ -0,0 +1,79 @@
+package codegen
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// initialisms 按Go命名习惯全部大写的缩写
+var initialisms = map[string]bool{
+	"API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true, "EOF": true,
+	"GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true,
+	"JSON": true, "LHS": true, "QPS": true, "RAM": true, "RHS": true, "RPC": true,
+	"SLA": true, "SMTP": true, "SQL": true, "SSH": true, "TCP": true, "TLS": true,
+	"TTL": true, "UDP": true, "UI": true, "UID": true, "UUID": true, "URI": true,
+	"URL": true, "UTF8": true, "VM": true, "XML": true, "XSRF": true, "XSS": true,
+}
+
+// exportedName 把JSON属性名转换为导出的Go标识符，例如 server_url 转换为 ServerURL
+func exportedName(s string) string {
+	name := identifierPart(s)
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
+
+// identifierPart 把字符串转换为驼峰形式，用于拼接在其他标识符之后
+func identifierPart(s string) string {
+	var words []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = nil
+		}
+	}
+
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])):
+			// camelCase的单词边界
+			flush()
+			current = append(current, r)
+		default:
+			current = append(current, r)
+		}
+	}
+	flush()
+
+	var b strings.Builder
+	for _, word := range words {
+		if upper := strings.ToUpper(word); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		wordRunes := []rune(word)
+		b.WriteRune(unicode.ToUpper(wordRunes[0]))
+		b.WriteString(string(wordRunes[1:]))
+	}
+
+	return b.String()
+}
+
+// nameSet 分配不重复的标识符
+type nameSet map[string]bool
+
+// unique 返回name，已被使用时追加数字后缀
+func (n nameSet) unique(name string) string {
+	candidate := name
+	for i := 2; n[candidate]; i++ {
+		candidate = name + strconv.Itoa(i)
+	}
+	n[candidate] = true
+	return candidate
+}
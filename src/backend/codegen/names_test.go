@@ -0,0 +1,28 @@
+package codegen
+
+import "testing"
+
+// 测试属性名到Go标识符的转换
+func TestExportedName(t *testing.T) {
+	tests := map[string]string{
+		"name":        "Name",
+		"server_port": "ServerPort",
+		"serverPort":  "ServerPort",
+		"api-url":     "APIURL",
+		"userId":      "UserID",
+		"tls cert":    "TLSCert",
+		"2fa":         "X2fa",
+		"":            "X",
+	}
+
+	for input, want := range tests {
+		if got := exportedName(input); got != want {
+			t.Errorf("exportedName(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	names := nameSet{}
+	if names.unique("Name") != "Name" || names.unique("Name") != "Name2" {
+		t.Errorf("unique did not add a suffix")
+	}
+}
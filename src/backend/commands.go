@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"goci/backend/archive"
+	"goci/backend/codegen"
+	"goci/backend/migration"
+	"goci/backend/refs"
+	"goci/backend/settings"
+	"goci/backend/storage"
+)
+
+// commands 是可用的子命令，第一个参数不是子命令时启动服务器
+var commands = map[string]func(args []string) error{
+	"codegen": runCodegen,
+	"migrate": runMigrate,
+	"fsck":    runFsck,
+	"export":  runExport,
+	"import":  runImport,
+}
+
+// runCommand 执行子命令，返回是否找到了子命令
+func runCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	command, exists := commands[args[0]]
+	if !exists {
+		return false
+	}
+
+	if err := command(args[1:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "goci %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+	return true
+}
+
+// storeFlags 为子命令注册读取数据目录所需的参数，默认值来自环境变量
+func storeFlags(flags *flag.FlagSet) *settings.Settings {
+	s := settings.Default()
+	if dataDir := os.Getenv("GOCI_DATA_DIR"); dataDir != "" {
+		s.DataDir = dataDir
+	}
+	if storage := os.Getenv("GOCI_STORAGE"); storage != "" {
+		s.Storage = storage
+	}
+
+	flags.StringVar(&s.DataDir, "data-dir", s.DataDir, "data directory (env GOCI_DATA_DIR)")
+	flags.StringVar(&s.Storage, "storage", s.Storage, "storage backend: filesystem or bolt (env GOCI_STORAGE)")
+	return &s
+}
+
+// runCodegen 根据Schema生成Go代码，可以在go:generate中使用：
+//
+//	//go:generate goci codegen -server http://localhost:8080 -schema app -o config_gen.go
+func runCodegen(args []string) error {
+	flags := flag.NewFlagSet("codegen", flag.ContinueOnError)
+	schemaID := flags.String("schema", "", "schema ID (required)")
+	packageName := flags.String("package", os.Getenv("GOPACKAGE"), "package name of the generated file (default $GOPACKAGE or config)")
+	typeName := flags.String("type", "", "root type name (default the schema title)")
+	output := flags.String("o", "", "output file (default stdout)")
+	server := flags.String("server", "", "read the schema from a running server instead of the data directory")
+	token := flags.String("token", os.Getenv("GOCI_TOKEN"), "bearer token for -server (env GOCI_TOKEN)")
+	s := storeFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *schemaID == "" {
+		return errors.New("-schema is required")
+	}
+	if *packageName == "" {
+		*packageName = "config"
+	}
+
+	var src []byte
+	var err error
+	if *server != "" {
+		src, err = fetchGo(*server, *token, *schemaID, *packageName, *typeName)
+	} else {
+		src, err = generateGo(*s, *schemaID, *packageName, *typeName)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*output, src, 0644)
+}
+
+// runMigrate 把数据目录中的配置升级到Schema的新版本，并输出迁移结果
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	schemaID := flags.String("schema", "", "schema ID (required)")
+	from := flags.Int("from", 0, "schema version the config currently matches (default the recorded version)")
+	to := flags.Int("to", 0, "target schema version (default the current version)")
+	dryRun := flags.Bool("dry-run", false, "print the migrated config without saving it")
+	s := storeFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *schemaID == "" {
+		return errors.New("-schema is required")
+	}
+	if _, err := os.Stat(s.DataDir); err != nil {
+		return fmt.Errorf("data directory: %w", err)
+	}
+
+	schemas, configs, closeStores, err := openStores(*s)
+	if err != nil {
+		return err
+	}
+	defer closeStores()
+
+	result, err := migration.NewRunner(schemas, configs).Migrate(*schemaID, migration.Options{From: *from, To: *to, DryRun: *dryRun})
+	if len(result.Violations) > 0 || err == nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(result)
+	}
+	return err
+}
+
+// runFsck 检查文件系统后端的数据目录，输出发现的问题，有未修复的问题时返回错误
+func runFsck(args []string) error {
+	flags := flag.NewFlagSet("fsck", flag.ContinueOnError)
+	repair := flags.Bool("repair", false, "rebuild registries and quarantine unreadable files")
+	s := storeFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if s.Storage == settings.StorageBolt {
+		return errors.New("fsck only applies to the filesystem backend")
+	}
+	if _, err := os.Stat(s.DataDir); err != nil {
+		return fmt.Errorf("data directory: %w", err)
+	}
+
+	report, err := storage.Fsck(s.DataDir, *repair)
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+	if err != nil {
+		return err
+	}
+
+	if unrepaired := report.Unrepaired(); unrepaired > 0 {
+		return fmt.Errorf("%d problems found, run with -repair to fix them", unrepaired)
+	}
+	return nil
+}
+
+// runExport 把Schema连同元数据和历史导出为归档
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	ids := flags.String("ids", "", "comma-separated schema IDs (default all schemas)")
+	format := flags.String("format", "tar.gz", "archive format: tar.gz or zip")
+	output := flags.String("o", "", "output file (default stdout)")
+	server := flags.String("server", "", "export from a running server instead of the data directory")
+	token := flags.String("token", os.Getenv("GOCI_TOKEN"), "bearer token for -server (env GOCI_TOKEN)")
+	s := storeFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	archiveFormat, err := archive.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if *server != "" {
+		query := url.Values{"format": {string(archiveFormat)}}
+		if *ids != "" {
+			query.Set("ids", *ids)
+		}
+		data, err = request(http.MethodGet, *server, "/api/export?"+query.Encode(), *token, nil)
+	} else {
+		data, err = exportArchive(*s, *ids, archiveFormat)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*output, data, 0644)
+}
+
+// exportArchive 从数据目录导出归档
+func exportArchive(s settings.Settings, ids string, format archive.Format) ([]byte, error) {
+	if _, err := os.Stat(s.DataDir); err != nil {
+		return nil, fmt.Errorf("data directory: %w", err)
+	}
+
+	schemas, _, closeStores, err := openStores(s)
+	if err != nil {
+		return nil, err
+	}
+	defer closeStores()
+
+	var selected []string
+	if ids != "" {
+		selected = strings.Split(ids, ",")
+	}
+	var buf bytes.Buffer
+	if err := archive.Export(&buf, schemas, selected, format); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// runImport 把归档导入数据目录或运行中的服务器，并输出导入报告
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	policy := flags.String("policy", "skip", "what to do when a schema ID already exists: skip, overwrite or rename")
+	dryRun := flags.Bool("dry-run", false, "print the report without importing anything")
+	server := flags.String("server", "", "import into a running server instead of the data directory")
+	token := flags.String("token", os.Getenv("GOCI_TOKEN"), "bearer token for -server (env GOCI_TOKEN)")
+	s := storeFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: goci import [flags] FILE (- for stdin)")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("exactly one archive file is required")
+	}
+	conflictPolicy, err := archive.ParsePolicy(*policy)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	if flags.Arg(0) == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(flags.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+
+	if *server != "" {
+		query := url.Values{"policy": {string(conflictPolicy)}, "dryRun": {fmt.Sprint(*dryRun)}}
+		body, err := request(http.MethodPost, *server, "/api/import?"+query.Encode(), *token, data)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(body)
+		return err
+	}
+
+	if _, err := os.Stat(s.DataDir); err != nil {
+		return fmt.Errorf("data directory: %w", err)
+	}
+	schemas, _, closeStores, err := openStores(*s)
+	if err != nil {
+		return err
+	}
+	defer closeStores()
+
+	imported, err := archive.Read(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	report, err := archive.Import(refs.WithReferenceCheck(schemas), imported, archive.Options{Policy: conflictPolicy, DryRun: *dryRun})
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+	return err
+}
+
+// generateGo 从数据目录读取Schema并生成代码
+func generateGo(s settings.Settings, schemaID, packageName, typeName string) ([]byte, error) {
+	if _, err := os.Stat(s.DataDir); err != nil {
+		return nil, fmt.Errorf("data directory: %w", err)
+	}
+
+	schemas, _, closeStores, err := openStores(s)
+	if err != nil {
+		return nil, err
+	}
+	defer closeStores()
+
+	schemaData, metadata, err := refs.BundleStored(schemas, schemaID)
+	if err != nil {
+		return nil, err
+	}
+
+	return codegen.GenerateGo(schemaData, codegen.GoOptions{
+		Package:  packageName,
+		TypeName: typeName,
+		Source:   fmt.Sprintf("schema %q version %d", schemaID, metadata.Version),
+	})
+}
+
+// fetchGo 通过服务端的codegen接口生成代码，token非空时作为Bearer令牌发送
+func fetchGo(server, token, schemaID, packageName, typeName string) ([]byte, error) {
+	query := url.Values{"package": {packageName}}
+	if typeName != "" {
+		query.Set("type", typeName)
+	}
+	return request(http.MethodGet, server, "/api/schemas/"+url.PathEscape(schemaID)+"/codegen/go?"+query.Encode(), token, nil)
+}
+
+// request 向服务端发送请求并返回响应体，token非空时作为Bearer令牌发送，状态码不是200时返回错误
+func request(method, server, path, token string, body []byte) ([]byte, error) {
+	endpoint := strings.TrimSuffix(server, "/") + path
+
+	req, err := http.NewRequest(method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return respBody, nil
+}
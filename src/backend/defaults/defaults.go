@@ -0,0 +1,361 @@
+// Package defaults 根据JSON Schema生成默认配置
+package defaults
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"goci/backend/internal/ordered"
+)
+
+// ErrUnresolved 表示生成的默认配置中有无法确定的值
+var ErrUnresolved = errors.New("unresolved default values")
+
+// Generate 根据Schema生成一份最小的默认配置，对象的键按Schema中properties的顺序输出
+//
+// 值的来源依次为：const、default、编辑器的value字段、enum的第一个值，
+// 都没有时使用满足minimum、minLength、minItems等约束的零值。
+// 对象只包含required属性和带有上述显式值的属性；$ref只支持文档内的引用。
+//
+// 无法自动得到有效值的位置不写入配置，它们的JSON Pointer在unresolved中返回，例如
+// 没有类型和默认值的required属性、递归引用的required属性，以及带format的字符串
+func Generate(schemaData []byte) (configData []byte, unresolved []string, err error) {
+	schema, err := ordered.Decode(schemaData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing schema: %w", err)
+	}
+
+	g := &generator{root: schema, resolving: make(map[string]bool)}
+	value, _ := g.generate(schema, "")
+	if value == nil {
+		value = ordered.New()
+	}
+
+	configData, err = json.MarshalIndent(value, "", "  ")
+	return configData, g.unresolved, err
+}
+
+// generator 保存根Schema，用于解析$ref
+type generator struct {
+	root      any
+	resolving map[string]bool
+	// unresolved 是无法生成有效值的位置
+	unresolved []string
+}
+
+// generate 生成Schema对应的值，explicit表示值来自Schema中显式给出的值，
+// value为nil且explicit为false表示无法生成值
+func (g *generator) generate(schema any, path string) (value any, explicit bool) {
+	node, ok := schema.(*ordered.Object)
+	if !ok {
+		return nil, false
+	}
+
+	if ref, ok := node.Value("$ref").(string); ok {
+		// 循环引用时不再展开
+		if g.resolving[ref] {
+			return nil, false
+		}
+		target, err := g.resolve(ref)
+		if err != nil {
+			return nil, false
+		}
+		g.resolving[ref] = true
+		defer delete(g.resolving, ref)
+		return g.generate(target, path)
+	}
+
+	for _, keyword := range []string{"const", "default", "value"} {
+		if v, exists := node.Get(keyword); exists && !(keyword == "value" && (v == nil || v == "")) {
+			return v, true
+		}
+	}
+
+	if enum, ok := node.Value("enum").([]any); ok && len(enum) > 0 {
+		return enum[0], true
+	}
+
+	if allOf, ok := node.Value("allOf").([]any); ok && len(allOf) > 0 {
+		return g.generateAllOf(node, allOf, path)
+	}
+	for _, keyword := range []string{"oneOf", "anyOf"} {
+		if branches, ok := node.Value(keyword).([]any); ok && len(branches) > 0 {
+			return g.generate(branches[0], path)
+		}
+	}
+
+	switch schemaType(node) {
+	case "object":
+		return g.generateObject(node, path)
+	case "array":
+		return g.generateArray(node, path)
+	case "string":
+		// 重复的x不满足email、uri等格式
+		if _, ok := node.Get("format"); ok {
+			g.unresolved = append(g.unresolved, path)
+			return nil, false
+		}
+		return strings.Repeat("x", intKeyword(node, "minLength")), false
+	case "integer":
+		return json.Number(strconv.FormatInt(integerDefault(node), 10)), false
+	case "number":
+		return json.Number(strconv.FormatFloat(numberDefault(node), 'f', -1, 64)), false
+	case "boolean":
+		return false, false
+	}
+	return nil, false
+}
+
+// generateObject 生成required属性和带显式值的属性
+func (g *generator) generateObject(node *ordered.Object, path string) (any, bool) {
+	required := map[string]bool{}
+	if list, ok := node.Value("required").([]any); ok {
+		for _, item := range list {
+			if name, ok := item.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+
+	object := ordered.New()
+	explicit := false
+	properties, _ := node.Value("properties").(*ordered.Object)
+	if properties != nil {
+		for _, name := range properties.Keys() {
+			propertyPath := path + "/" + escapePointer(name)
+			n := len(g.unresolved)
+			value, propertyExplicit := g.generate(properties.Value(name), propertyPath)
+			switch {
+			case value != nil && (required[name] || propertyExplicit) || required[name] && propertyExplicit:
+				object.Set(name, value)
+				explicit = explicit || propertyExplicit
+			case required[name]:
+				g.unresolve(n, propertyPath)
+			default:
+				// 不写入的属性内部无法生成的位置无关紧要
+				g.unresolved = g.unresolved[:n]
+			}
+		}
+	}
+
+	// required中没有在properties定义的属性
+	if list, ok := node.Value("required").([]any); ok {
+		for _, item := range list {
+			if name, ok := item.(string); ok && !object.Has(name) && (properties == nil || !properties.Has(name)) {
+				g.unresolve(len(g.unresolved), path+"/"+escapePointer(name))
+			}
+		}
+	}
+
+	return object, explicit
+}
+
+// generateArray 生成满足minItems的数组
+func (g *generator) generateArray(node *ordered.Object, path string) (any, bool) {
+	array := []any{}
+	explicit := false
+	for i := 0; i < intKeyword(node, "minItems"); i++ {
+		items := node.Value("items")
+		// 元组形式的items
+		if tuple, ok := items.([]any); ok {
+			items = nil
+			if i < len(tuple) {
+				items = tuple[i]
+			}
+		}
+		itemPath := path + "/" + strconv.Itoa(i)
+		n := len(g.unresolved)
+		value, itemExplicit := g.generate(items, itemPath)
+		if value == nil && !itemExplicit {
+			g.unresolve(n, itemPath)
+		}
+		array = append(array, value)
+		explicit = explicit || itemExplicit
+	}
+	return array, explicit
+}
+
+// generateAllOf 合并各分支生成的对象，不是对象时使用第一个分支
+func (g *generator) generateAllOf(node *ordered.Object, allOf []any, path string) (any, bool) {
+	merged := ordered.New()
+	explicit := false
+	isObject := false
+	n := len(g.unresolved)
+
+	// allOf所在Schema本身的properties也参与合并
+	branches := allOf
+	if node.Has("properties") {
+		rest := ordered.New()
+		for _, key := range node.Keys() {
+			if key != "allOf" {
+				rest.Set(key, node.Value(key))
+			}
+		}
+		branches = append([]any{rest}, allOf...)
+	}
+
+	for i, branch := range branches {
+		value, branchExplicit := g.generate(branch, path)
+		object, ok := value.(*ordered.Object)
+		if !ok {
+			if i == 0 {
+				return value, branchExplicit
+			}
+			continue
+		}
+		isObject = true
+		for _, key := range object.Keys() {
+			merged.Set(key, object.Value(key))
+		}
+		explicit = explicit || branchExplicit
+	}
+
+	if !isObject {
+		return nil, false
+	}
+
+	// 一个分支中required的属性可能由另一个分支生成
+	unresolved := g.unresolved[:n]
+	for _, pointer := range g.unresolved[n:] {
+		name, found := strings.CutPrefix(pointer, path+"/")
+		if !found || strings.Contains(name, "/") || !merged.Has(unescapePointer(name)) {
+			unresolved = append(unresolved, pointer)
+		}
+	}
+	g.unresolved = unresolved
+	return merged, explicit
+}
+
+// unresolve 记录无法生成值的位置，从n开始已经记录了更具体的位置时不再重复记录
+func (g *generator) unresolve(n int, pointer string) {
+	if len(g.unresolved) == n {
+		g.unresolved = append(g.unresolved, pointer)
+	}
+}
+
+// escapePointer 按JSON Pointer规则转义属性名
+func escapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
+
+// unescapePointer 还原escapePointer转义的属性名
+func unescapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+}
+
+// resolve 解析文档内的$ref，例如 #/definitions/server
+func (g *generator) resolve(ref string) (any, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported $ref: %s", ref)
+	}
+
+	fragment, err := url.PathUnescape(ref[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	current := g.root
+	if fragment == "" {
+		return current, nil
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(fragment, "/"), "/") {
+		token = unescapePointer(token)
+		switch node := current.(type) {
+		case *ordered.Object:
+			next, exists := node.Get(token)
+			if !exists {
+				return nil, fmt.Errorf("unresolved $ref: %s", ref)
+			}
+			current = next
+		case []any:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("unresolved $ref: %s", ref)
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("unresolved $ref: %s", ref)
+		}
+	}
+	return current, nil
+}
+
+// schemaType 返回Schema的类型，类型为数组时取第一个非null类型
+func schemaType(node *ordered.Object) string {
+	switch t := node.Value("type").(type) {
+	case string:
+		return t
+	case []any:
+		for _, item := range t {
+			if s, ok := item.(string); ok && s != "null" {
+				return s
+			}
+		}
+		return "null"
+	}
+
+	if node.Has("properties") {
+		return "object"
+	}
+	return ""
+}
+
+// intKeyword 返回非负整数关键字，例如minLength
+func intKeyword(node *ordered.Object, keyword string) int {
+	n, ok := number(node, keyword)
+	if !ok || n < 0 {
+		return 0
+	}
+	return int(n)
+}
+
+// integerDefault 返回满足minimum和maximum的整数，优先使用0
+func integerDefault(node *ordered.Object) int64 {
+	value := 0.0
+	if minimum, ok := number(node, "minimum"); ok && value < minimum {
+		value = math.Ceil(minimum)
+	}
+	if exclusive, ok := number(node, "exclusiveMinimum"); ok && value <= exclusive {
+		value = math.Floor(exclusive) + 1
+	}
+	if maximum, ok := number(node, "maximum"); ok && value > maximum {
+		value = math.Floor(maximum)
+	}
+	if exclusive, ok := number(node, "exclusiveMaximum"); ok && value >= exclusive {
+		value = math.Ceil(exclusive) - 1
+	}
+	return int64(value)
+}
+
+// numberDefault 返回满足minimum和maximum的数字，优先使用0
+func numberDefault(node *ordered.Object) float64 {
+	value := 0.0
+	if minimum, ok := number(node, "minimum"); ok && value < minimum {
+		value = minimum
+	}
+	if exclusive, ok := number(node, "exclusiveMinimum"); ok && value <= exclusive {
+		value = exclusive + 1
+	}
+	if maximum, ok := number(node, "maximum"); ok && value > maximum {
+		value = maximum
+	}
+	if exclusive, ok := number(node, "exclusiveMaximum"); ok && value >= exclusive {
+		value = exclusive - 1
+	}
+	return value
+}
+
+// number 返回数字关键字
+func number(node *ordered.Object, keyword string) (float64, bool) {
+	n, ok := node.Value(keyword).(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil
+}
@@ -0,0 +1,207 @@
+package defaults
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"goci/backend/validation"
+)
+
+// 测试辅助函数：生成默认配置并解析
+func generate(t *testing.T, schema string) any {
+	t.Helper()
+
+	data, unresolved, err := Generate([]byte(schema))
+	if err != nil {
+		t.Fatalf("Failed to generate default config: %v", err)
+	}
+	if len(unresolved) > 0 {
+		t.Errorf("Unexpected unresolved values: %v", unresolved)
+	}
+
+	// 生成的配置必须符合Schema
+	if err := validation.ValidateConfig([]byte(schema), data); err != nil {
+		t.Errorf("Generated config is invalid: %v\n%s", err, data)
+	}
+
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.Fatalf("Generated config is not JSON: %v", err)
+	}
+	return value
+}
+
+// 测试各种关键字生成的默认值
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{"empty schema", `{}`, `{}`},
+		{"default", `{"type": "string", "default": "x"}`, `"x"`},
+		{"const wins over default", `{"const": 1, "default": 2}`, `1`},
+		{"editor value", `{"type": "string", "value": "fixed"}`, `"fixed"`},
+		{"empty editor value", `{"type": "string", "value": ""}`, `""`},
+		{"enum", `{"type": "string", "enum": ["a", "b"]}`, `"a"`},
+		{"minimum", `{"type": "integer", "minimum": 3, "maximum": 10}`, `3`},
+		{"exclusive maximum", `{"type": "number", "exclusiveMaximum": -1}`, `-2`},
+		{"min length", `{"type": "string", "minLength": 2}`, `"xx"`},
+		{"min items", `{"type": "array", "minItems": 2, "items": {"type": "boolean"}}`, `[false, false]`},
+		{"nullable", `{"type": ["null", "integer"]}`, `0`},
+		{"one of", `{"oneOf": [{"type": "string"}, {"type": "integer"}]}`, `""`},
+		{
+			"object with required and defaults",
+			`{"type": "object", "required": ["name"], "properties": {
+				"name": {"type": "string"},
+				"port": {"type": "integer", "default": 8080},
+				"debug": {"type": "boolean"},
+				"server": {"type": "object", "properties": {"host": {"type": "string", "default": "localhost"}}},
+				"empty": {"type": "object", "properties": {"x": {"type": "string"}}}
+			}}`,
+			`{"name": "", "port": 8080, "server": {"host": "localhost"}}`,
+		},
+		{
+			"object default wins",
+			`{"type": "object", "properties": {"limits": {"type": "object", "default": {"rps": 1}, "properties": {"rps": {"type": "integer", "default": 5}}}}}`,
+			`{"limits": {"rps": 1}}`,
+		},
+		{
+			"ref",
+			`{"type": "object", "required": ["server"], "properties": {"server": {"$ref": "#/definitions/server"}},
+			  "definitions": {"server": {"type": "object", "required": ["port"], "properties": {"port": {"type": "integer", "minimum": 1}}}}}`,
+			`{"server": {"port": 1}}`,
+		},
+		{
+			"all of",
+			`{"allOf": [{"type": "object", "properties": {"a": {"default": 1}}}, {"type": "object", "required": ["b"], "properties": {"b": {"type": "string"}}}]}`,
+			`{"a": 1, "b": ""}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want any
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatalf("Invalid expected value: %v", err)
+			}
+
+			data, unresolved, err := Generate([]byte(tt.schema))
+			if err != nil {
+				t.Fatalf("Failed to generate default config: %v", err)
+			}
+			if len(unresolved) > 0 {
+				t.Errorf("Unexpected unresolved values: %v", unresolved)
+			}
+
+			var got any
+			json.Unmarshal(data, &got)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Unexpected default config: got %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
+
+// 测试编辑器生成的Schema得到有效的默认配置
+func TestGenerateEditorSchema(t *testing.T) {
+	schema := `{"$schema":"http://json-schema.org/draft-07/schema#","type":"object","properties":{"title":{"type":"string","isFixed":true,"readOnly":true,"value":"my","description":"配置标题","default":"my"},"son":{"type":"string","isFixed":false,"readOnly":false}},"required":["title"]}`
+
+	got := generate(t, schema)
+	if !reflect.DeepEqual(got, map[string]any{"title": "my"}) {
+		t.Errorf("Unexpected default config: %v", got)
+	}
+}
+
+// 测试无效的Schema
+func TestGenerateInvalid(t *testing.T) {
+	if _, _, err := Generate([]byte(`{"type":`)); err == nil {
+		t.Errorf("Expected error")
+	}
+}
+
+// 测试无法自动生成有效值的位置不写入配置，并通过unresolved返回
+func TestGenerateUnresolved(t *testing.T) {
+	tests := []struct {
+		name       string
+		schema     string
+		want       string
+		unresolved []string
+	}{
+		{
+			"required without type",
+			`{"type": "object", "required": ["token", "name"], "properties": {"token": {}, "name": {"type": "string"}}}`,
+			`{"name": ""}`,
+			[]string{"/token"},
+		},
+		{
+			"required not in properties",
+			`{"type": "object", "required": ["a/b"]}`,
+			`{}`,
+			[]string{"/a~1b"},
+		},
+		{
+			"recursive ref",
+			`{"type": "object", "required": ["child"], "properties": {"child": {"$ref": "#"}}}`,
+			`{"child": {}}`,
+			[]string{"/child/child"},
+		},
+		{
+			"format",
+			`{"type": "object", "required": ["email", "site"], "properties": {"email": {"type": "string", "format": "email"}, "site": {"type": "string", "format": "uri", "default": "https://example.com"}, "backup": {"type": "string", "format": "email"}}}`,
+			`{"site": "https://example.com"}`,
+			[]string{"/email"},
+		},
+		{
+			"array items",
+			`{"type": "array", "minItems": 1, "items": {"type": "string", "format": "date"}}`,
+			`[null]`,
+			[]string{"/0"},
+		},
+		{
+			"required in another all of branch",
+			`{"allOf": [{"type": "object", "properties": {"a": {"default": 1}}}, {"type": "object", "required": ["a", "b"]}]}`,
+			`{"a": 1}`,
+			[]string{"/b"},
+		},
+		{
+			"explicit null",
+			`{"type": "object", "required": ["a"], "properties": {"a": {"default": null}}}`,
+			`{"a": null}`,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, unresolved, err := Generate([]byte(tt.schema))
+			if err != nil {
+				t.Fatalf("Failed to generate default config: %v", err)
+			}
+			if !reflect.DeepEqual(unresolved, tt.unresolved) {
+				t.Errorf("Unexpected unresolved values: got %v, want %v", unresolved, tt.unresolved)
+			}
+
+			var got, want any
+			json.Unmarshal(data, &got)
+			json.Unmarshal([]byte(tt.want), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Unexpected default config: got %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
+
+// 测试输出的键按Schema中properties的顺序排列
+func TestGenerateOrder(t *testing.T) {
+	data, _, err := Generate([]byte(`{"type": "object", "properties": {"zeta": {"default": 1}, "alpha": {"default": 2}, "mid": {"type": "object", "properties": {"b": {"default": 3}, "a": {"default": 4}}}}}`))
+	if err != nil {
+		t.Fatalf("Failed to generate default config: %v", err)
+	}
+
+	want := "{\n  \"zeta\": 1,\n  \"alpha\": 2,\n  \"mid\": {\n    \"b\": 3,\n    \"a\": 4\n  }\n}"
+	if string(data) != want {
+		t.Errorf("Unexpected key order:\n%s", data)
+	}
+}
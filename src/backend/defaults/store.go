@@ -0,0 +1,71 @@
+package defaults
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"goci/backend/refs"
+	"goci/backend/storage"
+	"goci/backend/validation"
+)
+
+// defaultConfigStore 在Schema第一次保存时生成默认配置
+type defaultConfigStore struct {
+	storage.SchemaStore
+	configs storage.ConfigStore
+}
+
+// WithDefaultConfigs 包装一个SchemaStore，新建的Schema保存成功后，
+// 如果还没有对应的配置，就生成默认配置并保存到configs
+//
+// 只有新建Schema（第一个版本且不是归档导入的历史）才会生成，导入时重放的第一个版本
+// 已经过时，不能用来生成配置。生成的配置有无法确定的值，或不符合Schema时
+// （例如Schema中有无法自动满足的pattern）不会保存
+func WithDefaultConfigs(store storage.SchemaStore, configs storage.ConfigStore) storage.SchemaStore {
+	return &defaultConfigStore{SchemaStore: store, configs: configs}
+}
+
+// SaveSchema 保存Schema并在需要时生成默认配置
+func (s *defaultConfigStore) SaveSchema(id string, name string, description string, schemaData []byte) error {
+	_, err := s.SaveSchemaRevision(id, name, description, schemaData, storage.RevisionInfo{})
+	return err
+}
+
+// SaveSchemaRevision 保存Schema并在需要时生成默认配置
+func (s *defaultConfigStore) SaveSchemaRevision(id string, name string, description string, schemaData []byte, info storage.RevisionInfo) (storage.SchemaVersion, error) {
+	version, err := s.SchemaStore.SaveSchemaRevision(id, name, description, schemaData, info)
+	if err == nil && version.Version == 1 && !info.Imported {
+		s.materialize(id, version.Version, schemaData)
+	}
+	return version, err
+}
+
+// materialize 生成并保存默认配置，失败只记录日志，不影响Schema的保存
+//
+// 配置以仅创建的方式保存，已经存在的配置（包括并发创建的）不会被覆盖
+func (s *defaultConfigStore) materialize(id string, schemaVersion int, schemaData []byte) {
+	// 引用的定义内联后再生成
+	schemaData, err := refs.Bundle(refs.FromStore(s.SchemaStore), id, schemaData)
+	var configData []byte
+	if err == nil {
+		var unresolved []string
+		configData, unresolved, err = Generate(schemaData)
+		if err == nil && len(unresolved) > 0 {
+			err = fmt.Errorf("%w: %s", ErrUnresolved, strings.Join(unresolved, ", "))
+		}
+	}
+	if err == nil {
+		err = validation.ValidateConfig(schemaData, configData)
+	}
+	if err == nil {
+		err = s.configs.SaveConfigIfMatch(id, configData, schemaVersion, storage.CreateOnly)
+	}
+	if errors.Is(err, storage.ErrConfigExists) {
+		return
+	}
+	if err != nil {
+		slog.Warn("Failed to create default config", "schemaId", id, "error", err)
+	}
+}
@@ -0,0 +1,81 @@
+package defaults
+
+import (
+	"errors"
+	"testing"
+
+	"goci/backend/storage"
+)
+
+// 测试Schema第一次保存时生成默认配置
+func TestWithDefaultConfigs(t *testing.T) {
+	configs := storage.NewMemoryConfigStore()
+	schemas := WithDefaultConfigs(storage.NewMemorySchemaStore(), configs)
+
+	schema := []byte(`{"type": "object", "properties": {"port": {"type": "integer", "default": 8080}}}`)
+	if err := schemas.SaveSchema("app", "App", "", schema); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+
+	data, _, err := configs.GetConfig("app")
+	if err != nil {
+		t.Fatalf("Default config was not created: %v", err)
+	}
+	if string(data) != "{\n  \"port\": 8080\n}" {
+		t.Errorf("Unexpected default config: %s", data)
+	}
+
+	// 之后的版本不会覆盖配置
+	configs.SaveConfig("app", []byte(`{"port": 9090}`))
+	if err := schemas.SaveSchema("app", "App", "", []byte(`{"type": "object"}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if data, _, _ := configs.GetConfig("app"); string(data) != `{"port": 9090}` {
+		t.Errorf("Config was overwritten: %s", data)
+	}
+
+	// 无法自动满足的Schema不会生成配置，Schema仍然保存成功
+	if err := schemas.SaveSchema("pattern", "Pattern", "", []byte(`{"type": "string", "pattern": "^a+$"}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if _, _, err := configs.GetConfig("pattern"); !errors.Is(err, storage.ErrConfigNotFound) {
+		t.Errorf("Expected no config for unsatisfiable schema, got %v", err)
+	}
+}
+
+// 测试只有新建Schema才生成默认配置：导入的历史和有无法确定的值时都不生成
+func TestWithDefaultConfigsOnlyOnCreate(t *testing.T) {
+	configs := storage.NewMemoryConfigStore()
+	schemas := WithDefaultConfigs(storage.NewMemorySchemaStore(), configs)
+
+	// 导入时重放的第一个版本已经过时
+	info := storage.RevisionInfo{Imported: true}
+	if _, err := schemas.SaveSchemaRevision("imported", "Imported", "", []byte(`{"type": "object", "properties": {"port": {"type": "integer", "default": 80}}}`), info); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if _, err := schemas.SaveSchemaRevision("imported", "Imported", "", []byte(`{"type": "object", "required": ["host"], "properties": {"host": {"type": "string"}}}`), info); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if _, _, err := configs.GetConfig("imported"); !errors.Is(err, storage.ErrConfigNotFound) {
+		t.Errorf("Expected no config for imported schema, got %v", err)
+	}
+
+	// required属性没有可用的值
+	if err := schemas.SaveSchema("token", "Token", "", []byte(`{"type": "object", "required": ["token"], "properties": {"token": {}}}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if _, _, err := configs.GetConfig("token"); !errors.Is(err, storage.ErrConfigNotFound) {
+		t.Errorf("Expected no config for unresolved schema, got %v", err)
+	}
+
+	// 已有配置时不覆盖
+	if err := configs.SaveConfig("existing", []byte(`{"port": 9090}`)); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	if err := schemas.SaveSchema("existing", "Existing", "", []byte(`{"type": "object", "properties": {"port": {"type": "integer", "default": 80}}}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if data, _, _ := configs.GetConfig("existing"); string(data) != `{"port": 9090}` {
+		t.Errorf("Config was overwritten: %s", data)
+	}
+}
@@ -0,0 +1,150 @@
+// Package docformat 在JSON与YAML、TOML之间转换文档
+//
+// 存储的规范形式始终是JSON。YAML和TOML转换为JSON时保留对象键的顺序，
+// 语法错误和无法转换的值以*SyntaxError返回，带有行号和列号。
+package docformat
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"goci/backend/internal/ordered"
+)
+
+// Format 是文档格式
+type Format string
+
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+	TOML Format = "toml"
+)
+
+// ErrNotRepresentable 表示JSON文档无法用目标格式表示，例如TOML中的null
+var ErrNotRepresentable = errors.New("value cannot be represented")
+
+// mediaTypes 把媒体类型映射为格式
+var mediaTypes = map[string]Format{
+	"application/json":   JSON,
+	"text/json":          JSON,
+	"application/yaml":   YAML,
+	"application/x-yaml": YAML,
+	"text/yaml":          YAML,
+	"text/x-yaml":        YAML,
+	"application/toml":   TOML,
+	"text/toml":          TOML,
+}
+
+// ContentType 返回格式的媒体类型
+func (f Format) ContentType() string {
+	switch f {
+	case YAML:
+		return "application/yaml; charset=utf-8"
+	case TOML:
+		return "application/toml; charset=utf-8"
+	default:
+		return "application/json; charset=utf-8"
+	}
+}
+
+// FromContentType 返回Content-Type对应的格式，无法识别时为JSON
+func FromContentType(contentType string) Format {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return JSON
+	}
+	if format, ok := mediaTypes[mediaType]; ok {
+		return format
+	}
+	return JSON
+}
+
+// Negotiate 根据Accept请求头选择响应格式，没有可接受的YAML或TOML时为JSON
+func Negotiate(accept string) Format {
+	type mediaRange struct {
+		format Format
+		q      float64
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		format, ok := mediaTypes[mediaType]
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if value, exists := params["q"]; exists {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{format, q})
+		}
+	}
+
+	// 权重相同时按出现顺序
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	if len(ranges) == 0 {
+		return JSON
+	}
+	return ranges[0].format
+}
+
+// SyntaxError 是带有位置的转换错误，Column为0表示只知道行号
+type SyntaxError struct {
+	Format Format
+	Line   int
+	Column int
+	Msg    string
+}
+
+// Error 返回带格式和位置的错误信息
+func (e *SyntaxError) Error() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("%s: line %d, column %d: %s", e.Format, e.Line, e.Column, e.Msg)
+	}
+	return fmt.Sprintf("%s: line %d: %s", e.Format, e.Line, e.Msg)
+}
+
+// ToJSON 把format格式的文档转换为JSON，JSON文档原样返回
+func ToJSON(format Format, data []byte) ([]byte, error) {
+	var value any
+	var err error
+	switch format {
+	case YAML:
+		value, err = decodeYAML(data)
+	case TOML:
+		value, err = decodeTOML(data)
+	default:
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ordered.Marshal(value)
+}
+
+// FromJSON 把JSON文档转换为format格式，JSON原样返回
+func FromJSON(format Format, data []byte) ([]byte, error) {
+	if format != YAML && format != TOML {
+		return data, nil
+	}
+
+	value, err := ordered.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if format == YAML {
+		return encodeYAML(value)
+	}
+	return encodeTOML(value)
+}
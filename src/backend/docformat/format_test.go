@@ -0,0 +1,83 @@
+package docformat
+
+import (
+	"errors"
+	"testing"
+)
+
+// 测试根据Accept选择响应格式
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   Format
+	}{
+		{"", JSON},
+		{"*/*", JSON},
+		{"application/json", JSON},
+		{"application/yaml", YAML},
+		{"text/x-yaml", YAML},
+		{"application/toml", TOML},
+		{"application/json5", JSON},
+		{"application/json;q=0.5, application/yaml", YAML},
+		{"application/toml, application/yaml", TOML},
+		{"application/yaml;q=0, application/json", JSON},
+		{"text/html, application/toml;q=0.1", TOML},
+	}
+	for _, test := range tests {
+		if got := Negotiate(test.accept); got != test.want {
+			t.Errorf("Negotiate(%q) = %s, want %s", test.accept, got, test.want)
+		}
+	}
+}
+
+// 测试根据Content-Type识别请求格式
+func TestFromContentType(t *testing.T) {
+	tests := map[string]Format{
+		"":                                JSON,
+		"application/json":                JSON,
+		"application/yaml; charset=utf-8": YAML,
+		"application/x-yaml":              YAML,
+		"application/toml":                TOML,
+		"application/json5":               JSON,
+		"text/plain":                      JSON,
+		"not a media type;;":              JSON,
+	}
+	for contentType, want := range tests {
+		if got := FromContentType(contentType); got != want {
+			t.Errorf("FromContentType(%q) = %s, want %s", contentType, got, want)
+		}
+	}
+}
+
+// 测试JSON原样返回，无效的JSON无法转换为其他格式
+func TestConvertJSON(t *testing.T) {
+	data := []byte(`{"b": 1, "a": 2}`)
+	out, err := ToJSON(JSON, data)
+	if err != nil || string(out) != string(data) {
+		t.Errorf("Expected JSON to be returned as is, got %s, %v", out, err)
+	}
+	out, err = FromJSON(JSON, data)
+	if err != nil || string(out) != string(data) {
+		t.Errorf("Expected JSON output to be returned as is, got %s, %v", out, err)
+	}
+
+	if _, err := FromJSON(YAML, []byte(`{"a":`)); err == nil {
+		t.Errorf("Expected error for invalid JSON")
+	}
+}
+
+// 测试错误信息中的位置
+func TestSyntaxError(t *testing.T) {
+	err := error(&SyntaxError{Format: YAML, Line: 3, Column: 5, Msg: "bad"})
+	if err.Error() != "yaml: line 3, column 5: bad" {
+		t.Errorf("Unexpected message: %s", err)
+	}
+	err = &SyntaxError{Format: YAML, Line: 3, Msg: "bad"}
+	if err.Error() != "yaml: line 3: bad" {
+		t.Errorf("Unexpected message: %s", err)
+	}
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Errorf("Expected *SyntaxError")
+	}
+}
@@ -0,0 +1,375 @@
+package docformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"goci/backend/internal/ordered"
+)
+
+// decodeTOML 解析TOML文档
+//
+// go-toml解析为map后键的顺序会丢失，因此再按语法树中每个键第一次出现的位置排序
+func decodeTOML(data []byte) (any, error) {
+	var document map[string]any
+	if err := toml.Unmarshal(data, &document); err != nil {
+		var decodeErr *toml.DecodeError
+		if errors.As(err, &decodeErr) {
+			line, column := decodeErr.Position()
+			return nil, &SyntaxError{Format: TOML, Line: line, Column: column, Msg: strings.TrimPrefix(decodeErr.Error(), "toml: ")}
+		}
+		// 重复定义的键不是*toml.DecodeError，没有位置
+		if line, column, found := tomlDuplicateKey(data); found {
+			return nil, &SyntaxError{Format: TOML, Line: line, Column: column, Msg: strings.TrimPrefix(err.Error(), "toml: ")}
+		}
+		return nil, err
+	}
+
+	order, err := tomlKeyOrder(data)
+	if err != nil {
+		return nil, err
+	}
+	return tomlValue(document, "", order)
+}
+
+// tomlKeyOrder 返回每个键路径第一次出现的顺序，数组元素与数组使用相同的路径；
+// JSON无法表示的nan和inf返回带位置的*SyntaxError
+func tomlKeyOrder(data []byte) (map[string]int, error) {
+	var p unstable.Parser
+	var err error
+	order := make(map[string]int)
+	record := func(path string) string {
+		if _, exists := order[path]; !exists {
+			order[path] = len(order)
+		}
+		return path
+	}
+	join := func(prefix string, keys unstable.Iterator) string {
+		path := prefix
+		for keys.Next() {
+			path = record(path + "\x00" + string(keys.Node().Data))
+		}
+		return path
+	}
+
+	var value func(prefix string, node *unstable.Node)
+	value = func(prefix string, node *unstable.Node) {
+		switch node.Kind {
+		case unstable.Float:
+			literal := strings.TrimLeft(string(node.Data), "+-")
+			if (literal == "nan" || literal == "inf") && err == nil {
+				start := p.Shape(node.Raw).Start
+				err = &SyntaxError{Format: TOML, Line: start.Line, Column: start.Column, Msg: string(node.Data) + " cannot be represented in JSON"}
+			}
+		case unstable.InlineTable:
+			children := node.Children()
+			for children.Next() {
+				keyValue := children.Node()
+				value(join(prefix, keyValue.Key()), keyValue.Value())
+			}
+		case unstable.Array:
+			children := node.Children()
+			for children.Next() {
+				value(prefix, children.Node())
+			}
+		}
+	}
+
+	p.Reset(data)
+	table := ""
+	for p.NextExpression() {
+		expression := p.Expression()
+		switch expression.Kind {
+		case unstable.KeyValue:
+			value(join(table, expression.Key()), expression.Value())
+		case unstable.Table, unstable.ArrayTable:
+			table = join("", expression.Key())
+		}
+	}
+	return order, err
+}
+
+// tomlDuplicateKey 返回第一个重复定义的键或表的位置
+func tomlDuplicateKey(data []byte) (int, int, bool) {
+	var p unstable.Parser
+	defined := make(map[string]bool)
+	path := func(prefix string, keys unstable.Iterator) (string, *unstable.Node) {
+		var first *unstable.Node
+		for keys.Next() {
+			if first == nil {
+				first = keys.Node()
+			}
+			prefix += "\x00" + string(keys.Node().Data)
+		}
+		return prefix, first
+	}
+
+	p.Reset(data)
+	table := ""
+	for p.NextExpression() {
+		expression := p.Expression()
+		var key string
+		var node *unstable.Node
+		switch expression.Kind {
+		case unstable.KeyValue:
+			key, node = path(table, expression.Key())
+		case unstable.Table:
+			key, node = path("", expression.Key())
+			table = key
+		case unstable.ArrayTable:
+			// 表数组的每个元素重新开始定义
+			table, _ = path("", expression.Key())
+			for key := range defined {
+				if strings.HasPrefix(key, table+"\x00") {
+					delete(defined, key)
+				}
+			}
+			continue
+		default:
+			continue
+		}
+		if defined[key] {
+			start := p.Shape(node.Raw).Start
+			return start.Line, start.Column, true
+		}
+		defined[key] = true
+	}
+	return 0, 0, false
+}
+
+// tomlValue 把go-toml解析出的值转换为JSON值，path是值的键路径
+func tomlValue(value any, path string, order map[string]int) (any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		rank := func(key string) int {
+			if i, exists := order[path+"\x00"+key]; exists {
+				return i
+			}
+			return len(order)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			a, b := rank(keys[i]), rank(keys[j])
+			if a != b {
+				return a < b
+			}
+			return keys[i] < keys[j]
+		})
+
+		o := ordered.New()
+		for _, key := range keys {
+			converted, err := tomlValue(v[key], path+"\x00"+key, order)
+			if err != nil {
+				return nil, err
+			}
+			o.Set(key, converted)
+		}
+		return o, nil
+	case []any:
+		array := make([]any, len(v))
+		for i, child := range v {
+			converted, err := tomlValue(child, path, order)
+			if err != nil {
+				return nil, err
+			}
+			array[i] = converted
+		}
+		return array, nil
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10)), nil
+	case float64:
+		return json.Number(strconv.FormatFloat(v, 'g', -1, 64)), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case fmt.Stringer:
+		// LocalDate、LocalTime和LocalDateTime
+		return v.String(), nil
+	default:
+		return v, nil
+	}
+}
+
+// bareKeyPattern 匹配不需要引号的TOML键
+var bareKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// encodeTOML 把JSON对象输出为TOML
+//
+// 每个表中先输出普通的键值，再输出子表和表数组；null无法表示，返回ErrNotRepresentable
+func encodeTOML(value any) ([]byte, error) {
+	o, ok := value.(*ordered.Object)
+	if !ok {
+		return nil, fmt.Errorf("%w in TOML: the document must be an object", ErrNotRepresentable)
+	}
+
+	var buf bytes.Buffer
+	if err := writeTOMLTable(&buf, nil, o); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// isTOMLTable 判断值是否作为子表输出
+func isTOMLTable(value any) bool {
+	_, ok := value.(*ordered.Object)
+	return ok
+}
+
+// isTOMLArrayTable 判断值是否作为表数组输出
+func isTOMLArrayTable(value any) bool {
+	array, ok := value.([]any)
+	if !ok || len(array) == 0 {
+		return false
+	}
+	for _, child := range array {
+		if !isTOMLTable(child) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeTOMLTable 输出表的内容，path是表的键
+func writeTOMLTable(buf *bytes.Buffer, path []string, o *ordered.Object) error {
+	for _, key := range o.Keys() {
+		value := o.Value(key)
+		if isTOMLTable(value) || isTOMLArrayTable(value) {
+			continue
+		}
+		inline, err := tomlInline(value, append(path, key))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(buf, "%s = %s\n", tomlKey(key), inline)
+	}
+
+	for _, key := range o.Keys() {
+		value := o.Value(key)
+		childPath := append(append([]string{}, path...), key)
+		switch {
+		case isTOMLTable(value):
+			writeTOMLHeader(buf, "[%s]\n", childPath)
+			if err := writeTOMLTable(buf, childPath, value.(*ordered.Object)); err != nil {
+				return err
+			}
+		case isTOMLArrayTable(value):
+			for _, child := range value.([]any) {
+				writeTOMLHeader(buf, "[[%s]]\n", childPath)
+				if err := writeTOMLTable(buf, childPath, child.(*ordered.Object)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// writeTOMLHeader 输出表头，与前面的内容之间空一行
+func writeTOMLHeader(buf *bytes.Buffer, format string, path []string) {
+	if buf.Len() > 0 {
+		buf.WriteByte('\n')
+	}
+	keys := make([]string, len(path))
+	for i, key := range path {
+		keys[i] = tomlKey(key)
+	}
+	fmt.Fprintf(buf, format, strings.Join(keys, "."))
+}
+
+// tomlInline 返回值的行内写法
+func tomlInline(value any, path []string) (string, error) {
+	switch v := value.(type) {
+	case *ordered.Object:
+		parts := make([]string, 0, v.Len())
+		for _, key := range v.Keys() {
+			inline, err := tomlInline(v.Value(key), append(path, key))
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, tomlKey(key)+" = "+inline)
+		}
+		if len(parts) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(parts, ", ") + " }", nil
+	case []any:
+		parts := make([]string, 0, len(v))
+		for i, child := range v {
+			inline, err := tomlInline(child, append(path, strconv.Itoa(i)))
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, inline)
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	case string:
+		return tomlString(v), nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		return "", fmt.Errorf("%w in TOML: null at %s", ErrNotRepresentable, pointer(path))
+	}
+}
+
+// pointer 把键路径格式化为JSON Pointer
+func pointer(path []string) string {
+	var b strings.Builder
+	for _, key := range path {
+		b.WriteByte('/')
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1"))
+	}
+	return b.String()
+}
+
+// tomlKey 返回键的写法，不是裸键时加引号
+func tomlKey(key string) string {
+	if bareKeyPattern.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+// tomlString 返回TOML基本字符串
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package docformat
+
+import (
+	"errors"
+	"testing"
+)
+
+// 测试TOML转换为JSON时保留键的顺序，包括表数组中的子表
+func TestTOMLToJSON(t *testing.T) {
+	data := `
+title = "app"
+date = 2024-01-02
+ratio = 0.5
+
+[server]
+port = 8080
+host = "localhost"
+
+[[servers]]
+name = "a"
+weight = 1
+
+[servers.tls]
+enabled = true
+
+[[servers]]
+name = "b"
+`
+	out, err := ToJSON(TOML, []byte(data))
+	if err != nil {
+		t.Fatalf("Failed to convert TOML: %v", err)
+	}
+	want := `{"title":"app","date":"2024-01-02","ratio":0.5,"server":{"port":8080,"host":"localhost"},"servers":[{"name":"a","weight":1,"tls":{"enabled":true}},{"name":"b"}]}`
+	if string(out) != want {
+		t.Errorf("Expected %s, got %s", want, out)
+	}
+}
+
+// 测试TOML错误的位置
+func TestTOMLErrors(t *testing.T) {
+	tests := []struct {
+		data   string
+		line   int
+		column int
+	}{
+		{"a = 1\nb = [1,\nc = 3\n", 3, 1},
+		{"a = 1\n[t]\nb = -inf\n", 3, 5},
+		{"a = 1\na = 2\n", 2, 1},
+		{"[[s]]\na = 1\n[[s]]\na = 1\na = 2\n", 5, 1},
+		{"[t]\na = 1\n[u]\n[t]\n", 4, 2},
+	}
+	for _, test := range tests {
+		_, err := ToJSON(TOML, []byte(test.data))
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Expected *SyntaxError for %q, got %v", test.data, err)
+			continue
+		}
+		if syntaxErr.Line != test.line || syntaxErr.Column != test.column {
+			t.Errorf("Expected line %d, column %d for %q, got %v", test.line, test.column, test.data, err)
+		}
+	}
+}
+
+// 测试JSON转换为TOML，先输出普通键值，再输出子表和表数组
+func TestJSONToTOML(t *testing.T) {
+	data := `{"server":{"port":8080},"title":"a \"b\"","servers":[{"name":"a"},{"name":"b"}],"$id":"x","list":[1,"two",{"k":true}]}`
+	out, err := FromJSON(TOML, []byte(data))
+	if err != nil {
+		t.Fatalf("Failed to convert JSON: %v", err)
+	}
+	want := `title = "a \"b\""
+"$id" = "x"
+list = [1, "two", { k = true }]
+
+[server]
+port = 8080
+
+[[servers]]
+name = "a"
+
+[[servers]]
+name = "b"
+`
+	if string(out) != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, out)
+	}
+
+	if _, err := ToJSON(TOML, out); err != nil {
+		t.Errorf("Failed to parse generated TOML: %v", err)
+	}
+}
+
+// 测试TOML无法表示null和非对象的文档
+func TestTOMLNotRepresentable(t *testing.T) {
+	_, err := FromJSON(TOML, []byte(`{"a":{"b":[1,null]}}`))
+	if !errors.Is(err, ErrNotRepresentable) {
+		t.Fatalf("Expected ErrNotRepresentable, got %v", err)
+	}
+	if err.Error() != "value cannot be represented in TOML: null at /a/b/1" {
+		t.Errorf("Unexpected message: %v", err)
+	}
+
+	if _, err := FromJSON(TOML, []byte(`[1, 2]`)); !errors.Is(err, ErrNotRepresentable) {
+		t.Errorf("Expected ErrNotRepresentable for array document, got %v", err)
+	}
+}
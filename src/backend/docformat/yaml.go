@@ -0,0 +1,244 @@
+package docformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+
+	"goci/backend/internal/ordered"
+	"gopkg.in/yaml.v3"
+)
+
+// maxYAMLNodes 限制展开别名后的节点数，避免少量别名展开成巨大的文档
+const maxYAMLNodes = 1_000_000
+
+// yamlErrorPattern 匹配yaml.v3的语法错误，其中只有行号
+var yamlErrorPattern = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// decodeYAML 解析单个YAML文档
+func decodeYAML(data []byte) (any, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	var doc yaml.Node
+	if err := decoder.Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, &SyntaxError{Format: YAML, Line: 1, Msg: "empty document"}
+		}
+		return nil, yamlError(err)
+	}
+
+	var next yaml.Node
+	if err := decoder.Decode(&next); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return nil, yamlError(err)
+		}
+		return nil, &SyntaxError{Format: YAML, Line: next.Line, Column: next.Column, Msg: "expected a single document"}
+	}
+
+	c := &yamlConverter{}
+	return c.convert(&doc)
+}
+
+// yamlError 把yaml.v3的错误转换为*SyntaxError
+func yamlError(err error) error {
+	match := yamlErrorPattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return err
+	}
+	line, _ := strconv.Atoi(match[1])
+	return &SyntaxError{Format: YAML, Line: line, Msg: match[2]}
+}
+
+// yamlConverter 把YAML节点转换为JSON值
+type yamlConverter struct {
+	// nodes 是已经转换的节点数
+	nodes int
+}
+
+// errorAt 返回指向节点位置的错误
+func (c *yamlConverter) errorAt(node *yaml.Node, msg string) error {
+	return &SyntaxError{Format: YAML, Line: node.Line, Column: node.Column, Msg: msg}
+}
+
+// convert 转换一个节点
+func (c *yamlConverter) convert(node *yaml.Node) (any, error) {
+	c.nodes++
+	if c.nodes > maxYAMLNodes {
+		return nil, c.errorAt(node, "document is too large after expanding aliases")
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return c.convert(node.Content[0])
+	case yaml.AliasNode:
+		return c.convert(node.Alias)
+	case yaml.SequenceNode:
+		array := make([]any, 0, len(node.Content))
+		for _, child := range node.Content {
+			value, err := c.convert(child)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		return array, nil
+	case yaml.MappingNode:
+		o := ordered.New()
+		if err := c.mapping(o, node); err != nil {
+			return nil, err
+		}
+		return o, nil
+	case yaml.ScalarNode:
+		return c.scalar(node)
+	default:
+		return nil, c.errorAt(node, "unsupported YAML node")
+	}
+}
+
+// mapping 把映射的键值写入o，合并键（<<）引入的键不覆盖显式的键
+func (c *yamlConverter) mapping(o *ordered.Object, node *yaml.Node) error {
+	explicit := make(map[string]bool)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if key.Kind != yaml.ScalarNode {
+			return c.errorAt(key, "mapping keys must be scalars")
+		}
+		if key.ShortTag() == "!!merge" {
+			continue
+		}
+		if explicit[key.Value] {
+			return c.errorAt(key, "duplicate key "+strconv.Quote(key.Value))
+		}
+		explicit[key.Value] = true
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.ShortTag() != "!!merge" {
+			converted, err := c.convert(value)
+			if err != nil {
+				return err
+			}
+			o.Set(key.Value, converted)
+			continue
+		}
+
+		merged := value
+		if merged.Kind == yaml.AliasNode {
+			merged = merged.Alias
+		}
+		sources := []*yaml.Node{merged}
+		if merged.Kind == yaml.SequenceNode {
+			sources = merged.Content
+		}
+		for _, source := range sources {
+			if source.Kind == yaml.AliasNode {
+				source = source.Alias
+			}
+			if source.Kind != yaml.MappingNode {
+				return c.errorAt(value, "merge value must be a mapping or a list of mappings")
+			}
+			mergedObject := ordered.New()
+			if err := c.mapping(mergedObject, source); err != nil {
+				return err
+			}
+			for _, k := range mergedObject.Keys() {
+				if !explicit[k] && !o.Has(k) {
+					o.Set(k, mergedObject.Value(k))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// jsonNumberPattern 匹配JSON数字的语法
+var jsonNumberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// scalar 按标签转换标量，时间戳等JSON没有的类型保留为字符串
+//
+// 没有标签和引号的JSON数字保留原文，超出int64和float64范围的数字以及-0不会丢失
+func (c *yamlConverter) scalar(node *yaml.Node) (any, error) {
+	if node.Style == 0 && jsonNumberPattern.MatchString(node.Value) {
+		return json.Number(node.Value), nil
+	}
+	switch node.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err != nil {
+			return nil, c.errorAt(node, err.Error())
+		}
+		return b, nil
+	case "!!int":
+		var i int64
+		if err := node.Decode(&i); err == nil {
+			return json.Number(strconv.FormatInt(i, 10)), nil
+		}
+		var u uint64
+		if err := node.Decode(&u); err != nil {
+			return nil, c.errorAt(node, "integer "+node.Value+" is out of range")
+		}
+		return json.Number(strconv.FormatUint(u, 10)), nil
+	case "!!float":
+		var f float64
+		if err := node.Decode(&f); err != nil {
+			return nil, c.errorAt(node, err.Error())
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, c.errorAt(node, node.Value+" cannot be represented in JSON")
+		}
+		return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), nil
+	default:
+		return node.Value, nil
+	}
+}
+
+// encodeYAML 把JSON值输出为YAML
+func encodeYAML(value any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(yamlNode(value)); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// yamlNode 把JSON值转换为YAML节点，字符串使用!!str标签，看起来像其他类型时会加引号；数字输出为普通标量
+func yamlNode(value any) *yaml.Node {
+	switch v := value.(type) {
+	case *ordered.Object:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, key := range v.Keys() {
+			node.Content = append(node.Content, yamlNode(key), yamlNode(v.Value(key)))
+		}
+		return node
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, child := range v {
+			node.Content = append(node.Content, yamlNode(child))
+		}
+		return node
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	case json.Number:
+		// 不加标签，按原文输出，!!int和!!float标签无法表示超出范围的数字
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: v.String()}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v)}
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+}
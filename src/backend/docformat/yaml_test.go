@@ -0,0 +1,136 @@
+package docformat
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// 测试YAML转换为JSON时保留键的顺序和类型
+func TestYAMLToJSON(t *testing.T) {
+	data := `
+name: app
+port: 8080
+ratio: 0.5
+enabled: true
+version: "1.0"
+missing: ~
+tags: [a, b]
+nested:
+  z: 1
+  a: 2
+`
+	out, err := ToJSON(YAML, []byte(data))
+	if err != nil {
+		t.Fatalf("Failed to convert YAML: %v", err)
+	}
+	want := `{"name":"app","port":8080,"ratio":0.5,"enabled":true,"version":"1.0","missing":null,"tags":["a","b"],"nested":{"z":1,"a":2}}`
+	if string(out) != want {
+		t.Errorf("Expected %s, got %s", want, out)
+	}
+}
+
+// 测试合并键不覆盖显式的键
+func TestYAMLMergeKeys(t *testing.T) {
+	data := `
+base: &base
+  host: localhost
+  port: 80
+app:
+  <<: *base
+  port: 8080
+`
+	out, err := ToJSON(YAML, []byte(data))
+	if err != nil {
+		t.Fatalf("Failed to convert YAML: %v", err)
+	}
+	want := `{"base":{"host":"localhost","port":80},"app":{"host":"localhost","port":8080}}`
+	if string(out) != want {
+		t.Errorf("Expected %s, got %s", want, out)
+	}
+}
+
+// 测试YAML错误的位置
+func TestYAMLErrors(t *testing.T) {
+	tests := []struct {
+		data   string
+		line   int
+		column int
+	}{
+		{"a: 1\nb: 2\na: 3\n", 3, 1},
+		{"a: 1\nb:\n  c: .inf\n", 3, 6},
+		{"a: 1\n---\nb: 2\n", 2, 1},
+		{"a: [1, 2\nb: 3\n", 1, 0},
+		{"", 1, 0},
+	}
+	for _, test := range tests {
+		_, err := ToJSON(YAML, []byte(test.data))
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Expected *SyntaxError for %q, got %v", test.data, err)
+			continue
+		}
+		if syntaxErr.Line != test.line || syntaxErr.Column != test.column {
+			t.Errorf("Expected line %d, column %d for %q, got %v", test.line, test.column, test.data, err)
+		}
+	}
+}
+
+// 测试JSON转换为YAML，看起来像其他类型的字符串加引号
+func TestJSONToYAML(t *testing.T) {
+	out, err := FromJSON(YAML, []byte(`{"name":"app","port":8080,"version":"1.0","flag":"true","list":[1,null],"empty":{}}`))
+	if err != nil {
+		t.Fatalf("Failed to convert JSON: %v", err)
+	}
+	want := strings.Join([]string{
+		"name: app",
+		"port: 8080",
+		`version: "1.0"`,
+		`flag: "true"`,
+		"list:",
+		"  - 1",
+		"  - null",
+		"empty: {}",
+		"",
+	}, "\n")
+	if string(out) != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, out)
+	}
+
+	back, err := ToJSON(YAML, out)
+	if err != nil {
+		t.Fatalf("Failed to convert YAML: %v", err)
+	}
+	if string(back) != `{"name":"app","port":8080,"version":"1.0","flag":"true","list":[1,null],"empty":{}}` {
+		t.Errorf("Round trip changed the document: %s", back)
+	}
+}
+
+// 测试超出int64、uint64和float64范围的数字以及-0往返转换后保留原文
+func TestYAMLNumberRoundTrip(t *testing.T) {
+	doc := `{"big":123456789012345678901234567890,"huge":1e400,"neg":-123456789012345678901234567890,"zero":-0,"exact":0.10000000000000000001,"port":8080}`
+	out, err := FromJSON(YAML, []byte(doc))
+	if err != nil {
+		t.Fatalf("Failed to convert JSON: %v", err)
+	}
+	if strings.Contains(string(out), "!!") {
+		t.Errorf("Expected plain numbers, got:\n%s", out)
+	}
+
+	back, err := ToJSON(YAML, out)
+	if err != nil {
+		t.Fatalf("Failed to convert YAML: %v", err)
+	}
+	if string(back) != doc {
+		t.Errorf("Expected %s, got %s", doc, back)
+	}
+
+	// 带标签或引号的数字仍按标签转换
+	back, err = ToJSON(YAML, []byte("a: !!float 1\nb: \"1e400\"\nc: 0x1F\n"))
+	if err != nil {
+		t.Fatalf("Failed to convert YAML: %v", err)
+	}
+	if string(back) != `{"a":1,"b":"1e400","c":31}` {
+		t.Errorf("Unexpected conversion: %s", back)
+	}
+}
@@ -0,0 +1,318 @@
+// Package impact 分析Schema变更对已有配置的影响
+package impact
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// 变更类型
+const (
+	KindPropertyAdded       = "property.added"
+	KindPropertyRemoved     = "property.removed"
+	KindRequiredAdded       = "required.added"
+	KindRequiredRemoved     = "required.removed"
+	KindTypeChanged         = "type.changed"
+	KindConstraintTightened = "constraint.tightened"
+	KindConstraintLoosened  = "constraint.loosened"
+)
+
+// Change 是两个Schema版本之间的一处结构变化
+type Change struct {
+	// Pointer 是变化位置在Schema中的JSON Pointer，例如 /properties/server/properties/port
+	Pointer string `json:"pointer"`
+	Kind    string `json:"kind"`
+	// Keyword 是发生变化的关键字，例如minimum
+	Keyword string `json:"keyword,omitempty"`
+	Old     any    `json:"old,omitempty"`
+	New     any    `json:"new,omitempty"`
+	// Breaking 表示该变化可能使原本有效的配置变为无效
+	Breaking bool `json:"breaking"`
+}
+
+// 下限类关键字，值变大表示收紧
+var lowerBounds = []string{"minimum", "exclusiveMinimum", "minLength", "minItems", "minProperties"}
+
+// 上限类关键字，值变小表示收紧
+var upperBounds = []string{"maximum", "exclusiveMaximum", "maxLength", "maxItems", "maxProperties"}
+
+// 值变化即可能收紧的关键字
+var exactKeywords = []string{"pattern", "format", "const", "multipleOf", "uniqueItems"}
+
+// Diff 比较两个Schema，返回结构变化；oldSchema为空表示新建Schema
+func Diff(oldSchema, newSchema []byte) ([]Change, error) {
+	var oldValue, newValue any
+	if len(oldSchema) > 0 {
+		if err := json.Unmarshal(oldSchema, &oldValue); err != nil {
+			return nil, fmt.Errorf("error parsing previous schema: %w", err)
+		}
+	}
+	if err := json.Unmarshal(newSchema, &newValue); err != nil {
+		return nil, fmt.Errorf("error parsing schema: %w", err)
+	}
+
+	if oldValue == nil {
+		return []Change{}, nil
+	}
+
+	d := &differ{changes: []Change{}}
+	d.diff("", asMap(oldValue), asMap(newValue))
+	return d.changes, nil
+}
+
+// HasBreaking 判断变化中是否有可能破坏配置的变化
+func HasBreaking(changes []Change) bool {
+	for _, change := range changes {
+		if change.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// differ 收集变化
+type differ struct {
+	changes []Change
+}
+
+// add 记录一处变化
+func (d *differ) add(change Change) {
+	if change.Pointer == "" {
+		change.Pointer = "/"
+	}
+	d.changes = append(d.changes, change)
+}
+
+// diff 比较同一位置的两个子Schema
+func (d *differ) diff(pointer string, oldNode, newNode map[string]any) {
+	d.diffType(pointer, oldNode, newNode)
+	d.diffBounds(pointer, oldNode, newNode)
+	d.diffEnum(pointer, oldNode, newNode)
+	d.diffRequired(pointer, oldNode, newNode)
+	d.diffProperties(pointer, oldNode, newNode)
+
+	// additionalProperties从允许变为false
+	if newNode["additionalProperties"] == false && oldNode["additionalProperties"] != false {
+		d.add(Change{Pointer: pointer, Kind: KindConstraintTightened, Keyword: "additionalProperties", Old: oldNode["additionalProperties"], New: false, Breaking: true})
+	} else if oldNode["additionalProperties"] == false && newNode["additionalProperties"] != false {
+		d.add(Change{Pointer: pointer, Kind: KindConstraintLoosened, Keyword: "additionalProperties", Old: false, New: newNode["additionalProperties"]})
+	}
+
+	// 单一Schema形式的items
+	oldItems, oldOK := oldNode["items"].(map[string]any)
+	newItems, newOK := newNode["items"].(map[string]any)
+	if oldOK && newOK {
+		d.diff(pointer+"/items", oldItems, newItems)
+	}
+}
+
+// diffType 比较type，删除了允许的类型视为破坏性变化
+func (d *differ) diffType(pointer string, oldNode, newNode map[string]any) {
+	oldTypes, oldOK := typeSet(oldNode)
+	newTypes, newOK := typeSet(newNode)
+	if !oldOK && !newOK {
+		return
+	}
+	if reflect.DeepEqual(oldTypes, newTypes) && oldOK == newOK {
+		return
+	}
+
+	// 新增type限制，或删除了原来允许的类型
+	breaking := !oldOK && newOK
+	if oldOK && newOK {
+		for t := range oldTypes {
+			if !newTypes[t] && !(t == "integer" && newTypes["number"]) {
+				breaking = true
+			}
+		}
+	}
+	d.add(Change{Pointer: pointer, Kind: KindTypeChanged, Keyword: "type", Old: oldNode["type"], New: newNode["type"], Breaking: breaking})
+}
+
+// diffBounds 比较数值和长度约束以及pattern等关键字
+func (d *differ) diffBounds(pointer string, oldNode, newNode map[string]any) {
+	for _, keyword := range lowerBounds {
+		d.diffBound(pointer, keyword, oldNode, newNode, func(old, new float64) bool { return new > old })
+	}
+	for _, keyword := range upperBounds {
+		d.diffBound(pointer, keyword, oldNode, newNode, func(old, new float64) bool { return new < old })
+	}
+
+	for _, keyword := range exactKeywords {
+		oldValue, oldOK := oldNode[keyword]
+		newValue, newOK := newNode[keyword]
+		switch {
+		case newOK && (!oldOK || !reflect.DeepEqual(oldValue, newValue)):
+			// uniqueItems改为false是放宽
+			breaking := !(keyword == "uniqueItems" && newValue == false)
+			kind := KindConstraintTightened
+			if !breaking {
+				kind = KindConstraintLoosened
+			}
+			d.add(Change{Pointer: pointer, Kind: kind, Keyword: keyword, Old: oldValue, New: newValue, Breaking: breaking})
+		case oldOK && !newOK:
+			d.add(Change{Pointer: pointer, Kind: KindConstraintLoosened, Keyword: keyword, Old: oldValue})
+		}
+	}
+}
+
+// diffBound 比较一个数值约束，tighter判断新值是否比旧值更严格
+func (d *differ) diffBound(pointer, keyword string, oldNode, newNode map[string]any, tighter func(old, new float64) bool) {
+	oldValue, oldOK := oldNode[keyword].(float64)
+	newValue, newOK := newNode[keyword].(float64)
+
+	switch {
+	case newOK && (!oldOK || tighter(oldValue, newValue)):
+		change := Change{Pointer: pointer, Kind: KindConstraintTightened, Keyword: keyword, New: newValue, Breaking: true}
+		if oldOK {
+			change.Old = oldValue
+		}
+		d.add(change)
+	case oldOK && (!newOK || oldValue != newValue):
+		change := Change{Pointer: pointer, Kind: KindConstraintLoosened, Keyword: keyword, Old: oldValue}
+		if newOK {
+			change.New = newValue
+		}
+		d.add(change)
+	}
+}
+
+// diffEnum 比较enum，删除了允许的值视为破坏性变化
+func (d *differ) diffEnum(pointer string, oldNode, newNode map[string]any) {
+	oldEnum, oldOK := oldNode["enum"].([]any)
+	newEnum, newOK := newNode["enum"].([]any)
+	if !oldOK && !newOK {
+		return
+	}
+	if oldOK && newOK && reflect.DeepEqual(oldEnum, newEnum) {
+		return
+	}
+
+	breaking := newOK && !oldOK
+	if oldOK && newOK {
+		for _, value := range oldEnum {
+			if !containsValue(newEnum, value) {
+				breaking = true
+			}
+		}
+	}
+
+	kind := KindConstraintLoosened
+	if breaking {
+		kind = KindConstraintTightened
+	}
+	d.add(Change{Pointer: pointer, Kind: kind, Keyword: "enum", Old: oldNode["enum"], New: newNode["enum"], Breaking: breaking})
+}
+
+// diffRequired 比较required列表
+func (d *differ) diffRequired(pointer string, oldNode, newNode map[string]any) {
+	oldRequired := stringSet(oldNode["required"])
+	newRequired := stringSet(newNode["required"])
+	newProperties, _ := newNode["properties"].(map[string]any)
+
+	for _, name := range sortedKeys(newRequired) {
+		if !oldRequired[name] {
+			// 必填属性有默认值时，编辑器会自动填充，但已有配置仍然缺少该属性
+			change := Change{Pointer: pointer + "/properties/" + escape(name), Kind: KindRequiredAdded, Keyword: "required", Breaking: true}
+			if property, ok := newProperties[name].(map[string]any); ok {
+				change.New = property["default"]
+			}
+			d.add(change)
+		}
+	}
+	for _, name := range sortedKeys(oldRequired) {
+		if !newRequired[name] {
+			d.add(Change{Pointer: pointer + "/properties/" + escape(name), Kind: KindRequiredRemoved, Keyword: "required"})
+		}
+	}
+}
+
+// diffProperties 比较properties并递归比较共有的属性
+func (d *differ) diffProperties(pointer string, oldNode, newNode map[string]any) {
+	oldProperties, _ := oldNode["properties"].(map[string]any)
+	newProperties, _ := newNode["properties"].(map[string]any)
+
+	for _, name := range sortedKeys(newProperties) {
+		if _, exists := oldProperties[name]; !exists {
+			d.add(Change{Pointer: pointer + "/properties/" + escape(name), Kind: KindPropertyAdded, New: newProperties[name]})
+		}
+	}
+
+	for _, name := range sortedKeys(oldProperties) {
+		propertyPointer := pointer + "/properties/" + escape(name)
+		newProperty, exists := newProperties[name]
+		if !exists {
+			// 禁止额外属性时，原来带有该属性的配置会失效
+			d.add(Change{Pointer: propertyPointer, Kind: KindPropertyRemoved, Old: oldProperties[name], Breaking: newNode["additionalProperties"] == false})
+			continue
+		}
+		d.diff(propertyPointer, asMap(oldProperties[name]), asMap(newProperty))
+	}
+}
+
+// typeSet 返回type关键字允许的类型集合
+func typeSet(node map[string]any) (map[string]bool, bool) {
+	switch t := node["type"].(type) {
+	case string:
+		return map[string]bool{t: true}, true
+	case []any:
+		set := map[string]bool{}
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				set[s] = true
+			}
+		}
+		return set, true
+	}
+	return nil, false
+}
+
+// stringSet 把字符串数组转换为集合
+func stringSet(value any) map[string]bool {
+	set := map[string]bool{}
+	if array, ok := value.([]any); ok {
+		for _, item := range array {
+			if s, ok := item.(string); ok {
+				set[s] = true
+			}
+		}
+	}
+	return set
+}
+
+// sortedKeys 返回排序后的键，使结果稳定
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// containsValue 判断数组中是否有相同的JSON值
+func containsValue(array []any, value any) bool {
+	for _, item := range array {
+		if reflect.DeepEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// asMap 把子Schema转换为map，布尔Schema等视为空Schema
+func asMap(value any) map[string]any {
+	node, _ := value.(map[string]any)
+	if node == nil {
+		return map[string]any{}
+	}
+	return node
+}
+
+// escape 按JSON Pointer规则转义属性名
+func escape(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,75 @@
+package impact
+
+import (
+	"testing"
+)
+
+// 测试各类结构变化及其是否为破坏性变化
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		pointer  string
+		kind     string
+		keyword  string
+		breaking bool
+	}{
+		{"property added", `{"properties": {}}`, `{"properties": {"a": {"type": "string"}}}`, "/properties/a", KindPropertyAdded, "", false},
+		{"property removed", `{"properties": {"a": {}}}`, `{"properties": {}}`, "/properties/a", KindPropertyRemoved, "", false},
+		{"property removed without additional properties", `{"properties": {"a": {}}, "additionalProperties": false}`, `{"properties": {}, "additionalProperties": false}`, "/properties/a", KindPropertyRemoved, "", true},
+		{"required added", `{"properties": {"a": {}}}`, `{"properties": {"a": {}}, "required": ["a"]}`, "/properties/a", KindRequiredAdded, "required", true},
+		{"required removed", `{"required": ["a"]}`, `{}`, "/properties/a", KindRequiredRemoved, "required", false},
+		{"type narrowed", `{"type": ["string", "null"]}`, `{"type": "string"}`, "/", KindTypeChanged, "type", true},
+		{"type widened", `{"type": "integer"}`, `{"type": "number"}`, "/", KindTypeChanged, "type", false},
+		{"type added", `{}`, `{"type": "object"}`, "/", KindTypeChanged, "type", true},
+		{"minimum raised", `{"minimum": 1}`, `{"minimum": 5}`, "/", KindConstraintTightened, "minimum", true},
+		{"minimum lowered", `{"minimum": 5}`, `{"minimum": 1}`, "/", KindConstraintLoosened, "minimum", false},
+		{"max length added", `{}`, `{"maxLength": 10}`, "/", KindConstraintTightened, "maxLength", true},
+		{"max length removed", `{"maxLength": 10}`, `{}`, "/", KindConstraintLoosened, "maxLength", false},
+		{"pattern changed", `{"pattern": "^a"}`, `{"pattern": "^b"}`, "/", KindConstraintTightened, "pattern", true},
+		{"pattern removed", `{"pattern": "^a"}`, `{}`, "/", KindConstraintLoosened, "pattern", false},
+		{"enum value removed", `{"enum": ["a", "b"]}`, `{"enum": ["a"]}`, "/", KindConstraintTightened, "enum", true},
+		{"enum value added", `{"enum": ["a"]}`, `{"enum": ["a", "b"]}`, "/", KindConstraintLoosened, "enum", false},
+		{"additional properties disallowed", `{}`, `{"additionalProperties": false}`, "/", KindConstraintTightened, "additionalProperties", true},
+		{"nested property", `{"properties": {"server": {"properties": {"port": {"maximum": 100}}}}}`, `{"properties": {"server": {"properties": {"port": {"maximum": 10}}}}}`, "/properties/server/properties/port", KindConstraintTightened, "maximum", true},
+		{"array items", `{"items": {"type": "string"}}`, `{"items": {"type": "integer"}}`, "/items", KindTypeChanged, "type", true},
+		{"escaped name", `{"properties": {}}`, `{"properties": {"a/b": {}}}`, "/properties/a~1b", KindPropertyAdded, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := Diff([]byte(tt.old), []byte(tt.new))
+			if err != nil {
+				t.Fatalf("Failed to diff schemas: %v", err)
+			}
+
+			if len(changes) != 1 {
+				t.Fatalf("Expected one change, got %+v", changes)
+			}
+
+			change := changes[0]
+			if change.Pointer != tt.pointer || change.Kind != tt.kind || change.Keyword != tt.keyword || change.Breaking != tt.breaking {
+				t.Errorf("Unexpected change: %+v", change)
+			}
+		})
+	}
+}
+
+// 测试新建Schema和相同Schema没有变化
+func TestDiffNoChanges(t *testing.T) {
+	schema := []byte(`{"type": "object", "properties": {"a": {"type": "string", "minLength": 1}}, "required": ["a"]}`)
+
+	for name, old := range map[string][]byte{"new schema": nil, "same schema": schema} {
+		changes, err := Diff(old, schema)
+		if err != nil {
+			t.Fatalf("%s: Failed to diff schemas: %v", name, err)
+		}
+		if len(changes) != 0 || HasBreaking(changes) {
+			t.Errorf("%s: Expected no changes, got %+v", name, changes)
+		}
+	}
+
+	if _, err := Diff([]byte(`{`), schema); err == nil {
+		t.Errorf("Expected error for invalid previous schema")
+	}
+}
@@ -0,0 +1,66 @@
+package impact
+
+import (
+	"errors"
+
+	"goci/backend/storage"
+	"goci/backend/validation"
+)
+
+// ConfigImpact 是新Schema对一个已有配置的校验结果
+type ConfigImpact struct {
+	SchemaID   string                 `json:"schemaId"`
+	Valid      bool                   `json:"valid"`
+	Violations []validation.Violation `json:"violations,omitempty"`
+}
+
+// Report 是一次Schema变更的影响分析结果
+type Report struct {
+	Changes []Change `json:"changes"`
+	// Breaking 表示结构变化中有可能破坏配置的变化
+	Breaking bool           `json:"breaking"`
+	Configs  []ConfigImpact `json:"configs"`
+	// InvalidConfigs 是在新Schema下校验失败的配置数量
+	InvalidConfigs int `json:"invalidConfigs"`
+}
+
+// Analyze 比较Schema的前后两个版本，并用新Schema重新校验绑定的配置
+//
+// oldSchema为空表示新建Schema；configs为nil时不校验配置
+func Analyze(schemaID string, oldSchema, newSchema []byte, configs storage.ConfigStore) (Report, error) {
+	changes, err := Diff(oldSchema, newSchema)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{
+		Changes:  changes,
+		Breaking: HasBreaking(changes),
+		Configs:  []ConfigImpact{},
+	}
+	if configs == nil {
+		return report, nil
+	}
+
+	configData, _, err := configs.GetConfig(schemaID)
+	if errors.Is(err, storage.ErrConfigNotFound) {
+		return report, nil
+	}
+	if err != nil {
+		return Report{}, err
+	}
+
+	result := ConfigImpact{SchemaID: schemaID, Valid: true}
+	if err := validation.ValidateConfig(newSchema, configData); err != nil {
+		var validationErr *validation.Error
+		if !errors.As(err, &validationErr) {
+			return Report{}, err
+		}
+		result.Valid = false
+		result.Violations = validationErr.Violations
+		report.InvalidConfigs++
+	}
+	report.Configs = append(report.Configs, result)
+
+	return report, nil
+}
@@ -0,0 +1,49 @@
+package impact
+
+import (
+	"testing"
+
+	"goci/backend/storage"
+)
+
+// 测试用新Schema重新校验已有配置
+func TestAnalyze(t *testing.T) {
+	configs := storage.NewMemoryConfigStore()
+	oldSchema := []byte(`{"type": "object", "properties": {"port": {"type": "integer"}}}`)
+	newSchema := []byte(`{"type": "object", "properties": {"port": {"type": "integer", "maximum": 1024}}, "required": ["port"]}`)
+
+	// 没有配置时只比较结构
+	report, err := Analyze("app", oldSchema, newSchema, configs)
+	if err != nil {
+		t.Fatalf("Failed to analyze impact: %v", err)
+	}
+	if !report.Breaking || len(report.Changes) != 2 || len(report.Configs) != 0 || report.InvalidConfigs != 0 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	// 配置在新Schema下失效
+	configs.SaveConfig("app", []byte(`{"port": 8080}`))
+	report, err = Analyze("app", oldSchema, newSchema, configs)
+	if err != nil {
+		t.Fatalf("Failed to analyze impact: %v", err)
+	}
+	if report.InvalidConfigs != 1 || len(report.Configs) != 1 || report.Configs[0].Valid {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if violations := report.Configs[0].Violations; len(violations) != 1 || violations[0].Pointer != "/port" {
+		t.Errorf("Unexpected violations: %+v", violations)
+	}
+
+	// 配置仍然有效
+	configs.SaveConfig("app", []byte(`{"port": 80}`))
+	report, _ = Analyze("app", oldSchema, newSchema, configs)
+	if report.InvalidConfigs != 0 || !report.Configs[0].Valid {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	// 不传配置存储时不校验
+	report, _ = Analyze("app", oldSchema, newSchema, nil)
+	if len(report.Configs) != 0 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+}
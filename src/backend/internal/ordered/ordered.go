@@ -0,0 +1,186 @@
+// Package ordered 解析和输出保留对象键顺序的JSON
+//
+// encoding/json把对象解析为map，键的顺序会丢失；代码生成、引用打包和格式转换
+// 都需要按Schema或文档中的原始顺序输出，因此对象解析为*Object，数字保留为json.Number。
+package ordered
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// Object 是保留键顺序的JSON对象
+type Object struct {
+	keys   []string
+	values map[string]any
+}
+
+// New 创建一个空对象
+func New() *Object {
+	return &Object{values: make(map[string]any)}
+}
+
+// Keys 按原始顺序返回所有键
+func (o *Object) Keys() []string {
+	return o.keys
+}
+
+// Len 返回键的数量
+func (o *Object) Len() int {
+	return len(o.keys)
+}
+
+// Get 返回键对应的值
+func (o *Object) Get(key string) (any, bool) {
+	value, exists := o.values[key]
+	return value, exists
+}
+
+// Value 返回键对应的值，键不存在时为nil
+func (o *Object) Value(key string) any {
+	return o.values[key]
+}
+
+// Has 判断键是否存在
+func (o *Object) Has(key string) bool {
+	_, exists := o.values[key]
+	return exists
+}
+
+// Set 设置键的值，新键追加在末尾
+func (o *Object) Set(key string, value any) {
+	if !o.Has(key) {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// Delete 删除键，其余键的顺序不变
+func (o *Object) Delete(key string) {
+	if !o.Has(key) {
+		return
+	}
+	delete(o.values, key)
+	o.keys = slices.DeleteFunc(o.keys, func(k string) bool { return k == key })
+}
+
+// Rename 把键改名并保留其位置，新键已存在时返回false
+func (o *Object) Rename(key, newKey string) bool {
+	if !o.Has(key) || o.Has(newKey) {
+		return false
+	}
+	o.keys[slices.Index(o.keys, key)] = newKey
+	o.values[newKey] = o.values[key]
+	delete(o.values, key)
+	return true
+}
+
+// MarshalJSON 按原始顺序输出键
+func (o *Object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+
+		value, err := Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Marshal 输出紧凑的JSON，不转义HTML字符，使输出的内容与手写的一致
+func Marshal(value any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// MarshalIndent 同Marshal，输出带缩进的JSON
+func MarshalIndent(value any, prefix, indent string) ([]byte, error) {
+	data, err := Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, prefix, indent); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode 解析JSON，对象解析为*Object，数字保留为json.Number
+func Decode(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	value, err := decodeValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return value, nil
+}
+
+// decodeValue 读取一个JSON值
+func decodeValue(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		o := New()
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := decodeValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			o.Set(keyToken.(string), value)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return o, nil
+	case json.Delim('['):
+		array := []any{}
+		for decoder.More() {
+			value, err := decodeValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return array, nil
+	default:
+		return token, nil
+	}
+}
@@ -0,0 +1,83 @@
+package ordered
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// 测试解析和输出保留键的顺序、数字的写法，并且不转义HTML字符
+func TestRoundTrip(t *testing.T) {
+	data := `{"z":1,"a":{"y":1.50,"b":[true,null,"<tag>"]},"m":{}}`
+	value, err := Decode([]byte(data))
+	if err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+
+	out, err := Marshal(value)
+	if err != nil {
+		t.Fatalf("Failed to marshal JSON: %v", err)
+	}
+	if string(out) != data {
+		t.Errorf("Expected %s, got %s", data, out)
+	}
+
+	o := value.(*Object)
+	if keys := o.Keys(); len(keys) != 3 || keys[0] != "z" || keys[2] != "m" {
+		t.Errorf("Unexpected keys: %v", keys)
+	}
+	if number, ok := o.Get("z"); !ok || number != json.Number("1") {
+		t.Errorf("Expected json.Number 1, got %v", number)
+	}
+	if o.Value("missing") != nil || o.Has("missing") {
+		t.Errorf("Expected missing key to be absent")
+	}
+}
+
+// 测试Set覆盖已有的键时保持位置，新键追加在末尾
+func TestSet(t *testing.T) {
+	o := New()
+	o.Set("a", 1)
+	o.Set("b", 2)
+	o.Set("a", 3)
+
+	out, err := json.Marshal(o)
+	if err != nil {
+		t.Fatalf("Failed to marshal JSON: %v", err)
+	}
+	if string(out) != `{"a":3,"b":2}` || o.Len() != 2 {
+		t.Errorf("Unexpected object: %s", out)
+	}
+}
+
+// 测试Rename保持键的位置，Delete不影响其余键的顺序
+func TestRenameDelete(t *testing.T) {
+	value, err := Decode([]byte(`{"c":1,"a":2,"b":3}`))
+	if err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+	o := value.(*Object)
+
+	if !o.Rename("a", "x") {
+		t.Errorf("Expected rename to succeed")
+	}
+	if o.Rename("c", "b") || o.Rename("missing", "y") {
+		t.Errorf("Expected rename to fail for taken or missing keys")
+	}
+	o.Delete("c")
+	o.Delete("missing")
+
+	out, err := MarshalIndent(o, "", " ")
+	if err != nil {
+		t.Fatalf("Failed to marshal JSON: %v", err)
+	}
+	if string(out) != "{\n \"x\": 2,\n \"b\": 3\n}" {
+		t.Errorf("Unexpected object: %s", out)
+	}
+}
+
+// 测试拒绝JSON值之后的多余内容
+func TestDecodeTrailingData(t *testing.T) {
+	if _, err := Decode([]byte(`{} {}`)); err == nil {
+		t.Errorf("Expected error for trailing data")
+	}
+}
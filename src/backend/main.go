@@ -1,21 +1,51 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"path/filepath"
+	"time"
 
 	"goci/backend/api"
+	"goci/backend/audit"
+	"goci/backend/auth"
+	"goci/backend/defaults"
+	"goci/backend/refs"
+	"goci/backend/settings"
 	"goci/backend/storage"
+	"goci/backend/webui"
 
 	"github.com/gin-gonic/gin"
 )
 
-// corsMiddleware 创建一个CORS中间件，允许前端与后端API进行通信
-func corsMiddleware() gin.HandlerFunc {
+// corsMiddleware 创建一个CORS中间件，允许配置的前端来源与后端API进行通信
+func corsMiddleware(allowedOrigins []string) gin.HandlerFunc {
+	allowAny := false
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			allowAny = true
+		}
+		allowed[origin] = true
+	}
+
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Schema-Name, X-Schema-Description")
+		origin := c.GetHeader("Origin")
+		if allowAny {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		} else if allowed[origin] {
+			// 只回显允许的来源，此时才可以携带凭据
+			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+			c.Writer.Header().Add("Vary", "Origin")
+		}
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Schema-Name, X-Schema-Description, If-Match, If-None-Match, Last-Event-ID, X-Request-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag, X-Request-ID")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
@@ -27,22 +57,218 @@ func corsMiddleware() gin.HandlerFunc {
 	}
 }
 
+// configureLogging 根据日志级别设置Gin模式和默认日志
+func configureLogging(level string) {
+	var slogLevel slog.Level
+	if err := slogLevel.UnmarshalText([]byte(level)); err != nil {
+		slogLevel = slog.LevelInfo
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slogLevel})))
+
+	// 只有debug级别才使用Gin的调试模式
+	if level == "debug" {
+		gin.SetMode(gin.DebugMode)
+	} else {
+		gin.SetMode(gin.ReleaseMode)
+	}
+}
+
+// openStores 根据配置创建Schema和配置存储，返回的关闭函数用于释放资源
+func openStores(s settings.Settings) (storage.SchemaStore, storage.ConfigStore, func() error, error) {
+	if err := os.MkdirAll(s.DataDir, os.ModePerm); err != nil {
+		return nil, nil, nil, fmt.Errorf("error creating data directory: %w", err)
+	}
+
+	switch s.Storage {
+	case settings.StorageBolt:
+		store, err := storage.OpenBoltStore(filepath.Join(s.DataDir, "goci.db"))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return store.Schemas(), store.Configs(), store.Close, nil
+	default:
+		return storage.NewSchemaStorage(s.DataDir), storage.NewConfigStorage(s.DataDir), func() error { return nil }, nil
+	}
+}
+
+// authMiddleware 根据配置创建认证和授权中间件，没有配置认证方式时返回nil
+func authMiddleware(s settings.AuthSettings) (gin.HandlerFunc, error) {
+	if !s.Enabled() {
+		return nil, nil
+	}
+
+	// 角色授权的格式已在settings.Validate中检查
+	bindings := make(auth.Bindings, len(s.Users))
+	for user, roles := range s.Users {
+		bindings[user], _ = auth.ParseGrants(roles)
+	}
+
+	var authenticators []auth.Authenticator
+	if len(s.Tokens) > 0 {
+		tokens := make([]auth.Token, 0, len(s.Tokens))
+		for _, token := range s.Tokens {
+			grants, _ := auth.ParseGrants(token.Roles)
+			tokens = append(tokens, auth.Token{Name: token.Name, Token: token.Token, Grants: grants})
+		}
+		authenticator, err := auth.NewTokenAuthenticator(tokens)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, authenticator)
+	}
+	if s.Htpasswd != "" {
+		authenticator, err := auth.NewHtpasswdAuthenticator(s.Htpasswd, bindings)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, authenticator)
+	}
+	if s.JWT.Enabled() {
+		authenticator, err := auth.NewJWTAuthenticator(auth.JWTOptions{
+			Issuer:     s.JWT.Issuer,
+			JWKSURL:    s.JWT.JWKSURL,
+			Audience:   s.JWT.Audience,
+			RolesClaim: s.JWT.RolesClaim,
+			Bindings:   bindings,
+		})
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, authenticator)
+	}
+
+	anonymous, _ := auth.ParseGrants(s.Anonymous)
+	return auth.Middleware(authenticators, anonymous), nil
+}
+
+// trashPurgeInterval 是清理回收站的间隔
+const trashPurgeInterval = time.Hour
+
+// purgeTrash 每隔trashPurgeInterval永久删除回收站中超过保留时间的Schema
+func purgeTrash(store storage.SchemaStore, retention time.Duration) {
+	for {
+		purged, err := store.PurgeTrash(time.Now().Add(-retention))
+		if err != nil {
+			slog.Error("Failed to purge trash", "error", err)
+		}
+		if len(purged) > 0 {
+			slog.Info("Purged schemas from trash", "schemas", purged)
+		}
+		time.Sleep(trashPurgeInterval)
+	}
+}
+
+// checkStorage 在打开文件系统后端之前检查并修复数据目录，记录发现的每个问题
+func checkStorage(dataDir string) error {
+	report, err := storage.Fsck(dataDir, true)
+	for _, problem := range report.Problems {
+		slog.Warn("Repaired storage problem", "kind", problem.Kind, "path", problem.Path, "id", problem.ID, "detail", problem.Detail)
+	}
+	if report.Quarantine != "" {
+		slog.Warn("Moved unreadable files to quarantine", "dir", report.Quarantine)
+	}
+	return err
+}
+
 func main() {
+	// 子命令
+	if runCommand(os.Args[1:]) {
+		return
+	}
+
+	// 加载配置
+	cfg, err := settings.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Invalid settings: %v", err)
+	}
+
+	configureLogging(cfg.LogLevel)
+
 	// 创建Gin引擎
-	r := gin.Default()
+	r := gin.New()
+	r.Use(gin.Logger(), gin.Recovery())
 
 	// 添加CORS中间件
-	r.Use(corsMiddleware())
+	r.Use(corsMiddleware(cfg.AllowedOrigins))
+
+	// 认证和授权在所有API路由之前执行
+	authHandler, err := authMiddleware(cfg.Auth)
+	if err != nil {
+		log.Fatalf("Invalid authentication settings: %v", err)
+	}
+	if authHandler != nil {
+		r.Use(authHandler)
+	}
+
+	// 启动时修复损坏或不一致的注册表，避免丢失磁盘上的数据
+	if cfg.Storage != settings.StorageBolt {
+		if err := checkStorage(cfg.DataDir); err != nil {
+			log.Fatalf("Failed to check storage: %v", err)
+		}
+	}
 
 	// 创建存储服务
-	schemaStorage := storage.NewSchemaStorage()
+	schemaStore, configStore, closeStores, err := openStores(cfg)
+	if err != nil {
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+	defer closeStores()
+
+	// 引用必须能够解析，被引用的Schema不能删除
+	schemaStore = refs.WithReferenceCheck(schemaStore)
+
+	// 所有写操作都通过事件总线发布变更
+	bus := storage.NewEventBus(1000)
+	schemaStore = storage.WithSchemaEvents(schemaStore, bus)
+	configStore = storage.WithConfigEvents(configStore, bus)
+
+	// 审计记录API请求的写操作，处理器以请求的主体包装存储
+	auditLog, err := audit.Open(cfg.AuditLogPath())
+	if err != nil {
+		log.Fatalf("Failed to open audit log: %v", err)
+	}
+	defer auditLog.Close()
+	r.Use(audit.Middleware(auditLog))
+
+	// Schema第一次保存时生成默认配置，以system的名义记录审计
+	if cfg.DefaultConfigs {
+		schemaStore = defaults.WithDefaultConfigs(schemaStore, audit.WithConfigs(configStore, auditLog, audit.SystemActor))
+	}
+
+	// 定期清理回收站中超过保留时间的Schema
+	if cfg.TrashRetention > 0 {
+		go purgeTrash(audit.WithSchemas(schemaStore, auditLog, audit.SystemActor), cfg.TrashRetention)
+	}
 
 	// 注册API路由
-	api.RegisterRoutes(r, schemaStorage)
+	api.RegisterRoutes(r, schemaStore, configStore)
+	api.RegisterConfigRoutes(r, configStore, schemaStore)
+	api.RegisterEventRoutes(r, bus)
+	api.RegisterAuditRoutes(r, auditLog)
+
+	// 使用embedui构建时同时提供前端页面
+	if assets := webui.Assets(); assets != nil {
+		webui.Register(r, assets)
+	}
+
+	server := &http.Server{
+		Addr:         cfg.Listen,
+		Handler:      r,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+	}
 
 	// 启动服务器
-	log.Println("Starting server on :8080...")
-	if err := r.Run(":8080"); err != nil {
+	slog.Info("Starting server", "listen", cfg.Listen, "dataDir", cfg.DataDir, "storage", cfg.Storage, "tls", cfg.TLSEnabled(), "auth", cfg.Auth.Enabled())
+	if cfg.TLSEnabled() {
+		err = server.ListenAndServeTLS(cfg.TLSCert, cfg.TLSKey)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
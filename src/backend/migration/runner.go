@@ -0,0 +1,177 @@
+package migration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"goci/backend/internal/ordered"
+	"goci/backend/refs"
+	"goci/backend/storage"
+	"goci/backend/validation"
+)
+
+// 错误定义
+var (
+	// ErrUnknownVersion 表示配置没有记录对应的Schema版本，需要显式指定起始版本
+	ErrUnknownVersion = errors.New("config schema version is unknown")
+	// ErrInvalidRange 表示目标版本早于起始版本，不支持降级
+	ErrInvalidRange = errors.New("invalid migration range")
+	// ErrInvalidResult 表示迁移后的配置不符合目标版本的Schema，配置未被修改
+	ErrInvalidResult = errors.New("migrated config does not match schema")
+)
+
+// Options 控制一次迁移
+type Options struct {
+	// From 是配置当前对应的Schema版本，为0时使用配置记录的版本
+	From int
+	// To 是目标Schema版本，为0时使用Schema的当前版本
+	To int
+	// DryRun 为true时只计算结果，不保存
+	DryRun bool
+	// Resolve 解析目标版本引用的其他Schema，为nil时直接读取Schema存储
+	Resolve refs.Resolver
+}
+
+// VersionResult 是配置升级到某个版本后的内容
+type VersionResult struct {
+	Version int             `json:"version"`
+	Config  json.RawMessage `json:"config"`
+}
+
+// Result 是一次迁移的结果
+type Result struct {
+	SchemaID string `json:"schemaId"`
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+	// Versions 是逐个版本升级后的配置，按版本号升序
+	Versions []VersionResult `json:"versions"`
+	// Config 是迁移后的配置
+	Config json.RawMessage `json:"config"`
+	// Valid 表示迁移后的配置是否符合目标版本的Schema
+	Valid      bool                   `json:"valid"`
+	Violations []validation.Violation `json:"violations,omitempty"`
+	DryRun     bool                   `json:"dryRun"`
+	// Migrated 表示配置是否已被保存
+	Migrated bool `json:"migrated"`
+}
+
+// Runner 把保存的配置升级到Schema的新版本
+type Runner struct {
+	schemas storage.SchemaStore
+	configs storage.ConfigStore
+}
+
+// NewRunner 创建一个新的Runner实例
+func NewRunner(schemas storage.SchemaStore, configs storage.ConfigStore) *Runner {
+	return &Runner{schemas: schemas, configs: configs}
+}
+
+// Migrate 把schemaID的配置从opts.From逐个版本升级到opts.To
+//
+// 所有步骤都在内存中执行，迁移结果通过目标版本Schema的校验后才一次性保存；
+// 任何一步失败或校验不通过时，已保存的配置保持不变。
+// 保存时以开始时读取的内容为If-Match条件，配置在迁移期间被修改时返回storage.ErrPreconditionFailed
+func (r *Runner) Migrate(schemaID string, opts Options) (Result, error) {
+	configData, metadata, err := r.configs.GetConfig(schemaID)
+	if err != nil {
+		return Result{}, err
+	}
+
+	from := opts.From
+	if from == 0 {
+		from = metadata.SchemaVersion
+	}
+	if from == 0 {
+		return Result{}, fmt.Errorf("%w: %s", ErrUnknownVersion, schemaID)
+	}
+
+	to := opts.To
+	if to == 0 {
+		_, schemaMetadata, err := r.schemas.GetSchema(schemaID)
+		if err != nil {
+			return Result{}, err
+		}
+		to = schemaMetadata.Version
+	}
+	if to < from {
+		return Result{}, fmt.Errorf("%w: %s v%d to v%d", ErrInvalidRange, schemaID, from, to)
+	}
+
+	target, _, err := r.schemas.GetVersion(schemaID, to)
+	if err != nil {
+		return Result{}, err
+	}
+	// 目标版本引用的定义按其当前内容内联
+	resolve := opts.Resolve
+	if resolve == nil {
+		resolve = refs.FromStore(r.schemas)
+	}
+	target, err = refs.Bundle(resolve, schemaID, target)
+	if err != nil {
+		return Result{}, err
+	}
+
+	doc, err := decode(configData)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{
+		SchemaID: schemaID,
+		From:     from,
+		To:       to,
+		Versions: []VersionResult{},
+		Config:   configData,
+		DryRun:   opts.DryRun,
+	}
+
+	// 逐个版本应用迁移步骤
+	for next := from + 1; next <= to; next++ {
+		_, version, err := r.schemas.GetVersion(schemaID, next)
+		if err != nil {
+			return Result{}, err
+		}
+
+		if err := Apply(doc, version.Migration); err != nil {
+			return Result{}, fmt.Errorf("migrating %s to v%d: %w", schemaID, next, err)
+		}
+
+		data, err := ordered.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return Result{}, fmt.Errorf("error marshaling config: %w", err)
+		}
+		result.Versions = append(result.Versions, VersionResult{Version: next, Config: data})
+		result.Config = data
+	}
+
+	// 使用目标版本的Schema校验迁移结果
+	result.Valid = true
+	if err := validation.ValidateConfig(target, result.Config); err != nil {
+		var validationErr *validation.Error
+		if !errors.As(err, &validationErr) {
+			return Result{}, err
+		}
+		result.Valid = false
+		result.Violations = validationErr.Violations
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+	if !result.Valid {
+		return result, fmt.Errorf("%w: %s v%d", ErrInvalidResult, schemaID, to)
+	}
+
+	// 配置已是目标版本时不需要保存
+	if from == to && metadata.SchemaVersion == to {
+		return result, nil
+	}
+
+	if err := r.configs.SaveConfigIfMatch(schemaID, result.Config, to, storage.ConfigETag(configData)); err != nil {
+		return Result{}, err
+	}
+	result.Migrated = true
+
+	return result, nil
+}
@@ -0,0 +1,230 @@
+package migration
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"goci/backend/storage"
+)
+
+// 测试辅助函数：创建有三个版本的Schema
+//
+// v1: {host, port(string)}
+// v2: port转换为整数，host改名为hostname
+// v3: hostname和port移动到server下，新增必填的timeout
+func setupRunner(t *testing.T) (*Runner, *storage.MemorySchemaStore, *storage.MemoryConfigStore) {
+	schemas := storage.NewMemorySchemaStore()
+	configs := storage.NewMemoryConfigStore()
+
+	revisions := []struct {
+		schema string
+		steps  []storage.MigrationStep
+	}{
+		{
+			schema: `{"type": "object", "properties": {"host": {"type": "string"}, "port": {"type": "string"}}}`,
+		},
+		{
+			schema: `{"type": "object", "properties": {"hostname": {"type": "string"}, "port": {"type": "integer"}}}`,
+			steps: []storage.MigrationStep{
+				{Op: OpConvert, Path: "/port", Type: "integer"},
+				{Op: OpRename, Path: "/host", To: "hostname"},
+			},
+		},
+		{
+			schema: `{"type": "object", "properties": {"server": {"type": "object", "properties": {"hostname": {"type": "string"}, "port": {"type": "integer", "maximum": 65535}}}, "timeout": {"type": "integer"}}, "required": ["timeout"], "additionalProperties": false}`,
+			steps: []storage.MigrationStep{
+				{Op: OpMove, Path: "/hostname", To: "/server/hostname"},
+				{Op: OpMove, Path: "/port", To: "/server/port"},
+				{Op: OpSetDefault, Path: "/timeout", Value: json.RawMessage(`30`)},
+			},
+		},
+	}
+
+	for _, revision := range revisions {
+		info := storage.RevisionInfo{Migration: revision.steps}
+		if _, err := schemas.SaveSchemaRevision("app", "App", "", []byte(revision.schema), info); err != nil {
+			t.Fatalf("Failed to save schema: %v", err)
+		}
+	}
+
+	return NewRunner(schemas, configs), schemas, configs
+}
+
+// 测试逐个版本迁移配置
+func TestMigrate(t *testing.T) {
+	runner, _, configs := setupRunner(t)
+
+	original := `{"host": "localhost", "port": "8080"}`
+	if err := configs.SaveConfigVersion("app", []byte(original), 1); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	// dryRun返回每个版本的结果，不保存
+	result, err := runner.Migrate("app", Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("Failed to migrate config: %v", err)
+	}
+
+	if result.From != 1 || result.To != 3 || len(result.Versions) != 2 || !result.Valid || result.Migrated {
+		t.Fatalf("Unexpected result: %+v", result)
+	}
+
+	var v2 map[string]interface{}
+	json.Unmarshal(result.Versions[0].Config, &v2)
+	if v2["hostname"] != "localhost" || v2["port"] != float64(8080) {
+		t.Errorf("Unexpected v2 config: %s", result.Versions[0].Config)
+	}
+
+	data, metadata, _ := configs.GetConfig("app")
+	if string(data) != original || metadata.SchemaVersion != 1 {
+		t.Errorf("Dry run should not save: %s %+v", string(data), metadata)
+	}
+
+	// 实际迁移
+	result, err = runner.Migrate("app", Options{})
+	if err != nil {
+		t.Fatalf("Failed to migrate config: %v", err)
+	}
+	if !result.Migrated {
+		t.Errorf("Expected config to be migrated")
+	}
+
+	data, metadata, _ = configs.GetConfig("app")
+	var migrated struct {
+		Server struct {
+			Hostname string `json:"hostname"`
+			Port     int    `json:"port"`
+		} `json:"server"`
+		Timeout int `json:"timeout"`
+	}
+	if err := json.Unmarshal(data, &migrated); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	if migrated.Server.Hostname != "localhost" || migrated.Server.Port != 8080 || migrated.Timeout != 30 || metadata.SchemaVersion != 3 {
+		t.Errorf("Unexpected migrated config: %s %+v", string(data), metadata)
+	}
+
+	// 已是最新版本时不再保存
+	result, err = runner.Migrate("app", Options{})
+	if err != nil || result.Migrated || len(result.Versions) != 0 {
+		t.Errorf("Unexpected result: %+v, %v", result, err)
+	}
+}
+
+// 测试迁移失败时配置保持不变
+func TestMigrateIsTransactional(t *testing.T) {
+	runner, _, configs := setupRunner(t)
+
+	// 步骤失败
+	original := `{"host": "localhost", "port": "eighty"}`
+	configs.SaveConfigVersion("app", []byte(original), 1)
+
+	if _, err := runner.Migrate("app", Options{}); !errors.Is(err, ErrStepFailed) {
+		t.Errorf("Expected ErrStepFailed, got %v", err)
+	}
+
+	// 迁移结果不符合目标Schema
+	invalid := `{"host": "localhost", "port": "70000"}`
+	configs.SaveConfigVersion("app", []byte(invalid), 1)
+
+	result, err := runner.Migrate("app", Options{})
+	if !errors.Is(err, ErrInvalidResult) {
+		t.Fatalf("Expected ErrInvalidResult, got %v", err)
+	}
+	if result.Valid || len(result.Violations) != 1 || result.Violations[0].Pointer != "/server/port" {
+		t.Errorf("Unexpected violations: %+v", result.Violations)
+	}
+
+	data, metadata, _ := configs.GetConfig("app")
+	if string(data) != invalid || metadata.SchemaVersion != 1 {
+		t.Errorf("Config should not change: %s %+v", string(data), metadata)
+	}
+
+	// 只迁移到v2时结果有效
+	result, err = runner.Migrate("app", Options{To: 2})
+	if err != nil || !result.Migrated {
+		t.Errorf("Unexpected result: %+v, %v", result, err)
+	}
+	if _, metadata, _ := configs.GetConfig("app"); metadata.SchemaVersion != 2 {
+		t.Errorf("Expected schema version 2, got %d", metadata.SchemaVersion)
+	}
+}
+
+// racingConfigStore 在GetConfig返回后修改配置，模拟迁移期间通过界面的编辑
+type racingConfigStore struct {
+	storage.ConfigStore
+	edit []byte
+}
+
+// GetConfig 返回读取时的配置，然后写入edit
+func (s *racingConfigStore) GetConfig(schemaID string) ([]byte, storage.ConfigMetadata, error) {
+	data, metadata, err := s.ConfigStore.GetConfig(schemaID)
+	if err == nil && s.edit != nil {
+		if err := s.ConfigStore.SaveConfigVersion(schemaID, s.edit, metadata.SchemaVersion); err != nil {
+			return nil, storage.ConfigMetadata{}, err
+		}
+		s.edit = nil
+	}
+	return data, metadata, err
+}
+
+// 测试迁移期间配置被修改时不覆盖修改
+func TestMigrateConcurrentEdit(t *testing.T) {
+	_, schemas, configs := setupRunner(t)
+	configs.SaveConfigVersion("app", []byte(`{"host": "localhost", "port": "8080"}`), 1)
+
+	edited := `{"host": "example.com", "port": "8080"}`
+	runner := NewRunner(schemas, &racingConfigStore{ConfigStore: configs, edit: []byte(edited)})
+
+	result, err := runner.Migrate("app", Options{})
+	if !errors.Is(err, storage.ErrPreconditionFailed) {
+		t.Fatalf("Expected ErrPreconditionFailed, got %v", err)
+	}
+	if result.Migrated {
+		t.Errorf("Config should not be marked as migrated")
+	}
+
+	data, metadata, _ := configs.GetConfig("app")
+	if string(data) != edited || metadata.SchemaVersion != 1 {
+		t.Errorf("Concurrent edit was overwritten: %s %+v", string(data), metadata)
+	}
+
+	// 重新迁移时使用修改后的配置
+	result, err = runner.Migrate("app", Options{})
+	if err != nil || !result.Migrated {
+		t.Fatalf("Unexpected result: %+v, %v", result, err)
+	}
+	if data, _, _ := configs.GetConfig("app"); !strings.Contains(string(data), `"hostname": "example.com"`) {
+		t.Errorf("Expected edited config to be migrated, got %s", string(data))
+	}
+}
+
+// 测试无法确定迁移范围的情况
+func TestMigrateInvalidRange(t *testing.T) {
+	runner, _, configs := setupRunner(t)
+
+	if _, err := runner.Migrate("app", Options{}); !errors.Is(err, storage.ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound, got %v", err)
+	}
+
+	// 没有记录版本的配置需要指定起始版本
+	configs.SaveConfig("app", []byte(`{"hostname": "localhost", "port": 80}`))
+	if _, err := runner.Migrate("app", Options{}); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("Expected ErrUnknownVersion, got %v", err)
+	}
+
+	if _, err := runner.Migrate("app", Options{From: 3, To: 2}); !errors.Is(err, ErrInvalidRange) {
+		t.Errorf("Expected ErrInvalidRange, got %v", err)
+	}
+
+	if _, err := runner.Migrate("app", Options{From: 2, To: 7}); !errors.Is(err, storage.ErrVersionNotFound) {
+		t.Errorf("Expected ErrVersionNotFound, got %v", err)
+	}
+
+	result, err := runner.Migrate("app", Options{From: 2})
+	if err != nil || !result.Migrated || result.From != 2 || result.To != 3 {
+		t.Errorf("Unexpected result: %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,351 @@
+// Package migration 在Schema版本之间迁移配置
+//
+// Schema的每个版本可以携带一组声明式的迁移步骤，描述如何把上一个版本的配置升级到该版本。
+// 步骤中的路径是JSON Pointer，只能指向对象属性；要读取的属性不存在时步骤不做任何修改。
+package migration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"goci/backend/internal/ordered"
+	"goci/backend/storage"
+)
+
+// 支持的迁移操作
+const (
+	OpRename     = "rename"
+	OpMove       = "move"
+	OpSetDefault = "setDefault"
+	OpDelete     = "delete"
+	OpConvert    = "convert"
+)
+
+// 错误定义
+var (
+	// ErrInvalidStep 表示迁移步骤本身无效，例如未知的操作或路径格式错误
+	ErrInvalidStep = errors.New("invalid migration step")
+	// ErrStepFailed 表示迁移步骤无法应用到配置上，例如类型无法转换
+	ErrStepFailed = errors.New("migration step failed")
+)
+
+// convertTypes 是convert支持的目标类型
+var convertTypes = map[string]bool{
+	"string":  true,
+	"number":  true,
+	"integer": true,
+	"boolean": true,
+	"array":   true,
+}
+
+// Validate 检查迁移步骤是否有效，不需要配置即可完成
+func Validate(steps []storage.MigrationStep) error {
+	for i, step := range steps {
+		if reason := validateStep(step); reason != "" {
+			return fmt.Errorf("%w: step %d: %s", ErrInvalidStep, i+1, reason)
+		}
+	}
+	return nil
+}
+
+// validateStep 检查单个步骤，返回无效的原因，有效时返回空字符串
+func validateStep(step storage.MigrationStep) string {
+	path, err := parsePointer(step.Path)
+	if err != nil {
+		return err.Error()
+	}
+
+	switch step.Op {
+	case OpRename:
+		if step.To == "" {
+			return "rename requires the new property name in to"
+		}
+	case OpMove:
+		to, err := parsePointer(step.To)
+		if err != nil {
+			return "move target: " + err.Error()
+		}
+		if hasPrefix(to, path) {
+			return "cannot move a property into itself"
+		}
+	case OpSetDefault:
+		if len(step.Value) == 0 || !json.Valid(step.Value) {
+			return "setDefault requires a JSON value"
+		}
+	case OpDelete:
+	case OpConvert:
+		if !convertTypes[step.Type] {
+			return fmt.Sprintf("unsupported convert type %q", step.Type)
+		}
+	default:
+		return fmt.Sprintf("unknown op %q", step.Op)
+	}
+	return ""
+}
+
+// Apply 依次把迁移步骤应用到已解码的配置上，doc会被直接修改
+//
+// doc应使用ordered.Decode解码，以保留对象键的顺序和数字的原始精度；
+// 步骤只修改其路径指向的属性，其余内容和顺序保持不变，改名的属性保留原来的位置
+func Apply(doc interface{}, steps []storage.MigrationStep) error {
+	if err := Validate(steps); err != nil {
+		return err
+	}
+
+	for i, step := range steps {
+		if err := applyStep(doc, step); err != nil {
+			return fmt.Errorf("%w: step %d (%s %s): %s", ErrStepFailed, i+1, step.Op, step.Path, err.Error())
+		}
+	}
+	return nil
+}
+
+// applyStep 应用单个已校验的步骤
+func applyStep(doc interface{}, step storage.MigrationStep) error {
+	path, _ := parsePointer(step.Path)
+	parent, key, exists := lookup(doc, path)
+
+	switch step.Op {
+	case OpRename:
+		if !exists {
+			return nil
+		}
+		if !parent.Rename(key, step.To) {
+			return fmt.Errorf("property %q already exists", step.To)
+		}
+
+	case OpMove:
+		if !exists {
+			return nil
+		}
+		to, _ := parsePointer(step.To)
+		target, err := ensureParent(doc, to)
+		if err != nil {
+			return err
+		}
+		targetKey := to[len(to)-1]
+		if target.Has(targetKey) {
+			return fmt.Errorf("%s already exists", step.To)
+		}
+		target.Set(targetKey, parent.Value(key))
+		parent.Delete(key)
+
+	case OpSetDefault:
+		if exists {
+			return nil
+		}
+		target, err := ensureParent(doc, path)
+		if err != nil {
+			return err
+		}
+		value, err := decode(step.Value)
+		if err != nil {
+			return err
+		}
+		target.Set(path[len(path)-1], value)
+
+	case OpDelete:
+		if exists {
+			parent.Delete(key)
+		}
+
+	case OpConvert:
+		if !exists {
+			return nil
+		}
+		value, err := convert(parent.Value(key), step.Type)
+		if err != nil {
+			return err
+		}
+		parent.Set(key, value)
+	}
+
+	return nil
+}
+
+// convert 把值转换为目标类型，无法无损转换时返回错误
+func convert(value interface{}, typ string) (interface{}, error) {
+	switch typ {
+	case "string":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case json.Number:
+			return v.String(), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+
+	case "number":
+		switch v := value.(type) {
+		case json.Number:
+			return v, nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+				return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), nil
+			}
+		}
+
+	case "integer":
+		var text string
+		switch v := value.(type) {
+		case json.Number:
+			text = v.String()
+		case string:
+			text = strings.TrimSpace(v)
+		}
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return json.Number(strconv.FormatInt(i, 10)), nil
+		}
+		// 允许1.0这样没有小数部分的数字
+		if f, err := strconv.ParseFloat(text, 64); err == nil && f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return json.Number(strconv.FormatInt(int64(f), 10)), nil
+		}
+
+	case "boolean":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, nil
+			}
+		case json.Number:
+			// 只接受0和1
+			if b, err := strconv.ParseBool(v.String()); err == nil {
+				return b, nil
+			}
+		}
+
+	case "array":
+		if v, ok := value.([]interface{}); ok {
+			return v, nil
+		}
+		if value != nil {
+			return []interface{}{value}, nil
+		}
+	}
+
+	if text := describe(value); text != "" {
+		return nil, fmt.Errorf("cannot convert %s %s to %s", typeName(value), text, typ)
+	}
+	return nil, fmt.Errorf("cannot convert %s to %s", typeName(value), typ)
+}
+
+// typeName 返回值的JSON类型名
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// describe 返回值的简短JSON表示，用于错误信息
+func describe(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil || len(data) > 40 {
+		return ""
+	}
+	return string(data)
+}
+
+// parsePointer 把JSON Pointer拆分为属性名，不允许指向文档根
+func parsePointer(pointer string) ([]string, error) {
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("path %q must be a JSON Pointer to a property", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// hasPrefix 判断path是否等于prefix或位于其下
+func hasPrefix(path, prefix []string) bool {
+	if len(path) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup 查找路径指向的属性，返回其所在的对象和属性名
+func lookup(doc interface{}, path []string) (*ordered.Object, string, bool) {
+	current := doc
+	for _, token := range path[:len(path)-1] {
+		object, ok := current.(*ordered.Object)
+		if !ok {
+			return nil, "", false
+		}
+		current = object.Value(token)
+	}
+
+	parent, ok := current.(*ordered.Object)
+	if !ok {
+		return nil, "", false
+	}
+	key := path[len(path)-1]
+	return parent, key, parent.Has(key)
+}
+
+// ensureParent 返回路径指向的属性所在的对象，缺少的中间对象会被创建
+func ensureParent(doc interface{}, path []string) (*ordered.Object, error) {
+	parent, ok := doc.(*ordered.Object)
+	if !ok {
+		return nil, fmt.Errorf("config is not an object")
+	}
+
+	for i, token := range path[:len(path)-1] {
+		child, exists := parent.Get(token)
+		if !exists {
+			child = ordered.New()
+			parent.Set(token, child)
+		}
+		object, ok := child.(*ordered.Object)
+		if !ok {
+			return nil, fmt.Errorf("%s is not an object", formatPointer(path[:i+1]))
+		}
+		parent = object
+	}
+	return parent, nil
+}
+
+// formatPointer 把属性名拼接为JSON Pointer
+func formatPointer(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteString("/")
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+	}
+	return b.String()
+}
+
+// decode 解码JSON并保留对象键的顺序和数字精度
+func decode(data []byte) (interface{}, error) {
+	value, err := ordered.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing JSON: %w", err)
+	}
+	return value, nil
+}
@@ -0,0 +1,168 @@
+package migration
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"goci/backend/internal/ordered"
+	"goci/backend/storage"
+)
+
+// 测试辅助函数：应用步骤并返回压缩后的JSON
+func applyJSON(t *testing.T, config string, steps []storage.MigrationStep) (string, error) {
+	doc, err := decode([]byte(config))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	if err := Apply(doc, steps); err != nil {
+		return "", err
+	}
+
+	data, err := ordered.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Failed to marshal config: %v", err)
+	}
+	return string(data), nil
+}
+
+// 测试各种迁移操作
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		steps    []storage.MigrationStep
+		expected string
+	}{
+		{
+			name:     "rename",
+			config:   `{"host": "localhost", "port": 80}`,
+			steps:    []storage.MigrationStep{{Op: OpRename, Path: "/host", To: "hostname"}},
+			expected: `{"hostname":"localhost","port":80}`,
+		},
+		{
+			name:     "move into new object",
+			config:   `{"host": "localhost", "port": 80}`,
+			steps:    []storage.MigrationStep{{Op: OpMove, Path: "/host", To: "/server/host"}, {Op: OpMove, Path: "/port", To: "/server/port"}},
+			expected: `{"server":{"host":"localhost","port":80}}`,
+		},
+		{
+			name:     "set default",
+			config:   `{"debug": true}`,
+			steps:    []storage.MigrationStep{{Op: OpSetDefault, Path: "/debug", Value: json.RawMessage(`false`)}, {Op: OpSetDefault, Path: "/log/level", Value: json.RawMessage(`"info"`)}},
+			expected: `{"debug":true,"log":{"level":"info"}}`,
+		},
+		{
+			name:     "untouched keys keep their order",
+			config:   `{"zeta": 1, "host": "localhost", "alpha": {"b": 1, "a": "<x>"}, "legacy": true, "mid": 2}`,
+			steps:    []storage.MigrationStep{{Op: OpRename, Path: "/host", To: "hostname"}, {Op: OpDelete, Path: "/legacy"}},
+			expected: `{"zeta":1,"hostname":"localhost","alpha":{"b":1,"a":"<x>"},"mid":2}`,
+		},
+		{
+			name:     "delete",
+			config:   `{"legacy": {"a": 1}, "keep": 1}`,
+			steps:    []storage.MigrationStep{{Op: OpDelete, Path: "/legacy"}},
+			expected: `{"keep":1}`,
+		},
+		{
+			name:   "convert",
+			config: `{"port": "8080", "ratio": "0.5", "enabled": "true", "id": 42, "tags": "a", "count": 3.0}`,
+			steps: []storage.MigrationStep{
+				{Op: OpConvert, Path: "/port", Type: "integer"},
+				{Op: OpConvert, Path: "/ratio", Type: "number"},
+				{Op: OpConvert, Path: "/enabled", Type: "boolean"},
+				{Op: OpConvert, Path: "/id", Type: "string"},
+				{Op: OpConvert, Path: "/tags", Type: "array"},
+				{Op: OpConvert, Path: "/count", Type: "integer"},
+			},
+			expected: `{"port":8080,"ratio":0.5,"enabled":true,"id":"42","tags":["a"],"count":3}`,
+		},
+		{
+			name:     "missing path is a no-op",
+			config:   `{"a": 1}`,
+			steps:    []storage.MigrationStep{{Op: OpRename, Path: "/b", To: "c"}, {Op: OpConvert, Path: "/x/y", Type: "string"}, {Op: OpDelete, Path: "/a/b"}},
+			expected: `{"a":1}`,
+		},
+		{
+			name:     "escaped pointer",
+			config:   `{"a/b": 1}`,
+			steps:    []storage.MigrationStep{{Op: OpRename, Path: "/a~1b", To: "ab"}},
+			expected: `{"ab":1}`,
+		},
+		{
+			name:     "large integers keep precision",
+			config:   `{"id": 9007199254740993}`,
+			steps:    []storage.MigrationStep{{Op: OpConvert, Path: "/id", Type: "string"}},
+			expected: `{"id":"9007199254740993"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := applyJSON(t, tt.config, tt.steps)
+			if err != nil {
+				t.Fatalf("Failed to apply steps: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+// 测试无法应用的步骤
+func TestApplyFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		step   storage.MigrationStep
+	}{
+		{"rename onto existing", `{"a": 1, "b": 2}`, storage.MigrationStep{Op: OpRename, Path: "/a", To: "b"}},
+		{"move onto existing", `{"a": 1, "b": {"c": 2}}`, storage.MigrationStep{Op: OpMove, Path: "/a", To: "/b/c"}},
+		{"move below scalar", `{"a": 1, "b": 2}`, storage.MigrationStep{Op: OpMove, Path: "/a", To: "/b/c"}},
+		{"convert text to integer", `{"a": "eighty"}`, storage.MigrationStep{Op: OpConvert, Path: "/a", Type: "integer"}},
+		{"convert fraction to integer", `{"a": 1.5}`, storage.MigrationStep{Op: OpConvert, Path: "/a", Type: "integer"}},
+		{"convert object to string", `{"a": {}}`, storage.MigrationStep{Op: OpConvert, Path: "/a", Type: "string"}},
+		{"convert null to array", `{"a": null}`, storage.MigrationStep{Op: OpConvert, Path: "/a", Type: "array"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := applyJSON(t, tt.config, []storage.MigrationStep{tt.step}); !errors.Is(err, ErrStepFailed) {
+				t.Errorf("Expected ErrStepFailed, got %v", err)
+			}
+		})
+	}
+}
+
+// 测试校验迁移步骤
+func TestValidate(t *testing.T) {
+	valid := []storage.MigrationStep{
+		{Op: OpRename, Path: "/a", To: "b"},
+		{Op: OpMove, Path: "/a", To: "/b/a"},
+		{Op: OpSetDefault, Path: "/a", Value: json.RawMessage(`null`)},
+		{Op: OpDelete, Path: "/a"},
+		{Op: OpConvert, Path: "/a", Type: "boolean"},
+	}
+	if err := Validate(valid); err != nil {
+		t.Errorf("Expected valid steps, got %v", err)
+	}
+
+	invalid := map[string]storage.MigrationStep{
+		"unknown op":          {Op: "copy", Path: "/a"},
+		"path not a pointer":  {Op: OpDelete, Path: "a.b"},
+		"rename without name": {Op: OpRename, Path: "/a"},
+		"move into itself":    {Op: OpMove, Path: "/a", To: "/a/b"},
+		"move without target": {Op: OpMove, Path: "/a"},
+		"default not JSON":    {Op: OpSetDefault, Path: "/a", Value: json.RawMessage(`{`)},
+		"unknown type":        {Op: OpConvert, Path: "/a", Type: "date"},
+	}
+	for name, step := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if err := Validate([]storage.MigrationStep{step}); !errors.Is(err, ErrInvalidStep) {
+				t.Errorf("Expected ErrInvalidStep, got %v", err)
+			}
+		})
+	}
+}
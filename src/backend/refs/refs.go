@@ -0,0 +1,444 @@
+// Package refs 解析Schema之间的$ref引用，并把引用的内容打包进同一个文档
+//
+// 引用的目标是另一个存储的Schema，可以写成goci://defs/<id>，也可以写成相对引用<id>，
+// 两种形式都可以带#开头的JSON Pointer片段，例如goci://defs/address#/properties/street。
+// 以#开头的本地引用和其他URL不做处理。
+package refs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"goci/backend/internal/ordered"
+	"goci/backend/storage"
+)
+
+// DefsPrefix 是共享定义的绝对引用前缀
+const DefsPrefix = "goci://defs/"
+
+// ErrUnresolved 表示引用的Schema或片段不存在
+var ErrUnresolved = errors.New("unresolved reference")
+
+// ErrCycle 表示引用形成了环，无法内联
+var ErrCycle = errors.New("reference cycle")
+
+// Resolver 返回指定ID的Schema内容，不存在时返回storage.ErrSchemaNotFound
+type Resolver func(id string) ([]byte, error)
+
+// FromStore 返回从store读取Schema当前内容的Resolver
+func FromStore(store storage.SchemaStore) Resolver {
+	return func(id string) ([]byte, error) {
+		data, _, err := store.GetSchema(id)
+		return data, err
+	}
+}
+
+// Override 返回一个Resolver，id解析为schemaData，其他ID交给resolve，用于检查尚未保存的内容
+func Override(resolve Resolver, id string, schemaData []byte) Resolver {
+	return func(ref string) ([]byte, error) {
+		if ref == id {
+			return schemaData, nil
+		}
+		return resolve(ref)
+	}
+}
+
+// valueKeywords 的值是数据而不是Schema，其中的$ref不是引用
+var valueKeywords = map[string]bool{
+	"const":    true,
+	"enum":     true,
+	"default":  true,
+	"examples": true,
+}
+
+// parse 解析$ref，返回引用的Schema ID和片段，本地引用的ID为空；不是Schema引用时返回false
+func parse(ref string) (string, string, bool) {
+	path, fragment, _ := strings.Cut(ref, "#")
+	switch {
+	case path == "":
+		return "", fragment, true
+	case strings.HasPrefix(path, DefsPrefix):
+		return strings.TrimPrefix(path, DefsPrefix), fragment, true
+	case strings.Contains(path, ":"):
+		// 其他协议的URL，例如http://json-schema.org/draft-07/schema
+		return "", "", false
+	default:
+		return strings.TrimPrefix(path, "./"), fragment, true
+	}
+}
+
+// References 返回Schema直接引用的其他Schema ID，按ID排序
+func References(schemaData []byte) ([]string, error) {
+	root, err := ordered.Decode(schemaData)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	walkRefs(root, func(ref string) {
+		if id, _, ok := parse(ref); ok && id != "" {
+			seen[id] = true
+		}
+	})
+
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Rename 把指向ids中键的引用改为指向对应的值，保留引用的写法和片段
+//
+// 没有需要改写的引用时原样返回schemaData
+func Rename(schemaData []byte, ids map[string]string) ([]byte, error) {
+	root, err := ordered.Decode(schemaData)
+	if err != nil {
+		return nil, err
+	}
+
+	renamed := false
+	walkObjects(root, func(o *ordered.Object) {
+		ref, ok := o.Value("$ref").(string)
+		if !ok {
+			return
+		}
+		id, fragment, ok := parse(ref)
+		newID, exists := ids[id]
+		if !ok || id == "" || !exists {
+			return
+		}
+
+		prefix := ""
+		switch {
+		case strings.HasPrefix(ref, DefsPrefix):
+			prefix = DefsPrefix
+		case strings.HasPrefix(ref, "./"):
+			prefix = "./"
+		}
+		if strings.Contains(ref, "#") {
+			newID += "#" + fragment
+		}
+		o.Set("$ref", prefix+newID)
+		renamed = true
+	})
+
+	if !renamed {
+		return schemaData, nil
+	}
+	return json.Marshal(root)
+}
+
+// walkRefs 对文档中的每个$ref调用fn
+func walkRefs(node any, fn func(ref string)) {
+	walkObjects(node, func(o *ordered.Object) {
+		if ref, ok := o.Value("$ref").(string); ok {
+			fn(ref)
+		}
+	})
+}
+
+// walkObjects 对文档中的每个Schema对象调用fn，跳过valueKeywords中的数据
+func walkObjects(node any, fn func(o *ordered.Object)) {
+	switch value := node.(type) {
+	case *ordered.Object:
+		fn(value)
+		for _, key := range value.Keys() {
+			if !valueKeywords[key] {
+				walkObjects(value.Value(key), fn)
+			}
+		}
+	case []any:
+		for _, child := range value {
+			walkObjects(child, fn)
+		}
+	}
+}
+
+// Bundle 把Schema引用的其他Schema内联进文档，id是该Schema自己的ID
+//
+// 引用的文档放在根文档的definitions下，键为其ID（与已有定义重名时加后缀），指向它们的引用改写为本地引用，
+// 例如goci://defs/address#/properties/street改写为#/definitions/address/properties/street。
+// 内联的文档中的本地引用改写为指向其在definitions下的位置，因此文档内部的递归引用仍然有效。
+// 引用自身的$ref改写为本地引用，根文档的本地引用保持不变。没有引用其他Schema时原样返回schemaData。
+// 引用不存在时返回ErrUnresolved，文档之间的引用形成环时返回ErrCycle
+func Bundle(resolve Resolver, id string, schemaData []byte) ([]byte, error) {
+	refs, err := References(schemaData)
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) == 0 {
+		return schemaData, nil
+	}
+
+	root, err := ordered.Decode(schemaData)
+	if err != nil {
+		return nil, err
+	}
+	rootObject, ok := root.(*ordered.Object)
+	if !ok {
+		return nil, fmt.Errorf("cannot bundle references into a schema that is not an object")
+	}
+	definitions := ordered.New()
+	if value, exists := rootObject.Get("definitions"); exists {
+		if definitions, ok = value.(*ordered.Object); !ok {
+			return nil, fmt.Errorf("cannot bundle references: definitions is not an object")
+		}
+	}
+
+	b := &bundler{
+		resolve:     resolve,
+		rootID:      id,
+		docs:        map[string]any{id: root},
+		keys:        make(map[string]string),
+		reserved:    make(map[string]bool),
+		stack:       []string{id},
+		definitions: ordered.New(),
+	}
+	for _, key := range definitions.Keys() {
+		b.reserved[key] = true
+	}
+
+	walked, err := b.walk(rootObject, id)
+	if err != nil {
+		return nil, err
+	}
+	bundled := walked.(*ordered.Object)
+
+	// 内联的文档追加在已有的定义之后
+	merged, _ := bundled.Value("definitions").(*ordered.Object)
+	if merged == nil {
+		merged = ordered.New()
+	}
+	for _, key := range b.definitions.Keys() {
+		merged.Set(key, b.definitions.Value(key))
+	}
+	bundled.Set("definitions", merged)
+	return json.Marshal(bundled)
+}
+
+// bundler 保存一次打包的状态
+type bundler struct {
+	resolve Resolver
+	rootID  string
+	// docs 缓存已读取的文档
+	docs map[string]any
+	// keys 是已内联的文档在definitions下的键，reserved 是已被占用的键
+	keys     map[string]string
+	reserved map[string]bool
+	// stack 是正在内联的文档，用于发现文档之间的环
+	stack []string
+	// definitions 是内联的文档，按首次引用的顺序
+	definitions *ordered.Object
+}
+
+// walk 复制node并改写其中的引用，docID是node所在的文档
+func (b *bundler) walk(node any, docID string) (any, error) {
+	switch value := node.(type) {
+	case *ordered.Object:
+		if ref, ok := value.Value("$ref").(string); ok {
+			if id, fragment, ok := parse(ref); ok {
+				return b.rewrite(value, ref, id, fragment, docID)
+			}
+		}
+		return b.walkObject(value, docID, "")
+	case []any:
+		array := make([]any, len(value))
+		for i, child := range value {
+			walked, err := b.walk(child, docID)
+			if err != nil {
+				return nil, err
+			}
+			array[i] = walked
+		}
+		return array, nil
+	default:
+		return node, nil
+	}
+}
+
+// walkObject 复制对象并处理每个值，跳过名为skip的键
+func (b *bundler) walkObject(o *ordered.Object, docID string, skip string) (*ordered.Object, error) {
+	result := ordered.New()
+	for _, key := range o.Keys() {
+		if key == skip {
+			continue
+		}
+		if valueKeywords[key] {
+			result.Set(key, o.Value(key))
+			continue
+		}
+		walked, err := b.walk(o.Value(key), docID)
+		if err != nil {
+			return nil, err
+		}
+		result.Set(key, walked)
+	}
+	return result, nil
+}
+
+// rewrite 返回引用改写为本地引用的对象
+//
+// 指向其他文档的$ref之外还有其他关键字时，改写后的引用追加到allOf中
+func (b *bundler) rewrite(o *ordered.Object, ref string, id string, fragment string, docID string) (any, error) {
+	if id == "" {
+		id = docID
+	}
+
+	local, err := b.local(ref, id, fragment, docID)
+	if err != nil {
+		return nil, err
+	}
+
+	if id == docID || o.Len() == 1 {
+		result, err := b.walkObject(o, docID, "")
+		if err != nil {
+			return nil, err
+		}
+		result.Set("$ref", local)
+		return result, nil
+	}
+	result, err := b.walkObject(o, docID, "$ref")
+	if err != nil {
+		return nil, err
+	}
+	reference := ordered.New()
+	reference.Set("$ref", local)
+	allOf, _ := result.Value("allOf").([]any)
+	result.Set("allOf", append(allOf, reference))
+	return result, nil
+}
+
+// local 返回docID中指向id文档片段的引用在打包后的本地形式
+func (b *bundler) local(ref string, id string, fragment string, docID string) (string, error) {
+	// 根文档中指向自身的引用保持为本地引用
+	if id == b.rootID && docID == b.rootID {
+		return "#" + fragment, nil
+	}
+
+	if id != docID {
+		for i, entry := range b.stack {
+			if entry == id {
+				chain := append(append([]string{}, b.stack[i:]...), id)
+				return "", fmt.Errorf("%w: %s", ErrCycle, strings.Join(chain, " -> "))
+			}
+		}
+	}
+
+	doc, err := b.load(ref, id)
+	if err != nil {
+		return "", err
+	}
+	if fragment != "" {
+		if _, err := resolvePointer(doc, fragment); err != nil {
+			return "", fmt.Errorf("%w: %s: %v", ErrUnresolved, ref, err)
+		}
+	}
+
+	key, err := b.include(id, doc)
+	if err != nil {
+		return "", err
+	}
+	return "#/definitions/" + key + fragment, nil
+}
+
+// include 把文档内联到definitions下并返回其键，已内联的文档直接返回键
+//
+// 文档的$schema和$id被去掉，其余内容（包括自己的定义）保持原有结构
+func (b *bundler) include(id string, doc any) (string, error) {
+	if key, exists := b.keys[id]; exists {
+		return key, nil
+	}
+
+	key := id
+	for n := 2; b.reserved[key]; n++ {
+		key = fmt.Sprintf("%s-%d", id, n)
+	}
+	b.reserved[key] = true
+	b.keys[id] = key
+	// 先占位，保持首次引用的顺序
+	b.definitions.Set(key, nil)
+
+	if o, ok := doc.(*ordered.Object); ok {
+		trimmed := ordered.New()
+		for _, k := range o.Keys() {
+			if k != "$schema" && k != "$id" {
+				trimmed.Set(k, o.Value(k))
+			}
+		}
+		doc = trimmed
+	}
+
+	b.stack = append(b.stack, id)
+	walked, err := b.walk(doc, id)
+	b.stack = b.stack[:len(b.stack)-1]
+	if err != nil {
+		return "", err
+	}
+	b.definitions.Set(key, walked)
+	return key, nil
+}
+
+// load 读取并缓存文档
+func (b *bundler) load(ref string, id string) (any, error) {
+	if doc, exists := b.docs[id]; exists {
+		return doc, nil
+	}
+
+	if err := storage.ValidateID(id); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnresolved, ref)
+	}
+	data, err := b.resolve(id)
+	if errors.Is(err, storage.ErrSchemaNotFound) {
+		return nil, fmt.Errorf("%w: %s: schema %s does not exist", ErrUnresolved, ref, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := ordered.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing schema %s: %w", id, err)
+	}
+	b.docs[id] = doc
+	return doc, nil
+}
+
+// resolvePointer 按JSON Pointer片段查找值，片段可以是URL编码的
+func resolvePointer(doc any, fragment string) (any, error) {
+	pointer, err := url.PathUnescape(fragment)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("fragment must be a JSON Pointer")
+	}
+
+	node := doc
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch value := node.(type) {
+		case *ordered.Object:
+			child, exists := value.Get(token)
+			if !exists {
+				return nil, fmt.Errorf("%s not found", pointer)
+			}
+			node = child
+		case []any:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(value) {
+				return nil, fmt.Errorf("%s not found", pointer)
+			}
+			node = value[index]
+		default:
+			return nil, fmt.Errorf("%s not found", pointer)
+		}
+	}
+	return node, nil
+}
@@ -0,0 +1,164 @@
+package refs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"goci/backend/storage"
+)
+
+// mapResolver 从map中读取Schema
+func mapResolver(schemas map[string]string) Resolver {
+	return func(id string) ([]byte, error) {
+		data, exists := schemas[id]
+		if !exists {
+			return nil, fmt.Errorf("%w: %s", storage.ErrSchemaNotFound, id)
+		}
+		return []byte(data), nil
+	}
+}
+
+// 测试列出直接引用的Schema
+func TestReferences(t *testing.T) {
+	schema := `{
+		"properties": {
+			"home": {"$ref": "goci://defs/address"},
+			"work": {"$ref": "address#/properties/street"},
+			"tls": {"$ref": "./tls"},
+			"local": {"$ref": "#/definitions/x"},
+			"meta": {"$ref": "http://json-schema.org/draft-07/schema#"},
+			"data": {"const": {"$ref": "not-a-reference"}}
+		}
+	}`
+
+	refs, err := References([]byte(schema))
+	if err != nil {
+		t.Fatalf("Failed to list references: %v", err)
+	}
+	if fmt.Sprint(refs) != "[address tls]" {
+		t.Errorf("Unexpected references: %v", refs)
+	}
+}
+
+// 测试内联引用
+func TestBundle(t *testing.T) {
+	resolve := mapResolver(map[string]string{
+		"address": `{
+			"$schema": "http://json-schema.org/draft-07/schema#",
+			"type": "object",
+			"properties": {"street": {"$ref": "#/definitions/text"}, "city": {"type": "string"}},
+			"definitions": {"text": {"type": "string", "minLength": 1}}
+		}`,
+		"retry": `{"type": "integer", "minimum": 0}`,
+		"tree":  `{"$id": "https://example.com/tree", "properties": {"children": {"type": "array", "items": {"$ref": "#"}}}}`,
+		"list":  `{"definitions": {"node": {"properties": {"next": {"$ref": "#/definitions/node"}}}}}`,
+	})
+
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{
+			"no references",
+			`{"type": "object",  "properties": {"a": {"$ref": "#/definitions/a"}}}`,
+			`{"type": "object",  "properties": {"a": {"$ref": "#/definitions/a"}}}`,
+		},
+		{
+			"whole document",
+			`{"type": "object", "properties": {"home": {"$ref": "goci://defs/address"}, "retries": {"$ref": "retry"}}}`,
+			`{"type":"object","properties":{"home":{"$ref":"#/definitions/address"},"retries":{"$ref":"#/definitions/retry"}},"definitions":{"address":{"type":"object","properties":{"street":{"$ref":"#/definitions/address/definitions/text"},"city":{"type":"string"}},"definitions":{"text":{"type":"string","minLength":1}}},"retry":{"type":"integer","minimum":0}}}`,
+		},
+		{
+			"fragment",
+			`{"properties": {"street": {"$ref": "address#/properties/street"}}}`,
+			`{"properties":{"street":{"$ref":"#/definitions/address/properties/street"}},"definitions":{"address":{"type":"object","properties":{"street":{"$ref":"#/definitions/address/definitions/text"},"city":{"type":"string"}},"definitions":{"text":{"type":"string","minLength":1}}}}}`,
+		},
+		{
+			"sibling keywords",
+			`{"properties": {"retries": {"$ref": "retry", "description": "Attempts", "allOf": [{"maximum": 5}]}}}`,
+			`{"properties":{"retries":{"description":"Attempts","allOf":[{"maximum":5},{"$ref":"#/definitions/retry"}]}},"definitions":{"retry":{"type":"integer","minimum":0}}}`,
+		},
+		{
+			"self reference",
+			`{"definitions": {"r": {"$ref": "retry"}}, "properties": {"a": {"$ref": "app#/definitions/r"}}}`,
+			`{"definitions":{"r":{"$ref":"#/definitions/retry"},"retry":{"type":"integer","minimum":0}},"properties":{"a":{"$ref":"#/definitions/r"}}}`,
+		},
+		{
+			"name clash with local definition",
+			`{"definitions": {"retry": {"type": "string"}}, "properties": {"a": {"$ref": "retry"}}}`,
+			`{"definitions":{"retry":{"type":"string"},"retry-2":{"type":"integer","minimum":0}},"properties":{"a":{"$ref":"#/definitions/retry-2"}}}`,
+		},
+		{
+			"recursive definitions",
+			`{"properties": {"tree": {"$ref": "tree"}, "list": {"$ref": "goci://defs/list#/definitions/node"}}}`,
+			`{"properties":{"tree":{"$ref":"#/definitions/tree"},"list":{"$ref":"#/definitions/list/definitions/node"}},"definitions":{"tree":{"properties":{"children":{"type":"array","items":{"$ref":"#/definitions/tree"}}}},"list":{"definitions":{"node":{"properties":{"next":{"$ref":"#/definitions/list/definitions/node"}}}}}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundled, err := Bundle(resolve, "app", []byte(tt.schema))
+			if err != nil {
+				t.Fatalf("Failed to bundle: %v", err)
+			}
+			if string(bundled) != tt.want {
+				t.Errorf("Unexpected bundle:\ngot  %s\nwant %s", bundled, tt.want)
+			}
+		})
+	}
+}
+
+// 测试无法解析的引用和文档之间的环
+func TestBundleErrors(t *testing.T) {
+	resolve := mapResolver(map[string]string{
+		"a":      `{"properties": {"b": {"$ref": "b"}}}`,
+		"b":      `{"properties": {"a": {"$ref": "goci://defs/a"}}}`,
+		"broken": `{"properties": {"x": {"$ref": "#/definitions/missing"}}}`,
+	})
+
+	tests := []struct {
+		name   string
+		schema string
+		want   error
+	}{
+		{"missing schema", `{"$ref": "missing"}`, ErrUnresolved},
+		{"missing fragment", `{"$ref": "a#/properties/c"}`, ErrUnresolved},
+		{"invalid ID", `{"$ref": "../etc/passwd"}`, ErrUnresolved},
+		{"cycle between documents", `{"$ref": "a"}`, ErrCycle},
+		{"missing local reference", `{"$ref": "broken"}`, ErrUnresolved},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Bundle(resolve, "root", []byte(tt.schema)); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// 新内容引用了引用自己的Schema
+	override := Override(resolve, "a", []byte(`{"$ref": "b"}`))
+	if _, err := Bundle(override, "a", []byte(`{"$ref": "b"}`)); !errors.Is(err, ErrCycle) {
+		t.Errorf("Expected ErrCycle, got %v", err)
+	}
+}
+
+// 测试改写引用的Schema ID
+func TestRename(t *testing.T) {
+	schema := `{"properties": {"a": {"$ref": "goci://defs/address#/properties/street"}, "b": {"$ref": "./address"}, "c": {"$ref": "tls"}, "d": {"const": {"$ref": "address"}}}}`
+
+	renamed, err := Rename([]byte(schema), map[string]string{"address": "address-2"})
+	if err != nil {
+		t.Fatalf("Failed to rename references: %v", err)
+	}
+	expected := `{"properties":{"a":{"$ref":"goci://defs/address-2#/properties/street"},"b":{"$ref":"./address-2"},"c":{"$ref":"tls"},"d":{"const":{"$ref":"address"}}}}`
+	if string(renamed) != expected {
+		t.Errorf("Expected %s, got %s", expected, renamed)
+	}
+
+	// 没有需要改写的引用时原样返回
+	unchanged, err := Rename([]byte(schema), map[string]string{"other": "other-2"})
+	if err != nil || string(unchanged) != schema {
+		t.Errorf("Expected unchanged schema, got %s, %v", unchanged, err)
+	}
+}
@@ -0,0 +1,159 @@
+package refs
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"goci/backend/storage"
+)
+
+// ErrReferenced 表示Schema仍被其他Schema引用，不能删除
+var ErrReferenced = errors.New("schema is referenced")
+
+// ReferencedError 是删除仍被引用的Schema时返回的错误，errors.Is(err, ErrReferenced)为true
+type ReferencedError struct {
+	ID string
+	// Referrers 是列出的引用方，按ID排序
+	Referrers []string
+	// Hidden 表示还有没有列出的引用方
+	Hidden bool
+}
+
+// Error 返回列出引用方的错误信息
+func (e *ReferencedError) Error() string {
+	referrers := strings.Join(e.Referrers, ", ")
+	switch {
+	case e.Hidden && referrers == "":
+		referrers = "other schemas"
+	case e.Hidden:
+		referrers += " and other schemas"
+	}
+	return fmt.Sprintf("%s: %s is referenced by %s", ErrReferenced, e.ID, referrers)
+}
+
+// Unwrap 返回ErrReferenced
+func (e *ReferencedError) Unwrap() error {
+	return ErrReferenced
+}
+
+// Visible 返回只列出visible为true的引用方的错误，其余引用方只表示为other schemas
+func (e *ReferencedError) Visible(visible func(id string) bool) *ReferencedError {
+	filtered := &ReferencedError{ID: e.ID, Referrers: []string{}, Hidden: e.Hidden}
+	for _, id := range e.Referrers {
+		if visible(id) {
+			filtered.Referrers = append(filtered.Referrers, id)
+		} else {
+			filtered.Hidden = true
+		}
+	}
+	return filtered
+}
+
+// Referrers 返回直接引用了id的其他Schema，按ID排序
+func Referrers(store storage.SchemaStore, id string) ([]string, error) {
+	schemas, err := store.ListSchemas()
+	if err != nil {
+		return nil, err
+	}
+
+	referrers := []string{}
+	for _, schema := range schemas {
+		if schema.ID == id {
+			continue
+		}
+		data, _, err := store.GetSchema(schema.ID)
+		if errors.Is(err, storage.ErrSchemaNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		refs, err := References(data)
+		if err != nil {
+			// 无法解析的Schema不会引用任何Schema
+			continue
+		}
+		for _, ref := range refs {
+			if ref == id {
+				referrers = append(referrers, schema.ID)
+				break
+			}
+		}
+	}
+	// ListSchemas不保证顺序
+	sort.Strings(referrers)
+	return referrers, nil
+}
+
+// BundleStored 读取并打包store中的Schema，同时返回其元数据
+func BundleStored(store storage.SchemaStore, id string) ([]byte, storage.SchemaMetadata, error) {
+	data, metadata, err := store.GetSchema(id)
+	if err != nil {
+		return nil, metadata, err
+	}
+	bundled, err := Bundle(FromStore(store), id, data)
+	return bundled, metadata, err
+}
+
+// checkedStore 保证Schema之间的引用始终可以解析
+type checkedStore struct {
+	storage.SchemaStore
+}
+
+// WithReferenceCheck 包装一个SchemaStore，拒绝保存引用不存在或形成环的Schema，
+// 也拒绝删除仍被其他Schema引用的Schema
+func WithReferenceCheck(store storage.SchemaStore) storage.SchemaStore {
+	return &checkedStore{SchemaStore: store}
+}
+
+// check 检查id的内容为schemaData时能否打包
+func (s *checkedStore) check(id string, schemaData []byte) error {
+	_, err := Bundle(Override(FromStore(s.SchemaStore), id, schemaData), id, schemaData)
+	return err
+}
+
+// SaveSchema 检查引用后保存Schema
+func (s *checkedStore) SaveSchema(id string, name string, description string, schemaData []byte) error {
+	_, err := s.SaveSchemaRevision(id, name, description, schemaData, storage.RevisionInfo{})
+	return err
+}
+
+// SaveSchemaRevision 检查引用后保存Schema
+func (s *checkedStore) SaveSchemaRevision(id string, name string, description string, schemaData []byte, info storage.RevisionInfo) (storage.SchemaVersion, error) {
+	if err := s.check(id, schemaData); err != nil {
+		return storage.SchemaVersion{}, err
+	}
+	return s.SchemaStore.SaveSchemaRevision(id, name, description, schemaData, info)
+}
+
+// RestoreVersion 检查旧版本的引用后恢复
+func (s *checkedStore) RestoreVersion(id string, version int, info storage.RevisionInfo) (storage.SchemaVersion, error) {
+	data, _, err := s.SchemaStore.GetVersion(id, version)
+	if err != nil {
+		return storage.SchemaVersion{}, err
+	}
+	if err := s.check(id, data); err != nil {
+		return storage.SchemaVersion{}, err
+	}
+	return s.SchemaStore.RestoreVersion(id, version, info)
+}
+
+// DeleteSchema 在Schema没有被引用时删除
+func (s *checkedStore) DeleteSchema(id string) error {
+	return s.DeleteSchemaIfMatch(id, "")
+}
+
+// DeleteSchemaIfMatch 在Schema没有被引用时删除
+func (s *checkedStore) DeleteSchemaIfMatch(id string, ifMatch string) error {
+	referrers, err := Referrers(s.SchemaStore, id)
+	if err != nil {
+		return err
+	}
+	if len(referrers) > 0 {
+		return &ReferencedError{ID: id, Referrers: referrers}
+	}
+	return s.SchemaStore.DeleteSchemaIfMatch(id, ifMatch)
+}
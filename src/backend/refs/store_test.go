@@ -0,0 +1,83 @@
+package refs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"goci/backend/storage"
+)
+
+// 测试保存和删除时检查引用
+func TestWithReferenceCheck(t *testing.T) {
+	store := WithReferenceCheck(storage.NewMemorySchemaStore())
+
+	if err := store.SaveSchema("app", "App", "", []byte(`{"properties": {"tls": {"$ref": "goci://defs/tls"}}}`)); !errors.Is(err, ErrUnresolved) {
+		t.Errorf("Expected ErrUnresolved, got %v", err)
+	}
+
+	if err := store.SaveSchema("tls", "TLS", "", []byte(`{"type": "object"}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	for _, id := range []string{"app", "worker"} {
+		if err := store.SaveSchema(id, id, "", []byte(`{"properties": {"tls": {"$ref": "goci://defs/tls"}}}`)); err != nil {
+			t.Fatalf("Failed to save schema: %v", err)
+		}
+	}
+
+	// 被引用的定义不能再引用引用它的Schema
+	if err := store.SaveSchema("tls", "TLS", "", []byte(`{"$ref": "app"}`)); !errors.Is(err, ErrCycle) {
+		t.Errorf("Expected ErrCycle, got %v", err)
+	}
+
+	referrers, err := Referrers(store, "tls")
+	if err != nil || fmt.Sprint(referrers) != "[app worker]" {
+		t.Errorf("Unexpected referrers: %v, %v", referrers, err)
+	}
+
+	err = store.DeleteSchema("tls")
+	if !errors.Is(err, ErrReferenced) || err.Error() != "schema is referenced: tls is referenced by app, worker" {
+		t.Errorf("Expected ErrReferenced, got %v", err)
+	}
+
+	// 只列出可见的引用方
+	var referenced *ReferencedError
+	if !errors.As(err, &referenced) {
+		t.Fatalf("Expected *ReferencedError, got %T", err)
+	}
+	if message := referenced.Visible(func(id string) bool { return id == "worker" }).Error(); message != "schema is referenced: tls is referenced by worker and other schemas" {
+		t.Errorf("Unexpected visible error: %s", message)
+	}
+	if message := referenced.Visible(func(string) bool { return false }).Error(); message != "schema is referenced: tls is referenced by other schemas" {
+		t.Errorf("Unexpected visible error: %s", message)
+	}
+
+	// 引用方删除后可以删除
+	for _, id := range []string{"app", "worker", "tls"} {
+		if err := store.DeleteSchema(id); err != nil {
+			t.Errorf("Failed to delete %s: %v", id, err)
+		}
+	}
+}
+
+// 测试恢复的旧版本引用了已删除的Schema
+func TestRestoreVersionReferenceCheck(t *testing.T) {
+	store := WithReferenceCheck(storage.NewMemorySchemaStore())
+
+	if err := store.SaveSchema("retry", "Retry", "", []byte(`{"type": "integer"}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if err := store.SaveSchema("app", "App", "", []byte(`{"$ref": "retry"}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if err := store.SaveSchema("app", "App", "", []byte(`{"type": "object"}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if err := store.DeleteSchema("retry"); err != nil {
+		t.Fatalf("Failed to delete schema: %v", err)
+	}
+
+	if _, err := store.RestoreVersion("app", 1, storage.RevisionInfo{}); !errors.Is(err, ErrUnresolved) {
+		t.Errorf("Expected ErrUnresolved, got %v", err)
+	}
+}
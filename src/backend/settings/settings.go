@@ -0,0 +1,362 @@
+// Package settings 负责加载服务端运行参数
+//
+// 参数来源的优先级从低到高依次为：默认值、配置文件、GOCI_*环境变量、命令行参数。
+// 配置文件通过 -config 参数或 GOCI_CONFIG 环境变量指定，支持JSON和YAML。
+package settings
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"goci/backend/auth"
+	"gopkg.in/yaml.v3"
+)
+
+// 支持的存储后端
+const (
+	StorageFilesystem = "filesystem"
+	StorageBolt       = "bolt"
+)
+
+// Settings 是服务端的全部运行参数
+type Settings struct {
+	// Listen 是HTTP监听地址
+	Listen string `yaml:"listen"`
+	// DataDir 是数据目录，Schema和配置保存在其下的schemas和configs目录中
+	DataDir string `yaml:"dataDir"`
+	// Storage 是存储后端，filesystem或bolt
+	Storage string `yaml:"storage"`
+	// AllowedOrigins 是允许跨域访问的来源，"*"表示允许任意来源
+	AllowedOrigins []string `yaml:"allowedOrigins"`
+	// TLSCert 和 TLSKey 同时设置时启用HTTPS
+	TLSCert string `yaml:"tlsCert"`
+	TLSKey  string `yaml:"tlsKey"`
+	// LogLevel 是日志级别：debug、info、warn或error
+	LogLevel string `yaml:"logLevel"`
+	// ReadTimeout 和 WriteTimeout 是HTTP读写超时
+	ReadTimeout  time.Duration `yaml:"readTimeout"`
+	WriteTimeout time.Duration `yaml:"writeTimeout"`
+	// DefaultConfigs 为true时，Schema第一次保存后自动生成默认配置
+	DefaultConfigs bool `yaml:"defaultConfigs"`
+	// Auth 是API的认证和授权参数，没有配置任何认证方式时API对所有人开放
+	Auth AuthSettings `yaml:"auth"`
+	// AuditLog 是审计文件路径，为空时使用数据目录下的audit.jsonl
+	AuditLog string `yaml:"auditLog"`
+	// TrashRetention 是已删除的Schema在回收站中保留的时间，0表示永久保留
+	TrashRetention time.Duration `yaml:"trashRetention"`
+}
+
+// AuthSettings 是API的认证和授权参数
+//
+// 角色授权的格式为"role"或"role:schema-pattern"，角色是viewer、editor或admin
+type AuthSettings struct {
+	// Tokens 是静态API令牌
+	Tokens []TokenSettings `yaml:"tokens"`
+	// Htpasswd 是HTTP Basic认证使用的htpasswd文件
+	Htpasswd string `yaml:"htpasswd"`
+	// JWT 配置Bearer JWT校验
+	JWT JWTSettings `yaml:"jwt"`
+	// Users 为htpasswd用户和JWT主体分配角色，"*"适用于所有认证通过的用户
+	Users map[string][]string `yaml:"users"`
+	// Anonymous 是没有携带凭据的请求拥有的角色，为空时要求认证
+	Anonymous []string `yaml:"anonymous"`
+}
+
+// TokenSettings 是一个静态API令牌
+type TokenSettings struct {
+	Name string `yaml:"name"`
+	// Token 是令牌本身，或"sha256:"加十六进制摘要
+	Token string   `yaml:"token"`
+	Roles []string `yaml:"roles"`
+}
+
+// JWTSettings 配置JWT/OIDC Bearer令牌的校验
+type JWTSettings struct {
+	// Issuer 是要求的iss，没有设置JWKSURL时通过OIDC发现获取JWKS地址
+	Issuer  string `yaml:"issuer"`
+	JWKSURL string `yaml:"jwksUrl"`
+	// Audience 非空时要求aud包含该值
+	Audience string `yaml:"audience"`
+	// RolesClaim 是包含角色授权的claim，默认为roles
+	RolesClaim string `yaml:"rolesClaim"`
+}
+
+// Enabled 表示是否配置了任何认证方式
+func (a AuthSettings) Enabled() bool {
+	return len(a.Tokens) > 0 || a.Htpasswd != "" || a.JWT.Enabled()
+}
+
+// Enabled 表示是否配置了JWT校验
+func (j JWTSettings) Enabled() bool {
+	return j.Issuer != "" || j.JWKSURL != ""
+}
+
+// Default 返回默认参数，与引入配置之前的行为保持一致
+func Default() Settings {
+	return Settings{
+		Listen:         ":8080",
+		DataDir:        ".",
+		Storage:        StorageFilesystem,
+		AllowedOrigins: []string{"*"},
+		LogLevel:       "info",
+		ReadTimeout:    15 * time.Second,
+		WriteTimeout:   30 * time.Second,
+		TrashRetention: 30 * 24 * time.Hour,
+	}
+}
+
+// Load 按优先级合并默认值、配置文件、环境变量和命令行参数
+//
+// getenv通常传入os.Getenv，测试中可以替换
+func Load(args []string, getenv func(string) string) (Settings, error) {
+	s := Default()
+
+	// 先解析命令行，以便得到配置文件路径；参数值最后才应用
+	flags := flag.NewFlagSet("goci", flag.ContinueOnError)
+	configFile := flags.String("config", "", "path to a JSON or YAML settings file (env GOCI_CONFIG)")
+	listen := flags.String("listen", "", "HTTP listen address (env GOCI_LISTEN)")
+	dataDir := flags.String("data-dir", "", "data directory for schemas and configs (env GOCI_DATA_DIR)")
+	storage := flags.String("storage", "", "storage backend: filesystem or bolt (env GOCI_STORAGE)")
+	allowedOrigins := flags.String("allowed-origins", "", "comma separated CORS origins, * for any (env GOCI_ALLOWED_ORIGINS)")
+	tlsCert := flags.String("tls-cert", "", "TLS certificate file (env GOCI_TLS_CERT)")
+	tlsKey := flags.String("tls-key", "", "TLS key file (env GOCI_TLS_KEY)")
+	logLevel := flags.String("log-level", "", "log level: debug, info, warn or error (env GOCI_LOG_LEVEL)")
+	readTimeout := flags.Duration("read-timeout", 0, "HTTP read timeout (env GOCI_READ_TIMEOUT)")
+	writeTimeout := flags.Duration("write-timeout", 0, "HTTP write timeout (env GOCI_WRITE_TIMEOUT)")
+	defaultConfigs := flags.Bool("default-configs", false, "create a default config when a schema is first saved (env GOCI_DEFAULT_CONFIGS)")
+	htpasswd := flags.String("htpasswd", "", "htpasswd file for HTTP basic authentication (env GOCI_HTPASSWD)")
+	jwtIssuer := flags.String("jwt-issuer", "", "required JWT issuer, also used for OIDC discovery (env GOCI_JWT_ISSUER)")
+	jwksURL := flags.String("jwks-url", "", "JWKS URL for verifying JWT bearer tokens (env GOCI_JWKS_URL)")
+	jwtAudience := flags.String("jwt-audience", "", "required JWT audience (env GOCI_JWT_AUDIENCE)")
+	trashRetention := flags.Duration("trash-retention", 0, "how long deleted schemas stay in the trash, 0 to keep forever (env GOCI_TRASH_RETENTION)")
+	auditLog := flags.String("audit-log", "", "audit log file (default <data-dir>/audit.jsonl, env GOCI_AUDIT_LOG)")
+	anonymous := flags.String("anonymous-roles", "", "comma separated roles granted to requests without credentials (env GOCI_ANONYMOUS_ROLES)")
+	if err := flags.Parse(args); err != nil {
+		return Settings{}, err
+	}
+
+	// 配置文件
+	path := *configFile
+	if path == "" {
+		path = getenv("GOCI_CONFIG")
+	}
+	if path != "" {
+		if err := s.loadFile(path); err != nil {
+			return Settings{}, err
+		}
+	}
+
+	// 环境变量
+	if err := s.loadEnv(getenv); err != nil {
+		return Settings{}, err
+	}
+
+	// 只应用显式设置的命令行参数
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "listen":
+			s.Listen = *listen
+		case "data-dir":
+			s.DataDir = *dataDir
+		case "storage":
+			s.Storage = *storage
+		case "allowed-origins":
+			s.AllowedOrigins = splitList(*allowedOrigins)
+		case "tls-cert":
+			s.TLSCert = *tlsCert
+		case "tls-key":
+			s.TLSKey = *tlsKey
+		case "log-level":
+			s.LogLevel = *logLevel
+		case "read-timeout":
+			s.ReadTimeout = *readTimeout
+		case "write-timeout":
+			s.WriteTimeout = *writeTimeout
+		case "default-configs":
+			s.DefaultConfigs = *defaultConfigs
+		case "htpasswd":
+			s.Auth.Htpasswd = *htpasswd
+		case "jwt-issuer":
+			s.Auth.JWT.Issuer = *jwtIssuer
+		case "jwks-url":
+			s.Auth.JWT.JWKSURL = *jwksURL
+		case "jwt-audience":
+			s.Auth.JWT.Audience = *jwtAudience
+		case "trash-retention":
+			s.TrashRetention = *trashRetention
+		case "audit-log":
+			s.AuditLog = *auditLog
+		case "anonymous-roles":
+			s.Auth.Anonymous = splitList(*anonymous)
+		}
+	})
+
+	if err := s.Validate(); err != nil {
+		return Settings{}, err
+	}
+
+	return s, nil
+}
+
+// loadFile 从JSON或YAML文件加载参数，文件中未出现的字段保持原值
+func (s *Settings) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading settings file: %w", err)
+	}
+
+	// YAML是JSON的超集，同一个解析器可以处理两种格式
+	if err := yaml.Unmarshal(data, s); err != nil {
+		return fmt.Errorf("error parsing settings file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// loadEnv 从GOCI_*环境变量加载参数，未设置的变量不生效
+func (s *Settings) loadEnv(getenv func(string) string) error {
+	if v := getenv("GOCI_LISTEN"); v != "" {
+		s.Listen = v
+	}
+	if v := getenv("GOCI_DATA_DIR"); v != "" {
+		s.DataDir = v
+	}
+	if v := getenv("GOCI_STORAGE"); v != "" {
+		s.Storage = v
+	}
+	if v := getenv("GOCI_ALLOWED_ORIGINS"); v != "" {
+		s.AllowedOrigins = splitList(v)
+	}
+	if v := getenv("GOCI_TLS_CERT"); v != "" {
+		s.TLSCert = v
+	}
+	if v := getenv("GOCI_TLS_KEY"); v != "" {
+		s.TLSKey = v
+	}
+	if v := getenv("GOCI_LOG_LEVEL"); v != "" {
+		s.LogLevel = v
+	}
+	if v := getenv("GOCI_HTPASSWD"); v != "" {
+		s.Auth.Htpasswd = v
+	}
+	if v := getenv("GOCI_JWT_ISSUER"); v != "" {
+		s.Auth.JWT.Issuer = v
+	}
+	if v := getenv("GOCI_JWKS_URL"); v != "" {
+		s.Auth.JWT.JWKSURL = v
+	}
+	if v := getenv("GOCI_JWT_AUDIENCE"); v != "" {
+		s.Auth.JWT.Audience = v
+	}
+	if v := getenv("GOCI_AUDIT_LOG"); v != "" {
+		s.AuditLog = v
+	}
+	if v := getenv("GOCI_ANONYMOUS_ROLES"); v != "" {
+		s.Auth.Anonymous = splitList(v)
+	}
+
+	if v := getenv("GOCI_DEFAULT_CONFIGS"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid GOCI_DEFAULT_CONFIGS: %w", err)
+		}
+		s.DefaultConfigs = b
+	}
+
+	for name, target := range map[string]*time.Duration{
+		"GOCI_READ_TIMEOUT":    &s.ReadTimeout,
+		"GOCI_WRITE_TIMEOUT":   &s.WriteTimeout,
+		"GOCI_TRASH_RETENTION": &s.TrashRetention,
+	} {
+		if v := getenv(name); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", name, err)
+			}
+			*target = d
+		}
+	}
+
+	return nil
+}
+
+// Validate 检查参数组合是否有效
+func (s Settings) Validate() error {
+	if s.Listen == "" {
+		return fmt.Errorf("listen address is required")
+	}
+	if s.DataDir == "" {
+		return fmt.Errorf("data directory is required")
+	}
+	if s.Storage != StorageFilesystem && s.Storage != StorageBolt {
+		return fmt.Errorf("invalid storage backend %q: must be %s or %s", s.Storage, StorageFilesystem, StorageBolt)
+	}
+	if (s.TLSCert == "") != (s.TLSKey == "") {
+		return fmt.Errorf("tls cert and tls key must be set together")
+	}
+	switch s.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("invalid log level %q: must be debug, info, warn or error", s.LogLevel)
+	}
+	if s.ReadTimeout < 0 || s.WriteTimeout < 0 {
+		return fmt.Errorf("timeouts must not be negative")
+	}
+	if s.TrashRetention < 0 {
+		return fmt.Errorf("trash retention must not be negative")
+	}
+	return s.Auth.Validate()
+}
+
+// Validate 检查认证参数和角色授权的格式
+func (a AuthSettings) Validate() error {
+	for _, token := range a.Tokens {
+		if token.Name == "" || token.Token == "" {
+			return fmt.Errorf("api tokens require a name and a token")
+		}
+		if _, err := auth.ParseGrants(token.Roles); err != nil {
+			return fmt.Errorf("api token %s: %w", token.Name, err)
+		}
+	}
+	for user, roles := range a.Users {
+		if _, err := auth.ParseGrants(roles); err != nil {
+			return fmt.Errorf("user %s: %w", user, err)
+		}
+	}
+	if _, err := auth.ParseGrants(a.Anonymous); err != nil {
+		return fmt.Errorf("anonymous roles: %w", err)
+	}
+	if len(a.Anonymous) > 0 && !a.Enabled() {
+		return fmt.Errorf("anonymous roles require an authentication method")
+	}
+	return nil
+}
+
+// AuditLogPath 返回审计文件路径
+func (s Settings) AuditLogPath() string {
+	if s.AuditLog != "" {
+		return s.AuditLog
+	}
+	return filepath.Join(s.DataDir, "audit.jsonl")
+}
+
+// TLSEnabled 表示是否配置了HTTPS证书
+func (s Settings) TLSEnabled() bool {
+	return s.TLSCert != "" && s.TLSKey != ""
+}
+
+// splitList 拆分逗号分隔的列表并去掉空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,158 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 测试辅助函数：根据map构造getenv
+func envFrom(values map[string]string) func(string) string {
+	return func(key string) string {
+		return values[key]
+	}
+}
+
+// 测试没有任何输入时使用默认值
+func TestLoadDefaults(t *testing.T) {
+	s, err := Load(nil, envFrom(nil))
+	if err != nil {
+		t.Fatalf("Failed to load settings: %v", err)
+	}
+
+	if !reflect.DeepEqual(s, Default()) {
+		t.Errorf("Expected defaults, got %+v", s)
+	}
+}
+
+// 测试配置文件、环境变量和命令行参数的优先级
+func TestLoadPrecedence(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "settings-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	configPath := filepath.Join(tempDir, "goci.yaml")
+	content := []byte("listen: \":9000\"\ndataDir: /from/file\nlogLevel: warn\nreadTimeout: 5s\nallowedOrigins:\n  - https://file.example\n")
+	if err := os.WriteFile(configPath, content, 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	env := envFrom(map[string]string{
+		"GOCI_CONFIG":          configPath,
+		"GOCI_DATA_DIR":        "/from/env",
+		"GOCI_LOG_LEVEL":       "error",
+		"GOCI_ALLOWED_ORIGINS": "https://a.example, https://b.example",
+		"GOCI_DEFAULT_CONFIGS": "true",
+	})
+
+	s, err := Load([]string{"-log-level", "debug", "-write-timeout", "1m"}, env)
+	if err != nil {
+		t.Fatalf("Failed to load settings: %v", err)
+	}
+
+	// 只在配置文件中设置的值
+	if s.Listen != ":9000" || s.ReadTimeout != 5*time.Second {
+		t.Errorf("File values not applied: %+v", s)
+	}
+
+	// 环境变量覆盖配置文件
+	if s.DataDir != "/from/env" {
+		t.Errorf("Expected data dir from env, got %s", s.DataDir)
+	}
+
+	if !reflect.DeepEqual(s.AllowedOrigins, []string{"https://a.example", "https://b.example"}) {
+		t.Errorf("Unexpected allowed origins: %v", s.AllowedOrigins)
+	}
+
+	if !s.DefaultConfigs {
+		t.Errorf("Expected default configs from env")
+	}
+
+	// 审计文件默认位于数据目录下
+	if s.AuditLogPath() != filepath.Join("/from/env", "audit.jsonl") {
+		t.Errorf("Unexpected audit log path: %s", s.AuditLogPath())
+	}
+
+	// 命令行参数覆盖环境变量
+	if s.LogLevel != "debug" || s.WriteTimeout != time.Minute {
+		t.Errorf("Flag values not applied: %+v", s)
+	}
+}
+
+// 测试无效的参数组合
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+	}{
+		{"tls cert without key", []string{"-tls-cert", "cert.pem"}, nil},
+		{"unknown log level", []string{"-log-level", "verbose"}, nil},
+		{"unknown storage", nil, map[string]string{"GOCI_STORAGE": "s3"}},
+		{"bad timeout", nil, map[string]string{"GOCI_READ_TIMEOUT": "soon"}},
+		{"negative trash retention", []string{"-trash-retention", "-1h"}, nil},
+		{"bad bool", nil, map[string]string{"GOCI_DEFAULT_CONFIGS": "maybe"}},
+		{"missing config file", []string{"-config", "/non/existent.yaml"}, nil},
+		{"unknown role", []string{"-htpasswd", "users", "-anonymous-roles", "owner"}, nil},
+		{"anonymous without auth", nil, map[string]string{"GOCI_ANONYMOUS_ROLES": "viewer"}},
+		{"unknown flag", []string{"-port", "80"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.args, envFrom(tt.env)); err == nil {
+				t.Errorf("Expected error")
+			}
+		})
+	}
+}
+
+// 测试从配置文件加载认证参数
+func TestLoadAuth(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "settings-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	configPath := filepath.Join(tempDir, "goci.yaml")
+	content := []byte(`auth:
+  tokens:
+    - name: ci
+      token: secret
+      roles: [editor]
+  jwt:
+    issuer: https://id.example
+    rolesClaim: groups
+  users:
+    alice: [admin, "editor:team-*"]
+`)
+	if err := os.WriteFile(configPath, content, 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	s, err := Load([]string{"-config", configPath, "-anonymous-roles", "viewer"}, envFrom(map[string]string{
+		"GOCI_HTPASSWD": "/etc/goci/htpasswd",
+	}))
+	if err != nil {
+		t.Fatalf("Failed to load settings: %v", err)
+	}
+
+	if !s.Auth.Enabled() || !s.Auth.JWT.Enabled() || s.Auth.Htpasswd != "/etc/goci/htpasswd" {
+		t.Errorf("Unexpected auth settings: %+v", s.Auth)
+	}
+	if len(s.Auth.Tokens) != 1 || s.Auth.Tokens[0].Roles[0] != "editor" || s.Auth.JWT.RolesClaim != "groups" {
+		t.Errorf("Unexpected auth settings: %+v", s.Auth)
+	}
+	if !reflect.DeepEqual(s.Auth.Users["alice"], []string{"admin", "editor:team-*"}) || !reflect.DeepEqual(s.Auth.Anonymous, []string{"viewer"}) {
+		t.Errorf("Unexpected auth settings: %+v", s.Auth)
+	}
+
+	if Default().Auth.Enabled() {
+		t.Errorf("Auth should be disabled by default")
+	}
+}
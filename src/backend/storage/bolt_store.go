@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// bbolt中使用的bucket名称
+var (
+	schemasBucket = []byte("schemas")
+	configsBucket = []byte("configs")
+	trashBucket   = []byte("trash")
+)
+
+// BoltStore 是基于bbolt的嵌入式存储，一个数据库文件同时保存Schema和配置
+type BoltStore struct {
+	db      *bolt.DB
+	schemas *recordSchemaStore
+	configs *boltConfigStore
+}
+
+// OpenBoltStore 打开（不存在时创建）指定路径的bbolt数据库
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("error opening bolt database: %w", err)
+	}
+
+	// 创建所需的bucket
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{schemasBucket, configsBucket, trashBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating bolt buckets: %w", err)
+	}
+
+	return &BoltStore{
+		db: db,
+		schemas: &recordSchemaStore{
+			backend: &boltSchemaBackend{db: db, bucket: schemasBucket},
+			trash:   &boltSchemaBackend{db: db, bucket: trashBucket},
+		},
+		configs: &boltConfigStore{db: db},
+	}, nil
+}
+
+// Schemas 返回保存在该数据库中的Schema存储
+func (b *BoltStore) Schemas() SchemaStore {
+	return b.schemas
+}
+
+// Configs 返回保存在该数据库中的配置存储
+func (b *BoltStore) Configs() ConfigStore {
+	return b.configs
+}
+
+// Close 关闭数据库
+func (b *BoltStore) Close() error {
+	return b.db.Close()
+}
+
+// boltSchemaBackend 将每个Schema记录以JSON形式保存在一个bucket中
+type boltSchemaBackend struct {
+	db *bolt.DB
+	// bucket 是schemas或trash
+	bucket []byte
+}
+
+func (b *boltSchemaBackend) loadRecord(id string) (*schemaRecord, error) {
+	var record *schemaRecord
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(b.bucket).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		record = &schemaRecord{}
+		return json.Unmarshal(data, record)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema record: %w", err)
+	}
+	return record, nil
+}
+
+func (b *boltSchemaBackend) storeRecord(record *schemaRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("error marshaling schema record: %w", err)
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(b.bucket).Put([]byte(record.key()), data)
+	})
+}
+
+func (b *boltSchemaBackend) removeRecord(id string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(b.bucket).Delete([]byte(id))
+	})
+}
+
+func (b *boltSchemaBackend) moveRecord(id string, record *schemaRecord, to schemaRecordBackend) error {
+	target, ok := to.(*boltSchemaBackend)
+	if !ok || target.db != b.db {
+		return fmt.Errorf("cannot move schema record to a different store")
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("error marshaling schema record: %w", err)
+	}
+	// 删除和写入在同一个事务中，任何一步失败或崩溃时两步都不生效
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(b.bucket).Delete([]byte(id)); err != nil {
+			return err
+		}
+		return tx.Bucket(target.bucket).Put([]byte(record.key()), data)
+	})
+}
+
+func (b *boltSchemaBackend) listRecords() ([]*schemaRecord, error) {
+	var records []*schemaRecord
+	err := b.db.View(func(tx *bolt.Tx) error {
+		// bbolt按key排序遍历，结果天然稳定
+		return tx.Bucket(b.bucket).ForEach(func(k, v []byte) error {
+			record := &schemaRecord{}
+			if err := json.Unmarshal(v, record); err != nil {
+				return fmt.Errorf("error parsing schema record %s: %w", k, err)
+			}
+			records = append(records, record)
+			return nil
+		})
+	})
+	return records, err
+}
+
+// boltConfigStore 是基于bbolt的ConfigStore实现
+type boltConfigStore struct {
+	db *bolt.DB
+}
+
+// boltConfig 是configs bucket中保存的值
+type boltConfig struct {
+	Metadata ConfigMetadata `json:"metadata"`
+	Content  []byte         `json:"content"`
+}
+
+// SaveConfig 保存指定Schema的配置
+func (s *boltConfigStore) SaveConfig(schemaID string, configData []byte) error {
+	return s.SaveConfigVersion(schemaID, configData, 0)
+}
+
+// SaveConfigVersion 保存指定Schema的配置，并记录其对应的Schema版本
+func (s *boltConfigStore) SaveConfigVersion(schemaID string, configData []byte, schemaVersion int) error {
+	return s.SaveConfigIfMatch(schemaID, configData, schemaVersion, "")
+}
+
+// SaveConfigIfMatch 保存指定Schema的配置，ifMatch非空时仅在当前内容的ETag一致时保存
+func (s *boltConfigStore) SaveConfigIfMatch(schemaID string, configData []byte, schemaVersion int, ifMatch string) error {
+	if err := ValidateID(schemaID); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(configsBucket)
+
+		now := time.Now().Format(time.RFC3339)
+		config := boltConfig{
+			Metadata: ConfigMetadata{SchemaID: schemaID, CreatedAt: now, UpdatedAt: now, SchemaVersion: schemaVersion},
+			Content:  configData,
+		}
+
+		// 如果已存在，保留创建时间
+		var existing boltConfig
+		data := bucket.Get([]byte(schemaID))
+		if data != nil {
+			if err := json.Unmarshal(data, &existing); err == nil {
+				config.Metadata.CreatedAt = existing.Metadata.CreatedAt
+			}
+		}
+		if err := checkConfigIfMatch(schemaID, existing.Content, data != nil, ifMatch); err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(config)
+		if err != nil {
+			return fmt.Errorf("error marshaling config: %w", err)
+		}
+		return bucket.Put([]byte(schemaID), data)
+	})
+}
+
+// GetConfig 获取指定Schema的配置
+func (s *boltConfigStore) GetConfig(schemaID string) ([]byte, ConfigMetadata, error) {
+	var config boltConfig
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(configsBucket).Get([]byte(schemaID))
+		if data == nil {
+			return fmt.Errorf("%w: %s", ErrConfigNotFound, schemaID)
+		}
+		return json.Unmarshal(data, &config)
+	})
+	if err != nil {
+		return nil, ConfigMetadata{}, err
+	}
+	return config.Content, config.Metadata, nil
+}
+
+// ListConfigs 列出所有已保存的配置
+func (s *boltConfigStore) ListConfigs() ([]ConfigMetadata, error) {
+	configs := make([]ConfigMetadata, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(configsBucket).ForEach(func(k, v []byte) error {
+			var config boltConfig
+			if err := json.Unmarshal(v, &config); err != nil {
+				return fmt.Errorf("error parsing config %s: %w", k, err)
+			}
+			configs = append(configs, config.Metadata)
+			return nil
+		})
+	})
+	return configs, err
+}
+
+// DeleteConfig 删除指定Schema的配置
+func (s *boltConfigStore) DeleteConfig(schemaID string) error {
+	return s.DeleteConfigIfMatch(schemaID, "")
+}
+
+// DeleteConfigIfMatch 删除指定Schema的配置，ifMatch非空时仅在当前内容的ETag一致时删除
+func (s *boltConfigStore) DeleteConfigIfMatch(schemaID string, ifMatch string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(configsBucket)
+		data := bucket.Get([]byte(schemaID))
+		if data == nil {
+			return fmt.Errorf("%w: %s", ErrConfigNotFound, schemaID)
+		}
+		if ifMatch != "" {
+			var existing boltConfig
+			if err := json.Unmarshal(data, &existing); err != nil {
+				return fmt.Errorf("error parsing config %s: %w", schemaID, err)
+			}
+			if err := checkConfigIfMatch(schemaID, existing.Content, true, ifMatch); err != nil {
+				return err
+			}
+		}
+		return bucket.Delete([]byte(schemaID))
+	})
+}
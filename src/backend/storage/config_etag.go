@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// ConfigETag 根据内容计算配置的强ETag（包含引号）
+func ConfigETag(configData []byte) string {
+	hash := sha256.Sum256(configData)
+	return `"` + hex.EncodeToString(hash[:])[:32] + `"`
+}
+
+// checkConfigIfMatch 检查配置是否满足If-Match条件，ifMatch为空时不做检查
+//
+// current是配置的当前内容，exists为false表示配置不存在；ifMatch为CreateOnly时配置已存在返回ErrConfigExists
+func checkConfigIfMatch(schemaID string, current []byte, exists bool, ifMatch string) error {
+	if ifMatch == "" {
+		return nil
+	}
+	if ifMatch == CreateOnly {
+		if exists {
+			return fmt.Errorf("%w: %s", ErrConfigExists, schemaID)
+		}
+		return nil
+	}
+	if !exists {
+		return fmt.Errorf("%w: config %s does not exist", ErrPreconditionFailed, schemaID)
+	}
+	if !MatchETag(ifMatch, ConfigETag(current)) {
+		return fmt.Errorf("%w: config %s has been modified", ErrPreconditionFailed, schemaID)
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrConfigNotFound 表示指定Schema下还没有保存配置
+var ErrConfigNotFound = errors.New("config not found")
+
+// ErrConfigExists 表示只创建的保存遇到了已存在的配置
+var ErrConfigExists = errors.New("config already exists")
+
+// ConfigStorage 处理配置文件的存储和检索，每个配置对应一个Schema
+type ConfigStorage struct {
+	mutex sync.RWMutex
+	// 配置目录路径
+	configsDir string
+	// 配置注册表文件路径
+	registryPath string
+	// 配置注册表（内存中的缓存）
+	registry map[string]ConfigMetadata
+	// 预写日志，保证配置文件和注册表一起更新
+	journal journal
+	// loadErr 是加载注册表时的错误，非空时拒绝修改
+	loadErr error
+}
+
+// ConfigMetadata 表示配置的元数据
+type ConfigMetadata struct {
+	SchemaID  string `json:"schemaId"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+	// SchemaVersion 是配置最后一次校验所用的Schema版本，0表示未知
+	SchemaVersion int `json:"schemaVersion,omitempty"`
+	// Orphaned 表示配置对应的Schema已被删除，由API在读取时填写，不会保存
+	Orphaned bool `json:"orphaned,omitempty"`
+}
+
+// NewConfigStorage 创建一个新的ConfigStorage实例，配置保存在dataDir下的configs目录中
+func NewConfigStorage(dataDir string) *ConfigStorage {
+	// 创建存储目录
+	configsDir := filepath.Join(dataDir, "configs")
+	os.MkdirAll(configsDir, os.ModePerm)
+
+	// 注册表文件路径
+	registryPath := filepath.Join(configsDir, "config-registry.json")
+
+	// 初始化存储
+	storage := &ConfigStorage{
+		configsDir:   configsDir,
+		registryPath: registryPath,
+		registry:     make(map[string]ConfigMetadata),
+		journal:      newJournal(dataDir, filepath.Join(configsDir, ".journal.json")),
+	}
+
+	// 完成上次崩溃时没有完成的修改
+	if err := storage.journal.recover(); err != nil {
+		fmt.Printf("Error recovering config journal: %v\n", err)
+	}
+
+	// 加载注册表（无锁版本，避免初始化时的死锁）
+	storage.loadRegistryNoLock()
+
+	return storage
+}
+
+// loadRegistryNoLock 从文件加载配置注册表（无锁版本，仅在初始化时使用）
+func (s *ConfigStorage) loadRegistryNoLock() {
+	// 检查注册表文件是否存在
+	if _, err := os.Stat(s.registryPath); os.IsNotExist(err) {
+		// 如果不存在，创建一个空的注册表
+		s.registry = make(map[string]ConfigMetadata)
+		s.saveRegistryNoLock() // 保存空注册表
+		return
+	}
+
+	// 读取注册表文件
+	data, err := os.ReadFile(s.registryPath)
+	if err != nil {
+		fmt.Printf("Error reading config registry file: %v\n", err)
+		s.loadErr = fmt.Errorf("%w: %s", ErrRegistryUnreadable, s.registryPath)
+		return
+	}
+
+	// 解析JSON，失败时保留原文件，等待fsck修复
+	if err := json.Unmarshal(data, &s.registry); err != nil {
+		fmt.Printf("Error parsing config registry file: %v\n", err)
+		s.loadErr = fmt.Errorf("%w: %s", ErrRegistryUnreadable, s.registryPath)
+		return
+	}
+}
+
+// saveRegistryNoLock 将配置注册表保存到文件（无锁版本）
+func (s *ConfigStorage) saveRegistryNoLock() error {
+	// 将注册表转换为JSON
+	data, err := json.MarshalIndent(s.registry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling config registry: %w", err)
+	}
+
+	// 写入文件
+	if err := writeFileAtomic(s.registryPath, data, 0644); err != nil {
+		return fmt.Errorf("error writing config registry file: %w", err)
+	}
+
+	return nil
+}
+
+// registryOp 返回把注册表写入文件的日志操作
+func (s *ConfigStorage) registryOp(registry map[string]ConfigMetadata) (journalOp, error) {
+	data, err := json.MarshalIndent(registry, "", "  ")
+	if err != nil {
+		return journalOp{}, fmt.Errorf("error marshaling config registry: %w", err)
+	}
+	return writeOp(s.registryPath, data), nil
+}
+
+// recoverNoLock 在修改之前完成上一次失败的修改（无锁版本），并从文件重新加载注册表；注册表无法加载时返回ErrRegistryUnreadable
+func (s *ConfigStorage) recoverNoLock() error {
+	if !s.journal.pending() {
+		return s.loadErr
+	}
+
+	if err := s.journal.recover(); err != nil {
+		return err
+	}
+
+	s.loadErr = nil
+	s.registry = make(map[string]ConfigMetadata)
+	s.loadRegistryNoLock()
+	return s.loadErr
+}
+
+// commitNoLock 与注册表一起执行ops（无锁版本），成功后替换内存中的注册表
+func (s *ConfigStorage) commitNoLock(ops []journalOp, registry map[string]ConfigMetadata) error {
+	op, err := s.registryOp(registry)
+	if err != nil {
+		return err
+	}
+
+	if err := s.journal.commit(append(ops, op)...); err != nil {
+		return err
+	}
+
+	s.registry = registry
+	return nil
+}
+
+// SaveConfig 保存指定Schema的配置
+func (s *ConfigStorage) SaveConfig(schemaID string, configData []byte) error {
+	return s.SaveConfigVersion(schemaID, configData, 0)
+}
+
+// SaveConfigVersion 保存指定Schema的配置，并记录其对应的Schema版本
+func (s *ConfigStorage) SaveConfigVersion(schemaID string, configData []byte, schemaVersion int) error {
+	return s.SaveConfigIfMatch(schemaID, configData, schemaVersion, "")
+}
+
+// SaveConfigIfMatch 保存指定Schema的配置，ifMatch非空时仅在当前内容的ETag一致时保存
+func (s *ConfigStorage) SaveConfigIfMatch(schemaID string, configData []byte, schemaVersion int, ifMatch string) error {
+	if err := ValidateID(schemaID); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.recoverNoLock(); err != nil {
+		return err
+	}
+
+	configPath := filepath.Join(s.configsDir, schemaID, "config.json")
+	if ifMatch != "" {
+		_, exists := s.registry[schemaID]
+		var current []byte
+		if exists {
+			var err error
+			if current, err = os.ReadFile(configPath); err != nil {
+				return fmt.Errorf("error reading config file: %w", err)
+			}
+		}
+		if err := checkConfigIfMatch(schemaID, current, exists, ifMatch); err != nil {
+			return err
+		}
+	}
+
+	// 更新元数据
+	now := time.Now().Format(time.RFC3339)
+	metadata := ConfigMetadata{
+		SchemaID:      schemaID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		SchemaVersion: schemaVersion,
+	}
+
+	// 如果已存在，保留创建时间
+	if existing, exists := s.registry[schemaID]; exists {
+		metadata.CreatedAt = existing.CreatedAt
+	}
+
+	// 配置文件和注册表一起写入
+	registry := maps.Clone(s.registry)
+	registry[schemaID] = metadata
+	if err := s.commitNoLock([]journalOp{writeOp(configPath, configData)}, registry); err != nil {
+		return fmt.Errorf("error saving config: %w", err)
+	}
+
+	return nil
+}
+
+// GetConfig 获取指定Schema的配置
+func (s *ConfigStorage) GetConfig(schemaID string) ([]byte, ConfigMetadata, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	// 检查配置是否存在
+	metadata, exists := s.registry[schemaID]
+	if !exists {
+		return nil, ConfigMetadata{}, fmt.Errorf("%w: %s", ErrConfigNotFound, schemaID)
+	}
+
+	// 读取配置文件
+	configPath := filepath.Join(s.configsDir, schemaID, "config.json")
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, ConfigMetadata{}, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	return data, metadata, nil
+}
+
+// ListConfigs 列出所有已保存的配置
+func (s *ConfigStorage) ListConfigs() ([]ConfigMetadata, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	// 将注册表中的所有元数据转换为切片
+	configs := make([]ConfigMetadata, 0, len(s.registry))
+	for _, metadata := range s.registry {
+		configs = append(configs, metadata)
+	}
+
+	return configs, nil
+}
+
+// DeleteConfig 删除指定Schema的配置
+func (s *ConfigStorage) DeleteConfig(schemaID string) error {
+	return s.DeleteConfigIfMatch(schemaID, "")
+}
+
+// DeleteConfigIfMatch 删除指定Schema的配置，ifMatch非空时仅在当前内容的ETag一致时删除
+func (s *ConfigStorage) DeleteConfigIfMatch(schemaID string, ifMatch string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.recoverNoLock(); err != nil {
+		return err
+	}
+
+	// 检查配置是否存在
+	if _, exists := s.registry[schemaID]; !exists {
+		return fmt.Errorf("%w: %s", ErrConfigNotFound, schemaID)
+	}
+
+	// 检查并发修改条件
+	if ifMatch != "" {
+		current, err := os.ReadFile(filepath.Join(s.configsDir, schemaID, "config.json"))
+		if err != nil {
+			return fmt.Errorf("error reading config file: %w", err)
+		}
+		if err := checkConfigIfMatch(schemaID, current, true, ifMatch); err != nil {
+			return err
+		}
+	}
+
+	// 删除配置目录并从注册表中删除
+	registry := maps.Clone(s.registry)
+	delete(registry, schemaID)
+	if err := s.commitNoLock([]journalOp{removeOp(filepath.Join(s.configsDir, schemaID))}, registry); err != nil {
+		return fmt.Errorf("error deleting config: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 测试保存和获取配置
+func TestSaveAndGetConfig(t *testing.T) {
+	// 创建临时目录
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	// 保存当前工作目录
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory: %v", err)
+	}
+
+	// 切换到临时目录
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change working directory: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// 创建存储实例
+	storage := NewConfigStorage(".")
+
+	// 验证注册表文件是否已创建
+	registryPath := filepath.Join(".", "configs", "config-registry.json")
+	if _, err := os.Stat(registryPath); os.IsNotExist(err) {
+		t.Errorf("Config registry file was not created")
+	}
+
+	// 测试数据
+	schemaID := "test-schema"
+	configData := []byte(`{"name": "demo"}`)
+
+	// 保存配置
+	if err := storage.SaveConfig(schemaID, configData); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+
+	// 验证配置文件是否已创建
+	configPath := filepath.Join(".", "configs", schemaID, "config.json")
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+
+	if string(data) != string(configData) {
+		t.Errorf("Config content is incorrect: got %s, want %s", string(data), string(configData))
+	}
+
+	// 验证注册表是否已更新
+	data, err = os.ReadFile(registryPath)
+	if err != nil {
+		t.Fatalf("Failed to read registry file: %v", err)
+	}
+
+	var registry map[string]ConfigMetadata
+	if err := json.Unmarshal(data, &registry); err != nil {
+		t.Fatalf("Failed to parse registry file: %v", err)
+	}
+
+	if metadata, exists := registry[schemaID]; !exists || metadata.SchemaID != schemaID {
+		t.Errorf("Config metadata not found in registry: %v", registry)
+	}
+
+	// 获取配置
+	data, metadata, err := storage.GetConfig(schemaID)
+	if err != nil {
+		t.Fatalf("Failed to get config: %v", err)
+	}
+
+	if string(data) != string(configData) {
+		t.Errorf("Config content is incorrect: got %s, want %s", string(data), string(configData))
+	}
+
+	if metadata.CreatedAt == "" || metadata.UpdatedAt == "" {
+		t.Errorf("Config timestamps are not set: %+v", metadata)
+	}
+
+	// 验证不存在的配置
+	if _, _, err := storage.GetConfig("non-existent"); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound, got %v", err)
+	}
+}
+
+// 测试列出和删除配置
+func TestListAndDeleteConfig(t *testing.T) {
+	// 创建临时目录
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	// 保存当前工作目录
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory: %v", err)
+	}
+
+	// 切换到临时目录
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change working directory: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// 创建存储实例
+	storage := NewConfigStorage(".")
+
+	for _, schemaID := range []string{"schema1", "schema2"} {
+		if err := storage.SaveConfig(schemaID, []byte(`{}`)); err != nil {
+			t.Fatalf("Failed to save config: %v", err)
+		}
+	}
+
+	// 列出所有配置
+	list, err := storage.ListConfigs()
+	if err != nil {
+		t.Fatalf("Failed to list configs: %v", err)
+	}
+
+	if len(list) != 2 {
+		t.Errorf("Config list length is incorrect: got %d, want %d", len(list), 2)
+	}
+
+	// 删除配置
+	if err := storage.DeleteConfig("schema1"); err != nil {
+		t.Fatalf("Failed to delete config: %v", err)
+	}
+
+	// 验证配置目录是否已删除
+	configDir := filepath.Join(".", "configs", "schema1")
+	if _, err := os.Stat(configDir); !os.IsNotExist(err) {
+		t.Errorf("Config directory was not deleted")
+	}
+
+	// 验证删除不存在的配置
+	if err := storage.DeleteConfig("schema1"); !errors.Is(err, ErrConfigNotFound) {
+		t.Errorf("Expected ErrConfigNotFound, got %v", err)
+	}
+}
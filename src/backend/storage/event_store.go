@@ -0,0 +1,122 @@
+package storage
+
+import "time"
+
+// eventSchemaStore 在Schema写操作成功后发布事件
+type eventSchemaStore struct {
+	SchemaStore
+	bus *EventBus
+}
+
+// WithSchemaEvents 包装一个SchemaStore，使其所有写操作都向bus发布事件
+func WithSchemaEvents(store SchemaStore, bus *EventBus) SchemaStore {
+	return &eventSchemaStore{SchemaStore: store, bus: bus}
+}
+
+// SaveSchema 保存Schema并发布schema.saved
+func (s *eventSchemaStore) SaveSchema(id string, name string, description string, schemaData []byte) error {
+	_, err := s.SaveSchemaRevision(id, name, description, schemaData, RevisionInfo{})
+	return err
+}
+
+// SaveSchemaRevision 保存Schema并发布schema.saved
+func (s *eventSchemaStore) SaveSchemaRevision(id string, name string, description string, schemaData []byte, info RevisionInfo) (SchemaVersion, error) {
+	version, err := s.SchemaStore.SaveSchemaRevision(id, name, description, schemaData, info)
+	if err == nil {
+		s.bus.Publish(Event{Type: EventSchemaSaved, SchemaID: id, Version: version.Version})
+	}
+	return version, err
+}
+
+// DeleteSchema 删除Schema并发布schema.deleted
+func (s *eventSchemaStore) DeleteSchema(id string) error {
+	return s.DeleteSchemaIfMatch(id, "")
+}
+
+// DeleteSchemaIfMatch 删除Schema并发布schema.deleted
+func (s *eventSchemaStore) DeleteSchemaIfMatch(id string, ifMatch string) error {
+	err := s.SchemaStore.DeleteSchemaIfMatch(id, ifMatch)
+	if err == nil {
+		s.bus.Publish(Event{Type: EventSchemaDeleted, SchemaID: id})
+	}
+	return err
+}
+
+// RestoreVersion 恢复历史版本并发布schema.restored
+func (s *eventSchemaStore) RestoreVersion(id string, version int, info RevisionInfo) (SchemaVersion, error) {
+	restored, err := s.SchemaStore.RestoreVersion(id, version, info)
+	if err == nil {
+		s.bus.Publish(Event{Type: EventSchemaRestored, SchemaID: id, Version: restored.Version})
+	}
+	return restored, err
+}
+
+// RestoreSchema 从回收站恢复Schema并发布schema.undeleted
+func (s *eventSchemaStore) RestoreSchema(id string, trashID string) (SchemaMetadata, error) {
+	metadata, err := s.SchemaStore.RestoreSchema(id, trashID)
+	if err == nil {
+		s.bus.Publish(Event{Type: EventSchemaUndeleted, SchemaID: id, Version: metadata.Version})
+	}
+	return metadata, err
+}
+
+// PurgeTrash 清理回收站并为每个被永久删除的Schema发布schema.purged
+func (s *eventSchemaStore) PurgeTrash(deletedBefore time.Time) ([]string, error) {
+	purged, err := s.SchemaStore.PurgeTrash(deletedBefore)
+	for _, id := range purged {
+		s.bus.Publish(Event{Type: EventSchemaPurged, SchemaID: id})
+	}
+	return purged, err
+}
+
+// eventConfigStore 在配置写操作成功后发布事件
+type eventConfigStore struct {
+	ConfigStore
+	bus *EventBus
+}
+
+// WithConfigEvents 包装一个ConfigStore，使其所有写操作都向bus发布事件
+func WithConfigEvents(store ConfigStore, bus *EventBus) ConfigStore {
+	return &eventConfigStore{ConfigStore: store, bus: bus}
+}
+
+// SaveConfig 保存配置并发布config.saved
+func (s *eventConfigStore) SaveConfig(schemaID string, configData []byte) error {
+	err := s.ConfigStore.SaveConfig(schemaID, configData)
+	if err == nil {
+		s.bus.Publish(Event{Type: EventConfigSaved, SchemaID: schemaID})
+	}
+	return err
+}
+
+// SaveConfigVersion 保存配置并发布config.saved
+func (s *eventConfigStore) SaveConfigVersion(schemaID string, configData []byte, schemaVersion int) error {
+	err := s.ConfigStore.SaveConfigVersion(schemaID, configData, schemaVersion)
+	if err == nil {
+		s.bus.Publish(Event{Type: EventConfigSaved, SchemaID: schemaID})
+	}
+	return err
+}
+
+// SaveConfigIfMatch 在If-Match条件满足时保存配置并发布config.saved
+func (s *eventConfigStore) SaveConfigIfMatch(schemaID string, configData []byte, schemaVersion int, ifMatch string) error {
+	err := s.ConfigStore.SaveConfigIfMatch(schemaID, configData, schemaVersion, ifMatch)
+	if err == nil {
+		s.bus.Publish(Event{Type: EventConfigSaved, SchemaID: schemaID})
+	}
+	return err
+}
+
+// DeleteConfig 删除配置并发布config.deleted
+func (s *eventConfigStore) DeleteConfig(schemaID string) error {
+	return s.DeleteConfigIfMatch(schemaID, "")
+}
+
+// DeleteConfigIfMatch 在If-Match条件满足时删除配置并发布config.deleted
+func (s *eventConfigStore) DeleteConfigIfMatch(schemaID string, ifMatch string) error {
+	err := s.ConfigStore.DeleteConfigIfMatch(schemaID, ifMatch)
+	if err == nil {
+		s.bus.Publish(Event{Type: EventConfigDeleted, SchemaID: schemaID})
+	}
+	return err
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"sync"
+	"time"
+)
+
+// 存储事件类型
+const (
+	EventSchemaSaved     = "schema.saved"
+	EventSchemaRestored  = "schema.restored"
+	EventSchemaDeleted   = "schema.deleted"
+	EventSchemaUndeleted = "schema.undeleted"
+	EventSchemaPurged    = "schema.purged"
+	EventConfigSaved     = "config.saved"
+	EventConfigDeleted   = "config.deleted"
+)
+
+// Event 是一次存储变更
+type Event struct {
+	ID       uint64 `json:"id"`
+	Type     string `json:"type"`
+	SchemaID string `json:"schemaId"`
+	// Version 是Schema保存、恢复或从回收站恢复后的版本号，其他事件为0
+	Version int    `json:"version,omitempty"`
+	Time    string `json:"time"`
+}
+
+// EventBus 把存储变更广播给订阅者，并保留最近的事件用于断线续传
+//
+// 事件ID从创建时的纳秒时间戳开始递增，因此重启后的ID总是大于重启前的ID，
+// 旧的Last-Event-ID会被识别为缺口而不是被误认为未来的事件
+type EventBus struct {
+	mutex       sync.Mutex
+	lastID      uint64
+	buffer      []Event
+	capacity    int
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription 是一个事件订阅
+//
+// 订阅者处理过慢导致缓冲区满时，Events通道会被关闭，订阅者应使用最后收到的ID重新订阅
+type Subscription struct {
+	Events <-chan Event
+	events chan Event
+	bus    *EventBus
+}
+
+// NewEventBus 创建保留最近capacity个事件的事件总线
+func NewEventBus(capacity int) *EventBus {
+	return &EventBus{
+		lastID:      uint64(time.Now().UnixNano()),
+		capacity:    capacity,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish 分配ID和时间并广播事件
+func (b *EventBus) Publish(event Event) Event {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+	event.Time = time.Now().Format(time.RFC3339)
+
+	b.buffer = append(b.buffer, event)
+	if len(b.buffer) > b.capacity {
+		b.buffer = b.buffer[len(b.buffer)-b.capacity:]
+	}
+
+	for sub := range b.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			// 订阅者跟不上，断开后由其续传
+			b.removeNoLock(sub)
+		}
+	}
+
+	return event
+}
+
+// Subscribe 订阅afterID之后的事件，afterID为0表示只接收新事件
+//
+// 返回需要先补发的事件；complete为false表示afterID之后的部分事件已不在缓冲区中，
+// 订阅者应重新读取完整状态
+func (b *EventBus) Subscribe(afterID uint64) (replay []Event, complete bool, sub *Subscription) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	complete = true
+	if afterID != 0 {
+		replay, complete = b.replayNoLock(afterID)
+	}
+
+	events := make(chan Event, 64)
+	sub = &Subscription{Events: events, events: events, bus: b}
+	b.subscribers[sub] = struct{}{}
+
+	return replay, complete, sub
+}
+
+// replayNoLock 返回缓冲区中afterID之后的事件
+func (b *EventBus) replayNoLock(afterID uint64) ([]Event, bool) {
+	if afterID > b.lastID {
+		return nil, false
+	}
+
+	// 缓冲区中最早的事件之前还有未保留的事件
+	oldest := b.lastID + 1
+	if len(b.buffer) > 0 {
+		oldest = b.buffer[0].ID
+	}
+	complete := afterID+1 >= oldest
+
+	var replay []Event
+	for _, event := range b.buffer {
+		if event.ID > afterID {
+			replay = append(replay, event)
+		}
+	}
+	return replay, complete
+}
+
+// SubscriberCount 返回当前的订阅者数量
+func (b *EventBus) SubscriberCount() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return len(b.subscribers)
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() {
+	s.bus.mutex.Lock()
+	defer s.bus.mutex.Unlock()
+	s.bus.removeNoLock(s)
+}
+
+// removeNoLock 移除订阅并关闭其通道
+func (b *EventBus) removeNoLock(sub *Subscription) {
+	if _, exists := b.subscribers[sub]; exists {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
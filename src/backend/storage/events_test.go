@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+// 测试发布、订阅和断线续传
+func TestEventBusReplay(t *testing.T) {
+	bus := NewEventBus(2)
+
+	first := bus.Publish(Event{Type: EventSchemaSaved, SchemaID: "a"})
+	second := bus.Publish(Event{Type: EventSchemaSaved, SchemaID: "b"})
+	third := bus.Publish(Event{Type: EventSchemaDeleted, SchemaID: "a"})
+
+	if second.ID != first.ID+1 || third.ID != second.ID+1 || third.Time == "" {
+		t.Errorf("Unexpected event IDs: %d %d %d", first.ID, second.ID, third.ID)
+	}
+
+	// 缓冲区中仍有之后的全部事件
+	replay, complete, sub := bus.Subscribe(first.ID)
+	sub.Close()
+	if !complete || len(replay) != 2 || replay[0].ID != second.ID {
+		t.Errorf("Unexpected replay: %+v, complete %v", replay, complete)
+	}
+
+	// 第一个事件已被移出缓冲区
+	replay, complete, sub = bus.Subscribe(first.ID - 1)
+	sub.Close()
+	if complete || len(replay) != 2 {
+		t.Errorf("Expected incomplete replay, got %+v, complete %v", replay, complete)
+	}
+
+	// 来自重启前的旧ID
+	if _, complete, sub = bus.Subscribe(1); complete {
+		t.Errorf("Expected incomplete replay for stale ID")
+	}
+	sub.Close()
+
+	// 未来的ID
+	if _, complete, sub = bus.Subscribe(third.ID + 10); complete {
+		t.Errorf("Expected incomplete replay for unknown ID")
+	}
+	sub.Close()
+
+	// 订阅新事件
+	replay, complete, sub = bus.Subscribe(0)
+	defer sub.Close()
+	if !complete || len(replay) != 0 {
+		t.Errorf("Unexpected replay for new subscription: %+v", replay)
+	}
+
+	bus.Publish(Event{Type: EventConfigSaved, SchemaID: "c"})
+	select {
+	case event := <-sub.Events:
+		if event.Type != EventConfigSaved || event.SchemaID != "c" {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for event")
+	}
+}
+
+// 测试跟不上的订阅者会被断开
+func TestEventBusSlowSubscriber(t *testing.T) {
+	bus := NewEventBus(10)
+	_, _, sub := bus.Subscribe(0)
+
+	for i := 0; i < cap(sub.events)+1; i++ {
+		bus.Publish(Event{Type: EventConfigSaved, SchemaID: "a"})
+	}
+
+	count := 0
+	for range sub.Events {
+		count++
+	}
+	if count != cap(sub.events) {
+		t.Errorf("Expected %d buffered events, got %d", cap(sub.events), count)
+	}
+
+	// 重复关闭不会出错
+	sub.Close()
+}
+
+// 测试包装后的存储在写操作成功后发布事件
+func TestStoreEvents(t *testing.T) {
+	bus := NewEventBus(100)
+	schemas := WithSchemaEvents(NewMemorySchemaStore(), bus)
+	configs := WithConfigEvents(NewMemoryConfigStore(), bus)
+
+	schemas.SaveSchema("a", "A", "", []byte(`{"type": "object"}`))
+	schemas.SaveSchemaRevision("a", "A", "", []byte(`{"type": "string"}`), RevisionInfo{})
+	schemas.RestoreVersion("a", 1, RevisionInfo{})
+	configs.SaveConfig("a", []byte(`{}`))
+	configs.DeleteConfig("a")
+	schemas.DeleteSchemaIfMatch("a", `"stale"`)
+	schemas.DeleteSchema("a")
+	// 失败的写操作不发布事件
+	schemas.DeleteSchema("a")
+	configs.DeleteConfig("a")
+	// 回收站
+	schemas.RestoreSchema("a", "")
+	schemas.RestoreSchema("a", "")
+	schemas.DeleteSchema("a")
+	schemas.PurgeTrash(time.Now().Add(time.Hour))
+
+	replay, _, sub := bus.Subscribe(1)
+	sub.Close()
+
+	want := []Event{
+		{Type: EventSchemaSaved, SchemaID: "a", Version: 1},
+		{Type: EventSchemaSaved, SchemaID: "a", Version: 2},
+		{Type: EventSchemaRestored, SchemaID: "a", Version: 3},
+		{Type: EventConfigSaved, SchemaID: "a"},
+		{Type: EventConfigDeleted, SchemaID: "a"},
+		{Type: EventSchemaDeleted, SchemaID: "a"},
+		{Type: EventSchemaUndeleted, SchemaID: "a", Version: 3},
+		{Type: EventSchemaDeleted, SchemaID: "a"},
+		{Type: EventSchemaPurged, SchemaID: "a"},
+	}
+
+	if len(replay) != len(want) {
+		t.Fatalf("Unexpected events: %+v", replay)
+	}
+	for i, event := range replay {
+		if event.Type != want[i].Type || event.SchemaID != want[i].SchemaID || event.Version != want[i].Version {
+			t.Errorf("Event %d: got %+v, want %+v", i, event, want[i])
+		}
+	}
+}
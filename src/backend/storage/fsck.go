@@ -0,0 +1,519 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 一致性检查发现的问题类型
+const (
+	// FsckRegistryCorrupt 表示注册表文件无法解析
+	FsckRegistryCorrupt = "registry.corrupt"
+	// FsckUnparsableFile 表示Schema、版本索引或配置文件不是有效的JSON
+	FsckUnparsableFile = "file.unparsable"
+	// FsckOrphanedDir 表示目录不在注册表中
+	FsckOrphanedDir = "dir.orphaned"
+	// FsckMissingFiles 表示注册表条目对应的文件不存在
+	FsckMissingFiles = "entry.missing"
+	// FsckStaleEntry 表示注册表中的版本号与历史不一致
+	FsckStaleEntry = "entry.stale"
+)
+
+// ErrRegistryUnreadable 表示注册表无法读取或解析，为避免覆盖磁盘上的数据拒绝修改
+var ErrRegistryUnreadable = errors.New("registry is unreadable, run goci fsck -repair")
+
+// FsckProblem 是一致性检查发现的一个问题
+type FsckProblem struct {
+	Kind string `json:"kind"`
+	// Path 是相对于数据目录的路径
+	Path string `json:"path"`
+	// ID 是相关的Schema ID，注册表本身的问题为空
+	ID     string `json:"id,omitempty"`
+	Detail string `json:"detail"`
+	// Repaired 表示问题已经修复
+	Repaired bool `json:"repaired"`
+}
+
+// FsckReport 是一致性检查的结果
+type FsckReport struct {
+	Problems []FsckProblem `json:"problems"`
+	// Quarantine 是修复时移出的文件所在目录，没有隔离任何文件时为空
+	Quarantine string `json:"quarantine,omitempty"`
+}
+
+// Unrepaired 返回没有修复的问题数
+func (r FsckReport) Unrepaired() int {
+	count := 0
+	for _, problem := range r.Problems {
+		if !problem.Repaired {
+			count++
+		}
+	}
+	return count
+}
+
+// versionFilePattern 匹配历史版本文件名
+var versionFilePattern = regexp.MustCompile(`^schema_v(\d+)\.json$`)
+
+// Fsck 检查文件系统后端的数据目录，repair为true时修复发现的问题
+//
+// 检查之前先完成预写日志中未完成的修改。修复时注册表根据磁盘上的目录重建，
+// 无法解析的文件移入数据目录下的quarantine目录，不会被删除
+func Fsck(dataDir string, repair bool) (FsckReport, error) {
+	f := &fsck{
+		dataDir:    dataDir,
+		repair:     repair,
+		quarantine: filepath.Join(dataDir, "quarantine", time.Now().UTC().Format("20060102T150405Z")),
+		report:     FsckReport{Problems: []FsckProblem{}},
+	}
+
+	for _, dir := range []string{"schemas", "configs"} {
+		j := newJournal(dataDir, filepath.Join(dataDir, dir, ".journal.json"))
+		if err := j.recover(); err != nil {
+			return f.report, err
+		}
+	}
+
+	if err := f.checkSchemas(); err != nil {
+		return f.report, err
+	}
+	if err := f.checkTrash(); err != nil {
+		return f.report, err
+	}
+	if err := f.checkConfigs(); err != nil {
+		return f.report, err
+	}
+
+	return f.report, nil
+}
+
+// fsck 保存一次检查的状态
+type fsck struct {
+	dataDir    string
+	repair     bool
+	quarantine string
+	report     FsckReport
+}
+
+// add 记录一个问题
+func (f *fsck) add(kind, path, id, detail string) {
+	rel, err := filepath.Rel(f.dataDir, path)
+	if err != nil {
+		rel = path
+	}
+	f.report.Problems = append(f.report.Problems, FsckProblem{Kind: kind, Path: rel, ID: id, Detail: detail, Repaired: f.repair})
+}
+
+// isolate 把文件或目录移入隔离目录，保留其相对于数据目录的路径
+func (f *fsck) isolate(path string) error {
+	rel, err := filepath.Rel(f.dataDir, path)
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join(f.quarantine, rel)
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return fmt.Errorf("error creating quarantine directory: %w", err)
+	}
+	if err := os.Rename(path, target); err != nil {
+		return fmt.Errorf("error quarantining %s: %w", rel, err)
+	}
+
+	f.report.Quarantine = f.quarantine
+	return syncDir(filepath.Dir(path))
+}
+
+// loadJSON 读取JSON文件，不存在时返回false；无法解析时记录问题，修复时隔离该文件
+func (f *fsck) loadJSON(path string, kind string, id string, v any) (bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		f.add(kind, path, id, err.Error())
+		if f.repair {
+			return false, f.isolate(path)
+		}
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// idDirs 列出目录下的子目录，名称不是合法ID的目录无法通过API访问，修复时移入隔离区
+func (f *fsck) idDirs(dir string) ([]string, error) {
+	return f.validDirs(dir, ValidateID)
+}
+
+// validDirs 列出dir下名称通过validate的子目录，其余子目录报告为孤立目录，修复时移入隔离区
+func (f *fsck) validDirs(dir string, validate func(string) error) ([]string, error) {
+	names, err := subdirs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := names[:0]
+	for _, name := range names {
+		if err := validate(name); err != nil {
+			f.add(FsckOrphanedDir, filepath.Join(dir, name), "", err.Error())
+			if f.repair {
+				if err := f.isolate(filepath.Join(dir, name)); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		ids = append(ids, name)
+	}
+	return ids, nil
+}
+
+// subdirs 列出目录下的子目录，跳过以.开头的名称
+func subdirs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// schemaDirInfo 是检查后的一个Schema目录
+type schemaDirInfo struct {
+	content  []byte
+	versions []SchemaVersion
+	modTime  time.Time
+}
+
+// metadata 根据目录内容重建元数据，名称取Schema的title，没有时使用ID
+func (d schemaDirInfo) metadata(id string) SchemaMetadata {
+	metadata := SchemaMetadata{ID: id, Name: id, CreatedAt: d.modTime.Format(time.RFC3339), UpdatedAt: d.modTime.Format(time.RFC3339)}
+
+	var schema struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	}
+	if json.Unmarshal(d.content, &schema) == nil && schema.Title != "" {
+		metadata.Name = schema.Title
+		metadata.Description = schema.Description
+	}
+
+	if len(d.versions) > 0 {
+		metadata.CreatedAt = d.versions[0].CreatedAt
+		metadata.UpdatedAt = d.versions[len(d.versions)-1].CreatedAt
+		metadata.Version = d.versions[len(d.versions)-1].Version
+	}
+	return metadata
+}
+
+// checkSchemaDir 检查一个Schema目录的内容和历史，目录无法使用时返回false
+func (f *fsck) checkSchemaDir(dir string, id string) (schemaDirInfo, bool, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return schemaDirInfo{}, false, err
+	}
+	d := schemaDirInfo{modTime: info.ModTime()}
+
+	// 版本索引损坏时根据历史版本文件重建
+	historyDir := filepath.Join(dir, "history")
+	indexPath := filepath.Join(historyDir, "versions.json")
+	if data, err := os.ReadFile(indexPath); err == nil {
+		if err := json.Unmarshal(data, &d.versions); err != nil {
+			f.add(FsckUnparsableFile, indexPath, id, err.Error())
+			d.versions, err = rebuildVersions(historyDir)
+			if err != nil {
+				return d, false, err
+			}
+			if f.repair {
+				if err := f.isolate(indexPath); err != nil {
+					return d, false, err
+				}
+				data, _ := json.MarshalIndent(d.versions, "", "  ")
+				if err := writeFileAtomic(indexPath, data, 0644); err != nil {
+					return d, false, err
+				}
+			}
+		}
+	}
+
+	// 当前内容损坏或缺失时使用最新的历史版本
+	schemaPath := filepath.Join(dir, "schema.json")
+	content, err := os.ReadFile(schemaPath)
+	if err == nil && json.Valid(content) {
+		d.content = content
+		return d, true, nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return d, false, err
+	}
+	unparsable := err == nil
+
+	if len(d.versions) > 0 {
+		latest := d.versions[len(d.versions)-1].Version
+		content, err := os.ReadFile(filepath.Join(historyDir, fmt.Sprintf("schema_v%d.json", latest)))
+		if err == nil && json.Valid(content) {
+			detail := "schema file is missing, restored from version " + strconv.Itoa(latest)
+			if unparsable {
+				detail = "schema file is not valid JSON, restored from version " + strconv.Itoa(latest)
+			}
+			f.add(FsckUnparsableFile, schemaPath, id, detail)
+			if f.repair {
+				if unparsable {
+					if err := f.isolate(schemaPath); err != nil {
+						return d, false, err
+					}
+				}
+				if err := writeFileAtomic(schemaPath, content, 0644); err != nil {
+					return d, false, err
+				}
+			}
+			d.content = content
+			return d, true, nil
+		}
+	}
+
+	// 没有任何可用内容，整个目录移入隔离区
+	f.add(FsckOrphanedDir, dir, id, "directory has no usable schema file")
+	if f.repair {
+		if err := f.isolate(dir); err != nil {
+			return d, false, err
+		}
+	}
+	return d, false, nil
+}
+
+// rebuildVersions 根据历史版本文件重建版本索引，创建时间取文件的修改时间
+func rebuildVersions(historyDir string) ([]SchemaVersion, error) {
+	entries, err := os.ReadDir(historyDir)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []SchemaVersion{}
+	for _, entry := range entries {
+		match := versionFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		number, _ := strconv.Atoi(match[1])
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, SchemaVersion{
+			Version:   number,
+			Message:   "Recovered by fsck",
+			CreatedAt: info.ModTime().Format(time.RFC3339),
+		})
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+
+	return versions, nil
+}
+
+// checkSchemas 检查Schema注册表和schemas目录是否一致
+func (f *fsck) checkSchemas() error {
+	schemasDir := filepath.Join(f.dataDir, "schemas")
+	registryPath := filepath.Join(schemasDir, "schema-registry.json")
+
+	registry := make(map[string]SchemaMetadata)
+	if _, err := f.loadJSON(registryPath, FsckRegistryCorrupt, "", &registry); err != nil {
+		return err
+	}
+	rebuilt := maps.Clone(registry)
+
+	ids, err := f.idDirs(schemasDir)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		dir := filepath.Join(schemasDir, id)
+		d, ok, err := f.checkSchemaDir(dir, id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			delete(rebuilt, id)
+			continue
+		}
+
+		metadata, exists := registry[id]
+		switch {
+		case !exists:
+			f.add(FsckOrphanedDir, dir, id, "directory is not in the registry")
+			rebuilt[id] = d.metadata(id)
+		case len(d.versions) > 0 && metadata.Version != d.versions[len(d.versions)-1].Version:
+			latest := d.versions[len(d.versions)-1]
+			f.add(FsckStaleEntry, registryPath, id, fmt.Sprintf("registry has version %d, history has %d", metadata.Version, latest.Version))
+			metadata.Version = latest.Version
+			metadata.UpdatedAt = latest.CreatedAt
+			rebuilt[id] = metadata
+		}
+	}
+
+	for id := range registry {
+		if !contains(ids, id) {
+			f.add(FsckMissingFiles, filepath.Join(schemasDir, id), id, "registry entry has no schema files")
+			delete(rebuilt, id)
+		}
+	}
+
+	if !f.repair || maps.Equal(registry, rebuilt) {
+		return nil
+	}
+	return writeJSONAtomic(registryPath, rebuilt)
+}
+
+// checkTrash 检查回收站注册表和trash目录是否一致
+func (f *fsck) checkTrash() error {
+	trashDir := filepath.Join(f.dataDir, "trash")
+	registryPath := filepath.Join(trashDir, "trash-registry.json")
+
+	trash := make(map[string]TrashedSchema)
+	if _, err := f.loadJSON(registryPath, FsckRegistryCorrupt, "", &trash); err != nil {
+		return err
+	}
+	rebuilt := maps.Clone(trash)
+
+	// trash目录以回收站条目的键命名
+	trashIDs, err := f.validDirs(trashDir, validateTrashID)
+	if err != nil {
+		return err
+	}
+
+	for _, trashID := range trashIDs {
+		id := trashIDSchema(trashID)
+		dir := filepath.Join(trashDir, trashID)
+		d, ok, err := f.checkSchemaDir(dir, id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			delete(rebuilt, trashID)
+			continue
+		}
+		if _, exists := trash[trashID]; !exists {
+			f.add(FsckOrphanedDir, dir, id, "directory is not in the trash registry")
+			rebuilt[trashID] = TrashedSchema{SchemaMetadata: d.metadata(id), TrashID: trashID, DeletedAt: d.modTime.Format(time.RFC3339)}
+		}
+	}
+
+	for trashID, trashed := range trash {
+		if !contains(trashIDs, trashID) {
+			f.add(FsckMissingFiles, filepath.Join(trashDir, trashID), trashed.ID, "trash entry has no schema files")
+			delete(rebuilt, trashID)
+		}
+	}
+
+	if !f.repair || maps.Equal(trash, rebuilt) {
+		return nil
+	}
+	return writeJSONAtomic(registryPath, rebuilt)
+}
+
+// checkConfigs 检查配置注册表和configs目录是否一致
+func (f *fsck) checkConfigs() error {
+	configsDir := filepath.Join(f.dataDir, "configs")
+	registryPath := filepath.Join(configsDir, "config-registry.json")
+
+	registry := make(map[string]ConfigMetadata)
+	if _, err := f.loadJSON(registryPath, FsckRegistryCorrupt, "", &registry); err != nil {
+		return err
+	}
+	rebuilt := maps.Clone(registry)
+
+	ids, err := f.idDirs(configsDir)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		dir := filepath.Join(configsDir, id)
+		configPath := filepath.Join(dir, "config.json")
+		content, err := os.ReadFile(configPath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err != nil || !json.Valid(content) {
+			detail := "directory has no config file"
+			if err == nil {
+				detail = "config file is not valid JSON"
+			}
+			f.add(FsckUnparsableFile, dir, id, detail)
+			if f.repair {
+				if err := f.isolate(dir); err != nil {
+					return err
+				}
+			}
+			delete(rebuilt, id)
+			continue
+		}
+
+		if _, exists := registry[id]; !exists {
+			f.add(FsckOrphanedDir, dir, id, "directory is not in the config registry")
+			info, err := os.Stat(configPath)
+			if err != nil {
+				return err
+			}
+			modTime := info.ModTime().Format(time.RFC3339)
+			rebuilt[id] = ConfigMetadata{SchemaID: id, CreatedAt: modTime, UpdatedAt: modTime}
+		}
+	}
+
+	for id := range registry {
+		if !contains(ids, id) {
+			f.add(FsckMissingFiles, filepath.Join(configsDir, id), id, "registry entry has no config file")
+			delete(rebuilt, id)
+		}
+	}
+
+	if !f.repair || maps.Equal(registry, rebuilt) {
+		return nil
+	}
+	return writeJSONAtomic(registryPath, rebuilt)
+}
+
+// writeJSONAtomic 把v以缩进的JSON原子写入文件
+func writeJSONAtomic(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+// contains 判断列表中是否包含指定值
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,241 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fsckKinds 返回报告中每种问题的数量
+func fsckKinds(report FsckReport) map[string]int {
+	kinds := make(map[string]int)
+	for _, problem := range report.Problems {
+		kinds[problem.Kind]++
+	}
+	return kinds
+}
+
+// 测试注册表损坏时拒绝写入，修复后根据目录重建
+func TestFsckCorruptRegistry(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	store := NewSchemaStorage(tempDir)
+	if err := store.SaveSchema("app", "App", "", []byte(`{"title": "Application", "type": "object"}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if err := store.SaveSchema("app", "App", "", []byte(`{"title": "Application", "type": "object", "required": ["name"]}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if err := store.SaveSchema("db", "DB", "", []byte(`{"type": "object"}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+
+	registryPath := filepath.Join(tempDir, "schemas", "schema-registry.json")
+	if err := os.WriteFile(registryPath, []byte(`{"app": `), 0644); err != nil {
+		t.Fatalf("Failed to corrupt registry: %v", err)
+	}
+
+	// 损坏的注册表不能被空注册表覆盖
+	store = NewSchemaStorage(tempDir)
+	if err := store.SaveSchema("new", "New", "", []byte(`{}`)); !errors.Is(err, ErrRegistryUnreadable) {
+		t.Errorf("Expected ErrRegistryUnreadable, got %v", err)
+	}
+
+	// 只检查时不修改任何文件
+	report, err := Fsck(tempDir, false)
+	if err != nil {
+		t.Fatalf("Failed to check: %v", err)
+	}
+	kinds := fsckKinds(report)
+	if kinds[FsckRegistryCorrupt] != 1 || kinds[FsckOrphanedDir] != 2 || report.Unrepaired() != 3 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if data, _ := os.ReadFile(registryPath); string(data) != `{"app": ` {
+		t.Errorf("Registry changed without repair: %s", string(data))
+	}
+
+	report, err = Fsck(tempDir, true)
+	if err != nil {
+		t.Fatalf("Failed to repair: %v", err)
+	}
+	if report.Unrepaired() != 0 || report.Quarantine == "" {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if data, err := os.ReadFile(filepath.Join(report.Quarantine, "schemas", "schema-registry.json")); err != nil || string(data) != `{"app": ` {
+		t.Errorf("Corrupt registry not quarantined: %s, %v", string(data), err)
+	}
+
+	// 元数据根据Schema的title和历史重建
+	store = NewSchemaStorage(tempDir)
+	_, metadata, err := store.GetSchema("app")
+	if err != nil {
+		t.Fatalf("Failed to get schema: %v", err)
+	}
+	if metadata.Name != "Application" || metadata.Version != 2 {
+		t.Errorf("Unexpected rebuilt metadata: %+v", metadata)
+	}
+	if _, metadata, err := store.GetSchema("db"); err != nil || metadata.Name != "db" {
+		t.Errorf("Unexpected rebuilt metadata: %+v, %v", metadata, err)
+	}
+	if err := store.SaveSchema("new", "New", "", []byte(`{}`)); err != nil {
+		t.Errorf("Failed to save schema after repair: %v", err)
+	}
+
+	// 修复后再次检查没有问题
+	if report, err := Fsck(tempDir, false); err != nil || len(report.Problems) != 0 {
+		t.Errorf("Unexpected problems after repair: %+v, %v", report, err)
+	}
+}
+
+// 测试修复损坏的文件和与目录不一致的注册表条目
+func TestFsckRepairsEntries(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	schemas := NewSchemaStorage(tempDir)
+	for _, id := range []string{"broken", "stale", "missing", "empty"} {
+		if err := schemas.SaveSchema(id, id, "", []byte(`{"type": "object"}`)); err != nil {
+			t.Fatalf("Failed to save schema: %v", err)
+		}
+	}
+	if err := schemas.SaveSchema("stale", "stale", "", []byte(`{"type": "string"}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	configs := NewConfigStorage(tempDir)
+	for _, id := range []string{"stale", "missing", "broken"} {
+		if err := configs.SaveConfig(id, []byte(`{}`)); err != nil {
+			t.Fatalf("Failed to save config: %v", err)
+		}
+	}
+
+	schemasDir := filepath.Join(tempDir, "schemas")
+	configsDir := filepath.Join(tempDir, "configs")
+	writes := map[string]string{
+		// 损坏的Schema文件从历史恢复
+		filepath.Join(schemasDir, "broken", "schema.json"): `{"type": `,
+		// 损坏的配置文件被隔离
+		filepath.Join(configsDir, "broken", "config.json"): `{`,
+		// 注册表中没有的配置目录
+		filepath.Join(configsDir, "orphan", "config.json"): `{"port": 80}`,
+	}
+	for path, content := range writes {
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	for _, path := range []string{
+		filepath.Join(schemasDir, "missing"),
+		filepath.Join(configsDir, "missing"),
+		filepath.Join(schemasDir, "empty", "schema.json"),
+		filepath.Join(schemasDir, "empty", "history"),
+	} {
+		if err := os.RemoveAll(path); err != nil {
+			t.Fatalf("Failed to remove: %v", err)
+		}
+	}
+	// 注册表中的版本落后于历史
+	schemas.registry["stale"] = SchemaMetadata{ID: "stale", Name: "stale", Version: 1}
+	if err := schemas.saveRegistryNoLock(); err != nil {
+		t.Fatalf("Failed to save registry: %v", err)
+	}
+
+	report, err := Fsck(tempDir, true)
+	if err != nil {
+		t.Fatalf("Failed to repair: %v", err)
+	}
+	kinds := fsckKinds(report)
+	expected := map[string]int{
+		FsckUnparsableFile: 2,
+		FsckStaleEntry:     1,
+		FsckMissingFiles:   2,
+		FsckOrphanedDir:    2,
+	}
+	for kind, count := range expected {
+		if kinds[kind] != count {
+			t.Errorf("Expected %d %s problems, got %d: %+v", count, kind, kinds[kind], report.Problems)
+		}
+	}
+
+	schemas = NewSchemaStorage(tempDir)
+	if data, _, err := schemas.GetSchema("broken"); err != nil || string(data) != `{"type": "object"}` {
+		t.Errorf("Schema not restored from history: %s, %v", string(data), err)
+	}
+	if _, metadata, err := schemas.GetSchema("stale"); err != nil || metadata.Version != 2 {
+		t.Errorf("Stale entry not updated: %+v, %v", metadata, err)
+	}
+	for _, id := range []string{"missing", "empty"} {
+		if _, _, err := schemas.GetSchema(id); !errors.Is(err, ErrSchemaNotFound) {
+			t.Errorf("Expected %s to be removed, got %v", id, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(report.Quarantine, "schemas", "empty")); err != nil {
+		t.Errorf("Empty schema directory not quarantined: %v", err)
+	}
+
+	configs = NewConfigStorage(tempDir)
+	if data, _, err := configs.GetConfig("orphan"); err != nil || string(data) != `{"port": 80}` {
+		t.Errorf("Orphaned config not registered: %s, %v", string(data), err)
+	}
+	for _, id := range []string{"missing", "broken"} {
+		if _, _, err := configs.GetConfig(id); !errors.Is(err, ErrConfigNotFound) {
+			t.Errorf("Expected config %s to be removed, got %v", id, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(report.Quarantine, "configs", "broken", "config.json")); err != nil {
+		t.Errorf("Broken config not quarantined: %v", err)
+	}
+
+	if report, err := Fsck(tempDir, false); err != nil || len(report.Problems) != 0 {
+		t.Errorf("Unexpected problems after repair: %+v, %v", report, err)
+	}
+}
+
+// 测试回收站中同一Schema的多个副本都能被检查和重新登记
+func TestFsckTrash(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	store := NewSchemaStorage(tempDir)
+	for _, content := range []string{`{"title": "First"}`, `{"title": "Second"}`} {
+		if err := store.SaveSchema("app", "App", "", []byte(content)); err != nil {
+			t.Fatalf("Failed to save schema: %v", err)
+		}
+		if err := store.DeleteSchema("app"); err != nil {
+			t.Fatalf("Failed to delete schema: %v", err)
+		}
+	}
+	if report, err := Fsck(tempDir, false); err != nil || len(report.Problems) != 0 {
+		t.Fatalf("Unexpected problems: %+v, %v", report, err)
+	}
+
+	// 注册表丢失后根据trash目录重建
+	if err := os.WriteFile(filepath.Join(tempDir, "trash", "trash-registry.json"), []byte(`{}`), 0644); err != nil {
+		t.Fatalf("Failed to reset trash registry: %v", err)
+	}
+	report, err := Fsck(tempDir, true)
+	if err != nil {
+		t.Fatalf("Failed to repair: %v", err)
+	}
+	if kinds := fsckKinds(report); kinds[FsckOrphanedDir] != 2 || report.Unrepaired() != 0 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	store = NewSchemaStorage(tempDir)
+	trash, err := store.ListTrash()
+	if err != nil || len(trash) != 2 || trash[0].ID != "app" || trash[1].ID != "app" || trash[0].TrashID == trash[1].TrashID {
+		t.Fatalf("Unexpected rebuilt trash: %+v, %v", trash, err)
+	}
+	for _, trashed := range trash {
+		if _, err := store.RestoreSchema("app", trashed.TrashID); err != nil {
+			t.Fatalf("Failed to restore %s: %v", trashed.TrashID, err)
+		}
+		if err := store.DeleteSchema("app"); err != nil {
+			t.Fatalf("Failed to delete schema: %v", err)
+		}
+	}
+}
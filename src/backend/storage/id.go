@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidID 表示Schema ID不符合ID语法或是保留名称
+var ErrInvalidID = errors.New("invalid schema ID")
+
+// MaxIDLength 是Schema ID的最大长度
+const MaxIDLength = 64
+
+// idPattern 是Schema ID的语法：以字母或数字开头，只包含字母、数字、-、_和.
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// reservedIDs 是与存储文件同名、不能用作Schema ID的名称
+var reservedIDs = map[string]bool{
+	"schema-registry.json": true,
+	"config-registry.json": true,
+	"trash-registry.json":  true,
+	"quarantine":           true,
+}
+
+// ValidateID 检查Schema ID，ID同时用作文件系统后端的目录名，不能包含路径分隔符或与存储文件重名
+func ValidateID(id string) error {
+	switch {
+	case id == "":
+		return fmt.Errorf("%w: ID is empty", ErrInvalidID)
+	case len(id) > MaxIDLength:
+		return fmt.Errorf("%w: %q is longer than %d characters", ErrInvalidID, id, MaxIDLength)
+	case !idPattern.MatchString(id):
+		return fmt.Errorf("%w: %q must start with a letter or digit and contain only letters, digits, '.', '-' and '_'", ErrInvalidID, id)
+	case reservedIDs[strings.ToLower(id)]:
+		return fmt.Errorf("%w: %q is a reserved name", ErrInvalidID, id)
+	}
+	return nil
+}
+
+// slugPattern 匹配名称中不能出现在ID里的字符
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// NewID 根据Schema名称生成一个新的ID，格式为名称的小写形式加随机后缀，名称为空时使用schema
+func NewID(name string) string {
+	slug := strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(slug) > 40 {
+		slug = strings.TrimRight(slug[:40], "-")
+	}
+	if slug == "" {
+		slug = "schema"
+	}
+
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return slug + "-" + hex.EncodeToString(suffix)
+}
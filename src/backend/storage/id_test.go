@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// 测试Schema ID语法和保留名称
+func TestValidateID(t *testing.T) {
+	valid := []string{"app", "App_1", "team-a.billing", "0", strings.Repeat("a", MaxIDLength)}
+	for _, id := range valid {
+		if err := ValidateID(id); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", id, err)
+		}
+	}
+
+	invalid := []string{"", ".", "..", "../etc", "a/b", `a\b`, ".journal.json", "-app", "a b", "中文",
+		"schema-registry.json", "Config-Registry.json", "trash-registry.json", "quarantine", strings.Repeat("a", MaxIDLength+1)}
+	for _, id := range invalid {
+		if err := ValidateID(id); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("Expected %q to be invalid, got %v", id, err)
+		}
+	}
+}
+
+// 测试生成的ID符合语法且不重复
+func TestNewID(t *testing.T) {
+	for _, name := range []string{"Billing Service", "", "  ", "中文", strings.Repeat("long name ", 20)} {
+		id := NewID(name)
+		if err := ValidateID(id); err != nil {
+			t.Errorf("Generated invalid ID for %q: %v", name, err)
+		}
+	}
+
+	if id := NewID("Billing Service"); !strings.HasPrefix(id, "billing-service-") {
+		t.Errorf("Expected ID derived from name, got %q", id)
+	}
+	if NewID("app") == NewID("app") {
+		t.Errorf("Expected unique IDs")
+	}
+}
+
+// 测试所有后端拒绝保存非法ID
+func TestStoresRejectInvalidID(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	bolt, err := OpenBoltStore(tempDir + "/goci.db")
+	if err != nil {
+		t.Fatalf("Failed to open bolt store: %v", err)
+	}
+	defer bolt.Close()
+
+	schemaStores := map[string]SchemaStore{
+		"filesystem": NewSchemaStorage(tempDir),
+		"memory":     NewMemorySchemaStore(),
+		"bolt":       bolt.Schemas(),
+	}
+	for name, store := range schemaStores {
+		if err := store.SaveSchema("..", "Up", "", []byte(`{}`)); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("%s: expected ErrInvalidID, got %v", name, err)
+		}
+	}
+
+	configStores := map[string]ConfigStore{
+		"filesystem": NewConfigStorage(tempDir),
+		"memory":     NewMemoryConfigStore(),
+		"bolt":       bolt.Configs(),
+	}
+	for name, store := range configStores {
+		if err := store.SaveConfig("config-registry.json", []byte(`{}`)); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("%s: expected ErrInvalidID, got %v", name, err)
+		}
+	}
+}
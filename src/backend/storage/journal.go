@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic 以临时文件加重命名的方式写入文件
+//
+// 数据先写入同一目录下的临时文件并fsync，再重命名为目标文件并fsync目录，
+// 因此崩溃或磁盘写满时目标文件要么是旧内容，要么是完整的新内容
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	// 成功重命名后临时文件已不存在，删除会失败，可以忽略
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir fsync目录，使其中的创建、重命名和删除落盘
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// journalOp 是日志中的一个文件操作，重复执行的结果与执行一次相同
+type journalOp struct {
+	// Path 是目标路径，写入日志时转换为相对于根目录的路径
+	Path string `json:"path"`
+	// Data 是要写入Path的内容
+	Data []byte `json:"data,omitempty"`
+	// RenameFrom 非空时把该路径移动到Path，Path已存在时先删除；源路径不存在时视为已完成
+	RenameFrom string `json:"renameFrom,omitempty"`
+	// Remove 为true时删除Path及其下的所有内容
+	Remove bool `json:"remove,omitempty"`
+}
+
+// writeOp 返回写入文件的操作
+func writeOp(path string, data []byte) journalOp {
+	return journalOp{Path: path, Data: data}
+}
+
+// renameOp 返回移动文件或目录的操作
+func renameOp(from string, to string) journalOp {
+	return journalOp{Path: to, RenameFrom: from}
+}
+
+// removeOp 返回删除文件或目录的操作
+func removeOp(path string) journalOp {
+	return journalOp{Path: path, Remove: true}
+}
+
+// journal 是文件系统后端的预写日志，保证一次修改涉及的多个文件要么全部更新，要么全部不变
+//
+// 修改先完整写入日志文件，然后依次执行，全部完成后删除日志。
+// 中途崩溃或执行失败时，日志会保留，下次启动或下一次修改之前重新执行其中的全部操作。
+type journal struct {
+	// root 是操作路径的根目录
+	root string
+	// path 是日志文件路径
+	path string
+}
+
+// newJournal 创建一个以root为根目录、日志保存在path的预写日志
+func newJournal(root string, path string) journal {
+	return journal{root: root, path: path}
+}
+
+// pending 表示是否有未完成的日志
+func (j journal) pending() bool {
+	_, err := os.Stat(j.path)
+	return err == nil
+}
+
+// commit 记录并执行一组操作，返回错误时日志可能已经写入，需要通过recover完成
+func (j journal) commit(ops ...journalOp) error {
+	recorded, err := j.write(ops)
+	if err != nil {
+		return err
+	}
+	return j.apply(recorded)
+}
+
+// write 把操作写入日志文件，返回以相对路径记录的操作
+func (j journal) write(ops []journalOp) ([]journalOp, error) {
+	// 记录相对路径，数据目录整体移动后仍然可以恢复
+	recorded := make([]journalOp, len(ops))
+	for i, op := range ops {
+		var err error
+		if op.Path, err = filepath.Rel(j.root, op.Path); err != nil {
+			return nil, fmt.Errorf("error recording journal: %w", err)
+		}
+		if op.RenameFrom != "" {
+			if op.RenameFrom, err = filepath.Rel(j.root, op.RenameFrom); err != nil {
+				return nil, fmt.Errorf("error recording journal: %w", err)
+			}
+		}
+		recorded[i] = op
+	}
+
+	data, err := json.Marshal(recorded)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling journal: %w", err)
+	}
+
+	if err := writeFileAtomic(j.path, data, 0644); err != nil {
+		return nil, fmt.Errorf("error writing journal: %w", err)
+	}
+
+	return recorded, nil
+}
+
+// recover 重新执行上次没有完成的操作，没有日志时什么也不做
+func (j journal) recover() error {
+	data, err := os.ReadFile(j.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading journal: %w", err)
+	}
+
+	// 日志本身是原子写入的，无法解析说明写入日志前就已失败，修改从未开始
+	var ops []journalOp
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return os.Remove(j.path)
+	}
+
+	return j.apply(ops)
+}
+
+// apply 依次执行操作，全部成功后删除日志
+func (j journal) apply(ops []journalOp) error {
+	for _, op := range ops {
+		if err := j.applyOp(op); err != nil {
+			return fmt.Errorf("error applying journal to %s: %w", op.Path, err)
+		}
+	}
+
+	if err := os.Remove(j.path); err != nil {
+		return fmt.Errorf("error removing journal: %w", err)
+	}
+	return syncDir(filepath.Dir(j.path))
+}
+
+// applyOp 执行一个操作
+func (j journal) applyOp(op journalOp) error {
+	path := filepath.Join(j.root, op.Path)
+
+	switch {
+	case op.Remove:
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		return syncDir(filepath.Dir(path))
+
+	case op.RenameFrom != "":
+		from := filepath.Join(j.root, op.RenameFrom)
+		if _, err := os.Stat(from); errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return err
+		}
+		if err := os.Rename(from, path); err != nil {
+			return err
+		}
+		if err := syncDir(filepath.Dir(from)); err != nil {
+			return err
+		}
+		return syncDir(filepath.Dir(path))
+
+	default:
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return err
+		}
+		return writeFileAtomic(path, op.Data, 0644)
+	}
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 测试原子写入不留下临时文件
+func TestWriteFileAtomic(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	path := filepath.Join(tempDir, "registry.json")
+	for _, content := range []string{"first", "second"} {
+		if err := writeFileAtomic(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != content {
+			t.Errorf("Unexpected content: %q, %v", string(data), err)
+		}
+	}
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the target file, got %d entries", len(entries))
+	}
+}
+
+// 测试日志中的操作可以重复执行
+func TestJournalRecover(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	for _, dir := range []string{"from", "removed"} {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir), os.ModePerm); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "from", "file.json"), []byte("moved"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	j := newJournal(tempDir, filepath.Join(tempDir, ".journal.json"))
+	ops := []journalOp{
+		writeOp(filepath.Join(tempDir, "new", "file.json"), []byte("written")),
+		renameOp(filepath.Join(tempDir, "from"), filepath.Join(tempDir, "to")),
+		removeOp(filepath.Join(tempDir, "removed")),
+	}
+
+	// 模拟写入日志后、执行之前崩溃
+	if _, err := j.write(ops); err != nil {
+		t.Fatalf("Failed to write journal: %v", err)
+	}
+	if !j.pending() {
+		t.Fatalf("Expected journal to be pending")
+	}
+
+	// 重复恢复的结果相同
+	for i := 0; i < 2; i++ {
+		if i > 0 {
+			if _, err := j.write(ops); err != nil {
+				t.Fatalf("Failed to write journal: %v", err)
+			}
+		}
+		if err := j.recover(); err != nil {
+			t.Fatalf("Failed to recover journal: %v", err)
+		}
+
+		if data, err := os.ReadFile(filepath.Join(tempDir, "new", "file.json")); err != nil || string(data) != "written" {
+			t.Errorf("Unexpected written file: %q, %v", string(data), err)
+		}
+		if data, err := os.ReadFile(filepath.Join(tempDir, "to", "file.json")); err != nil || string(data) != "moved" {
+			t.Errorf("Unexpected moved file: %q, %v", string(data), err)
+		}
+		if _, err := os.Stat(filepath.Join(tempDir, "removed")); !os.IsNotExist(err) {
+			t.Errorf("Expected directory to be removed")
+		}
+		if j.pending() {
+			t.Errorf("Expected journal to be removed")
+		}
+	}
+
+	// 没有日志时什么也不做
+	if err := j.recover(); err != nil {
+		t.Errorf("Unexpected error without journal: %v", err)
+	}
+}
+
+// 测试Schema文件和注册表在崩溃后仍然一致
+func TestSchemaStorageJournalRecovery(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	store := NewSchemaStorage(tempDir)
+	if err := store.SaveSchema("a", "A", "", []byte(`{"type": "object"}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+
+	// 模拟保存b时写入日志后崩溃：磁盘上只有日志
+	data := []byte(`{"type": "string"}`)
+	version, ops, err := store.versionOpsNoLock("b", data, RevisionInfo{})
+	if err != nil {
+		t.Fatalf("Failed to prepare version: %v", err)
+	}
+	registry := maps.Clone(store.registry)
+	registry["b"] = SchemaMetadata{ID: "b", Name: "B", Version: version.Version}
+	op, err := store.registryOp(registry)
+	if err != nil {
+		t.Fatalf("Failed to prepare registry: %v", err)
+	}
+	ops = append(ops, writeOp(filepath.Join(store.schemasDir, "b", "schema.json"), data), op)
+	if _, err := store.journal.write(ops); err != nil {
+		t.Fatalf("Failed to write journal: %v", err)
+	}
+
+	// 重新打开时完成修改
+	store = NewSchemaStorage(tempDir)
+	if got, metadata, err := store.GetSchema("b"); err != nil || string(got) != string(data) || metadata.Name != "B" {
+		t.Errorf("Unexpected recovered schema: %s %+v, %v", string(got), metadata, err)
+	}
+	if versions, err := store.ListVersions("b"); err != nil || len(versions) != 1 {
+		t.Errorf("Unexpected recovered versions: %+v, %v", versions, err)
+	}
+	if _, _, err := store.GetSchema("a"); err != nil {
+		t.Errorf("Existing schema lost: %v", err)
+	}
+
+	// 数据目录中不留下临时文件和日志
+	filepath.Walk(tempDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && (strings.Contains(info.Name(), ".tmp-") || info.Name() == ".journal.json") {
+			t.Errorf("Unexpected leftover file: %s", path)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemorySchemaStore 是保存在内存中的SchemaStore实现，主要用于测试和嵌入场景
+type MemorySchemaStore struct {
+	recordSchemaStore
+}
+
+// NewMemorySchemaStore 创建一个空的内存Schema存储
+func NewMemorySchemaStore() *MemorySchemaStore {
+	return &MemorySchemaStore{
+		recordSchemaStore: recordSchemaStore{
+			backend: &memorySchemaBackend{records: make(map[string]*schemaRecord)},
+			trash:   &memorySchemaBackend{records: make(map[string]*schemaRecord)},
+		},
+	}
+}
+
+// memorySchemaBackend 用map保存记录，读写时拷贝以隔离调用方
+type memorySchemaBackend struct {
+	records map[string]*schemaRecord
+}
+
+func (b *memorySchemaBackend) loadRecord(id string) (*schemaRecord, error) {
+	record, exists := b.records[id]
+	if !exists {
+		return nil, nil
+	}
+	return cloneRecord(record), nil
+}
+
+func (b *memorySchemaBackend) storeRecord(record *schemaRecord) error {
+	b.records[record.key()] = cloneRecord(record)
+	return nil
+}
+
+func (b *memorySchemaBackend) removeRecord(id string) error {
+	delete(b.records, id)
+	return nil
+}
+
+func (b *memorySchemaBackend) moveRecord(id string, record *schemaRecord, to schemaRecordBackend) error {
+	// 调用方持有存储的写锁，两步之间不会被观察到
+	if err := to.storeRecord(record); err != nil {
+		return err
+	}
+	return b.removeRecord(id)
+}
+
+func (b *memorySchemaBackend) listRecords() ([]*schemaRecord, error) {
+	records := make([]*schemaRecord, 0, len(b.records))
+	for _, record := range b.records {
+		records = append(records, cloneRecord(record))
+	}
+	sortRecords(records)
+	return records, nil
+}
+
+// MemoryConfigStore 是保存在内存中的ConfigStore实现
+type MemoryConfigStore struct {
+	mutex   sync.RWMutex
+	configs map[string]memoryConfig
+}
+
+// memoryConfig 是一个配置及其元数据
+type memoryConfig struct {
+	metadata ConfigMetadata
+	content  []byte
+}
+
+// NewMemoryConfigStore 创建一个空的内存配置存储
+func NewMemoryConfigStore() *MemoryConfigStore {
+	return &MemoryConfigStore{configs: make(map[string]memoryConfig)}
+}
+
+// SaveConfig 保存指定Schema的配置
+func (s *MemoryConfigStore) SaveConfig(schemaID string, configData []byte) error {
+	return s.SaveConfigVersion(schemaID, configData, 0)
+}
+
+// SaveConfigVersion 保存指定Schema的配置，并记录其对应的Schema版本
+func (s *MemoryConfigStore) SaveConfigVersion(schemaID string, configData []byte, schemaVersion int) error {
+	return s.SaveConfigIfMatch(schemaID, configData, schemaVersion, "")
+}
+
+// SaveConfigIfMatch 保存指定Schema的配置，ifMatch非空时仅在当前内容的ETag一致时保存
+func (s *MemoryConfigStore) SaveConfigIfMatch(schemaID string, configData []byte, schemaVersion int, ifMatch string) error {
+	if err := ValidateID(schemaID); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	existing, exists := s.configs[schemaID]
+	if err := checkConfigIfMatch(schemaID, existing.content, exists, ifMatch); err != nil {
+		return err
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	metadata := ConfigMetadata{SchemaID: schemaID, CreatedAt: now, UpdatedAt: now, SchemaVersion: schemaVersion}
+
+	// 如果已存在，保留创建时间
+	if exists {
+		metadata.CreatedAt = existing.metadata.CreatedAt
+	}
+
+	s.configs[schemaID] = memoryConfig{metadata: metadata, content: append([]byte(nil), configData...)}
+	return nil
+}
+
+// GetConfig 获取指定Schema的配置
+func (s *MemoryConfigStore) GetConfig(schemaID string) ([]byte, ConfigMetadata, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	config, exists := s.configs[schemaID]
+	if !exists {
+		return nil, ConfigMetadata{}, fmt.Errorf("%w: %s", ErrConfigNotFound, schemaID)
+	}
+
+	return append([]byte(nil), config.content...), config.metadata, nil
+}
+
+// ListConfigs 列出所有已保存的配置
+func (s *MemoryConfigStore) ListConfigs() ([]ConfigMetadata, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	configs := make([]ConfigMetadata, 0, len(s.configs))
+	for _, config := range s.configs {
+		configs = append(configs, config.metadata)
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].SchemaID < configs[j].SchemaID
+	})
+
+	return configs, nil
+}
+
+// DeleteConfig 删除指定Schema的配置
+func (s *MemoryConfigStore) DeleteConfig(schemaID string) error {
+	return s.DeleteConfigIfMatch(schemaID, "")
+}
+
+// DeleteConfigIfMatch 删除指定Schema的配置，ifMatch非空时仅在当前内容的ETag一致时删除
+func (s *MemoryConfigStore) DeleteConfigIfMatch(schemaID string, ifMatch string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	existing, exists := s.configs[schemaID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrConfigNotFound, schemaID)
+	}
+	if err := checkConfigIfMatch(schemaID, existing.content, true, ifMatch); err != nil {
+		return err
+	}
+
+	delete(s.configs, schemaID)
+	return nil
+}
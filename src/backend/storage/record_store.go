@@ -0,0 +1,394 @@
+package storage
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// schemaRecord 是非文件系统后端中一个Schema的完整记录，包含当前内容和全部历史
+type schemaRecord struct {
+	Metadata SchemaMetadata  `json:"metadata"`
+	Content  []byte          `json:"content"`
+	Versions []versionRecord `json:"versions"`
+	// DeletedAt 是记录移入回收站的时间，可用的记录为空
+	DeletedAt string `json:"deletedAt,omitempty"`
+	// TrashID 是记录在回收站中的键，可用的记录为空
+	TrashID string `json:"trashId,omitempty"`
+}
+
+// key 返回记录在后端中的键，回收站中的记录使用TrashID
+func (r *schemaRecord) key() string {
+	if r.TrashID != "" {
+		return r.TrashID
+	}
+	return r.Metadata.ID
+}
+
+// trashed 返回回收站中的记录对应的TrashedSchema
+func (r *schemaRecord) trashed() TrashedSchema {
+	return TrashedSchema{SchemaMetadata: r.Metadata, TrashID: r.key(), DeletedAt: r.DeletedAt}
+}
+
+// versionRecord 是一个历史版本及其内容
+type versionRecord struct {
+	SchemaVersion
+	Content []byte `json:"content"`
+}
+
+// schemaRecordBackend 是记录的持久化方式，由内存和bbolt后端实现
+type schemaRecordBackend interface {
+	// loadRecord 读取记录，不存在时返回nil
+	loadRecord(id string) (*schemaRecord, error)
+	// storeRecord 以record.key()为键写入记录
+	storeRecord(record *schemaRecord) error
+	// removeRecord 删除记录
+	removeRecord(id string) error
+	// moveRecord 在同一次写入中把record以record.key()为键写入to并删除本后端中的id，
+	// to必须是同一存储的另一个后端，例如回收站
+	moveRecord(id string, record *schemaRecord, to schemaRecordBackend) error
+	// listRecords 列出所有记录
+	listRecords() ([]*schemaRecord, error)
+}
+
+// recordSchemaStore 基于schemaRecordBackend实现SchemaStore的全部语义
+type recordSchemaStore struct {
+	mutex   sync.RWMutex
+	backend schemaRecordBackend
+	// trash 保存回收站中的记录
+	trash schemaRecordBackend
+}
+
+// SaveSchema 保存JSON Schema
+func (s *recordSchemaStore) SaveSchema(id string, name string, description string, schemaData []byte) error {
+	_, err := s.SaveSchemaRevision(id, name, description, schemaData, RevisionInfo{})
+	return err
+}
+
+// SaveSchemaRevision 保存JSON Schema并在历史中追加一个新版本
+func (s *recordSchemaStore) SaveSchemaRevision(id string, name string, description string, schemaData []byte, info RevisionInfo) (SchemaVersion, error) {
+	if err := ValidateID(id); err != nil {
+		return SchemaVersion{}, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, err := s.backend.loadRecord(id)
+	if err != nil {
+		return SchemaVersion{}, err
+	}
+
+	return s.saveNoLock(id, record, name, description, schemaData, info)
+}
+
+// saveNoLock 将新内容写入记录（无锁版本），record为nil表示新建
+func (s *recordSchemaStore) saveNoLock(id string, record *schemaRecord, name string, description string, schemaData []byte, info RevisionInfo) (SchemaVersion, error) {
+	// 检查并发修改条件
+	if err := checkRecordIfMatch(id, record, info.IfMatch); err != nil {
+		return SchemaVersion{}, err
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	if record == nil {
+		record = &schemaRecord{Metadata: SchemaMetadata{ID: id, CreatedAt: now}}
+	}
+
+	// 版本号单调递增
+	next := 1
+	if len(record.Versions) > 0 {
+		next = record.Versions[len(record.Versions)-1].Version + 1
+	}
+
+	version := SchemaVersion{
+		Version:   next,
+		Author:    info.Author,
+		Message:   info.Message,
+		CreatedAt: now,
+		Migration: info.Migration,
+	}
+
+	content := append([]byte(nil), schemaData...)
+	record.Versions = append(record.Versions, versionRecord{SchemaVersion: version, Content: content})
+	record.Content = content
+	record.Metadata.Name = name
+	record.Metadata.Description = description
+	record.Metadata.UpdatedAt = now
+	record.Metadata.Version = next
+
+	if err := s.backend.storeRecord(record); err != nil {
+		return SchemaVersion{}, fmt.Errorf("error saving schema: %w", err)
+	}
+
+	return version, nil
+}
+
+// GetSchema 获取指定ID的Schema
+func (s *recordSchemaStore) GetSchema(id string) ([]byte, SchemaMetadata, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	record, err := s.backend.loadRecord(id)
+	if err != nil {
+		return nil, SchemaMetadata{}, err
+	}
+	if record == nil {
+		return nil, SchemaMetadata{}, fmt.Errorf("%w: %s", ErrSchemaNotFound, id)
+	}
+
+	return record.Content, record.Metadata, nil
+}
+
+// ListSchemas 列出所有可用的Schema
+func (s *recordSchemaStore) ListSchemas() ([]SchemaMetadata, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	records, err := s.backend.listRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := make([]SchemaMetadata, 0, len(records))
+	for _, record := range records {
+		schemas = append(schemas, record.Metadata)
+	}
+
+	return schemas, nil
+}
+
+// DeleteSchema 删除指定ID的Schema
+func (s *recordSchemaStore) DeleteSchema(id string) error {
+	return s.DeleteSchemaIfMatch(id, "")
+}
+
+// DeleteSchemaIfMatch 删除指定ID的Schema，ifMatch非空时仅在ETag一致时删除
+func (s *recordSchemaStore) DeleteSchemaIfMatch(id string, ifMatch string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, err := s.backend.loadRecord(id)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		return fmt.Errorf("%w: %s", ErrSchemaNotFound, id)
+	}
+
+	if err := checkRecordIfMatch(id, record, ifMatch); err != nil {
+		return err
+	}
+
+	// 写入回收站和删除在同一次写入中完成，不会出现两边都有或都没有的情况；回收站中同一ID的旧副本会保留
+	now := time.Now()
+	record.DeletedAt = now.Format(time.RFC3339)
+	record.TrashID = newTrashID(id, now, func(trashID string) bool {
+		existing, err := s.trash.loadRecord(trashID)
+		return err == nil && existing != nil
+	})
+	if err := s.backend.moveRecord(id, record, s.trash); err != nil {
+		return fmt.Errorf("error moving schema to trash: %w", err)
+	}
+
+	return nil
+}
+
+// ListTrash 列出回收站中的Schema，最近删除的在前
+func (s *recordSchemaStore) ListTrash() ([]TrashedSchema, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	records, err := s.trash.listRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	trash := make([]TrashedSchema, 0, len(records))
+	for _, record := range records {
+		trash = append(trash, record.trashed())
+	}
+	sortTrash(trash)
+
+	return trash, nil
+}
+
+// RestoreSchema 把回收站中的Schema连同历史恢复为可用状态，trashID为空时恢复最近删除的副本
+func (s *recordSchemaStore) RestoreSchema(id string, trashID string) (SchemaMetadata, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	records, err := s.trash.listRecords()
+	if err != nil {
+		return SchemaMetadata{}, err
+	}
+	trash := make([]TrashedSchema, 0, len(records))
+	for _, record := range records {
+		trash = append(trash, record.trashed())
+	}
+	trashed, exists := latestTrashed(trash, id, trashID)
+	if !exists {
+		return SchemaMetadata{}, fmt.Errorf("%w: %s", ErrNotInTrash, id)
+	}
+	record := records[slices.IndexFunc(records, func(record *schemaRecord) bool { return record.key() == trashed.TrashID })]
+
+	// 删除后又创建了同一ID的Schema时不能覆盖
+	existing, err := s.backend.loadRecord(id)
+	if err != nil {
+		return SchemaMetadata{}, err
+	}
+	if existing != nil {
+		return SchemaMetadata{}, fmt.Errorf("%w: %s", ErrSchemaExists, id)
+	}
+
+	record.DeletedAt = ""
+	record.TrashID = ""
+	if err := s.trash.moveRecord(trashed.TrashID, record, s.backend); err != nil {
+		return SchemaMetadata{}, fmt.Errorf("error restoring schema from trash: %w", err)
+	}
+
+	return record.Metadata, nil
+}
+
+// PurgeTrash 永久删除回收站中在deletedBefore之前删除的Schema，返回被删除的ID，同一Schema的多个副本各返回一次
+func (s *recordSchemaStore) PurgeTrash(deletedBefore time.Time) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	records, err := s.trash.listRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	purged := []string{}
+	for _, record := range records {
+		if !record.trashed().deletedBefore(deletedBefore) {
+			continue
+		}
+		if err := s.trash.removeRecord(record.key()); err != nil {
+			return purged, err
+		}
+		purged = append(purged, record.Metadata.ID)
+	}
+
+	return purged, nil
+}
+
+// ListVersions 列出Schema的所有历史版本，按版本号升序
+func (s *recordSchemaStore) ListVersions(id string) ([]SchemaVersion, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	record, err := s.backend.loadRecord(id)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSchemaNotFound, id)
+	}
+
+	versions := make([]SchemaVersion, 0, len(record.Versions))
+	for _, v := range record.Versions {
+		versions = append(versions, v.SchemaVersion)
+	}
+
+	return versions, nil
+}
+
+// GetVersion 获取Schema指定版本的内容
+func (s *recordSchemaStore) GetVersion(id string, version int) ([]byte, SchemaVersion, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	record, err := s.backend.loadRecord(id)
+	if err != nil {
+		return nil, SchemaVersion{}, err
+	}
+
+	return findRecordVersion(id, record, version)
+}
+
+// RestoreVersion 将Schema恢复为指定版本的内容
+func (s *recordSchemaStore) RestoreVersion(id string, version int, info RevisionInfo) (SchemaVersion, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, err := s.backend.loadRecord(id)
+	if err != nil {
+		return SchemaVersion{}, err
+	}
+
+	data, _, err := findRecordVersion(id, record, version)
+	if err != nil {
+		return SchemaVersion{}, err
+	}
+
+	if info.Message == "" {
+		info.Message = fmt.Sprintf("Restore version %d", version)
+	}
+
+	return s.saveNoLock(id, record, record.Metadata.Name, record.Metadata.Description, data, info)
+}
+
+// findRecordVersion 在记录中查找指定版本
+func findRecordVersion(id string, record *schemaRecord, version int) ([]byte, SchemaVersion, error) {
+	if record == nil {
+		return nil, SchemaVersion{}, fmt.Errorf("%w: %s", ErrSchemaNotFound, id)
+	}
+
+	for _, v := range record.Versions {
+		if v.Version == version {
+			return v.Content, v.SchemaVersion, nil
+		}
+	}
+
+	return nil, SchemaVersion{}, fmt.Errorf("%w: %s v%d", ErrVersionNotFound, id, version)
+}
+
+// checkRecordIfMatch 检查记录是否满足If-Match条件，ifMatch为空时不做检查；
+// ifMatch为CreateOnly时记录已存在返回ErrSchemaExists
+func checkRecordIfMatch(id string, record *schemaRecord, ifMatch string) error {
+	if ifMatch == "" {
+		return nil
+	}
+
+	if ifMatch == CreateOnly {
+		if record != nil {
+			return fmt.Errorf("%w: %s", ErrSchemaExists, id)
+		}
+		return nil
+	}
+
+	if record == nil {
+		return fmt.Errorf("%w: schema %s does not exist", ErrPreconditionFailed, id)
+	}
+
+	if !MatchETag(ifMatch, SchemaETag(record.Metadata.Version, record.Content)) {
+		return fmt.Errorf("%w: schema %s has been modified", ErrPreconditionFailed, id)
+	}
+
+	return nil
+}
+
+// cloneRecord 深拷贝记录，避免调用方修改后端内部状态
+func cloneRecord(record *schemaRecord) *schemaRecord {
+	clone := &schemaRecord{
+		Metadata:  record.Metadata,
+		Content:   append([]byte(nil), record.Content...),
+		Versions:  make([]versionRecord, len(record.Versions)),
+		DeletedAt: record.DeletedAt,
+		TrashID:   record.TrashID,
+	}
+	for i, v := range record.Versions {
+		clone.Versions[i] = versionRecord{SchemaVersion: v.SchemaVersion, Content: append([]byte(nil), v.Content...)}
+	}
+	return clone
+}
+
+// sortRecords 按ID排序，使列表结果稳定
+func sortRecords(records []*schemaRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Metadata.ID < records[j].Metadata.ID
+	})
+}
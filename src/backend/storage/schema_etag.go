@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SchemaETag 根据版本号和内容计算Schema的强ETag（包含引号）
+func SchemaETag(version int, schemaData []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(strconv.Itoa(version) + ":"))
+	hash.Write(schemaData)
+	return `"` + hex.EncodeToString(hash.Sum(nil))[:32] + `"`
+}
+
+// MatchETag 判断If-Match或If-None-Match头是否与ETag匹配
+//
+// 头中可以包含逗号分隔的多个ETag，"*"匹配任意ETag
+func MatchETag(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateOnly 作为If-Match条件传给存储时表示只在目标不存在时保存，对应HTTP的If-None-Match: *
+const CreateOnly = "If-None-Match: *"
+
+// checkIfMatchNoLock 检查If-Match条件（无锁版本），ifMatch为空时不做检查；
+// ifMatch为CreateOnly时Schema已存在返回ErrSchemaExists
+func (s *SchemaStorage) checkIfMatchNoLock(id string, ifMatch string) error {
+	if ifMatch == "" {
+		return nil
+	}
+
+	metadata, exists := s.registry[id]
+	if ifMatch == CreateOnly {
+		if exists {
+			return fmt.Errorf("%w: %s", ErrSchemaExists, id)
+		}
+		return nil
+	}
+	if !exists {
+		return fmt.Errorf("%w: schema %s does not exist", ErrPreconditionFailed, id)
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.schemasDir, id, "schema.json"))
+	if err != nil {
+		return fmt.Errorf("error reading schema file: %w", err)
+	}
+
+	if !MatchETag(ifMatch, SchemaETag(metadata.Version, data)) {
+		return fmt.Errorf("%w: schema %s has been modified", ErrPreconditionFailed, id)
+	}
+
+	return nil
+}
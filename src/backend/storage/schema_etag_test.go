@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// 测试ETag匹配规则
+func TestMatchETag(t *testing.T) {
+	etag := SchemaETag(1, []byte(`{}`))
+
+	if SchemaETag(2, []byte(`{}`)) == etag {
+		t.Errorf("ETag should change with version")
+	}
+
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{etag, true},
+		{"*", true},
+		{`"other", ` + etag, true},
+		{`"other"`, false},
+		{"W/" + etag, false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchETag(tt.header, etag); got != tt.want {
+			t.Errorf("MatchETag(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+// 测试存储层的If-Match检查
+func TestSaveSchemaIfMatch(t *testing.T) {
+	// 创建临时目录
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	// 保存当前工作目录
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory: %v", err)
+	}
+
+	// 切换到临时目录
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change working directory: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// 创建存储实例
+	storage := NewSchemaStorage(".")
+
+	id := "test-schema"
+	schemaData := []byte(`{"type": "object"}`)
+	version, err := storage.SaveSchemaRevision(id, "Test", "", schemaData, RevisionInfo{})
+	if err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	etag := SchemaETag(version.Version, schemaData)
+
+	if _, err := storage.SaveSchemaRevision(id, "Test", "", schemaData, RevisionInfo{IfMatch: `"stale"`}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("Expected ErrPreconditionFailed, got %v", err)
+	}
+
+	if _, err := storage.SaveSchemaRevision(id, "Test", "", schemaData, RevisionInfo{IfMatch: etag}); err != nil {
+		t.Errorf("Failed to save schema with matching ETag: %v", err)
+	}
+
+	if err := storage.DeleteSchemaIfMatch(id, etag); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("Expected ErrPreconditionFailed, got %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrVersionNotFound 表示Schema的指定版本不存在
+var ErrVersionNotFound = errors.New("version not found")
+
+// RevisionInfo 描述一次保存操作的作者和说明
+type RevisionInfo struct {
+	Author  string `json:"author"`
+	Message string `json:"message"`
+	// Migration 是把上一个版本的配置升级到本版本的步骤，可以为空
+	Migration []MigrationStep `json:"migration,omitempty"`
+	// IfMatch 非空时，仅当Schema当前ETag与其匹配时才保存；为CreateOnly时仅在Schema不存在时保存，否则返回ErrSchemaExists
+	IfMatch string `json:"-"`
+	// Imported 表示版本来自归档导入的历史，而不是用户新建或修改Schema
+	Imported bool `json:"-"`
+}
+
+// SchemaVersion 表示Schema历史中的一个不可变版本
+type SchemaVersion struct {
+	Version   int    `json:"version"`
+	Author    string `json:"author"`
+	Message   string `json:"message"`
+	CreatedAt string `json:"createdAt"`
+	// Migration 是把上一个版本的配置升级到本版本的步骤
+	Migration []MigrationStep `json:"migration,omitempty"`
+}
+
+// MigrationStep 是一个声明式的配置迁移步骤，由migration包解释执行
+type MigrationStep struct {
+	// Op 是操作类型：rename、move、setDefault、delete或convert
+	Op string `json:"op"`
+	// Path 是要操作的属性的JSON Pointer
+	Path string `json:"path"`
+	// To 是rename的新属性名，或move的目标JSON Pointer
+	To string `json:"to,omitempty"`
+	// Value 是setDefault在属性不存在时写入的值
+	Value json.RawMessage `json:"value,omitempty"`
+	// Type 是convert的目标类型：string、number、integer、boolean或array
+	Type string `json:"type,omitempty"`
+}
+
+// historyDir 返回Schema历史版本目录
+func (s *SchemaStorage) historyDir(id string) string {
+	return filepath.Join(s.schemasDir, id, "history")
+}
+
+// versionPath 返回指定版本的Schema文件路径
+func (s *SchemaStorage) versionPath(id string, version int) string {
+	return filepath.Join(s.historyDir(id), fmt.Sprintf("schema_v%d.json", version))
+}
+
+// loadVersionsNoLock 读取Schema的版本索引（无锁版本），没有历史时返回空列表
+func (s *SchemaStorage) loadVersionsNoLock(id string) ([]SchemaVersion, error) {
+	data, err := os.ReadFile(filepath.Join(s.historyDir(id), "versions.json"))
+	if os.IsNotExist(err) {
+		return []SchemaVersion{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading version index: %w", err)
+	}
+
+	var versions []SchemaVersion
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, fmt.Errorf("error parsing version index: %w", err)
+	}
+
+	return versions, nil
+}
+
+// versionsOp 返回把版本索引写入文件的日志操作
+func (s *SchemaStorage) versionsOp(id string, versions []SchemaVersion) (journalOp, error) {
+	data, err := json.MarshalIndent(versions, "", "  ")
+	if err != nil {
+		return journalOp{}, fmt.Errorf("error marshaling version index: %w", err)
+	}
+	return writeOp(filepath.Join(s.historyDir(id), "versions.json"), data), nil
+}
+
+// versionOpsNoLock 准备为Schema追加一个新版本（无锁版本），返回新版本信息和写入历史的日志操作
+func (s *SchemaStorage) versionOpsNoLock(id string, schemaData []byte, info RevisionInfo) (SchemaVersion, []journalOp, error) {
+	versions, err := s.loadVersionsNoLock(id)
+	if err != nil {
+		return SchemaVersion{}, nil, err
+	}
+
+	var ops []journalOp
+
+	// 升级前保存的Schema没有历史，先把当前内容记为第一个版本，避免被覆盖后丢失
+	if len(versions) == 0 {
+		if existing, err := os.ReadFile(filepath.Join(s.schemasDir, id, "schema.json")); err == nil {
+			ops = append(ops, writeOp(s.versionPath(id, 1), existing))
+			versions = append(versions, SchemaVersion{
+				Version:   1,
+				Message:   "Existing schema before version history",
+				CreatedAt: s.registry[id].UpdatedAt,
+			})
+		}
+	}
+
+	// 版本号单调递增
+	next := 1
+	if len(versions) > 0 {
+		next = versions[len(versions)-1].Version + 1
+	}
+
+	version := SchemaVersion{
+		Version:   next,
+		Author:    info.Author,
+		Message:   info.Message,
+		CreatedAt: time.Now().Format(time.RFC3339),
+		Migration: info.Migration,
+	}
+
+	// 历史版本文件只写入一次，之后不再修改
+	ops = append(ops, writeOp(s.versionPath(id, next), schemaData))
+
+	op, err := s.versionsOp(id, append(versions, version))
+	if err != nil {
+		return SchemaVersion{}, nil, err
+	}
+
+	return version, append(ops, op), nil
+}
+
+// ListVersions 列出Schema的所有历史版本，按版本号升序
+func (s *SchemaStorage) ListVersions(id string) ([]SchemaVersion, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if _, exists := s.registry[id]; !exists {
+		return nil, fmt.Errorf("%w: %s", ErrSchemaNotFound, id)
+	}
+
+	return s.loadVersionsNoLock(id)
+}
+
+// GetVersion 获取Schema指定版本的内容
+func (s *SchemaStorage) GetVersion(id string, version int) ([]byte, SchemaVersion, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.getVersionNoLock(id, version)
+}
+
+// getVersionNoLock 获取Schema指定版本的内容（无锁版本）
+func (s *SchemaStorage) getVersionNoLock(id string, version int) ([]byte, SchemaVersion, error) {
+	if _, exists := s.registry[id]; !exists {
+		return nil, SchemaVersion{}, fmt.Errorf("%w: %s", ErrSchemaNotFound, id)
+	}
+
+	versions, err := s.loadVersionsNoLock(id)
+	if err != nil {
+		return nil, SchemaVersion{}, err
+	}
+
+	for _, v := range versions {
+		if v.Version == version {
+			data, err := os.ReadFile(s.versionPath(id, version))
+			if err != nil {
+				return nil, SchemaVersion{}, fmt.Errorf("error reading version file: %w", err)
+			}
+			return data, v, nil
+		}
+	}
+
+	return nil, SchemaVersion{}, fmt.Errorf("%w: %s v%d", ErrVersionNotFound, id, version)
+}
+
+// RestoreVersion 将Schema恢复为指定版本的内容
+//
+// 恢复不会改写历史，而是以旧内容追加一个新版本
+func (s *SchemaStorage) RestoreVersion(id string, version int, info RevisionInfo) (SchemaVersion, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.recoverNoLock(); err != nil {
+		return SchemaVersion{}, err
+	}
+
+	data, _, err := s.getVersionNoLock(id, version)
+	if err != nil {
+		return SchemaVersion{}, err
+	}
+
+	if info.Message == "" {
+		info.Message = fmt.Sprintf("Restore version %d", version)
+	}
+
+	metadata := s.registry[id]
+	return s.saveSchemaNoLock(id, metadata.Name, metadata.Description, data, info)
+}
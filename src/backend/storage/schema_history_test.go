@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 测试每次保存都追加版本，并可以恢复旧版本
+func TestSchemaVersionHistory(t *testing.T) {
+	// 创建临时目录
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	// 保存当前工作目录
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory: %v", err)
+	}
+
+	// 切换到临时目录
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change working directory: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// 创建存储实例
+	storage := NewSchemaStorage(".")
+
+	id := "test-schema"
+	first := []byte(`{"type": "object"}`)
+	second := []byte(`{"type": "object", "properties": {"name": {"type": "string"}}}`)
+
+	// 保存两个版本
+	if _, err := storage.SaveSchemaRevision(id, "Test", "", first, RevisionInfo{Author: "alice", Message: "initial"}); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	version, err := storage.SaveSchemaRevision(id, "Test", "", second, RevisionInfo{Author: "bob", Message: "add name"})
+	if err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+
+	if version.Version != 2 || version.Author != "bob" || version.Message != "add name" {
+		t.Errorf("Unexpected version: %+v", version)
+	}
+
+	// 列出历史版本
+	versions, err := storage.ListVersions(id)
+	if err != nil {
+		t.Fatalf("Failed to list versions: %v", err)
+	}
+
+	if len(versions) != 2 || versions[0].Version != 1 || versions[0].Author != "alice" {
+		t.Errorf("Unexpected versions: %+v", versions)
+	}
+
+	// 获取旧版本内容
+	data, _, err := storage.GetVersion(id, 1)
+	if err != nil {
+		t.Fatalf("Failed to get version: %v", err)
+	}
+
+	if string(data) != string(first) {
+		t.Errorf("Version content is incorrect: got %s, want %s", string(data), string(first))
+	}
+
+	if _, _, err := storage.GetVersion(id, 3); !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("Expected ErrVersionNotFound, got %v", err)
+	}
+
+	// 恢复第一个版本会追加第三个版本
+	restored, err := storage.RestoreVersion(id, 1, RevisionInfo{Author: "carol"})
+	if err != nil {
+		t.Fatalf("Failed to restore version: %v", err)
+	}
+
+	if restored.Version != 3 || restored.Message != "Restore version 1" {
+		t.Errorf("Unexpected restored version: %+v", restored)
+	}
+
+	data, metadata, err := storage.GetSchema(id)
+	if err != nil {
+		t.Fatalf("Failed to get schema: %v", err)
+	}
+
+	if string(data) != string(first) {
+		t.Errorf("Schema content is incorrect: got %s, want %s", string(data), string(first))
+	}
+
+	if metadata.Version != 3 {
+		t.Errorf("Schema version is incorrect: got %d, want %d", metadata.Version, 3)
+	}
+
+	// 历史版本不会被改写
+	data, _, err = storage.GetVersion(id, 2)
+	if err != nil || string(data) != string(second) {
+		t.Errorf("Version 2 was modified: %s, %v", string(data), err)
+	}
+}
+
+// 测试没有历史的旧Schema在下次保存时保留原内容
+func TestSchemaVersionHistoryLegacySchema(t *testing.T) {
+	// 创建临时目录
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	// 保存当前工作目录
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory: %v", err)
+	}
+
+	// 切换到临时目录
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change working directory: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	// 创建存储实例
+	storage := NewSchemaStorage(".")
+
+	id := "legacy-schema"
+	legacy := []byte(`{"type": "string"}`)
+	if err := storage.SaveSchema(id, "Legacy", "", legacy); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+
+	// 模拟升级前没有历史目录的Schema
+	if err := os.RemoveAll(filepath.Join("schemas", id, "history")); err != nil {
+		t.Fatalf("Failed to remove history: %v", err)
+	}
+
+	if err := storage.SaveSchema(id, "Legacy", "", []byte(`{"type": "object"}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+
+	data, _, err := storage.GetVersion(id, 1)
+	if err != nil {
+		t.Fatalf("Failed to get version: %v", err)
+	}
+
+	if string(data) != string(legacy) {
+		t.Errorf("Legacy content is incorrect: got %s, want %s", string(data), string(legacy))
+	}
+}
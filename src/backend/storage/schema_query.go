@@ -0,0 +1,261 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInvalidQuery 表示排序方式未知，或分页游标无法解析、与当前的排序方式不一致
+var ErrInvalidQuery = errors.New("invalid schema query")
+
+// Schema列表的排序字段
+const (
+	SortByName      = "name"
+	SortByCreatedAt = "createdAt"
+	SortByUpdatedAt = "updatedAt"
+)
+
+// Schema列表的排序方向
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// SchemaQuery 是列出Schema时的过滤、排序和分页条件，零值表示按名称升序列出全部Schema
+type SchemaQuery struct {
+	// Limit 是每页最多返回的数量，0表示不分页
+	Limit int
+	// Cursor 是上一页返回的NextCursor，为空时从第一页开始
+	Cursor string
+	// Sort 是排序字段，默认为name；相同时按ID排序
+	Sort string
+	// Order 是排序方向，默认为asc
+	Order string
+	// NamePrefix 只保留名称以此开头的Schema，不区分大小写
+	NamePrefix string
+	// CreatedAfter、CreatedBefore、UpdatedAfter和UpdatedBefore限定时间范围，零值表示不限
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+	// Search 是全文搜索的关键词，以空格分隔，全部出现在名称、描述或Schema内的title和description中才匹配
+	Search string
+	// Visible 为nil时所有Schema可见，否则只保留返回true的ID
+	Visible func(id string) bool
+}
+
+// SchemaPage 是一页Schema列表
+type SchemaPage struct {
+	Schemas []SchemaMetadata `json:"schemas"`
+	// Total 是符合条件的Schema总数
+	Total int `json:"total"`
+	// NextCursor 用于获取下一页，已经是最后一页时为空
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// schemaCursor 记录上一页最后一个Schema的排序键，插入或删除Schema不会影响后续分页
+type schemaCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Key   string `json:"k"`
+	ID    string `json:"i"`
+}
+
+// QuerySchemas 按条件列出store中的Schema
+func QuerySchemas(store SchemaStore, query SchemaQuery) (SchemaPage, error) {
+	if query.Sort == "" {
+		query.Sort = SortByName
+	}
+	if query.Order == "" {
+		query.Order = OrderAsc
+	}
+	if query.Sort != SortByName && query.Sort != SortByCreatedAt && query.Sort != SortByUpdatedAt {
+		return SchemaPage{}, fmt.Errorf("%w: unknown sort field %q", ErrInvalidQuery, query.Sort)
+	}
+	if query.Order != OrderAsc && query.Order != OrderDesc {
+		return SchemaPage{}, fmt.Errorf("%w: unknown sort order %q", ErrInvalidQuery, query.Order)
+	}
+
+	var after *schemaCursor
+	if query.Cursor != "" {
+		cursor, err := decodeCursor(query.Cursor)
+		if err != nil || cursor.Sort != query.Sort || cursor.Order != query.Order {
+			return SchemaPage{}, fmt.Errorf("%w: invalid cursor %q", ErrInvalidQuery, query.Cursor)
+		}
+		after = &cursor
+	}
+
+	schemas, err := store.ListSchemas()
+	if err != nil {
+		return SchemaPage{}, err
+	}
+
+	matched := make([]SchemaMetadata, 0, len(schemas))
+	for _, schema := range schemas {
+		ok, err := query.matches(store, schema)
+		if err != nil {
+			return SchemaPage{}, err
+		}
+		if ok {
+			matched = append(matched, schema)
+		}
+	}
+
+	// 排序键相同时按ID排序，保证顺序稳定
+	before := func(keyA, idA, keyB, idB string) bool {
+		if keyA != keyB {
+			return (keyA < keyB) == (query.Order == OrderAsc)
+		}
+		if query.Order == OrderAsc {
+			return idA < idB
+		}
+		return idA > idB
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return before(sortKey(matched[i], query.Sort), matched[i].ID, sortKey(matched[j], query.Sort), matched[j].ID)
+	})
+
+	page := SchemaPage{Schemas: matched, Total: len(matched)}
+	if after != nil {
+		start := sort.Search(len(matched), func(i int) bool {
+			return before(after.Key, after.ID, sortKey(matched[i], query.Sort), matched[i].ID)
+		})
+		page.Schemas = matched[start:]
+	}
+
+	if query.Limit > 0 && len(page.Schemas) > query.Limit {
+		page.Schemas = page.Schemas[:query.Limit]
+		last := page.Schemas[len(page.Schemas)-1]
+		page.NextCursor = encodeCursor(schemaCursor{Sort: query.Sort, Order: query.Order, Key: sortKey(last, query.Sort), ID: last.ID})
+	}
+
+	return page, nil
+}
+
+// matches 判断Schema是否符合过滤条件，只有元数据不匹配搜索词时才读取Schema内容
+func (q SchemaQuery) matches(store SchemaStore, schema SchemaMetadata) (bool, error) {
+	if q.Visible != nil && !q.Visible(schema.ID) {
+		return false, nil
+	}
+	if q.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(schema.Name), strings.ToLower(q.NamePrefix)) {
+		return false, nil
+	}
+	if !inRange(schema.CreatedAt, q.CreatedAfter, q.CreatedBefore) || !inRange(schema.UpdatedAt, q.UpdatedAfter, q.UpdatedBefore) {
+		return false, nil
+	}
+
+	terms := strings.Fields(strings.ToLower(q.Search))
+	if len(terms) == 0 {
+		return true, nil
+	}
+
+	text := []string{strings.ToLower(schema.Name), strings.ToLower(schema.Description)}
+	if containsAll(text, terms) {
+		return true, nil
+	}
+
+	data, _, err := store.GetSchema(schema.ID)
+	if errors.Is(err, ErrSchemaNotFound) {
+		// 列出之后被并发删除
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var body any
+	if err := json.Unmarshal(data, &body); err != nil {
+		return false, nil
+	}
+	return containsAll(append(text, schemaText(body)...), terms), nil
+}
+
+// inRange 判断RFC 3339时间是否在(after, before)之间，无法解析的时间只在不限范围时匹配
+func inRange(value string, after time.Time, before time.Time) bool {
+	if after.IsZero() && before.IsZero() {
+		return true
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return false
+	}
+	return (after.IsZero() || t.After(after)) && (before.IsZero() || t.Before(before))
+}
+
+// containsAll 判断每个关键词都出现在text中的某一项里
+func containsAll(text []string, terms []string) bool {
+	for _, term := range terms {
+		found := false
+		for _, item := range text {
+			if strings.Contains(item, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// schemaText 收集Schema中所有字符串类型的title和description，转换为小写
+func schemaText(node any) []string {
+	var text []string
+	switch value := node.(type) {
+	case map[string]any:
+		for key, child := range value {
+			if s, ok := child.(string); ok && (key == "title" || key == "description") {
+				text = append(text, strings.ToLower(s))
+				continue
+			}
+			text = append(text, schemaText(child)...)
+		}
+	case []any:
+		for _, child := range value {
+			text = append(text, schemaText(child)...)
+		}
+	}
+	return text
+}
+
+// sortKey 返回排序键，时间统一转换为UTC以便按字符串比较
+func sortKey(schema SchemaMetadata, field string) string {
+	var value string
+	switch field {
+	case SortByCreatedAt:
+		value = schema.CreatedAt
+	case SortByUpdatedAt:
+		value = schema.UpdatedAt
+	default:
+		return strings.ToLower(schema.Name)
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+	return t.UTC().Format("2006-01-02T15:04:05.000000000Z")
+}
+
+// encodeCursor 把游标编码为URL安全的字符串
+func encodeCursor(cursor schemaCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor 解析encodeCursor生成的字符串
+func decodeCursor(value string) (schemaCursor, error) {
+	var cursor schemaCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, err
+	}
+	err = json.Unmarshal(data, &cursor)
+	return cursor, err
+}
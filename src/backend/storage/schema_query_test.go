@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// querySchemaStore 返回预置了几个Schema的内存存储
+func querySchemaStore(t *testing.T) SchemaStore {
+	store := NewMemorySchemaStore()
+	schemas := []struct {
+		id, name, description, data string
+	}{
+		{"billing", "Billing", "Invoices and payments", `{"type": "object"}`},
+		{"auth", "auth", "Login settings", `{"type": "object", "properties": {"ttl": {"type": "integer", "title": "Session Lifetime"}}}`},
+		{"cache", "Cache", "", `{"type": "object", "properties": {"title": {"type": "string", "description": "Redis address"}}}`},
+		{"cache-eu", "Cache", "Europe", `{"type": "object"}`},
+		{"db", "Database", "", `{"type": "object"}`},
+	}
+	for _, schema := range schemas {
+		if err := store.SaveSchema(schema.id, schema.name, schema.description, []byte(schema.data)); err != nil {
+			t.Fatalf("Failed to save schema: %v", err)
+		}
+	}
+	return store
+}
+
+// ids 返回一页中的Schema ID
+func ids(page SchemaPage) []string {
+	result := make([]string, len(page.Schemas))
+	for i, schema := range page.Schemas {
+		result[i] = schema.ID
+	}
+	return result
+}
+
+// equalIDs 比较两个ID列表
+func equalIDs(got []string, want ...string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// 测试排序和游标分页
+func TestQuerySchemasPaging(t *testing.T) {
+	store := querySchemaStore(t)
+
+	// 默认按名称升序，不区分大小写，名称相同时按ID排序
+	page, err := QuerySchemas(store, SchemaQuery{})
+	if err != nil {
+		t.Fatalf("Failed to query schemas: %v", err)
+	}
+	if got := ids(page); !equalIDs(got, "auth", "billing", "cache", "cache-eu", "db") || page.Total != 5 || page.NextCursor != "" {
+		t.Errorf("Unexpected page: %v %+v", got, page)
+	}
+
+	// 逐页读取降序列表
+	var all []string
+	query := SchemaQuery{Limit: 2, Order: OrderDesc}
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("Too many pages")
+		}
+		page, err := QuerySchemas(store, query)
+		if err != nil {
+			t.Fatalf("Failed to query schemas: %v", err)
+		}
+		all = append(all, ids(page)...)
+
+		// 翻页之间新增的Schema不会导致重复或遗漏
+		if pages == 0 {
+			if err := store.SaveSchema("aaa", "A", "", []byte(`{}`)); err != nil {
+				t.Fatalf("Failed to save schema: %v", err)
+			}
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	if !equalIDs(all, "db", "cache-eu", "cache", "billing", "auth", "aaa") {
+		t.Errorf("Unexpected pages: %v", all)
+	}
+
+	// 游标不能用于其他排序方式
+	page, _ = QuerySchemas(store, SchemaQuery{Limit: 1})
+	if _, err := QuerySchemas(store, SchemaQuery{Cursor: page.NextCursor, Sort: SortByUpdatedAt}); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Expected ErrInvalidQuery, got %v", err)
+	}
+	for _, query := range []SchemaQuery{{Cursor: "!"}, {Sort: "size"}, {Order: "up"}} {
+		if _, err := QuerySchemas(store, query); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("Expected ErrInvalidQuery for %+v, got %v", query, err)
+		}
+	}
+}
+
+// 测试过滤和全文搜索
+func TestQuerySchemasFilters(t *testing.T) {
+	store := querySchemaStore(t)
+
+	tests := []struct {
+		name  string
+		query SchemaQuery
+		want  []string
+	}{
+		{"name prefix", SchemaQuery{NamePrefix: "CA"}, []string{"cache", "cache-eu"}},
+		{"visible", SchemaQuery{Visible: func(id string) bool { return id != "cache" }, NamePrefix: "cache"}, []string{"cache-eu"}},
+		{"description", SchemaQuery{Search: "payments"}, []string{"billing"}},
+		{"property title", SchemaQuery{Search: "session lifetime"}, []string{"auth"}},
+		{"property description", SchemaQuery{Search: "redis"}, []string{"cache"}},
+		{"all terms", SchemaQuery{Search: "cache europe"}, []string{"cache-eu"}},
+		{"property names are not searched", SchemaQuery{Search: "ttl"}, []string{}},
+		{"updated after", SchemaQuery{UpdatedAfter: time.Now().Add(-time.Hour)}, []string{"auth", "billing", "cache", "cache-eu", "db"}},
+		{"created before", SchemaQuery{CreatedBefore: time.Now().Add(-time.Hour)}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := QuerySchemas(store, tt.query)
+			if err != nil {
+				t.Fatalf("Failed to query schemas: %v", err)
+			}
+			if got := ids(page); !equalIDs(got, tt.want...) || page.Total != len(tt.want) {
+				t.Errorf("Expected %v, got %v (total %d)", tt.want, got, page.Total)
+			}
+		})
+	}
+}
@@ -2,13 +2,20 @@ package storage
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"sync"
-	"time"
 )
 
+// ErrSchemaNotFound 表示指定ID的Schema不存在
+var ErrSchemaNotFound = errors.New("schema not found")
+
+// ErrPreconditionFailed 表示If-Match条件与Schema的当前ETag不一致
+var ErrPreconditionFailed = errors.New("precondition failed")
+
 // SchemaStorage 处理JSON Schema的存储和检索
 type SchemaStorage struct {
 	mutex sync.RWMutex
@@ -18,6 +25,14 @@ type SchemaStorage struct {
 	registryPath string
 	// Schema注册表（内存中的缓存）
 	registry map[string]SchemaMetadata
+	// 回收站目录路径
+	trashDir string
+	// 回收站中的Schema（内存中的缓存）
+	trash map[string]TrashedSchema
+	// 预写日志，保证Schema文件、历史和注册表一起更新
+	journal journal
+	// loadErr 是加载注册表时的错误，非空时拒绝修改
+	loadErr error
 }
 
 // SchemaMetadata 表示Schema的元数据
@@ -27,12 +42,14 @@ type SchemaMetadata struct {
 	Description string `json:"description"`
 	CreatedAt   string `json:"createdAt"`
 	UpdatedAt   string `json:"updatedAt"`
+	// Version 是当前内容对应的历史版本号
+	Version int `json:"version"`
 }
 
-// NewSchemaStorage 创建一个新的SchemaStorage实例
-func NewSchemaStorage() *SchemaStorage {
+// NewSchemaStorage 创建一个新的SchemaStorage实例，Schema保存在dataDir下的schemas目录中
+func NewSchemaStorage(dataDir string) *SchemaStorage {
 	// 创建存储目录
-	schemasDir := filepath.Join(".", "schemas")
+	schemasDir := filepath.Join(dataDir, "schemas")
 	os.MkdirAll(schemasDir, os.ModePerm)
 
 	// 注册表文件路径
@@ -43,10 +60,19 @@ func NewSchemaStorage() *SchemaStorage {
 		schemasDir:   schemasDir,
 		registryPath: registryPath,
 		registry:     make(map[string]SchemaMetadata),
+		trashDir:     filepath.Join(dataDir, "trash"),
+		trash:        make(map[string]TrashedSchema),
+		journal:      newJournal(dataDir, filepath.Join(schemasDir, ".journal.json")),
+	}
+
+	// 完成上次崩溃时没有完成的修改
+	if err := storage.journal.recover(); err != nil {
+		fmt.Printf("Error recovering schema journal: %v\n", err)
 	}
 
 	// 加载注册表（无锁版本，避免初始化时的死锁）
 	storage.loadRegistryNoLock()
+	storage.loadTrashNoLock()
 
 	return storage
 }
@@ -65,40 +91,14 @@ func (s *SchemaStorage) loadRegistryNoLock() {
 	data, err := os.ReadFile(s.registryPath)
 	if err != nil {
 		fmt.Printf("Error reading registry file: %v\n", err)
+		s.loadErr = fmt.Errorf("%w: %s", ErrRegistryUnreadable, s.registryPath)
 		return
 	}
 
-	// 解析JSON
+	// 解析JSON，失败时保留原文件，等待fsck修复
 	if err := json.Unmarshal(data, &s.registry); err != nil {
 		fmt.Printf("Error parsing registry file: %v\n", err)
-		return
-	}
-}
-
-// loadRegistry 从文件加载Schema注册表
-func (s *SchemaStorage) loadRegistry() {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	// 检查注册表文件是否存在
-	if _, err := os.Stat(s.registryPath); os.IsNotExist(err) {        
-		// 如果不存在，创建一个空的注册表
-		s.registry = make(map[string]SchemaMetadata)
-		// 使用无锁版本，避免死锁
-		s.saveRegistryNoLock()
-		return
-	}
-
-	// 读取注册表文件
-	data, err := os.ReadFile(s.registryPath)
-	if err != nil {
-		fmt.Printf("Error reading registry file: %v\n", err)      
-		return
-	}
-
-	// 解析JSON
-	if err := json.Unmarshal(data, &s.registry); err != nil {
-		fmt.Printf("Error parsing registry file: %v\n", err)      
+		s.loadErr = fmt.Errorf("%w: %s", ErrRegistryUnreadable, s.registryPath)
 		return
 	}
 }
@@ -112,57 +112,84 @@ func (s *SchemaStorage) saveRegistryNoLock() error {
 	}
 
 	// 写入文件
-	if err := os.WriteFile(s.registryPath, data, 0644); err != nil {
+	if err := writeFileAtomic(s.registryPath, data, 0644); err != nil {
 		return fmt.Errorf("error writing registry file: %w", err)
 	}
 
 	return nil
 }
 
-// saveRegistry 将Schema注册表保存到文件
-func (s *SchemaStorage) saveRegistry() error {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-
-	// 将注册表转换为JSON
-	data, err := json.MarshalIndent(s.registry, "", "  ")
+// registryOp 返回把注册表写入文件的日志操作
+func (s *SchemaStorage) registryOp(registry map[string]SchemaMetadata) (journalOp, error) {
+	data, err := json.MarshalIndent(registry, "", "  ")
 	if err != nil {
-		return fmt.Errorf("error marshaling registry: %w", err)
+		return journalOp{}, fmt.Errorf("error marshaling registry: %w", err)
 	}
+	return writeOp(s.registryPath, data), nil
+}
 
-	// 写入文件
-	if err := os.WriteFile(s.registryPath, data, 0644); err != nil {
-		return fmt.Errorf("error writing registry file: %w", err)
+// recoverNoLock 在修改之前完成上一次失败的修改（无锁版本），并从文件重新加载注册表；注册表无法加载时返回ErrRegistryUnreadable
+func (s *SchemaStorage) recoverNoLock() error {
+	if !s.journal.pending() {
+		return s.loadErr
 	}
 
-	return nil
+	if err := s.journal.recover(); err != nil {
+		return err
+	}
+
+	s.loadErr = nil
+	s.registry = make(map[string]SchemaMetadata)
+	s.loadRegistryNoLock()
+	s.trash = make(map[string]TrashedSchema)
+	s.loadTrashNoLock()
+	return s.loadErr
 }
 
 // SaveSchema 保存JSON Schema
 func (s *SchemaStorage) SaveSchema(id string, name string, description string, schemaData []byte) error {
+	_, err := s.SaveSchemaRevision(id, name, description, schemaData, RevisionInfo{})
+	return err
+}
+
+// SaveSchemaRevision 保存JSON Schema并在历史中追加一个带作者和说明的新版本
+func (s *SchemaStorage) SaveSchemaRevision(id string, name string, description string, schemaData []byte, info RevisionInfo) (SchemaVersion, error) {
+	if err := ValidateID(id); err != nil {
+		return SchemaVersion{}, err
+	}
+
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	// 创建Schema目录
-	schemaDir := filepath.Join(s.schemasDir, id)
-	if err := os.MkdirAll(schemaDir, os.ModePerm); err != nil {
-		return fmt.Errorf("error creating schema directory: %w", err)
+	if err := s.recoverNoLock(); err != nil {
+		return SchemaVersion{}, err
+	}
+
+	return s.saveSchemaNoLock(id, name, description, schemaData, info)
+}
+
+// saveSchemaNoLock 保存JSON Schema（无锁版本）
+func (s *SchemaStorage) saveSchemaNoLock(id string, name string, description string, schemaData []byte, info RevisionInfo) (SchemaVersion, error) {
+	// 检查并发修改条件
+	if err := s.checkIfMatchNoLock(id, info.IfMatch); err != nil {
+		return SchemaVersion{}, err
 	}
 
-	// 保存Schema文件
-	schemaPath := filepath.Join(schemaDir, "schema.json")
-	if err := os.WriteFile(schemaPath, schemaData, 0644); err != nil {
-		return fmt.Errorf("error writing schema file: %w", err)
+	// 先追加历史版本，再覆盖当前内容
+	version, ops, err := s.versionOpsNoLock(id, schemaData, info)
+	if err != nil {
+		return SchemaVersion{}, fmt.Errorf("error saving schema version: %w", err)
 	}
+	ops = append(ops, writeOp(filepath.Join(s.schemasDir, id, "schema.json"), schemaData))
 
 	// 更新元数据
-	now := time.Now().Format(time.RFC3339)
 	metadata := SchemaMetadata{
 		ID:          id,
 		Name:        name,
 		Description: description,
-		CreatedAt:   now,
-		UpdatedAt:   now,
+		CreatedAt:   version.CreatedAt,
+		UpdatedAt:   version.CreatedAt,
+		Version:     version.Version,
 	}
 
 	// 如果已存在，保留创建时间
@@ -170,15 +197,20 @@ func (s *SchemaStorage) SaveSchema(id string, name string, description string, s
 		metadata.CreatedAt = existing.CreatedAt
 	}
 
-	// 更新注册表
-	s.registry[id] = metadata
+	// 更新注册表，与Schema文件和历史一起写入
+	registry := maps.Clone(s.registry)
+	registry[id] = metadata
+	op, err := s.registryOp(registry)
+	if err != nil {
+		return SchemaVersion{}, err
+	}
 
-	// 保存注册表（使用无锁版本，避免死锁）
-	if err := s.saveRegistryNoLock(); err != nil {
-		return fmt.Errorf("error saving registry: %w", err)
+	if err := s.journal.commit(append(ops, op)...); err != nil {
+		return SchemaVersion{}, fmt.Errorf("error saving schema: %w", err)
 	}
+	s.registry = registry
 
-	return nil
+	return version, nil
 }
 
 // GetSchema 获取指定ID的Schema
@@ -189,7 +221,7 @@ func (s *SchemaStorage) GetSchema(id string) ([]byte, SchemaMetadata, error) {
 	// 检查Schema是否存在
 	metadata, exists := s.registry[id]
 	if !exists {
-		return nil, SchemaMetadata{}, fmt.Errorf("schema not found: %s", id)
+		return nil, SchemaMetadata{}, fmt.Errorf("%w: %s", ErrSchemaNotFound, id)
 	}
 
 	// 读取Schema文件
@@ -218,27 +250,29 @@ func (s *SchemaStorage) ListSchemas() ([]SchemaMetadata, error) {
 
 // DeleteSchema 删除指定ID的Schema
 func (s *SchemaStorage) DeleteSchema(id string) error {
+	return s.DeleteSchemaIfMatch(id, "")
+}
+
+// DeleteSchemaIfMatch 把指定ID的Schema移入回收站，ifMatch非空时仅在ETag一致时删除
+func (s *SchemaStorage) DeleteSchemaIfMatch(id string, ifMatch string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	// 检查Schema是否存在
-	if _, exists := s.registry[id]; !exists {
-		return fmt.Errorf("schema not found: %s", id)
+	if err := s.recoverNoLock(); err != nil {
+		return err
 	}
 
-	// 删除Schema目录
-	schemaDir := filepath.Join(s.schemasDir, id)
-	if err := os.RemoveAll(schemaDir); err != nil {
-		return fmt.Errorf("error deleting schema directory: %w", err)
+	// 检查Schema是否存在
+	metadata, exists := s.registry[id]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrSchemaNotFound, id)
 	}
 
-	// 从注册表中删除
-	delete(s.registry, id)
-
-	// 保存注册表（使用无锁版本，避免死锁）
-	if err := s.saveRegistryNoLock(); err != nil {
-		return fmt.Errorf("error saving registry: %w", err)
+	// 检查并发修改条件
+	if err := s.checkIfMatchNoLock(id, ifMatch); err != nil {
+		return err
 	}
 
-	return nil
+	// 把Schema目录连同历史移入回收站
+	return s.moveToTrashNoLock(metadata)
 }
@@ -43,7 +43,7 @@ func TestNewSchemaStorage(t *testing.T) {
 	defer os.Chdir(oldWd)
 
 	// 创建存储实例
-	storage := NewSchemaStorage()
+	storage := NewSchemaStorage(".")
 	
 	// 确保存储实例创建成功
 	if storage == nil {
@@ -97,7 +97,7 @@ func TestSaveSchema(t *testing.T) {
 	defer os.Chdir(oldWd)
 
 	// 创建存储实例
-	storage := NewSchemaStorage()
+	storage := NewSchemaStorage(".")
 
 	// 测试数据
 	id := "test-schema"
@@ -190,7 +190,7 @@ func TestGetSchema(t *testing.T) {
 	defer os.Chdir(oldWd)
 
 	// 创建存储实例
-	storage := NewSchemaStorage()
+	storage := NewSchemaStorage(".")
 
 	// 测试数据
 	id := "test-schema"
@@ -253,7 +253,7 @@ func TestListSchemas(t *testing.T) {
 	defer os.Chdir(oldWd)
 
 	// 创建存储实例
-	storage := NewSchemaStorage()
+	storage := NewSchemaStorage(".")
 
 	// 测试数据
 	schemas := []struct {
@@ -334,7 +334,7 @@ func TestDeleteSchema(t *testing.T) {
 	defer os.Chdir(oldWd)
 
 	// 创建存储实例
-	storage := NewSchemaStorage()
+	storage := NewSchemaStorage(".")
 
 	// 测试数据
 	id := "test-schema"
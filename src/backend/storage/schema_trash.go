@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotInTrash 表示回收站中没有指定ID的Schema
+var ErrNotInTrash = errors.New("schema not in trash")
+
+// ErrSchemaExists 表示同一ID的Schema已经存在，不能从回收站恢复或以CreateOnly条件保存
+var ErrSchemaExists = errors.New("schema already exists")
+
+// TrashedSchema 是回收站中的一个Schema
+type TrashedSchema struct {
+	SchemaMetadata
+	// TrashID 区分同一Schema被多次删除后留下的副本
+	TrashID string `json:"trashId"`
+	// DeletedAt 是Schema被删除的时间
+	DeletedAt string `json:"deletedAt"`
+}
+
+// newTrashID 返回回收站条目的键，由Schema ID和删除时间组成；exists判断键是否已被占用
+func newTrashID(id string, deletedAt time.Time, exists func(string) bool) string {
+	for nanos := deletedAt.UnixNano(); ; nanos++ {
+		trashID := id + "@" + strconv.FormatInt(nanos, 10)
+		if !exists(trashID) {
+			return trashID
+		}
+	}
+}
+
+// trashIDSchema 返回回收站条目的键对应的Schema ID，旧版本的键就是Schema ID
+func trashIDSchema(trashID string) string {
+	id, _, _ := strings.Cut(trashID, "@")
+	return id
+}
+
+// validateTrashID 检查回收站条目的键，键同时用作trash目录下的目录名
+func validateTrashID(trashID string) error {
+	if err := ValidateID(trashIDSchema(trashID)); err != nil {
+		return err
+	}
+	if _, suffix, found := strings.Cut(trashID, "@"); found {
+		if _, err := strconv.ParseInt(suffix, 10, 64); err != nil {
+			return fmt.Errorf("%w: %q has an invalid trash suffix", ErrInvalidID, trashID)
+		}
+	}
+	return nil
+}
+
+// latestTrashed 在回收站中选出要恢复的条目：trashID非空时选该条目，否则选id最近删除的副本
+func latestTrashed(trash []TrashedSchema, id string, trashID string) (TrashedSchema, bool) {
+	var latest TrashedSchema
+	found := false
+	for _, trashed := range trash {
+		if trashed.ID != id || (trashID != "" && trashed.TrashID != trashID) {
+			continue
+		}
+		if !found || trashed.DeletedAt > latest.DeletedAt || (trashed.DeletedAt == latest.DeletedAt && trashed.TrashID > latest.TrashID) {
+			latest, found = trashed, true
+		}
+	}
+	return latest, found
+}
+
+// deletedBefore 判断Schema是否在指定时间之前被删除，删除时间无法解析时视为已过期
+func (t TrashedSchema) deletedBefore(cutoff time.Time) bool {
+	deletedAt, err := time.Parse(time.RFC3339, t.DeletedAt)
+	return err != nil || deletedAt.Before(cutoff)
+}
+
+// sortTrash 按删除时间降序排序，最近删除的在前
+func sortTrash(trash []TrashedSchema) {
+	sort.Slice(trash, func(i, j int) bool {
+		if trash[i].DeletedAt != trash[j].DeletedAt {
+			return trash[i].DeletedAt > trash[j].DeletedAt
+		}
+		if trash[i].ID != trash[j].ID {
+			return trash[i].ID < trash[j].ID
+		}
+		return trash[i].TrashID > trash[j].TrashID
+	})
+}
+
+// trashRegistryPath 返回回收站注册表文件路径
+func (s *SchemaStorage) trashRegistryPath() string {
+	return filepath.Join(s.trashDir, "trash-registry.json")
+}
+
+// loadTrashNoLock 从文件加载回收站注册表（无锁版本，仅在初始化时使用）
+func (s *SchemaStorage) loadTrashNoLock() {
+	data, err := os.ReadFile(s.trashRegistryPath())
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error reading trash registry file: %v\n", err)
+		s.loadErr = fmt.Errorf("%w: %s", ErrRegistryUnreadable, s.trashRegistryPath())
+		return
+	}
+
+	if err := json.Unmarshal(data, &s.trash); err != nil {
+		fmt.Printf("Error parsing trash registry file: %v\n", err)
+		s.loadErr = fmt.Errorf("%w: %s", ErrRegistryUnreadable, s.trashRegistryPath())
+		return
+	}
+
+	// 旧版本的回收站以Schema ID为键
+	for trashID, trashed := range s.trash {
+		if trashed.TrashID == "" {
+			trashed.TrashID = trashID
+			s.trash[trashID] = trashed
+		}
+	}
+}
+
+// trashOp 返回把回收站注册表写入文件的日志操作
+func (s *SchemaStorage) trashOp(trash map[string]TrashedSchema) (journalOp, error) {
+	data, err := json.MarshalIndent(trash, "", "  ")
+	if err != nil {
+		return journalOp{}, fmt.Errorf("error marshaling trash registry: %w", err)
+	}
+	return writeOp(s.trashRegistryPath(), data), nil
+}
+
+// moveToTrashNoLock 把Schema目录移入回收站并更新两个注册表（无锁版本），回收站中同一ID的旧副本会保留
+func (s *SchemaStorage) moveToTrashNoLock(metadata SchemaMetadata) error {
+	now := time.Now()
+	trashID := newTrashID(metadata.ID, now, func(trashID string) bool {
+		_, exists := s.trash[trashID]
+		return exists
+	})
+
+	registry := maps.Clone(s.registry)
+	delete(registry, metadata.ID)
+	trash := maps.Clone(s.trash)
+	trash[trashID] = TrashedSchema{SchemaMetadata: metadata, TrashID: trashID, DeletedAt: now.Format(time.RFC3339)}
+
+	ops := []journalOp{renameOp(filepath.Join(s.schemasDir, metadata.ID), filepath.Join(s.trashDir, trashID))}
+	if err := s.commitRegistriesNoLock(ops, registry, trash); err != nil {
+		return fmt.Errorf("error moving schema to trash: %w", err)
+	}
+
+	return nil
+}
+
+// commitRegistriesNoLock 与两个注册表一起执行ops（无锁版本），成功后替换内存中的注册表
+func (s *SchemaStorage) commitRegistriesNoLock(ops []journalOp, registry map[string]SchemaMetadata, trash map[string]TrashedSchema) error {
+	registryOp, err := s.registryOp(registry)
+	if err != nil {
+		return err
+	}
+	trashOp, err := s.trashOp(trash)
+	if err != nil {
+		return err
+	}
+
+	if err := s.journal.commit(append(ops, registryOp, trashOp)...); err != nil {
+		return err
+	}
+
+	s.registry = registry
+	s.trash = trash
+	return nil
+}
+
+// ListTrash 列出回收站中的Schema，最近删除的在前
+func (s *SchemaStorage) ListTrash() ([]TrashedSchema, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	trash := make([]TrashedSchema, 0, len(s.trash))
+	for _, trashed := range s.trash {
+		trash = append(trash, trashed)
+	}
+	sortTrash(trash)
+
+	return trash, nil
+}
+
+// RestoreSchema 把回收站中的Schema连同历史恢复为可用状态，trashID为空时恢复最近删除的副本
+func (s *SchemaStorage) RestoreSchema(id string, trashID string) (SchemaMetadata, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.recoverNoLock(); err != nil {
+		return SchemaMetadata{}, err
+	}
+
+	trashed, exists := latestTrashed(slices.Collect(maps.Values(s.trash)), id, trashID)
+	if !exists {
+		return SchemaMetadata{}, fmt.Errorf("%w: %s", ErrNotInTrash, id)
+	}
+
+	// 删除后又创建了同一ID的Schema时不能覆盖
+	if _, exists := s.registry[id]; exists {
+		return SchemaMetadata{}, fmt.Errorf("%w: %s", ErrSchemaExists, id)
+	}
+
+	registry := maps.Clone(s.registry)
+	registry[id] = trashed.SchemaMetadata
+	trash := maps.Clone(s.trash)
+	delete(trash, trashed.TrashID)
+
+	ops := []journalOp{renameOp(filepath.Join(s.trashDir, trashed.TrashID), filepath.Join(s.schemasDir, id))}
+	if err := s.commitRegistriesNoLock(ops, registry, trash); err != nil {
+		return SchemaMetadata{}, fmt.Errorf("error restoring schema from trash: %w", err)
+	}
+
+	return trashed.SchemaMetadata, nil
+}
+
+// PurgeTrash 永久删除回收站中在deletedBefore之前删除的Schema，返回被删除的ID，同一Schema的多个副本各返回一次
+func (s *SchemaStorage) PurgeTrash(deletedBefore time.Time) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.recoverNoLock(); err != nil {
+		return nil, err
+	}
+
+	purged := []string{}
+	trash := maps.Clone(s.trash)
+	var ops []journalOp
+	for trashID, trashed := range s.trash {
+		if trashed.deletedBefore(deletedBefore) {
+			purged = append(purged, trashed.ID)
+			delete(trash, trashID)
+			ops = append(ops, removeOp(filepath.Join(s.trashDir, trashID)))
+		}
+	}
+	if len(purged) == 0 {
+		return purged, nil
+	}
+	sort.Strings(purged)
+
+	if err := s.commitRegistriesNoLock(ops, s.registry, trash); err != nil {
+		return nil, fmt.Errorf("error purging trash: %w", err)
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 测试所有SchemaStore实现的回收站行为一致
+func TestSchemaTrash(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	bolt, err := OpenBoltStore(filepath.Join(tempDir, "goci.db"))
+	if err != nil {
+		t.Fatalf("Failed to open bolt store: %v", err)
+	}
+	defer bolt.Close()
+
+	stores := map[string]SchemaStore{
+		"filesystem": NewSchemaStorage(filepath.Join(tempDir, "data")),
+		"memory":     NewMemorySchemaStore(),
+		"bolt":       bolt.Schemas(),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			testSchemaTrash(t, store)
+		})
+	}
+}
+
+// testSchemaTrash 对一个SchemaStore执行回收站的行为检查
+func testSchemaTrash(t *testing.T, store SchemaStore) {
+	id := "trash-schema"
+	first := []byte(`{"type": "object"}`)
+	second := []byte(`{"type": "string"}`)
+
+	if err := store.SaveSchema(id, "Trash", "A trashed schema", first); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if err := store.SaveSchema(id, "Trash", "A trashed schema", second); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+
+	if _, err := store.RestoreSchema(id, ""); !errors.Is(err, ErrNotInTrash) {
+		t.Errorf("Expected ErrNotInTrash, got %v", err)
+	}
+
+	// 删除后Schema不可用，但出现在回收站中
+	if err := store.DeleteSchema(id); err != nil {
+		t.Fatalf("Failed to delete schema: %v", err)
+	}
+	if _, _, err := store.GetSchema(id); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("Expected ErrSchemaNotFound, got %v", err)
+	}
+	if list, _ := store.ListSchemas(); len(list) != 0 {
+		t.Errorf("Deleted schema still listed: %+v", list)
+	}
+
+	trash, err := store.ListTrash()
+	if err != nil || len(trash) != 1 || trash[0].ID != id || trash[0].Version != 2 || trash[0].DeletedAt == "" {
+		t.Fatalf("Unexpected trash: %+v, %v", trash, err)
+	}
+
+	// 恢复后内容和历史都在
+	metadata, err := store.RestoreSchema(id, "")
+	if err != nil || metadata.Name != "Trash" || metadata.Version != 2 {
+		t.Fatalf("Unexpected restored schema: %+v, %v", metadata, err)
+	}
+	if data, _, err := store.GetSchema(id); err != nil || string(data) != string(second) {
+		t.Errorf("Unexpected restored content: %s, %v", string(data), err)
+	}
+	if versions, err := store.ListVersions(id); err != nil || len(versions) != 2 {
+		t.Errorf("Unexpected restored versions: %+v, %v", versions, err)
+	}
+	if trash, _ := store.ListTrash(); len(trash) != 0 {
+		t.Errorf("Restored schema still in trash: %+v", trash)
+	}
+
+	// 删除后又创建了同一ID的Schema时不能恢复
+	if err := store.DeleteSchema(id); err != nil {
+		t.Fatalf("Failed to delete schema: %v", err)
+	}
+	if err := store.SaveSchema(id, "New", "", first); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if _, err := store.RestoreSchema(id, ""); !errors.Is(err, ErrSchemaExists) {
+		t.Errorf("Expected ErrSchemaExists, got %v", err)
+	}
+
+	// 只清理在截止时间之前删除的Schema
+	purged, err := store.PurgeTrash(time.Now().Add(-time.Hour))
+	if err != nil || len(purged) != 0 {
+		t.Errorf("Unexpected purge result: %v, %v", purged, err)
+	}
+	purged, err = store.PurgeTrash(time.Now().Add(time.Hour))
+	if err != nil || len(purged) != 1 || purged[0] != id {
+		t.Errorf("Unexpected purge result: %v, %v", purged, err)
+	}
+	if _, err := store.RestoreSchema(id, ""); !errors.Is(err, ErrNotInTrash) {
+		t.Errorf("Expected ErrNotInTrash after purge, got %v", err)
+	}
+
+	// 清理回收站不影响可用的Schema
+	if _, metadata, err := store.GetSchema(id); err != nil || metadata.Name != "New" {
+		t.Errorf("Unexpected schema after purge: %+v, %v", metadata, err)
+	}
+
+	// 同一ID多次删除时回收站保留每个副本
+	if err := store.DeleteSchema(id); err != nil {
+		t.Fatalf("Failed to delete schema: %v", err)
+	}
+	if err := store.SaveSchema(id, "Newer", "", second); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if err := store.DeleteSchema(id); err != nil {
+		t.Fatalf("Failed to delete schema: %v", err)
+	}
+	trash, err = store.ListTrash()
+	if err != nil || len(trash) != 2 || trash[0].Name != "Newer" || trash[1].Name != "New" || trash[0].TrashID == trash[1].TrashID {
+		t.Fatalf("Unexpected trash: %+v, %v", trash, err)
+	}
+
+	// 指定trashID恢复较早删除的副本
+	if _, err := store.RestoreSchema(id, id+"@1"); !errors.Is(err, ErrNotInTrash) {
+		t.Errorf("Expected ErrNotInTrash, got %v", err)
+	}
+	if _, err := store.RestoreSchema("other", trash[1].TrashID); !errors.Is(err, ErrNotInTrash) {
+		t.Errorf("Expected ErrNotInTrash for another schema, got %v", err)
+	}
+	if metadata, err := store.RestoreSchema(id, trash[1].TrashID); err != nil || metadata.Name != "New" {
+		t.Fatalf("Unexpected restored schema: %+v, %v", metadata, err)
+	}
+	if data, _, err := store.GetSchema(id); err != nil || string(data) != string(first) {
+		t.Errorf("Unexpected restored content: %s, %v", string(data), err)
+	}
+	if remaining, _ := store.ListTrash(); len(remaining) != 1 || remaining[0].TrashID != trash[0].TrashID {
+		t.Errorf("Unexpected trash after restore: %+v", remaining)
+	}
+
+	// 不指定trashID时恢复最近删除的副本
+	if err := store.DeleteSchema(id); err != nil {
+		t.Fatalf("Failed to delete schema: %v", err)
+	}
+	if metadata, err := store.RestoreSchema(id, ""); err != nil || metadata.Name != "New" {
+		t.Errorf("Unexpected restored schema: %+v, %v", metadata, err)
+	}
+	if remaining, _ := store.ListTrash(); len(remaining) != 1 || remaining[0].Name != "Newer" {
+		t.Errorf("Unexpected trash after restore: %+v", remaining)
+	}
+}
+
+// 测试文件系统回收站在重新打开后仍然可用
+func TestSchemaStorageTrashPersistence(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	store := NewSchemaStorage(tempDir)
+	if err := store.SaveSchema("a", "A", "", []byte(`{"type": "object"}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if err := store.DeleteSchema("a"); err != nil {
+		t.Fatalf("Failed to delete schema: %v", err)
+	}
+
+	// 重新打开
+	store = NewSchemaStorage(tempDir)
+	trash, err := store.ListTrash()
+	if err != nil || len(trash) != 1 || trash[0].ID != "a" {
+		t.Fatalf("Unexpected trash after reopen: %+v, %v", trash, err)
+	}
+	if _, err := store.RestoreSchema("a", ""); err != nil {
+		t.Fatalf("Failed to restore schema: %v", err)
+	}
+	if data, _, err := store.GetSchema("a"); err != nil || string(data) != `{"type": "object"}` {
+		t.Errorf("Unexpected restored content: %s, %v", string(data), err)
+	}
+}
+
+// 测试bbolt移入回收站时写入失败不会删除可用的记录
+func TestBoltTrashMoveAtomic(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	bolt, err := OpenBoltStore(filepath.Join(tempDir, "goci.db"))
+	if err != nil {
+		t.Fatalf("Failed to open bolt store: %v", err)
+	}
+	defer bolt.Close()
+
+	store := bolt.Schemas().(*recordSchemaStore)
+	if err := store.SaveSchema("a", "A", "", []byte(`{"type": "object"}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+
+	// 空键无法写入回收站，删除也随事务回滚
+	if err := store.backend.moveRecord("a", &schemaRecord{}, store.trash); err == nil {
+		t.Fatalf("Expected error moving record with an empty key")
+	}
+	if _, _, err := store.GetSchema("a"); err != nil {
+		t.Errorf("Schema was removed by a failed move: %v", err)
+	}
+	if trash, err := store.ListTrash(); err != nil || len(trash) != 0 {
+		t.Errorf("Unexpected trash: %+v, %v", trash, err)
+	}
+}
+
+// 测试旧版本以Schema ID为键的回收站仍然可以列出和恢复
+func TestSchemaStorageLegacyTrash(t *testing.T) {
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	store := NewSchemaStorage(tempDir)
+	if err := store.SaveSchema("a", "A", "", []byte(`{"type": "object"}`)); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+
+	// 把Schema目录按旧版本的方式移入回收站
+	trashDir := filepath.Join(tempDir, "trash")
+	if err := os.MkdirAll(trashDir, os.ModePerm); err != nil {
+		t.Fatalf("Failed to create trash dir: %v", err)
+	}
+	if err := os.Rename(filepath.Join(tempDir, "schemas", "a"), filepath.Join(trashDir, "a")); err != nil {
+		t.Fatalf("Failed to move schema: %v", err)
+	}
+	legacy := `{"a": {"id": "a", "name": "A", "version": 1, "deletedAt": "2024-01-01T00:00:00Z"}}`
+	if err := os.WriteFile(filepath.Join(trashDir, "trash-registry.json"), []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write trash registry: %v", err)
+	}
+	store.registry = map[string]SchemaMetadata{}
+	if err := store.saveRegistryNoLock(); err != nil {
+		t.Fatalf("Failed to save registry: %v", err)
+	}
+
+	store = NewSchemaStorage(tempDir)
+	trash, err := store.ListTrash()
+	if err != nil || len(trash) != 1 || trash[0].ID != "a" || trash[0].TrashID != "a" {
+		t.Fatalf("Unexpected legacy trash: %+v, %v", trash, err)
+	}
+	if _, err := store.RestoreSchema("a", "a"); err != nil {
+		t.Fatalf("Failed to restore schema: %v", err)
+	}
+	if data, _, err := store.GetSchema("a"); err != nil || string(data) != `{"type": "object"}` {
+		t.Errorf("Unexpected restored content: %s, %v", string(data), err)
+	}
+}
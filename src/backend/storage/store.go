@@ -0,0 +1,61 @@
+package storage
+
+import "time"
+
+// SchemaStore 定义Schema存储后端需要提供的操作
+//
+// SchemaStorage是基于文件系统的实现，MemorySchemaStore用于测试，
+// BoltStore提供基于bbolt的嵌入式实现
+type SchemaStore interface {
+	// SaveSchema 保存Schema并追加一个匿名版本
+	SaveSchema(id string, name string, description string, schemaData []byte) error
+	// SaveSchemaRevision 保存Schema并追加一个带作者和说明的版本，ID不符合ValidateID时返回ErrInvalidID
+	SaveSchemaRevision(id string, name string, description string, schemaData []byte, info RevisionInfo) (SchemaVersion, error)
+	// GetSchema 获取Schema的当前内容和元数据
+	GetSchema(id string) ([]byte, SchemaMetadata, error)
+	// ListSchemas 列出所有Schema的元数据
+	ListSchemas() ([]SchemaMetadata, error)
+	// DeleteSchema 把Schema及其历史移入回收站
+	DeleteSchema(id string) error
+	// DeleteSchemaIfMatch 在ETag匹配时把Schema移入回收站，ifMatch为空时不做检查
+	DeleteSchemaIfMatch(id string, ifMatch string) error
+	// ListVersions 列出Schema的历史版本
+	ListVersions(id string) ([]SchemaVersion, error)
+	// GetVersion 获取Schema指定版本的内容
+	GetVersion(id string, version int) ([]byte, SchemaVersion, error)
+	// RestoreVersion 以指定版本的内容追加一个新版本
+	RestoreVersion(id string, version int, info RevisionInfo) (SchemaVersion, error)
+	// ListTrash 列出回收站中的Schema，最近删除的在前
+	ListTrash() ([]TrashedSchema, error)
+	// RestoreSchema 把回收站中的Schema连同历史恢复为可用状态，trashID为空时恢复最近删除的副本
+	RestoreSchema(id string, trashID string) (SchemaMetadata, error)
+	// PurgeTrash 永久删除回收站中在deletedBefore之前删除的Schema，返回被删除的ID，同一Schema的多个副本各返回一次
+	PurgeTrash(deletedBefore time.Time) ([]string, error)
+}
+
+// ConfigStore 定义配置存储后端需要提供的操作
+type ConfigStore interface {
+	// SaveConfig 保存指定Schema的配置
+	SaveConfig(schemaID string, configData []byte) error
+	// SaveConfigVersion 保存配置并记录其对应的Schema版本，SaveConfig记录的版本为0；ID不符合ValidateID时返回ErrInvalidID
+	SaveConfigVersion(schemaID string, configData []byte, schemaVersion int) error
+	// SaveConfigIfMatch 同SaveConfigVersion，ifMatch非空时仅在配置当前的ConfigETag与之一致时保存，
+	// 否则返回ErrPreconditionFailed
+	SaveConfigIfMatch(schemaID string, configData []byte, schemaVersion int, ifMatch string) error
+	// GetConfig 获取指定Schema的配置
+	GetConfig(schemaID string) ([]byte, ConfigMetadata, error)
+	// ListConfigs 列出所有配置的元数据
+	ListConfigs() ([]ConfigMetadata, error)
+	// DeleteConfig 删除指定Schema的配置
+	DeleteConfig(schemaID string) error
+	// DeleteConfigIfMatch 在ETag匹配时删除配置，ifMatch为空时不做检查
+	DeleteConfigIfMatch(schemaID string, ifMatch string) error
+}
+
+// 确保各实现满足接口
+var (
+	_ SchemaStore = (*SchemaStorage)(nil)
+	_ SchemaStore = (*MemorySchemaStore)(nil)
+	_ ConfigStore = (*ConfigStorage)(nil)
+	_ ConfigStore = (*MemoryConfigStore)(nil)
+)
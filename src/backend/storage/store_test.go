@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 测试所有SchemaStore实现的行为一致
+func TestSchemaStoreImplementations(t *testing.T) {
+	// 创建临时目录
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	// 保存当前工作目录
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory: %v", err)
+	}
+
+	// 切换到临时目录
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change working directory: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	bolt, err := OpenBoltStore(filepath.Join(tempDir, "goci.db"))
+	if err != nil {
+		t.Fatalf("Failed to open bolt store: %v", err)
+	}
+	defer bolt.Close()
+
+	stores := map[string]SchemaStore{
+		"filesystem": NewSchemaStorage("."),
+		"memory":     NewMemorySchemaStore(),
+		"bolt":       bolt.Schemas(),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			testSchemaStore(t, store)
+		})
+	}
+}
+
+// testSchemaStore 对一个SchemaStore执行通用的行为检查
+func testSchemaStore(t *testing.T, store SchemaStore) {
+	id := "test-schema"
+	first := []byte(`{"type": "object"}`)
+	second := []byte(`{"type": "string"}`)
+
+	if _, _, err := store.GetSchema(id); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("Expected ErrSchemaNotFound, got %v", err)
+	}
+
+	// 保存两个版本，第一个版本只在Schema不存在时创建
+	if _, err := store.SaveSchemaRevision(id, "Test", "A test schema", first, RevisionInfo{IfMatch: CreateOnly}); err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+	if _, err := store.SaveSchemaRevision(id, "Test", "A test schema", second, RevisionInfo{IfMatch: CreateOnly}); !errors.Is(err, ErrSchemaExists) {
+		t.Errorf("Expected ErrSchemaExists, got %v", err)
+	}
+	migration := []MigrationStep{{Op: "rename", Path: "/name", To: "title"}}
+	version, err := store.SaveSchemaRevision(id, "Test", "A test schema", second, RevisionInfo{Author: "alice", Migration: migration})
+	if err != nil {
+		t.Fatalf("Failed to save schema: %v", err)
+	}
+
+	if version.Version != 2 || version.Author != "alice" {
+		t.Errorf("Unexpected version: %+v", version)
+	}
+
+	// 迁移步骤随版本一起保存
+	if _, saved, err := store.GetVersion(id, 2); err != nil || len(saved.Migration) != 1 || saved.Migration[0].To != "title" {
+		t.Errorf("Unexpected migration: %+v, %v", saved.Migration, err)
+	}
+
+	data, metadata, err := store.GetSchema(id)
+	if err != nil {
+		t.Fatalf("Failed to get schema: %v", err)
+	}
+
+	if string(data) != string(second) || metadata.Name != "Test" || metadata.Version != 2 || metadata.CreatedAt == "" {
+		t.Errorf("Unexpected schema: %s %+v", string(data), metadata)
+	}
+
+	list, err := store.ListSchemas()
+	if err != nil || len(list) != 1 || list[0].ID != id {
+		t.Errorf("Unexpected schema list: %+v, %v", list, err)
+	}
+
+	// 历史版本
+	versions, err := store.ListVersions(id)
+	if err != nil || len(versions) != 2 {
+		t.Errorf("Unexpected versions: %+v, %v", versions, err)
+	}
+
+	if data, _, err := store.GetVersion(id, 1); err != nil || string(data) != string(first) {
+		t.Errorf("Unexpected version 1: %s, %v", string(data), err)
+	}
+
+	if _, _, err := store.GetVersion(id, 5); !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("Expected ErrVersionNotFound, got %v", err)
+	}
+
+	restored, err := store.RestoreVersion(id, 1, RevisionInfo{})
+	if err != nil || restored.Version != 3 {
+		t.Errorf("Unexpected restored version: %+v, %v", restored, err)
+	}
+
+	// If-Match
+	data, metadata, _ = store.GetSchema(id)
+	etag := SchemaETag(metadata.Version, data)
+
+	if err := store.DeleteSchemaIfMatch(id, `"stale"`); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("Expected ErrPreconditionFailed, got %v", err)
+	}
+
+	if err := store.DeleteSchemaIfMatch(id, etag); err != nil {
+		t.Fatalf("Failed to delete schema: %v", err)
+	}
+
+	if err := store.DeleteSchema(id); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("Expected ErrSchemaNotFound, got %v", err)
+	}
+}
+
+// 测试所有ConfigStore实现的行为一致
+func TestConfigStoreImplementations(t *testing.T) {
+	// 创建临时目录
+	tempDir := createTempDir(t)
+	defer cleanupTempDir(t, tempDir)
+
+	// 保存当前工作目录
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current working directory: %v", err)
+	}
+
+	// 切换到临时目录
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatalf("Failed to change working directory: %v", err)
+	}
+	defer os.Chdir(oldWd)
+
+	bolt, err := OpenBoltStore(filepath.Join(tempDir, "goci.db"))
+	if err != nil {
+		t.Fatalf("Failed to open bolt store: %v", err)
+	}
+	defer bolt.Close()
+
+	stores := map[string]ConfigStore{
+		"filesystem": NewConfigStorage("."),
+		"memory":     NewMemoryConfigStore(),
+		"bolt":       bolt.Configs(),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			if err := store.SaveConfig("schema1", []byte(`{"a": 1}`)); err != nil {
+				t.Fatalf("Failed to save config: %v", err)
+			}
+
+			data, metadata, err := store.GetConfig("schema1")
+			if err != nil || string(data) != `{"a": 1}` || metadata.SchemaID != "schema1" {
+				t.Errorf("Unexpected config: %s %+v, %v", string(data), metadata, err)
+			}
+
+			if list, err := store.ListConfigs(); err != nil || len(list) != 1 {
+				t.Errorf("Unexpected config list: %+v, %v", list, err)
+			}
+
+			// 记录配置对应的Schema版本
+			if err := store.SaveConfigVersion("schema1", []byte(`{"a": 2}`), 3); err != nil {
+				t.Fatalf("Failed to save config: %v", err)
+			}
+
+			if _, metadata, err := store.GetConfig("schema1"); err != nil || metadata.SchemaVersion != 3 {
+				t.Errorf("Unexpected config metadata: %+v, %v", metadata, err)
+			}
+
+			// If-Match条件与当前内容不一致时不保存
+			if err := store.SaveConfigIfMatch("schema1", []byte(`{"a": 3}`), 4, ConfigETag([]byte(`{"a": 1}`))); !errors.Is(err, ErrPreconditionFailed) {
+				t.Errorf("Expected ErrPreconditionFailed, got %v", err)
+			}
+			if err := store.SaveConfigIfMatch("schema1", []byte(`{"a": 3}`), 4, ConfigETag([]byte(`{"a": 2}`))); err != nil {
+				t.Errorf("Failed to save config: %v", err)
+			}
+			if data, metadata, err := store.GetConfig("schema1"); err != nil || string(data) != `{"a": 3}` || metadata.SchemaVersion != 4 {
+				t.Errorf("Unexpected config: %s %+v, %v", string(data), metadata, err)
+			}
+			if err := store.SaveConfigIfMatch("missing", []byte(`{}`), 1, `"*"`); !errors.Is(err, ErrPreconditionFailed) {
+				t.Errorf("Expected ErrPreconditionFailed for missing config, got %v", err)
+			}
+
+			// 只创建的保存不覆盖已有的配置
+			if err := store.SaveConfigIfMatch("schema1", []byte(`{"a": 4}`), 4, CreateOnly); !errors.Is(err, ErrConfigExists) {
+				t.Errorf("Expected ErrConfigExists, got %v", err)
+			}
+			if err := store.SaveConfigIfMatch("schema2", []byte(`{"b": 1}`), 1, CreateOnly); err != nil {
+				t.Errorf("Failed to create config: %v", err)
+			}
+			// If-Match条件与当前内容不一致时不删除
+			if err := store.DeleteConfigIfMatch("schema2", ConfigETag([]byte(`{"b": 2}`))); !errors.Is(err, ErrPreconditionFailed) {
+				t.Errorf("Expected ErrPreconditionFailed, got %v", err)
+			}
+			if err := store.DeleteConfigIfMatch("schema2", ConfigETag([]byte(`{"b": 1}`))); err != nil {
+				t.Errorf("Failed to delete config: %v", err)
+			}
+
+			if err := store.DeleteConfig("schema1"); err != nil {
+				t.Fatalf("Failed to delete config: %v", err)
+			}
+
+			if _, _, err := store.GetConfig("schema1"); !errors.Is(err, ErrConfigNotFound) {
+				t.Errorf("Expected ErrConfigNotFound, got %v", err)
+			}
+
+			if err := store.DeleteConfig("schema1"); !errors.Is(err, ErrConfigNotFound) {
+				t.Errorf("Expected ErrConfigNotFound, got %v", err)
+			}
+		})
+	}
+}
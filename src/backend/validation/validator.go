@@ -0,0 +1,155 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// schemaURL 是编译Schema时使用的资源地址，仅用于错误信息中定位
+const schemaURL = "goci://schemas/schema.json"
+
+// Violation 描述一条校验失败项
+type Violation struct {
+	// Pointer 是失败值在文档中的JSON Pointer
+	Pointer string `json:"pointer"`
+	// Keyword 是导致失败的Schema关键字
+	Keyword string `json:"keyword"`
+	// Message 是失败原因
+	Message string `json:"message"`
+}
+
+// Error 表示文档未通过Schema校验，包含所有失败项
+type Error struct {
+	Violations []Violation
+}
+
+// Error 实现error接口
+func (e *Error) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, fmt.Sprintf("%s: %s", pointerOrRoot(v.Pointer), v.Message))
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// ValidateConfig 使用Schema校验配置文档
+//
+// 配置未通过校验时返回*Error，Schema本身无法编译时返回普通错误
+func ValidateConfig(schemaData []byte, configData []byte) error {
+	schema, err := compile(schemaData)
+	if err != nil {
+		return fmt.Errorf("error compiling schema: %w", err)
+	}
+
+	// 使用json.Number保证数值精度
+	decoder := json.NewDecoder(bytes.NewReader(configData))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return fmt.Errorf("error parsing config: %w", err)
+	}
+
+	if err := schema.Validate(document); err != nil {
+		var validationErr *jsonschema.ValidationError
+		if errors.As(err, &validationErr) {
+			return &Error{Violations: violations(validationErr)}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// ValidateSchema 使用draft-07元Schema校验Schema文档本身
+//
+// Schema不合法时返回*Error，失败项的Pointer指向Schema文档中的位置
+func ValidateSchema(schemaData []byte) error {
+	var document interface{}
+	if err := json.Unmarshal(schemaData, &document); err != nil {
+		return &Error{Violations: []Violation{{Message: "invalid JSON: " + err.Error()}}}
+	}
+
+	// 编辑器和存储都要求Schema是一个对象
+	if _, ok := document.(map[string]interface{}); !ok {
+		return &Error{Violations: []Violation{{Keyword: "type", Message: "schema must be a JSON object"}}}
+	}
+
+	if _, err := compile(schemaData); err != nil {
+		var validationErr *jsonschema.ValidationError
+		if errors.As(err, &validationErr) {
+			return &Error{Violations: violations(validationErr)}
+		}
+		return &Error{Violations: []Violation{{Message: compileMessage(err)}}}
+	}
+
+	return nil
+}
+
+// compileMessage 去掉编译错误中与用户无关的前缀
+func compileMessage(err error) string {
+	var schemaErr *jsonschema.SchemaError
+	if errors.As(err, &schemaErr) && schemaErr.Err != nil {
+		return strings.TrimPrefix(schemaErr.Err.Error(), "jsonschema: ")
+	}
+	return err.Error()
+}
+
+// compile 按draft-07编译Schema
+func compile(schemaData []byte) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	// 存储的Schema声明的是draft-07，缺少$schema时也按draft-07处理
+	compiler.Draft = jsonschema.Draft7
+	// 禁止加载外部文件或网络资源
+	compiler.LoadURL = func(url string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("external reference not allowed: %s", url)
+	}
+
+	if err := compiler.AddResource(schemaURL, bytes.NewReader(schemaData)); err != nil {
+		return nil, err
+	}
+
+	return compiler.Compile(schemaURL)
+}
+
+// violations 将校验错误树展开为叶子失败项列表
+func violations(err *jsonschema.ValidationError) []Violation {
+	var result []Violation
+	var flatten func(*jsonschema.ValidationError)
+	flatten = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			result = append(result, Violation{
+				Pointer: e.InstanceLocation,
+				Keyword: keyword(e.KeywordLocation),
+				Message: e.Message,
+			})
+			return
+		}
+		for _, cause := range e.Causes {
+			flatten(cause)
+		}
+	}
+	flatten(err)
+	return result
+}
+
+// keyword 返回关键字路径的最后一段，例如"/properties/port/maximum"返回"maximum"
+func keyword(location string) string {
+	if i := strings.LastIndex(location, "/"); i >= 0 {
+		return location[i+1:]
+	}
+	return location
+}
+
+// pointerOrRoot 在错误信息中用"/"表示文档根
+func pointerOrRoot(pointer string) string {
+	if pointer == "" {
+		return "/"
+	}
+	return pointer
+}
@@ -0,0 +1,150 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+)
+
+// 测试用Schema，覆盖常用的draft-07关键字
+var testSchema = []byte(`{
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "pattern": "^[a-z]+$"},
+		"level": {"type": "string", "enum": ["debug", "info"]},
+		"server": {
+			"type": "object",
+			"properties": {
+				"port": {"type": "integer", "minimum": 1, "maximum": 65535}
+			},
+			"required": ["port"]
+		},
+		"tags": {"type": "array", "items": {"type": "string"}}
+	},
+	"required": ["name"]
+}`)
+
+// 测试合法配置
+func TestValidateConfigValid(t *testing.T) {
+	config := []byte(`{"name": "demo", "level": "info", "server": {"port": 8080}, "tags": ["a", "b"]}`)
+	if err := ValidateConfig(testSchema, config); err != nil {
+		t.Errorf("Expected config to be valid, got %v", err)
+	}
+}
+
+// 测试非法配置返回每个失败项
+func TestValidateConfigViolations(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		pointer string
+		keyword string
+	}{
+		{"required", `{}`, "", "required"},
+		{"pattern", `{"name": "Demo"}`, "/name", "pattern"},
+		{"enum", `{"name": "demo", "level": "trace"}`, "/level", "enum"},
+		{"maximum", `{"name": "demo", "server": {"port": 70000}}`, "/server/port", "maximum"},
+		{"minimum", `{"name": "demo", "server": {"port": 0}}`, "/server/port", "minimum"},
+		{"nested required", `{"name": "demo", "server": {}}`, "/server", "required"},
+		{"items", `{"name": "demo", "tags": ["a", 1]}`, "/tags/1", "type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(testSchema, []byte(tt.config))
+
+			var validationErr *Error
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected *Error, got %v", err)
+			}
+
+			if len(validationErr.Violations) != 1 {
+				t.Fatalf("Expected 1 violation, got %+v", validationErr.Violations)
+			}
+
+			violation := validationErr.Violations[0]
+			if violation.Pointer != tt.pointer {
+				t.Errorf("Pointer is incorrect: got %q, want %q", violation.Pointer, tt.pointer)
+			}
+			if violation.Keyword != tt.keyword {
+				t.Errorf("Keyword is incorrect: got %q, want %q", violation.Keyword, tt.keyword)
+			}
+			if violation.Message == "" {
+				t.Errorf("Message is empty")
+			}
+		})
+	}
+}
+
+// 测试多个失败项同时返回
+func TestValidateConfigMultipleViolations(t *testing.T) {
+	err := ValidateConfig(testSchema, []byte(`{"level": "trace", "tags": [1]}`))
+
+	var validationErr *Error
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected *Error, got %v", err)
+	}
+
+	if len(validationErr.Violations) != 3 {
+		t.Errorf("Expected 3 violations, got %+v", validationErr.Violations)
+	}
+}
+
+// 测试无法编译的Schema和外部引用
+func TestValidateConfigBadSchema(t *testing.T) {
+	var validationErr *Error
+
+	err := ValidateConfig([]byte(`{"$ref": "file:///etc/passwd"}`), []byte(`{}`))
+	if err == nil || errors.As(err, &validationErr) {
+		t.Errorf("Expected compile error for external reference, got %v", err)
+	}
+
+	err = ValidateConfig(testSchema, []byte(`{`))
+	if err == nil || errors.As(err, &validationErr) {
+		t.Errorf("Expected parse error for malformed config, got %v", err)
+	}
+}
+
+// 测试Schema元校验
+func TestValidateSchema(t *testing.T) {
+	if err := ValidateSchema(testSchema); err != nil {
+		t.Errorf("Expected schema to be valid, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		schema  string
+		pointer string
+	}{
+		{"null", `null`, ""},
+		{"array", `[{"type": "string"}]`, ""},
+		{"garbage", `{"type": `, ""},
+		{"misspelled type", `{"type": "strnig"}`, "/type"},
+		{"bad required", `{"type": "object", "required": "name"}`, "/required"},
+		{"nested", `{"properties": {"tags": {"items": {"type": 1}}}}`, "/properties/tags/items/type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var validationErr *Error
+			if err := ValidateSchema([]byte(tt.schema)); !errors.As(err, &validationErr) {
+				t.Fatalf("Expected *Error, got %v", err)
+			}
+
+			if len(validationErr.Violations) == 0 {
+				t.Fatalf("Expected violations")
+			}
+
+			// anyOf类关键字可能在多个位置产生失败项，至少要有一项指向出错位置
+			found := false
+			for _, violation := range validationErr.Violations {
+				if violation.Pointer == tt.pointer {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("No violation at %q: %+v", tt.pointer, validationErr.Violations)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !embedui
+
+package webui
+
+import "io/fs"
+
+// Assets 在未使用 embedui 构建标签时返回 nil，表示不提供前端
+func Assets() fs.FS {
+	return nil
+}
@@ -0,0 +1,65 @@
+// Package webui 负责把构建好的Vue前端与API一起由Gin提供
+//
+// 使用 embedui 构建标签时，dist 目录会被嵌入到二进制中；否则 Assets 返回 nil，
+// 服务端只提供 /api。
+package webui
+
+import (
+	"bytes"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// indexFile 是SPA的入口页面
+const indexFile = "index.html"
+
+// Register 在路由未匹配时提供前端静态文件
+//
+// 存在的文件直接返回；其他非 /api 的GET请求回退到 index.html，交给Vue Router处理。
+func Register(r *gin.Engine, assets fs.FS) {
+	r.NoRoute(func(c *gin.Context) {
+		urlPath := c.Request.URL.Path
+		if strings.HasPrefix(urlPath, "/api/") || urlPath == "/api" ||
+			(c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean(urlPath), "/")
+		if name == "" {
+			name = indexFile
+		}
+
+		if info, err := fs.Stat(assets, name); err != nil || info.IsDir() {
+			name = indexFile
+		}
+
+		serveFile(c, assets, name)
+	})
+}
+
+// serveFile 返回单个文件，并根据是否带哈希设置缓存头
+func serveFile(c *gin.Context, assets fs.FS, name string) {
+	data, err := fs.ReadFile(assets, name)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+
+	c.Header("Cache-Control", cacheControl(name))
+	http.ServeContent(c.Writer, c.Request, name, time.Time{}, bytes.NewReader(data))
+}
+
+// cacheControl Vite输出到assets目录的文件名带内容哈希，可以永久缓存；
+// 其他文件（包括index.html）每次都需要重新验证
+func cacheControl(name string) string {
+	if strings.HasPrefix(name, "assets/") {
+		return "public, max-age=31536000, immutable"
+	}
+	return "no-cache"
+}
@@ -0,0 +1,70 @@
+package webui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 设置测试环境
+func setupTest() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/schemas", func(c *gin.Context) {
+		c.JSON(http.StatusOK, []string{})
+	})
+
+	Register(r, fstest.MapFS{
+		"index.html":           {Data: []byte("<html>app</html>")},
+		"assets/index-1a2b.js": {Data: []byte("console.log('app')")},
+		"favicon.ico":          {Data: []byte("icon")},
+	})
+	return r
+}
+
+// 测试静态文件、SPA回退和API路由
+func TestRegister(t *testing.T) {
+	r := setupTest()
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		status       int
+		body         string
+		cacheControl string
+	}{
+		{"index", http.MethodGet, "/", http.StatusOK, "<html>app</html>", "no-cache"},
+		{"hashed asset", http.MethodGet, "/assets/index-1a2b.js", http.StatusOK, "console.log('app')", "public, max-age=31536000, immutable"},
+		{"other file", http.MethodGet, "/favicon.ico", http.StatusOK, "icon", "no-cache"},
+		{"history fallback", http.MethodGet, "/schema-editor/test", http.StatusOK, "<html>app</html>", "no-cache"},
+		{"directory fallback", http.MethodGet, "/assets", http.StatusOK, "<html>app</html>", "no-cache"},
+		{"api route", http.MethodGet, "/api/schemas", http.StatusOK, "[]", ""},
+		{"unknown api route", http.MethodGet, "/api/unknown", http.StatusNotFound, "Not found", ""},
+		{"non-GET fallback", http.MethodPost, "/schemas", http.StatusNotFound, "Not found", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+
+			if !strings.Contains(w.Body.String(), tt.body) {
+				t.Errorf("Unexpected body: %s", w.Body.String())
+			}
+
+			if got := w.Header().Get("Cache-Control"); got != tt.cacheControl {
+				t.Errorf("Expected Cache-Control %q, got %q", tt.cacheControl, got)
+			}
+		})
+	}
+}
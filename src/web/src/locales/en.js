@@ -65,7 +65,9 @@ export default {
       hostname: 'Hostname',
       ipv4: 'IPv4',
       ipv6: 'IPv6'
-    }
+    },
+    conflictError: 'This schema was changed by someone else. Reload it before saving again',
+    invalidConfigsWarning: '{count} existing config(s) no longer match this schema'
   },
   fixedFields: {
     title: 'Fixed Field',
@@ -102,7 +104,7 @@ export default {
     view: 'View',
     edit: 'Edit',
     delete: 'Delete',
-    deleteConfirm: 'Confirm delete this Schema?',
+    deleteConfirm: 'Move this Schema to the trash? It can be restored until the trash is purged.',
     noSchemas: 'No Schemas',
     loadError: 'Failed to load schema list'
   }
@@ -65,7 +65,9 @@ export default {
       hostname: '主机名',
       ipv4: 'IPv4',
       ipv6: 'IPv6'
-    }
+    },
+    conflictError: '该 Schema 已被他人修改，请重新加载后再保存',
+    invalidConfigsWarning: '有 {count} 个已有配置不再符合该 Schema'
   },
   fixedFields: {
     title: '固定字段',
@@ -102,7 +104,7 @@ export default {
     view: '查看',
     edit: '编辑',
     delete: '删除',
-    deleteConfirm: '确认删除该 Schema？',
+    deleteConfirm: '确认将该 Schema 移入回收站？在回收站清理之前可以恢复。',
     noSchemas: '暂无 Schema',
     loadError: '加载 Schema 列表失败'
   }
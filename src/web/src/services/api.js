@@ -1,8 +1,8 @@
 import axios from 'axios';
 
-// 创建axios实例
+// 创建axios实例，默认与页面同源；开发时由Vite代理到后端
 const api = axios.create({
-  baseURL: 'http://localhost:8080/api',
+  baseURL: import.meta.env.VITE_API_BASE_URL || '/api',
   timeout: 10000,
   headers: {
     'Content-Type': 'application/json'
@@ -11,30 +11,82 @@ const api = axios.create({
 
 // Schema API服务
 export const schemaService = {
-  // 保存Schema
-  saveSchema(id, name, description, schemaData) {
+  // 保存Schema，传入etag时仅在Schema未被他人修改的情况下保存
+  saveSchema(id, name, description, schemaData, etag) {
     return api.post(`/schemas/${id}`, {
       metadata: {
         name: name,
         description: description
       },
       schema: schemaData
+    }, {
+      headers: etag ? { 'If-Match': etag } : {}
     });
   },
 
-  // 获取Schema
-  getSchema(id) {
-    return api.get(`/schemas/${id}`);
+  // 创建Schema，ID由服务端根据名称生成，响应的metadata.id是新ID
+  createSchema(name, description, schemaData) {
+    return api.post('/schemas', {
+      metadata: {
+        name: name,
+        description: description
+      },
+      schema: schemaData
+    });
   },
 
-  // 列出所有Schema
-  listSchemas() {
-    return api.get('/schemas');
+  // 获取Schema，params为{ bundle: true }时内联所有$ref引用
+  getSchema(id, params) {
+    return api.get(`/schemas/${id}`, { params });
   },
 
-  // 删除Schema
+  // 列出Schema，params支持limit、cursor、sort、order、namePrefix和q等参数
+  listSchemas(params) {
+    return api.get('/schemas', { params });
+  },
+
+  // 删除Schema，Schema会被移入回收站
   deleteSchema(id) {
     return api.delete(`/schemas/${id}`);
+  },
+
+  // 列出Schema历史版本
+  listVersions(id) {
+    return api.get(`/schemas/${id}/versions`);
+  },
+
+  // 获取Schema指定版本
+  getVersion(id, version) {
+    return api.get(`/schemas/${id}/versions/${version}`);
+  },
+
+  // 恢复Schema指定版本
+  restoreVersion(id, version, author, message) {
+    return api.post(`/schemas/${id}/versions/${version}/restore`, { author, message });
+  },
+
+  // 列出回收站中的Schema
+  listTrash() {
+    return api.get('/trash');
+  },
+
+  // 从回收站恢复Schema
+  restoreSchema(id) {
+    return api.post(`/trash/${id}/restore`);
+  }
+};
+
+// 存储变更事件服务
+export const eventService = {
+  // 订阅存储变更事件，返回取消订阅的函数；EventSource断线后会自动带上Last-Event-ID重连
+  subscribe(types, handler) {
+    const source = new EventSource(`${api.defaults.baseURL}/events`);
+    for (const type of [...types, 'reset']) {
+      source.addEventListener(type, (event) => {
+        handler(type, event.data ? JSON.parse(event.data) : null);
+      });
+    }
+    return () => source.close();
   }
 };
 
@@ -12,6 +12,12 @@ export default defineConfig({
       '@': fileURLToPath(new URL('./src', import.meta.url))
     }
   },
+  server: {
+    // 开发时把API请求代理到Go后端
+    proxy: {
+      '/api': 'http://localhost:8080'
+    }
+  },
   test: {
     environment: 'jsdom',
     globals: true